@@ -0,0 +1,181 @@
+/*
+ @Desc 渲染以及写入生成的代码。生成的文件带有"Code generated ... DO NOT EDIT."标记，
+ 只有带有该标记的文件会被覆盖或者删除，内容没有变化的文件不会重写，保证重复生成的结果不变
+*/
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const generatedMarker = "// Code generated by daogen. DO NOT EDIT."
+
+//generatedPattern go工具链识别生成代码的标记，见https://golang.org/s/generatedcode
+var generatedPattern = regexp.MustCompile(`(?m)^// Code generated .* DO NOT EDIT\.$`)
+
+var fileTemplate = template.Must(template.New("dao").Parse(`{{.Marker}}
+
+package {{.Package}}
+
+import (
+{{- if .Model.UsesTime}}
+	"time"
+{{end}}
+	"github.com/yinjk/go-utils/pkg/database/mysql"
+)
+{{with .Model}}
+{{- if .Generate}}
+// {{.Name}} is the model of the table {{.Table}}.
+type {{.Name}} struct {
+{{- range .Fields}}
+{{- if .Comment}}
+	// {{.Comment}}
+{{- end}}
+	{{.Name}} {{.Type}} ` + "`" + `gorm:"{{.Tag}}" json:"{{.JSON}}"` + "`" + `
+{{- end}}
+}
+
+// TableName implements mysql.Tabler.
+func ({{.Name}}) TableName() string {
+	return {{printf "%q" .Table}}
+}
+{{end}}
+// {{.Name}}Columns are the column names of the table {{.Table}}, use them in mysql.Spec and query conditions.
+var {{.Name}}Columns = struct {
+{{- range .Fields}}
+	{{.Name}} string
+{{- end}}
+}{
+{{- range .Fields}}
+	{{.Name}}: {{printf "%q" .Column}},
+{{- end}}
+}
+
+// {{.Name}}Repository is the typed repository of {{.Name}},
+// hand-written methods belong to another file of the package so that they survive the regeneration.
+type {{.Name}}Repository struct {
+	*mysql.Repository[{{.Name}}]
+}
+
+// New{{.Name}}Repository creates the {{.Name}}Repository on a *mysql.BaseOrm or *mysql.Tx.
+func New{{.Name}}Repository(db mysql.Executor) *{{.Name}}Repository {
+	return &{{.Name}}Repository{mysql.NewRepository[{{.Name}}](db)}
+}
+
+// WithTx returns a {{.Name}}Repository bound to tx.
+func (r *{{.Name}}Repository) WithTx(tx *mysql.Tx) *{{.Name}}Repository {
+	return &{{.Name}}Repository{r.Repository.WithTx(tx)}
+}
+
+// Spec creates a query condition of {{.Name}}.
+func (r *{{.Name}}Repository) Spec() *mysql.Spec {
+	return mysql.NewSpec[{{.Name}}]()
+}
+{{- $model := .}}
+{{- with .PrimaryKey}}
+
+// FindByID finds the {{$model.Name}} by the primary key, mysql.ErrRecordNotFound if it does not exist.
+func (r *{{$model.Name}}Repository) FindByID(id {{.Type}}) ({{$model.Name}}, error) {
+	return r.Repository.FindByID(id)
+}
+
+// FindByIDs finds the {{$model.Name}} records of the primary keys.
+func (r *{{$model.Name}}Repository) FindByIDs(ids ...{{.Type}}) ([]{{$model.Name}}, error) {
+	return r.List(r.Spec().In({{$model.Name}}Columns.{{.Name}}, ids))
+}
+
+// DeleteByID deletes the {{$model.Name}} by the primary key and returns the number of deleted records.
+func (r *{{$model.Name}}Repository) DeleteByID(id {{.Type}}) (int64, error) {
+	return r.Repository.DeleteByID(id)
+}
+{{- end}}
+{{- end}}
+`))
+
+//render 渲染模型的代码并使用gofmt格式化
+func render(pkg string, m *model) ([]byte, error) {
+	var buf bytes.Buffer
+	err := fileTemplate.Execute(&buf, map[string]interface{}{
+		"Marker":  generatedMarker,
+		"Package": pkg,
+		"Model":   m,
+	})
+	if err != nil {
+		return nil, err
+	}
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format %s: %v", m.fileName(), err)
+	}
+	return source, nil
+}
+
+/**
+ * 写入生成的文件
+ * @param : dir 输出目录，不存在时创建
+ * @param : files 文件名以及内容
+ * @param : clean 是否删除目录中不在files中的生成文件
+ * @param : report 报告每个文件的处理结果：write、unchanged、remove
+ * @return: 文件不是生成的文件时返回错误，不会覆盖手写的代码
+ */
+func writeFiles(dir string, files map[string][]byte, clean bool, report func(action, file string)) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		old, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err == nil && !generatedPattern.Match(old) {
+			return fmt.Errorf("%s is not a generated file, refuse to overwrite it", path)
+		}
+		if bytes.Equal(old, files[name]) {
+			report("unchanged", name)
+			continue
+		}
+		if err = os.WriteFile(path, files[name], 0644); err != nil {
+			return err
+		}
+		report("write", name)
+	}
+	if !clean {
+		return nil
+	}
+	stale, err := filepath.Glob(filepath.Join(dir, "*.gen.go"))
+	if err != nil {
+		return err
+	}
+	for _, path := range stale {
+		name := filepath.Base(path)
+		if files[name] != nil {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(string(content), generatedMarker) {
+			continue
+		}
+		if err = os.Remove(path); err != nil {
+			return err
+		}
+		report("remove", name)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yinjk/go-utils/pkg/database/mysql"
+)
+
+const extensionSource = `package dao
+
+// FindByName is a hand-written extension of the generated repository.
+func (r *ProductRepository) FindByName(name string) (Product, error) {
+	return r.FindOne(r.Spec().Eq(ProductColumns.Name, name))
+}
+`
+
+type Product struct {
+	ID        uint
+	Name      string `gorm:"size:64;not null"`
+	Price     *float64
+	ImageURL  string
+	CreatedAt time.Time
+}
+
+//generateDir 生成代码的目录需要在模块中，才能编译检查生成的代码
+func generateDir(t *testing.T) string {
+	if err := os.MkdirAll("testdata", 0755); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := os.MkdirTemp("testdata", "gen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+		_ = os.Remove("testdata")
+	})
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "shop.db")
+	orm := mysql.NewMySQL(&mysql.Config{Dialect: mysql.DialectSQLite, DSN: dsn})
+	orm.CreateTable(&Product{})
+	_ = orm.Close()
+
+	root := generateDir(t)
+	out := filepath.Join(root, "dao")
+	if err := os.MkdirAll(out, 0755); err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(out, "dropped.gen.go")
+	for name, content := range map[string]string{
+		"product.go":     extensionSource,
+		"dropped.gen.go": generatedMarker + "\n\npackage dao\n",
+	} {
+		if err := os.WriteFile(filepath.Join(out, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var actions []string
+	report := func(action, file string) {
+		actions = append(actions, action+" "+file)
+	}
+	o := options{dialect: mysql.DialectSQLite, dsn: dsn, out: out}
+	if err := run(o, report); err != nil {
+		t.Fatal(err)
+	}
+	if joined := strings.Join(actions, ","); joined != "write product.gen.go,remove dropped.gen.go" {
+		t.Fatalf("unexpected actions %s", joined)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatal("stale generated file should be removed")
+	}
+	generated, _ := os.ReadFile(filepath.Join(out, "product.gen.go"))
+	for _, want := range []string{
+		"ID        int64      `gorm:\"column:id;primary_key;AUTO_INCREMENT\" json:\"id\"`",
+		"Name      string     `gorm:\"column:name;type:varchar(64);not null\" json:\"name\"`",
+		"ImageURL  *string    `gorm:\"column:image_url;type:varchar(255)\" json:\"imageUrl\"`",
+		"func (r *ProductRepository) FindByID(id int64) (Product, error) {",
+	} {
+		if !strings.Contains(string(generated), want) {
+			t.Fatalf("generated code should contain %s:\n%s", want, generated)
+		}
+	}
+
+	actions = nil
+	if err := run(o, report); err != nil {
+		t.Fatal(err)
+	}
+	if joined := strings.Join(actions, ","); joined != "unchanged product.gen.go" {
+		t.Fatalf("regeneration should be idempotent, got %s", joined)
+	}
+	if extension, _ := os.ReadFile(filepath.Join(out, "product.go")); string(extension) != extensionSource {
+		t.Fatal("hand-written file should survive the regeneration")
+	}
+	if err := writeFiles(out, map[string][]byte{"product.go": generated}, false, report); err == nil {
+		t.Fatal("hand-written file should not be overwritten")
+	}
+
+	models := filepath.Join(root, "shop")
+	if err := os.MkdirAll(models, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(models, "models.go"), []byte(modelsSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(options{models: models}, report); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(models, "order_item.gen.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	if testing.Short() {
+		return
+	}
+	//生成的代码以及手写的扩展必须能够通过编译
+	cmd := exec.Command("go", "vet", "./"+filepath.ToSlash(out), "./"+filepath.ToSlash(models))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("generated code does not compile: %v\n%s", err, output)
+	}
+}
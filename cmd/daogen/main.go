@@ -0,0 +1,139 @@
+/*
+ @Desc daogen 根据数据库表结构或者Go模型结构体生成DAO代码，生成的代码依赖pkg/database/mysql：
+   1. 模型结构体（只有从表结构生成时才会生成），带有gorm、json标签以及TableName方法
+   2. 列名常量XxxColumns，用于mysql.Spec以及查询条件，避免在代码中手写列名
+   3. 强类型的XxxRepository，嵌入mysql.Repository并提供以主键类型为参数的FindByID、FindByIDs、DeleteByID
+
+ 从表结构生成：
+
+	daogen -dialect mysql -dsn "root:123456@tcp(127.0.0.1:3306)/shop?parseTime=true" -tables products,orders -out ./dao
+
+ 从模型结构体生成（在模型所在的目录中生成，可以配合go:generate使用）：
+
+	//go:generate go run github.com/yinjk/go-utils/cmd/daogen -models .
+
+ 每个模型生成一个<name>.gen.go文件，重复生成时内容不变的文件不会被重写。手写的扩展方法放在同一个包的其它文件中，
+ 重新生成时不会被覆盖；没有生成标记的文件不会被覆盖，不再对应任何表或模型的.gen.go文件会被删除（指定了-tables或-types时除外）。
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yinjk/go-utils/pkg/database/mysql"
+	_ "github.com/yinjk/go-utils/pkg/database/mysql/sqlite"
+)
+
+type options struct {
+	dialect string
+	dsn     string
+	tables  []string
+	prefix  string
+	models  string
+	types   []string
+	out     string
+	pkg     string
+}
+
+func main() {
+	var o options
+	var tables, types string
+	flag.StringVar(&o.dialect, "dialect", mysql.DialectMySQL, "database dialect: mysql, sqlite3")
+	flag.StringVar(&o.dsn, "dsn", "", "data source name of the database to introspect")
+	flag.StringVar(&tables, "tables", "", "comma separated tables to generate, all the tables by default")
+	flag.StringVar(&o.prefix, "prefix", "", "only generate the tables with the prefix, the prefix is trimmed from the type names")
+	flag.StringVar(&o.models, "models", "", "directory of the Go model structs, generate from the structs instead of the database")
+	flag.StringVar(&types, "types", "", "comma separated model structs to generate, all the structs with a primary key by default")
+	flag.StringVar(&o.out, "out", ".", "output directory, ignored when generating from model structs")
+	flag.StringVar(&o.pkg, "pkg", "", "package name of the generated code, the name of the output directory by default")
+	flag.Parse()
+	o.tables, o.types = splitList(tables), splitList(types)
+	report := func(action, file string) {
+		fmt.Printf("%-9s %s\n", action, filepath.Join(o.out, file))
+	}
+	if err := run(o, report); err != nil {
+		fmt.Fprintln(os.Stderr, "daogen:", err)
+		os.Exit(1)
+	}
+}
+
+//run 加载模型、生成代码并写入输出目录，report报告每个文件的处理结果
+func run(o options, report func(action, file string)) error {
+	var (
+		models []*model
+		err    error
+		clean  bool
+	)
+	if o.models != "" {
+		o.out = o.models
+		o.pkg, models, err = parseModels(o.models, o.types)
+		clean = len(o.types) == 0
+	} else {
+		if o.dsn == "" {
+			return fmt.Errorf("-dsn or -models is required")
+		}
+		models, err = loadSchema(o)
+		clean = len(o.tables) == 0
+	}
+	if err != nil {
+		return err
+	}
+	if o.pkg == "" {
+		abs, err := filepath.Abs(o.out)
+		if err != nil {
+			return err
+		}
+		o.pkg = packageName(filepath.Base(abs))
+	}
+	files := make(map[string][]byte, len(models))
+	for _, m := range models {
+		if files[m.fileName()] != nil {
+			return fmt.Errorf("duplicated model %s", m.Name)
+		}
+		if files[m.fileName()], err = render(o.pkg, m); err != nil {
+			return err
+		}
+	}
+	return writeFiles(o.out, files, clean, report)
+}
+
+//loadSchema 连接数据库，读取表结构
+func loadSchema(o options) ([]*model, error) {
+	orm, err := mysql.Open(&mysql.Config{Dialect: o.dialect, DSN: o.dsn})
+	if err != nil {
+		return nil, err
+	}
+	defer orm.Close()
+	return schemaModels(orm, o.tables, o.prefix)
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+//packageName 将目录名转换为合法的包名
+func packageName(dir string) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		if r >= 'A' && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return -1
+	}, dir)
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "dao" + name
+	}
+	return name
+}
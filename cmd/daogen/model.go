@@ -0,0 +1,220 @@
+/*
+ @Desc 模型描述以及从表结构生成模型，列类型按照mysql的类型映射为Go类型，可以为空的列映射为指针
+*/
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jinzhu/inflection"
+	"github.com/yinjk/go-utils/pkg/database/mysql"
+)
+
+//commonInitialisms 生成字段名时保持全部大写的单词，与golint以及gorm的命名规则一致
+var commonInitialisms = map[string]bool{
+	"API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true, "EOF": true, "GUID": true, "HTML": true,
+	"HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true, "LHS": true, "QPS": true, "RAM": true,
+	"RHS": true, "RPC": true, "SLA": true, "SMTP": true, "SSH": true, "TLS": true, "TTL": true, "UID": true,
+	"UI": true, "UUID": true, "URI": true, "URL": true, "UTF8": true, "VM": true, "XML": true, "XSRF": true, "XSS": true,
+}
+
+type model struct {
+	Name  string
+	Table string
+	//Generate 是否生成模型结构体，从模型结构体生成时为false
+	Generate   bool
+	Fields     []*field
+	PrimaryKey *field //唯一的主键，联合主键或者没有主键时为nil
+}
+
+type field struct {
+	Name    string
+	Column  string
+	Type    string
+	Tag     string
+	JSON    string
+	Comment string
+}
+
+//fileName 生成的文件名，使用.gen.go后缀避免表名以_linux、_test等结尾时被当作构建约束
+func (m *model) fileName() string {
+	return gorm.ToTableName(m.Name) + ".gen.go"
+}
+
+//UsesTime 生成的模型结构体是否需要导入time包
+func (m *model) UsesTime() bool {
+	if !m.Generate {
+		return false
+	}
+	for _, f := range m.Fields {
+		if strings.Contains(f.Type, "time.") {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * 读取表结构生成模型
+ * @param : orm 数据库连接
+ * @param : tables 需要生成的表，为空时生成所有以prefix开头的表
+ * @param : prefix 表名前缀，生成类型名时去掉前缀
+ * @return: 按表名排序的模型
+ */
+func schemaModels(orm *mysql.BaseOrm, tables []string, prefix string) ([]*model, error) {
+	if len(tables) == 0 {
+		var err error
+		if tables, err = orm.ListTables(prefix); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(tables)
+	models := make([]*model, 0, len(tables))
+	for _, table := range tables {
+		columns, err := orm.ListColumns(table)
+		if err != nil {
+			return nil, err
+		}
+		if len(columns) == 0 {
+			return nil, fmt.Errorf("table %s does not exist", table)
+		}
+		models = append(models, tableModel(table, prefix, columns))
+	}
+	return models, nil
+}
+
+//tableModel 根据表的列生成模型
+func tableModel(table, prefix string, columns []mysql.Column) *model {
+	m := &model{
+		Name:     goName(inflection.Singular(strings.TrimPrefix(table, prefix))),
+		Table:    table,
+		Generate: true,
+	}
+	var primaryKeys []*field
+	for _, column := range columns {
+		f := &field{
+			Name:    goName(column.Name),
+			Column:  column.Name,
+			Type:    goType(column),
+			Tag:     gormTag(column),
+			JSON:    jsonName(column.Name),
+			Comment: strings.Join(strings.Fields(column.Comment), " "),
+		}
+		m.Fields = append(m.Fields, f)
+		if column.PrimaryKey {
+			primaryKeys = append(primaryKeys, f)
+		}
+	}
+	if len(primaryKeys) == 1 {
+		m.PrimaryKey = primaryKeys[0]
+	}
+	return m
+}
+
+//goType 列类型对应的Go类型，可以为空的列使用指针
+func goType(column mysql.Column) string {
+	t := column.Type
+	base := t
+	if i := strings.IndexAny(t, "( "); i >= 0 {
+		base = t[:i]
+	}
+	var typ string
+	switch base {
+	case "tinyint":
+		typ = "int8"
+		if strings.HasPrefix(t, "tinyint(1)") {
+			typ = "bool"
+		}
+	case "bool", "boolean":
+		typ = "bool"
+	case "smallint", "year":
+		typ = "int16"
+	case "mediumint", "int":
+		typ = "int32"
+	case "integer", "bigint":
+		typ = "int64"
+	case "float":
+		typ = "float32"
+	case "double", "real", "decimal", "numeric":
+		typ = "float64"
+	case "date", "datetime", "timestamp":
+		typ = "time.Time"
+	case "binary", "varbinary", "blob", "tinyblob", "mediumblob", "longblob":
+		return "[]byte"
+	default:
+		typ = "string"
+	}
+	if strings.Contains(t, "unsigned") && strings.HasPrefix(typ, "int") {
+		typ = "u" + typ
+	}
+	if column.Nullable {
+		typ = "*" + typ
+	}
+	return typ
+}
+
+//gormTag 自增主键不指定类型，由gorm根据Go类型生成带有自增属性的列类型
+func gormTag(column mysql.Column) string {
+	settings := []string{"column:" + column.Name}
+	if !column.AutoIncrement {
+		settings = append(settings, "type:"+column.Type)
+	}
+	if column.PrimaryKey {
+		settings = append(settings, "primary_key")
+	}
+	if column.AutoIncrement {
+		settings = append(settings, "AUTO_INCREMENT")
+	}
+	if !column.Nullable && !column.PrimaryKey {
+		settings = append(settings, "not null")
+	}
+	return strings.Join(settings, ";")
+}
+
+//goName 将下划线分隔的名字转换为导出的驼峰名，如user_id转换为UserID
+func goName(name string) string {
+	var b strings.Builder
+	for _, word := range splitWords(name) {
+		if upper := strings.ToUpper(word); commonInitialisms[upper] {
+			b.WriteString(upper)
+		} else {
+			b.WriteString(title(word))
+		}
+	}
+	if b.Len() == 0 || !unicode.IsLetter(rune(b.String()[0])) {
+		return "X" + b.String()
+	}
+	return b.String()
+}
+
+//jsonName 将下划线分隔的名字转换为首字母小写的驼峰名，如user_id转换为userId
+func jsonName(name string) string {
+	words := splitWords(name)
+	for i, word := range words {
+		if i == 0 {
+			words[i] = strings.ToLower(word)
+		} else {
+			words[i] = title(word)
+		}
+	}
+	return strings.Join(words, "")
+}
+
+//title 首字母大写，全部大写的单词其余字母转换为小写，驼峰的单词保持不变
+func title(word string) string {
+	rest := word[1:]
+	if word == strings.ToUpper(word) {
+		rest = strings.ToLower(rest)
+	}
+	return strings.ToUpper(word[:1]) + rest
+}
+
+func splitWords(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
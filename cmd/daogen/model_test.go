@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/yinjk/go-utils/pkg/database/mysql"
+)
+
+func TestGoType(t *testing.T) {
+	for column, want := range map[mysql.Column]string{
+		{Type: "tinyint(1)"}:                          "bool",
+		{Type: "tinyint(4)", Nullable: true}:          "*int8",
+		{Type: "int(10) unsigned"}:                    "uint32",
+		{Type: "bigint(20) unsigned", Nullable: true}: "*uint64",
+		{Type: "integer"}:                             "int64",
+		{Type: "decimal(10,2)"}:                       "float64",
+		{Type: "datetime", Nullable: true}:            "*time.Time",
+		{Type: "varchar(64)"}:                         "string",
+		{Type: "json", Nullable: true}:                "*string",
+		{Type: "longblob", Nullable: true}:            "[]byte",
+	} {
+		if got := goType(column); got != want {
+			t.Fatalf("goType(%s) = %s, want %s", column.Type, got, want)
+		}
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for name, want := range map[string]string{
+		"id":         "ID",
+		"user_id":    "UserID",
+		"image_url":  "ImageURL",
+		"createdAt":  "CreatedAt",
+		"NAME":       "Name",
+		"2fa_secret": "X2faSecret",
+	} {
+		if got := goName(name); got != want {
+			t.Fatalf("goName(%s) = %s, want %s", name, got, want)
+		}
+	}
+	if got := jsonName("image_url"); got != "imageUrl" {
+		t.Fatalf("jsonName = %s", got)
+	}
+	m := tableModel("t_order_items", "t_", []mysql.Column{
+		{Name: "order_id", Type: "bigint(20)", PrimaryKey: true},
+		{Name: "product_id", Type: "bigint(20)", PrimaryKey: true},
+		{Name: "remark", Type: "varchar(255)", Nullable: true, Comment: "buyer's\nremark"},
+	})
+	if m.Name != "OrderItem" || m.PrimaryKey != nil || m.fileName() != "order_item.gen.go" {
+		t.Fatalf("unexpected model %+v", m)
+	}
+	if f := m.Fields[2]; f.Tag != "column:remark;type:varchar(255)" || f.Comment != "buyer's remark" {
+		t.Fatalf("unexpected field %+v", f)
+	}
+	if tag := gormTag(mysql.Column{Name: "id", Type: "bigint(20)", PrimaryKey: true, AutoIncrement: true}); tag != "column:id;primary_key;AUTO_INCREMENT" {
+		t.Fatalf("unexpected tag %s", tag)
+	}
+}
@@ -0,0 +1,259 @@
+/*
+ @Desc 从Go模型结构体生成模型。列名、主键的推断规则与gorm一致：
+   1. gorm:"-"的字段以及关联字段（同一个包中的结构体、结构体切片）不是列
+   2. 匿名嵌入的gorm.Model、mysql.SoftDelete、mysql.Audit、mysql.Versioned以及同一个包中的结构体展开为列
+   3. 带有primary_key标签的字段为主键，没有时列名为id的字段为主键
+*/
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jinzhu/inflection"
+)
+
+//knownEmbedded 其它包中常用的嵌入结构体展开后的字段
+var knownEmbedded = map[string][]*field{
+	"gorm.Model": {
+		{Name: "ID", Column: "id", Type: "uint", Tag: "primary_key"},
+		{Name: "CreatedAt", Column: "created_at", Type: "time.Time"},
+		{Name: "UpdatedAt", Column: "updated_at", Type: "time.Time"},
+		{Name: "DeletedAt", Column: "deleted_at", Type: "*time.Time"},
+	},
+	"mysql.SoftDelete": {{Name: "DeletedAt", Column: "deleted_at", Type: "*time.Time"}},
+	"mysql.Audit": {
+		{Name: "CreatedBy", Column: "created_by", Type: "string"},
+		{Name: "UpdatedBy", Column: "updated_by", Type: "string"},
+	},
+	"mysql.Versioned": {{Name: "Version", Column: "version", Type: "mysql.Version"}},
+}
+
+type structParser struct {
+	structs map[string]*ast.StructType
+	tables  map[string]string //TableName方法返回的表名，方法不是直接返回字符串常量时为空
+}
+
+/**
+ * 解析目录中的模型结构体，忽略测试文件以及生成的文件
+ * @param : dir 模型所在的目录
+ * @param : names 需要生成的结构体，为空时生成所有带有主键的导出结构体
+ * @return: pkg 包名
+ * @return: models 按结构体名排序的模型
+ */
+func parseModels(dir string, names []string) (pkg string, models []*model, err error) {
+	pkgs, err := parser.ParseDir(token.NewFileSet(), dir, func(info fs.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go") && !strings.HasSuffix(info.Name(), ".gen.go")
+	}, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(pkgs) != 1 {
+		return "", nil, fmt.Errorf("expect one package in %s, found %d", dir, len(pkgs))
+	}
+	p := &structParser{structs: make(map[string]*ast.StructType), tables: make(map[string]string)}
+	for name, astPkg := range pkgs {
+		pkg = name
+		for _, file := range astPkg.Files {
+			p.collect(file)
+		}
+	}
+	explicit := len(names) > 0
+	if !explicit {
+		for name := range p.structs {
+			if ast.IsExported(name) {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		m, keys, err := p.model(name)
+		if err != nil {
+			return "", nil, err
+		}
+		if explicit || keys > 0 {
+			models = append(models, m)
+		}
+	}
+	return pkg, models, nil
+}
+
+//collect 收集文件中的结构体以及TableName方法
+func (p *structParser) collect(file *ast.File) {
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				if spec, ok := spec.(*ast.TypeSpec); ok && spec.TypeParams == nil {
+					if st, ok := spec.Type.(*ast.StructType); ok {
+						p.structs[spec.Name.Name] = st
+					}
+				}
+			}
+		case *ast.FuncDecl:
+			if decl.Name.Name != "TableName" || decl.Recv == nil || len(decl.Recv.List) != 1 {
+				continue
+			}
+			recv := decl.Recv.List[0].Type
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+			}
+			if ident, ok := recv.(*ast.Ident); ok {
+				p.tables[ident.Name] = tableNameOf(decl)
+			}
+		}
+	}
+}
+
+//tableNameOf TableName方法直接返回的字符串常量
+func tableNameOf(decl *ast.FuncDecl) string {
+	if decl.Body == nil || len(decl.Body.List) != 1 {
+		return ""
+	}
+	ret, ok := decl.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return ""
+	}
+	if lit, ok := ret.Results[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+		table, _ := strconv.Unquote(lit.Value)
+		return table
+	}
+	return ""
+}
+
+//model 解析结构体，keys为主键的个数
+func (p *structParser) model(name string) (m *model, keys int, err error) {
+	st, ok := p.structs[name]
+	if !ok {
+		return nil, 0, fmt.Errorf("struct %s not found", name)
+	}
+	m = &model{Name: name, Table: p.tables[name]}
+	if _, ok = p.tables[name]; !ok {
+		m.Table = inflection.Plural(gorm.ToTableName(name))
+	}
+	fields, err := p.fields(st, "", map[string]bool{name: true})
+	if err != nil {
+		return nil, 0, fmt.Errorf("struct %s: %v", name, err)
+	}
+	var primaryKeys []*field
+	for _, f := range fields {
+		if _, ok := tagSettings(f.Tag)["PRIMARY_KEY"]; ok {
+			primaryKeys = append(primaryKeys, f)
+		}
+	}
+	if len(primaryKeys) == 0 {
+		for _, f := range fields {
+			if f.Column == "id" {
+				primaryKeys = append(primaryKeys, f)
+			}
+		}
+	}
+	m.Fields = fields
+	if len(primaryKeys) == 1 && types.Universe.Lookup(primaryKeys[0].Type) != nil {
+		m.PrimaryKey = primaryKeys[0]
+	}
+	return m, len(primaryKeys), nil
+}
+
+//fields 展开结构体的列，prefix为gorm:"embedded;embedded_prefix:xx"指定的列名前缀，visiting用于检测循环嵌入
+func (p *structParser) fields(st *ast.StructType, prefix string, visiting map[string]bool) ([]*field, error) {
+	var fields []*field
+	for _, f := range st.Fields.List {
+		var tag string
+		if f.Tag != nil {
+			raw, _ := strconv.Unquote(f.Tag.Value)
+			tag = reflect.StructTag(raw).Get("gorm")
+		}
+		if tag == "-" {
+			continue
+		}
+		settings := tagSettings(tag)
+		typ := types.ExprString(f.Type)
+		_, embedded := settings["EMBEDDED"]
+		if len(f.Names) == 0 || embedded {
+			if known, ok := knownEmbedded[typ]; ok {
+				for _, k := range known {
+					copied := *k
+					copied.Column = prefix + settings["EMBEDDED_PREFIX"] + copied.Column
+					fields = append(fields, &copied)
+				}
+				continue
+			}
+			if inner, ok := p.structs[typ]; ok {
+				if visiting[typ] {
+					return nil, fmt.Errorf("recursive embedded struct %s", typ)
+				}
+				visiting[typ] = true
+				innerFields, err := p.fields(inner, prefix+settings["EMBEDDED_PREFIX"], visiting)
+				delete(visiting, typ)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, innerFields...)
+				continue
+			}
+			if len(f.Names) == 0 {
+				continue //其它包中的嵌入类型无法展开
+			}
+		}
+		if p.isAssociation(f.Type) && settings["TYPE"] == "" {
+			continue
+		}
+		for _, name := range f.Names {
+			if !name.IsExported() {
+				continue
+			}
+			column := settings["COLUMN"]
+			if column == "" {
+				column = gorm.ToColumnName(name.Name)
+			}
+			fields = append(fields, &field{Name: name.Name, Column: prefix + column, Type: typ, Tag: tag})
+		}
+	}
+	return fields, nil
+}
+
+//isAssociation 同一个包中的结构体、结构体指针以及切片是关联，不是列；[]byte以外的其它切片也不是列
+func (p *structParser) isAssociation(expr ast.Expr) bool {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return p.isAssociation(t.X)
+	case *ast.ArrayType:
+		if ident, ok := t.Elt.(*ast.Ident); ok && (ident.Name == "byte" || ident.Name == "uint8") {
+			return false
+		}
+		return t.Len == nil
+	case *ast.Ident:
+		_, ok := p.structs[t.Name]
+		return ok
+	}
+	return false
+}
+
+//tagSettings 解析gorm标签，key转换为大写，与gorm的解析规则一致
+func tagSettings(tag string) map[string]string {
+	settings := make(map[string]string)
+	for _, item := range strings.Split(tag, ";") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, ":", 2)
+		key := strings.ToUpper(strings.TrimSpace(kv[0]))
+		if len(kv) == 2 {
+			settings[key] = kv[1]
+		} else {
+			settings[key] = key
+		}
+	}
+	return settings
+}
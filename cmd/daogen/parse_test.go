@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const modelsSource = `package shop
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/yinjk/go-utils/pkg/database/mysql"
+)
+
+type Order struct {
+	gorm.Model
+	Buyer    string
+	Items    []OrderItem
+	Address  Address ` + "`gorm:\"embedded;embedded_prefix:ship_\"`" + `
+	Note     string  ` + "`gorm:\"-\"`" + `
+	internal string
+}
+
+type OrderItem struct {
+	OrderID   uint   ` + "`gorm:\"primary_key\"`" + `
+	ProductID uint   ` + "`gorm:\"primary_key;column:sku_id\"`" + `
+	Payload   []byte
+}
+
+func (OrderItem) TableName() string {
+	return "t_order_items"
+}
+
+type Article struct {
+	Code      string ` + "`gorm:\"primary_key\"`" + `
+	CreatedAt time.Time
+	mysql.SoftDelete
+	mysql.Versioned
+}
+
+type Address struct {
+	City   string
+	Street string
+}
+`
+
+func TestParseModels(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "models.go"), []byte(modelsSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pkg, models, err := parseModels(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, m := range models {
+		names = append(names, m.Name+":"+m.Table)
+	}
+	if pkg != "shop" || strings.Join(names, ",") != "Article:articles,Order:orders,OrderItem:t_order_items" {
+		t.Fatalf("unexpected models %s %v", pkg, names)
+	}
+	article, order, item := models[0], models[1], models[2]
+	if columns := fieldColumns(order); columns != "id,created_at,updated_at,deleted_at,buyer,ship_city,ship_street" {
+		t.Fatalf("unexpected order columns %s", columns)
+	}
+	if order.PrimaryKey == nil || order.PrimaryKey.Type != "uint" || order.Generate {
+		t.Fatalf("unexpected order primary key %+v", order.PrimaryKey)
+	}
+	if columns := fieldColumns(item); columns != "order_id,sku_id,payload" || item.PrimaryKey != nil {
+		t.Fatalf("composite primary key: %s %+v", columns, item.PrimaryKey)
+	}
+	if columns := fieldColumns(article); columns != "code,created_at,deleted_at,version" || article.PrimaryKey.Name != "Code" {
+		t.Fatalf("unexpected article columns %s", columns)
+	}
+
+	if _, models, err = parseModels(dir, []string{"Address"}); err != nil || len(models) != 1 || models[0].PrimaryKey != nil {
+		t.Fatalf("explicit struct without primary key: %v, %v", models, err)
+	}
+	if _, _, err = parseModels(dir, []string{"Unknown"}); err == nil {
+		t.Fatal("unknown struct should be rejected")
+	}
+}
+
+func fieldColumns(m *model) string {
+	columns := make([]string, 0, len(m.Fields))
+	for _, f := range m.Fields {
+		columns = append(columns, f.Column)
+	}
+	return strings.Join(columns, ",")
+}
@@ -3,27 +3,57 @@ module github.com/yinjk/go-utils
 go 1.18
 
 require (
-	github.com/astaxie/beego v1.12.1 // indirect
-	github.com/buaazp/fasthttprouter v0.1.1 // indirect
+	github.com/alicebob/miniredis/v2 v2.30.0
 	github.com/ghodss/yaml v1.0.0
 	github.com/gin-gonic/gin v1.6.2
-	github.com/go-errors/errors v1.0.2 // indirect
+	github.com/go-sql-driver/mysql v1.4.1
 	github.com/gomodule/redigo v2.0.0+incompatible
 	github.com/influxdata/influxdb1-client v0.0.0-20191209144304-8bf82d3c094d
-	github.com/intel-go/bytebuf v0.0.0-20180921204951-e7ac7b1f8f1d // indirect
 	github.com/jinzhu/gorm v1.9.12
-	github.com/konsorten/go-windows-terminal-sequences v1.0.2 // indirect
+	github.com/jinzhu/inflection v1.0.0
 	github.com/mitchellh/hashstructure v1.0.0
 	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_golang v1.0.0
 	github.com/prometheus/common v0.9.1
+	github.com/spf13/viper v1.6.3
+	github.com/ugorji/go/codec v1.1.7
+	k8s.io/apimachinery v0.18.2
+)
+
+require (
+	github.com/alecthomas/template v0.0.0-20190718012654-fb15b899a751 // indirect
+	github.com/alecthomas/units v0.0.0-20190717042225-c3de453c63f4 // indirect
+	github.com/alicebob/gopher-json v0.0.0-20200520072559-a9ecdc9d1d3a // indirect
+	github.com/astaxie/beego v1.12.1 // indirect
+	github.com/beorn7/perks v1.0.0 // indirect
+	github.com/buaazp/fasthttprouter v0.1.1 // indirect
+	github.com/gin-contrib/sse v0.1.0 // indirect
+	github.com/go-errors/errors v1.0.2 // indirect
+	github.com/go-playground/locales v0.13.0 // indirect
+	github.com/go-playground/universal-translator v0.17.0 // indirect
+	github.com/go-playground/validator/v10 v10.2.0 // indirect
+	github.com/gogo/protobuf v1.3.1 // indirect
+	github.com/golang/protobuf v1.3.3 // indirect
+	github.com/intel-go/bytebuf v0.0.0-20180921204951-e7ac7b1f8f1d // indirect
+	github.com/konsorten/go-windows-terminal-sequences v1.0.2 // indirect
+	github.com/leodido/go-urn v1.2.0 // indirect
+	github.com/mattn/go-isatty v0.0.12 // indirect
+	github.com/mattn/go-sqlite3 v2.0.3+incompatible // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
+	github.com/prometheus/client_model v0.2.0 // indirect
+	github.com/prometheus/procfs v0.0.2 // indirect
 	github.com/shiena/ansicolor v0.0.0-20151119151921-a422bbe96644 // indirect
+	github.com/sirupsen/logrus v1.4.2 // indirect
 	github.com/snowzach/rotatefilehook v0.0.0-20180327172521-2f64f265f58c // indirect
-	github.com/spf13/viper v1.6.3
 	github.com/tidwall/gjson v1.3.6 // indirect
 	github.com/valyala/fasthttp v1.11.0 // indirect
+	github.com/yuin/gopher-lua v0.0.0-20220504180219-658193537a64 // indirect
 	golang.org/x/net v0.0.0-20191011234655-491137f69257 // indirect
+	golang.org/x/sys v0.0.0-20200116001909-b77594299b42 // indirect
 	google.golang.org/appengine v1.6.1 // indirect
+	gopkg.in/alecthomas/kingpin.v2 v2.2.6 // indirect
+	gopkg.in/inf.v0 v0.9.1 // indirect
 	gopkg.in/natefinch/lumberjack.v2 v2.0.0 // indirect
 	gopkg.in/square/go-jose.v2 v2.5.0 // indirect
-	k8s.io/apimachinery v0.18.2
+	gopkg.in/yaml.v2 v2.2.8 // indirect
 )
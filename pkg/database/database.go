@@ -15,14 +15,16 @@ import (
 var baseDao *BaseDao
 
 type Config struct {
-	Mysql  *mysql.Config
-	Redis  *redis.Config
-	Influx *influx.Config
+	Mysql       *mysql.Config
+	DataSources []*mysql.DataSourceConfig //多个命名的mysql数据源，支持读写分离
+	Redis       *redis.Config
+	Influx      *influx.Config
 }
 
 //BaseDao base dao
 type BaseDao struct {
 	db          *mysql.BaseOrm
+	dataSources *mysql.DataSources
 	influxDB    *influx.DB
 	redis       *redis.Pool
 	redisExpire int32
@@ -41,11 +43,24 @@ func New(config *Config) (dao *BaseDao) {
 		db       *mysql.BaseOrm
 		rs       *redis.Pool
 		influxDB *influx.DB
+		sources  = mysql.NewDataSources()
 	)
 	if config.Mysql != nil {
 		log.Info("init db connection")
 		db = mysql.NewMySQL(config.Mysql)
 	}
+	for _, c := range config.DataSources {
+		log.Infof("init data source %s", c.Name)
+		if _, err := sources.Open(c); err != nil {
+			log.Errorf("init data source %s error: %v", c.Name, err)
+			//关闭已经打开的连接之后再panic，调用方recover之后不会泄漏连接
+			_ = sources.Close()
+			if db != nil {
+				_ = db.Close()
+			}
+			panic(err)
+		}
+	}
 	if config.Influx != nil {
 		influxDB = influx.New(config.Influx)
 	}
@@ -54,7 +69,8 @@ func New(config *Config) (dao *BaseDao) {
 	}
 	dao = &BaseDao{
 		// mysql
-		db: db,
+		db:          db,
+		dataSources: sources,
 		// redis
 		redis: rs,
 		// influxDB
@@ -67,6 +83,11 @@ func (d *BaseDao) DB() *mysql.BaseOrm {
 	return d.db
 }
 
+//DataSource 获取名称为name的数据源，数据源不存在时panic
+func (d *BaseDao) DataSource(name string) *mysql.DataSource {
+	return d.dataSources.MustGet(name)
+}
+
 func (d *BaseDao) Redis() *redis.Pool {
 	return d.redis
 }
@@ -75,13 +96,25 @@ func (d *BaseDao) InfluxDB() *influx.DB {
 	return d.influxDB
 }
 
-// Close close the resource.
+// Close close the resource, the components not configured are skipped.
 func (d *BaseDao) Close() (err error) {
-	if err = d.redis.Close(); err != nil {
-		log.Error(err)
+	closers := make([]func() error, 0, 3)
+	if d.redis != nil {
+		closers = append(closers, d.redis.Close)
+	}
+	if d.db != nil {
+		closers = append(closers, d.db.Close)
+	}
+	if d.dataSources != nil {
+		closers = append(closers, d.dataSources.Close)
 	}
-	if err = d.db.Close(); err != nil {
-		log.Error(err)
+	for _, closer := range closers {
+		if e := closer(); e != nil {
+			log.Error(e)
+			if err == nil {
+				err = e
+			}
+		}
 	}
 	return
 }
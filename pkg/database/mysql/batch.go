@@ -0,0 +1,352 @@
+/*
+ @Desc 批量写入：BatchInsert使用多行INSERT语句，Upsert使用ON DUPLICATE KEY UPDATE，BulkUpdate使用CASE WHEN按主键更新，
+ 每batchSize条记录执行一条sql，返回每一批的影响行数。批量操作不会回写自增主键，多个批次之间也不是原子的，
+ 需要原子性时请在Transaction中执行。注册了写钩子时每一批与该批的钩子在同一个事务中执行，详见BeforeWrite。单条sql的占位符个数有上限（mysql为65535，sqlite为999，详见Dialect.MaxPlaceholders），batchSize * 每条记录的占位符数超过上限时会自动减小batchSize。
+*/
+package mysql
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/yinjk/go-utils/pkg/utils/collection/collections"
+)
+
+// BatchResult reports the rows affected by a batch operation.
+type BatchResult struct {
+	// Batches are the rows affected of every executed statement, in order.
+	Batches []int64 `json:"batches"`
+	// RowsAffected is the sum of Batches.
+	RowsAffected int64 `json:"rowsAffected"`
+}
+
+func (r *BatchResult) add(rows int64) {
+	r.Batches = append(r.Batches, rows)
+	r.RowsAffected += rows
+}
+
+/**
+ * 批量插入，每batchSize条记录生成一条多行INSERT语句，CreatedAt、UpdatedAt为零值时会被设置为当前时间，
+ * CreatedBy、UpdatedBy为空时会被设置为ctx中的操作人（参见WithContext）
+ * @param : beans 要插入的记录，结构体或结构体指针的slice
+ * @param : batchSize 每条sql插入的记录数
+ * @return: 每一批的影响行数，出错时返回已经执行成功的批次
+ */
+func (bo BaseOrm) BatchInsert(beans interface{}, batchSize int) (*BatchResult, error) {
+	return batchInsert(bo.DB, beans, batchSize, nil)
+}
+
+/**
+ * 批量插入或更新，记录的主键或唯一键冲突时更新updateColumns中的列（mysql：ON DUPLICATE KEY UPDATE）
+ * 注意mysql中插入的行影响行数为1，更新的行影响行数为2
+ * @param : beans 要插入的记录，结构体或结构体指针的slice
+ * @param : batchSize 每条sql插入的记录数
+ * @param : updateColumns 冲突时要更新的字段名或列名，为空时更新除主键和CreatedAt以外的所有列
+ * @return: 每一批的影响行数
+ */
+func (bo BaseOrm) Upsert(beans interface{}, batchSize int, updateColumns ...string) (*BatchResult, error) {
+	if updateColumns == nil {
+		updateColumns = []string{}
+	}
+	return batchInsert(bo.DB, beans, batchSize, updateColumns)
+}
+
+/**
+ * 按主键批量更新，每batchSize条记录生成一条 UPDATE ... SET col = CASE id WHEN ? THEN ? ... END WHERE id IN (...) 语句，
+ * 模型包含UpdatedAt字段时会被设置为当前时间，包含UpdatedBy字段时会被设置为ctx中的操作人，包含Version字段时版本号加1（不检查版本号），
+ * 模型包含DeletedAt字段时不会更新已逻辑删除的记录
+ * @param : beans 要更新的记录，结构体或结构体指针的slice，主键不能为空
+ * @param : batchSize 每条sql更新的记录数
+ * @param : columns 要更新的字段名或列名，不能为空
+ * @return: 每一批的影响行数
+ */
+func (bo BaseOrm) BulkUpdate(beans interface{}, batchSize int, columns ...string) (*BatchResult, error) {
+	return bulkUpdate(bo.DB, beans, batchSize, columns)
+}
+
+//BatchInsert 批量插入，详见BaseOrm.BatchInsert
+func (t Tx) BatchInsert(beans interface{}, batchSize int) (*BatchResult, error) {
+	return batchInsert(t.DB, beans, batchSize, nil)
+}
+
+//Upsert 批量插入或更新，详见BaseOrm.Upsert
+func (t Tx) Upsert(beans interface{}, batchSize int, updateColumns ...string) (*BatchResult, error) {
+	if updateColumns == nil {
+		updateColumns = []string{}
+	}
+	return batchInsert(t.DB, beans, batchSize, updateColumns)
+}
+
+//BulkUpdate 按主键批量更新，详见BaseOrm.BulkUpdate
+func (t Tx) BulkUpdate(beans interface{}, batchSize int, columns ...string) (*BatchResult, error) {
+	return bulkUpdate(t.DB, beans, batchSize, columns)
+}
+
+//BatchInsert 批量插入，详见BaseOrm.BatchInsert
+func (r *Repository[T]) BatchInsert(entities []T, batchSize int) (*BatchResult, error) {
+	return batchInsert(r.db, entities, batchSize, nil)
+}
+
+//Upsert 批量插入或更新，详见BaseOrm.Upsert
+func (r *Repository[T]) Upsert(entities []T, batchSize int, updateColumns ...string) (*BatchResult, error) {
+	if updateColumns == nil {
+		updateColumns = []string{}
+	}
+	return batchInsert(r.db, entities, batchSize, updateColumns)
+}
+
+//BulkUpdate 按主键批量更新，详见BaseOrm.BulkUpdate
+func (r *Repository[T]) BulkUpdate(entities []T, batchSize int, columns ...string) (*BatchResult, error) {
+	return bulkUpdate(r.db, entities, batchSize, columns)
+}
+
+//batchInsert updateColumns为nil时为普通插入，否则为upsert
+func batchInsert(db *gorm.DB, beans interface{}, batchSize int, updateColumns []string) (*BatchResult, error) {
+	scopes, err := batchScopes(db, beans, batchSize)
+	if err != nil || len(scopes) == 0 {
+		return &BatchResult{}, err
+	}
+	now, operator := gorm.NowFunc(), operatorOf(db)
+	for _, scope := range scopes {
+		defaults := map[string]interface{}{"CreatedAt": now, "UpdatedAt": now}
+		if operator != "" {
+			defaults[auditCreatedBy], defaults[auditUpdatedBy] = operator, operator
+		}
+		for name, value := range defaults {
+			if field, ok := scope.FieldByName(name); ok && field.IsBlank {
+				if err = field.Set(value); err != nil {
+					return &BatchResult{}, err
+				}
+			}
+		}
+	}
+	//所有记录的主键都为空时不插入主键列，由数据库生成
+	var columns []string
+	for _, field := range scopes[0].Fields() {
+		if !field.IsNormal || field.IsIgnored || (field.IsPrimaryKey && allBlank(scopes, field.Name)) {
+			continue
+		}
+		columns = append(columns, field.DBName)
+	}
+	var onConflict string
+	if updateColumns != nil {
+		if onConflict, err = upsertClause(scopes[0], columns, updateColumns); err != nil {
+			return &BatchResult{}, err
+		}
+	}
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, scopes[0].Quote(column))
+	}
+	placeholder := "(" + strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",") + ")"
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES ", scopes[0].QuotedTableName(), strings.Join(quoted, ","))
+
+	batchSize = clampBatchSize(batchSize, len(columns), 0, dialectOf(db).MaxPlaceholders())
+	result := &BatchResult{}
+	for start := 0; start < len(scopes); start += batchSize {
+		batch := scopes[start:minInt(start+batchSize, len(scopes))]
+		values := make([]string, 0, len(batch))
+		args := make([]interface{}, 0, len(batch)*len(columns))
+		for _, scope := range batch {
+			values = append(values, placeholder)
+			for _, column := range columns {
+				field, _ := scope.FieldByName(column)
+				args = append(args, field.Field.Interface())
+			}
+		}
+		op := OpCreate
+		if updateColumns != nil {
+			op = OpUpsert
+		}
+		exec := writeBatch(db, op, batch, nil, func(db *gorm.DB) *gorm.DB {
+			return execSQL(db, prefix+strings.Join(values, ",")+onConflict, args...)
+		})
+		if exec.Error != nil {
+			return result, exec.Error
+		}
+		result.add(exec.RowsAffected)
+	}
+	return result, nil
+}
+
+//upsertClause 生成冲突时的更新语句，mysql使用ON DUPLICATE KEY UPDATE，sqlite、postgres使用主键作为冲突目标的ON CONFLICT，详见Dialect
+func upsertClause(scope *gorm.Scope, insertColumns, updateColumns []string) (string, error) {
+	var columns []string
+	if len(updateColumns) == 0 {
+		for _, column := range insertColumns {
+			if field, ok := scope.FieldByName(column); ok && !field.IsPrimaryKey && field.Name != "CreatedAt" {
+				columns = append(columns, column)
+			}
+		}
+	} else {
+		for _, name := range updateColumns {
+			field, ok := scope.FieldByName(name)
+			if !ok || !field.IsNormal {
+				return "", fmt.Errorf("mysql: unknown column %q of table %s", name, scope.TableName())
+			}
+			columns = append(columns, field.DBName)
+		}
+	}
+	if len(columns) == 0 {
+		return "", errors.New("mysql: no column to update on conflict")
+	}
+	primaryKeys := make([]string, 0, len(scope.PrimaryFields()))
+	for _, field := range scope.PrimaryFields() {
+		primaryKeys = append(primaryKeys, field.DBName)
+	}
+	return dialectOf(scope.DB()).UpsertClause(scope.Quote, primaryKeys, columns), nil
+}
+
+func bulkUpdate(db *gorm.DB, beans interface{}, batchSize int, columns []string) (*BatchResult, error) {
+	if len(columns) == 0 {
+		return &BatchResult{}, errors.New("mysql: no column to update")
+	}
+	scopes, err := batchScopes(db, beans, batchSize)
+	if err != nil || len(scopes) == 0 {
+		return &BatchResult{}, err
+	}
+	first := scopes[0]
+	if len(first.PrimaryFields()) != 1 {
+		return &BatchResult{}, fmt.Errorf("mysql: bulk update requires exactly one primary key, table %s", first.TableName())
+	}
+	primaryKey := first.PrimaryField().DBName
+	var updates []string
+	for _, name := range columns {
+		field, ok := first.FieldByName(name)
+		if !ok || !field.IsNormal || field.IsPrimaryKey {
+			return &BatchResult{}, fmt.Errorf("mysql: invalid update column %q of table %s", name, first.TableName())
+		}
+		updates = append(updates, field.DBName)
+	}
+	for _, scope := range scopes {
+		if scope.PrimaryKeyZero() {
+			return &BatchResult{}, ErrMissingPrimaryKey
+		}
+	}
+	//自动更新的列
+	var (
+		autoSets []string
+		autoArgs []interface{}
+		where    string
+	)
+	if field, ok := first.FieldByName("UpdatedAt"); ok && !collections.IsStringIn(field.DBName, updates...) {
+		autoSets = append(autoSets, first.Quote(field.DBName)+" = ?")
+		autoArgs = append(autoArgs, gorm.NowFunc())
+	}
+	if field, ok := first.FieldByName(auditUpdatedBy); ok && operatorOf(db) != "" && !collections.IsStringIn(field.DBName, updates...) {
+		autoSets = append(autoSets, first.Quote(field.DBName)+" = ?")
+		autoArgs = append(autoArgs, operatorOf(db))
+	}
+	if field, ok := versionField(first); ok && !collections.IsStringIn(field.DBName, updates...) {
+		autoSets = append(autoSets, fmt.Sprintf("%s = %s + 1", first.Quote(field.DBName), first.Quote(field.DBName)))
+	}
+	if field, ok := first.FieldByName(softDeleteField); ok && !first.Search.Unscoped {
+		where = fmt.Sprintf(" AND %s IS NULL", first.Quote(field.DBName))
+	}
+
+	//每条记录的每个更新列占用WHEN ? THEN ?两个占位符，主键在IN中再占用一个
+	batchSize = clampBatchSize(batchSize, 2*len(updates)+1, len(autoArgs), dialectOf(db).MaxPlaceholders())
+	result := &BatchResult{}
+	for start := 0; start < len(scopes); start += batchSize {
+		batch := scopes[start:minInt(start+batchSize, len(scopes))]
+		var (
+			sets []string
+			args []interface{}
+			ids  []interface{}
+		)
+		for _, column := range updates {
+			var sb strings.Builder
+			sb.WriteString(first.Quote(column) + " = CASE " + first.Quote(primaryKey))
+			for _, scope := range batch {
+				field, _ := scope.FieldByName(column)
+				sb.WriteString(" WHEN ? THEN ?")
+				args = append(args, scope.PrimaryKeyValue(), field.Field.Interface())
+			}
+			sb.WriteString(" END")
+			sets = append(sets, sb.String())
+		}
+		sets = append(sets, autoSets...)
+		args = append(args, autoArgs...)
+		for _, scope := range batch {
+			ids = append(ids, scope.PrimaryKeyValue())
+		}
+		sql := fmt.Sprintf("UPDATE %s SET %s WHERE %s IN (?)%s", first.QuotedTableName(), strings.Join(sets, ", "), first.Quote(primaryKey), where)
+		exec := writeBatch(db, OpUpdate, batch, updates, func(db *gorm.DB) *gorm.DB {
+			return execSQL(db, sql, append(args, ids)...)
+		})
+		if exec.Error != nil {
+			return result, exec.Error
+		}
+		result.add(exec.RowsAffected)
+	}
+	return result, nil
+}
+
+//batchScopes 为slice中的每一条记录创建gorm.Scope
+func batchScopes(db *gorm.DB, beans interface{}, batchSize int) ([]*gorm.Scope, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("mysql: invalid batch size %d", batchSize)
+	}
+	slice := reflect.Indirect(reflect.ValueOf(beans))
+	if slice.Kind() != reflect.Slice && slice.Kind() != reflect.Array {
+		return nil, errors.New("mysql: batch operations only support slice type")
+	}
+	scopes := make([]*gorm.Scope, 0, slice.Len())
+	for i := 0; i < slice.Len(); i++ {
+		row := slice.Index(i)
+		for row.Kind() == reflect.Ptr || row.Kind() == reflect.Interface {
+			if row.IsNil() {
+				return nil, fmt.Errorf("mysql: nil element at index %d", i)
+			}
+			row = row.Elem()
+		}
+		if row.Kind() != reflect.Struct {
+			return nil, errors.New("mysql: batch operations only support slice of struct")
+		}
+		if !row.CanAddr() {
+			copied := reflect.New(row.Type())
+			copied.Elem().Set(row)
+			row = copied.Elem()
+		}
+		scopes = append(scopes, db.NewScope(row.Addr().Interface()))
+	}
+	return scopes, nil
+}
+
+//clampBatchSize 保证每条sql的占位符不超过maxPlaceholders，perRow为每条记录的占位符数，fixed为每条sql固定的占位符数
+func clampBatchSize(batchSize, perRow, fixed, maxPlaceholders int) int {
+	if perRow <= 0 {
+		return batchSize
+	}
+	if limit := (maxPlaceholders - fixed) / perRow; batchSize > limit {
+		return maxInt(limit, 1)
+	}
+	return batchSize
+}
+
+func allBlank(scopes []*gorm.Scope, name string) bool {
+	for _, scope := range scopes {
+		if field, ok := scope.FieldByName(name); ok && !field.IsBlank {
+			return false
+		}
+	}
+	return true
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,148 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type Statistic struct {
+	ID        uint `gorm:"primary_key"`
+	Metric    string
+	Value     float64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func TestBaseOrm_BatchInsert(t *testing.T) {
+	orm := newTestOrm(t, &Statistic{})
+	stats := make([]*Statistic, 0, 7)
+	for i := 0; i < 7; i++ {
+		stats = append(stats, &Statistic{Metric: fmt.Sprint("m", i), Value: float64(i)})
+	}
+	result, err := orm.BatchInsert(stats, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Batches, []int64{3, 3, 1}) || result.RowsAffected != 7 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	repo := NewRepository[Statistic](orm)
+	list, err := repo.List(NewSpec[Statistic]().OrderBy("id"))
+	if err != nil || len(list) != 7 || list[6].Metric != "m6" || list[6].ID != 7 || list[0].CreatedAt.IsZero() {
+		t.Fatalf("unexpected records %+v, %v", list, err)
+	}
+
+	if _, err = orm.BatchInsert([]Statistic{{ID: 7, Metric: "dup"}}, 10); err == nil {
+		t.Fatal("duplicate primary key should fail")
+	}
+	if _, err = orm.BatchInsert(Statistic{}, 10); err == nil {
+		t.Fatal("non slice beans should fail")
+	}
+	if result, err = orm.BatchInsert([]Statistic{}, 10); err != nil || len(result.Batches) != 0 {
+		t.Fatalf("empty slice: %+v, %v", result, err)
+	}
+}
+
+func TestBaseOrm_Upsert(t *testing.T) {
+	orm := newTestOrm(t, &Statistic{})
+	repo := NewRepository[Statistic](orm)
+	created := time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := repo.BatchInsert([]Statistic{{ID: 1, Metric: "cpu", Value: 1, CreatedAt: created}}, 10); err != nil {
+		t.Fatal(err)
+	}
+	result, err := repo.Upsert([]Statistic{{ID: 1, Metric: "cpu2", Value: 2}, {ID: 2, Metric: "mem", Value: 3}}, 10, "Value")
+	if err != nil || result.RowsAffected == 0 {
+		t.Fatalf("upsert: %+v, %v", result, err)
+	}
+	list, _ := repo.List(NewSpec[Statistic]().OrderBy("id"))
+	if len(list) != 2 || list[0].Metric != "cpu" || list[0].Value != 2 || list[1].Metric != "mem" {
+		t.Fatalf("only the chosen columns should be updated: %+v", list)
+	}
+	if _, err = repo.Upsert([]Statistic{{ID: 1, Metric: "cpu3", Value: 4}}, 10); err != nil {
+		t.Fatal(err)
+	}
+	cpu, _ := repo.FindByID(1)
+	if cpu.Metric != "cpu3" || cpu.Value != 4 || !cpu.CreatedAt.Equal(created) {
+		t.Fatalf("all columns except primary key and created_at should be updated: %+v", cpu)
+	}
+	if _, err = repo.Upsert([]Statistic{{ID: 1}}, 10, "unknown"); err == nil {
+		t.Fatal("unknown update column should fail")
+	}
+}
+
+func TestBaseOrm_BulkUpdate(t *testing.T) {
+	orm := newTestOrm(t, &Statistic{})
+	err := orm.Transaction(context.Background(), func(tx *Tx) error {
+		stats := []Statistic{{ID: 1, Metric: "a"}, {ID: 2, Metric: "b"}, {ID: 3, Metric: "c"}}
+		if _, err := tx.BatchInsert(stats, 10); err != nil {
+			return err
+		}
+		for i := range stats {
+			stats[i].Value = float64(i + 10)
+			stats[i].Metric = "changed"
+		}
+		result, err := tx.BulkUpdate(stats, 2, "value")
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(result.Batches, []int64{2, 1}) {
+			return fmt.Errorf("unexpected result %+v", result)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, _ := NewRepository[Statistic](orm).List(NewSpec[Statistic]().OrderBy("id"))
+	for i, s := range list {
+		if s.Value != float64(i+10) || s.Metric == "changed" {
+			t.Fatalf("unexpected record %+v", s)
+		}
+	}
+	if _, err = orm.BulkUpdate([]Statistic{{Value: 1}}, 10, "value"); err != ErrMissingPrimaryKey {
+		t.Fatalf("expect missing primary key, got %v", err)
+	}
+	if _, err = orm.BulkUpdate([]Statistic{{ID: 1}}, 10, "id"); err == nil {
+		t.Fatal("primary key should not be updated")
+	}
+}
+
+func TestClampBatchSize(t *testing.T) {
+	cases := []struct{ batchSize, perRow, fixed, want int }{
+		{1000, 10, 0, 1000},
+		{10000, 10, 0, 6553},
+		{10000, 21, 2, 3120},
+		{10, 70000, 0, 1},
+		{10, 0, 0, 10},
+	}
+	for _, c := range cases {
+		if got := clampBatchSize(c.batchSize, c.perRow, c.fixed, 65535); got != c.want {
+			t.Errorf("clampBatchSize(%d, %d, %d) = %d, want %d", c.batchSize, c.perRow, c.fixed, got, c.want)
+		}
+	}
+}
+
+func TestBatchInsert_SQLitePlaceholders(t *testing.T) {
+	orm := newTestOrm(t, &Product{})
+	products := make([]Product, 2000)
+	for i := range products {
+		products[i] = Product{Name: fmt.Sprintf("p%d", i), Price: i}
+	}
+	//每条记录3个占位符，batchSize 1000超过了sqlite的999个占位符
+	result, err := orm.BatchInsert(products, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RowsAffected != 2000 || len(result.Batches) != 7 {
+		t.Fatalf("unexpected result %d rows in %d batches", result.RowsAffected, len(result.Batches))
+	}
+	for i := range products {
+		products[i].ID, products[i].Price = uint(i+1), 0
+	}
+	if result, err = orm.BulkUpdate(products, 1000, "Price"); err != nil || result.RowsAffected != 2000 {
+		t.Fatalf("BulkUpdate: %v, %v", result, err)
+	}
+}
@@ -0,0 +1,195 @@
+/*
+ @Desc ctx传递。gorm v1不支持context，WithContext使用绑定了ctx的连接（包装*sql.DB、*sql.Tx）重新打开一个gorm.DB，
+ 之后的语句都通过QueryContext、ExecContext执行：
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	err := orm.WithContext(ctx).FindById(id, &user)
+	rows, err := tx.WithContext(ctx).FastQuery(sql, &result)
+
+ BaseOrm、Tx、Repository的所有方法都可以通过WithContext(ctx)获得带ctx的版本：
+   1. 查询（Find、First、Count、FastQuery、QueryIterator等）以及事务中的语句在ctx取消或者超时时会被中断
+   2. Begin、Transaction使用ctx开启事务，ctx结束时database/sql会回滚事务
+   3. 不在事务中的Create、Update、Delete在gorm自动开启的事务中执行，事务同样绑定ctx：ctx结束时写操作会被回滚，
+      但是要等正在执行的语句返回，需要立即中断写操作时请在Transaction中执行
+
+ 新的gorm.DB只保留本包的设置（写钩子、埋点等）、日志模式、Table以及Unscoped，之前通过Where、Order等添加的条件不会保留，
+ 请先调用WithContext再添加查询条件。绑定了ctx的gorm.DB不能调用DB()（gorm只支持*sql.DB），请使用BaseOrm.SQLDB。
+*/
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jinzhu/gorm"
+)
+
+//logModeSetting Open时设置的日志模式，重新打开gorm.DB时恢复
+const logModeSetting = "mysql:log_mode"
+
+//boundSettings 绑定ctx时带到新的gorm.DB上的设置
+var boundSettings = []string{hooksSetting, instrumentSetting, logModeSetting}
+
+//contextSQL 使用ctx执行语句的gorm.SQLCommon
+type contextSQL struct {
+	ctx context.Context
+	db  interface {
+		ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+		PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+		QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+		QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	}
+}
+
+func (c *contextSQL) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.db.ExecContext(c.ctx, query, args...)
+}
+
+func (c *contextSQL) Prepare(query string) (*sql.Stmt, error) {
+	return c.db.PrepareContext(c.ctx, query)
+}
+
+func (c *contextSQL) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.db.QueryContext(c.ctx, query, args...)
+}
+
+func (c *contextSQL) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.db.QueryRowContext(c.ctx, query, args...)
+}
+
+//QueryContext 供QueryStream使用，以调用方传入的ctx为准
+func (c *contextSQL) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return c.db.QueryContext(ctx, query, args...)
+}
+
+//contextDB 绑定了ctx的*sql.DB，gorm开启的事务也绑定到ctx上
+type contextDB struct {
+	contextSQL
+	sqlDB *sql.DB
+}
+
+func (c *contextDB) Begin() (*sql.Tx, error) {
+	return c.sqlDB.BeginTx(c.ctx, nil)
+}
+
+func (c *contextDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return c.sqlDB.BeginTx(ctx, opts)
+}
+
+//Close 与没有绑定ctx的BaseOrm一样关闭连接池
+func (c *contextDB) Close() error {
+	return c.sqlDB.Close()
+}
+
+//contextTx 绑定了ctx的*sql.Tx
+type contextTx struct {
+	contextSQL
+	sqlTx *sql.Tx
+}
+
+func (c *contextTx) Commit() error {
+	return c.sqlTx.Commit()
+}
+
+func (c *contextTx) Rollback() error {
+	return c.sqlTx.Rollback()
+}
+
+//WithContext 返回绑定了ctx的BaseOrm，之后的语句都使用ctx执行，ctx中的操作人用于填充审计字段
+func (bo BaseOrm) WithContext(ctx context.Context) *BaseOrm {
+	return &BaseOrm{withContext(bo.DB, ctx)}
+}
+
+//WithContext 返回绑定了ctx的Tx，详见BaseOrm.WithContext
+func (t Tx) WithContext(ctx context.Context) *Tx {
+	return &Tx{withContext(t.DB, ctx)}
+}
+
+//WithContext 返回绑定了ctx的Repository，原Repository不受影响
+func (r *Repository[T]) WithContext(ctx context.Context) *Repository[T] {
+	return &Repository[T]{db: withContext(r.db, ctx)}
+}
+
+//SQLDB 底层的*sql.DB，绑定了ctx的BaseOrm也可以使用；在事务中时panic
+func (bo BaseOrm) SQLDB() *sql.DB {
+	return sqlDBOf(bo.DB)
+}
+
+//contextOf 获取db绑定的ctx，没有绑定时返回context.Background()
+func contextOf(db *gorm.DB) context.Context {
+	if ctx, ok := boundContext(db); ok {
+		return ctx
+	}
+	return context.Background()
+}
+
+//boundContext 获取db绑定的ctx
+func boundContext(db *gorm.DB) (context.Context, bool) {
+	if ctx, ok := db.Get(contextSetting); ok {
+		if ctx, ok := ctx.(context.Context); ok && ctx != nil {
+			return ctx, true
+		}
+	}
+	return nil, false
+}
+
+/**
+ * 返回绑定了ctx的gorm.DB：使用包装了ctx的连接重新打开gorm.DB，并恢复本包的设置、日志模式、Table以及Unscoped，
+ * ctx同时保存在设置中，供审计字段、写钩子等使用
+ * @param : db 要绑定ctx的db，不会被修改
+ * @param : ctx 上下文
+ * @return: 新的db，db的连接既不是*sql.DB也不是*sql.Tx时只在设置中保存ctx
+ */
+func withContext(db *gorm.DB, ctx context.Context) *gorm.DB {
+	var conn gorm.SQLCommon
+	switch origin := unwrapSQL(db.CommonDB()).(type) {
+	case *sql.DB:
+		conn = &contextDB{contextSQL: contextSQL{ctx: ctx, db: origin}, sqlDB: origin}
+	case *sql.Tx:
+		conn = &contextTx{contextSQL: contextSQL{ctx: ctx, db: origin}, sqlTx: origin}
+	default:
+		return db.Set(contextSetting, ctx)
+	}
+	bound, err := gorm.Open(db.Dialect().GetName(), conn)
+	if err != nil {
+		return db.Set(contextSetting, ctx)
+	}
+	for _, name := range boundSettings {
+		if value, ok := db.Get(name); ok {
+			bound.InstantSet(name, value)
+		}
+	}
+	bound.InstantSet(contextSetting, ctx)
+	if logMode, ok := db.Get(logModeSetting); ok {
+		bound.LogMode(logMode.(bool))
+	}
+	bound.BlockGlobalUpdate(db.HasBlockGlobalUpdate())
+	if scope := db.NewScope(nil); scope.Search.Unscoped {
+		bound = bound.Unscoped()
+	}
+	//设置了Table时TableName与清除了条件的db不同
+	if table := db.NewScope(nil).TableName(); table != db.New().NewScope(nil).TableName() {
+		bound = bound.Table(table)
+	}
+	return bound
+}
+
+//unwrapSQL 获取被绑定了ctx的*sql.DB、*sql.Tx，重复调用WithContext时以最后一次的ctx为准
+func unwrapSQL(common gorm.SQLCommon) gorm.SQLCommon {
+	switch c := common.(type) {
+	case *contextDB:
+		return c.sqlDB
+	case *contextTx:
+		return c.sqlTx
+	}
+	return common
+}
+
+//sqlDBOf 获取db底层的*sql.DB，绑定了ctx的gorm.DB调用DB()会panic，本包内部统一使用该方法
+func sqlDBOf(db *gorm.DB) *sql.DB {
+	if sqlDB, ok := unwrapSQL(db.CommonDB()).(*sql.DB); ok {
+		return sqlDB
+	}
+	return db.DB()
+}
@@ -0,0 +1,114 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithContext_Cancel(t *testing.T) {
+	orm := newProducts(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewRepository[Product](orm).WithContext(ctx).List(nil); err != context.Canceled {
+		t.Fatalf("expect canceled, got %v", err)
+	}
+	if _, err := orm.WithContext(ctx).Exec("UPDATE products SET stock = 0"); err != context.Canceled {
+		t.Fatalf("expect canceled, got %v", err)
+	}
+
+	//执行中的查询在超时之后被中断
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	var counts []int64
+	err := orm.WithContext(ctx).FastQuery(`WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 100000000)
+		SELECT COUNT(*) FROM c`, &counts)
+	if err == nil || time.Since(start) > 5*time.Second {
+		t.Fatalf("long query should be interrupted, got %v after %v", err, time.Since(start))
+	}
+
+	//gorm的查询同样会被中断
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	var result struct{ Total int64 }
+	err = orm.WithContext(ctx).Raw(`WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 100000000)
+		SELECT COUNT(*) AS total FROM c`).Scan(&result).Error
+	if err == nil || time.Since(start) > 5*time.Second {
+		t.Fatalf("long gorm query should be interrupted, got %v after %v", err, time.Since(start))
+	}
+
+	//原来的BaseOrm不受影响
+	if n, err := orm.Count(&Product{}, nil); err != nil || n != 4 {
+		t.Fatalf("count: %d, %v", n, err)
+	}
+	if orm.DB.DB() == nil {
+		t.Fatal("the original orm should keep the *sql.DB")
+	}
+}
+
+func TestWithContext_Tx(t *testing.T) {
+	orm := newProducts(t)
+	ctx := WithOperator(context.Background(), "alice")
+	tx := orm.WithContext(ctx).Begin()
+	if err := tx.Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.WithContext(ctx).Exec("UPDATE products SET stock = 100 WHERE name = ?", "apple"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.WithContext(ctx).Commit().Error; err != nil {
+		t.Fatal(err)
+	}
+	var apple Product
+	if err := orm.FindOneEq(&apple, "name", "apple"); err != nil || apple.Stock != 100 {
+		t.Fatalf("tx should be committed: %+v, %v", apple, err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	err := orm.Transaction(canceled, func(tx *Tx) error {
+		cancel()
+		_, err := tx.WithContext(canceled).Exec("UPDATE products SET stock = 0")
+		return err
+	}, WithRetry(0, 0))
+	if err == nil {
+		t.Fatal("transaction should fail after ctx canceled")
+	}
+	if err = orm.FindOneEq(&apple, "name", "apple"); err != nil || apple.Stock != 100 {
+		t.Fatalf("canceled transaction should be rolled back: %+v, %v", apple, err)
+	}
+}
+
+func TestWithContext_KeepsSQLDB(t *testing.T) {
+	orm := newProducts(t)
+	ctx := context.Background()
+	bound := orm.WithContext(ctx)
+	if bound.SQLDB() != orm.DB.DB() {
+		t.Fatal("WithContext should keep the *sql.DB")
+	}
+	migrator := NewMigrator(bound)
+	_ = migrator.Register(&Migration{Version: 1, Name: "add_index", UpSQL: "CREATE INDEX idx_products_name ON products (name)"})
+	if done, err := migrator.Up(ctx); err != nil || len(done) != 1 {
+		t.Fatalf("migrate with a bound ctx: %+v, %v", done, err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	var product Product
+	if err := orm.WithContext(canceled).FindById(1, &product); err != context.Canceled {
+		t.Fatalf("expect canceled, got %v", err)
+	}
+	if _, err := orm.WithContext(canceled).Count(&Product{}, nil); err != context.Canceled {
+		t.Fatalf("expect canceled, got %v", err)
+	}
+	if err := orm.WithContext(canceled).Create(&Product{Name: "fig"}); err != context.Canceled {
+		t.Fatalf("expect canceled, got %v", err)
+	}
+	if tx := orm.WithContext(canceled).Begin(); tx.Error == nil {
+		t.Fatal("begin with a canceled ctx should fail")
+	}
+	if n, err := orm.Count(&Product{}, nil); err != nil || n != 4 {
+		t.Fatalf("count: %d, %v", n, err)
+	}
+}
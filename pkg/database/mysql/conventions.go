@@ -0,0 +1,172 @@
+/*
+ @Desc 实体约定，按需开启：
+   1. 逻辑删除：实体包含DeletedAt *time.Time字段（可以嵌入SoftDelete），Delete时只设置deleted_at，
+      BaseOrm、Tx、Repository的查询都会过滤已删除的记录，WithDeleted可以查询已删除的记录，WithDeleted().Delete为物理删除
+   2. 审计字段：实体包含CreatedBy、UpdatedBy字段（可以嵌入Audit），创建、更新时从ctx中获取操作人（WithOperator）自动填充，
+      ctx通过WithContext或者Transaction传入
+   3. 乐观锁：实体包含Version类型的字段（可以嵌入Versioned），Update、Updates、Save时以当前版本号作为条件并将版本号加1，
+      记录已经被其他人修改（版本号不一致）时返回ErrStaleObject，更新之前必须先查询出记录的版本号
+
+	type Article struct {
+		ID    uint
+		Title string
+		mysql.SoftDelete
+		mysql.Audit
+		mysql.Versioned
+	}
+	ctx = mysql.WithOperator(ctx, "admin")
+	err := mysql.NewRepository[Article](orm.WithContext(ctx)).Update(&article)
+	if errors.Is(err, mysql.ErrStaleObject) {
+		//提示用户刷新之后重新编辑
+	}
+*/
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	contextSetting     = "mysql:context"
+	versionSetting     = "mysql:version"
+	auditCreatedBy     = "CreatedBy"
+	auditUpdatedBy     = "UpdatedBy"
+	softDeleteField    = "DeletedAt"
+	updateColumnOption = "gorm:update_column"
+)
+
+//ErrStaleObject 乐观锁冲突，记录已经被其他人修改或者已经被删除
+var ErrStaleObject = errors.New("mysql: stale object, the record has been modified by others")
+
+var versionType = reflect.TypeOf(Version(0))
+
+type operatorKey struct{}
+
+// Version is the type of an optimistic locking column.
+type Version int64
+
+// SoftDelete enables logical deletion when embedded in a model.
+type SoftDelete struct {
+	DeletedAt *time.Time `gorm:"index" json:"deletedAt,omitempty"`
+}
+
+// Audit records the operator creating and last updating a record when embedded in a model.
+type Audit struct {
+	CreatedBy string `gorm:"size:64" json:"createdBy"`
+	UpdatedBy string `gorm:"size:64" json:"updatedBy"`
+}
+
+// Versioned enables optimistic locking when embedded in a model.
+type Versioned struct {
+	Version Version `gorm:"not null;default:0" json:"version"`
+}
+
+//gorm在同时指定Before和After时会重复注册回调，这里只指定其中一个
+func init() {
+	gorm.DefaultCallback.Create().Before("gorm:create").Register("mysql:audit_create", auditCreateCallback)
+	gorm.DefaultCallback.Update().Before("gorm:update").Register("mysql:audit_update", auditUpdateCallback)
+	gorm.DefaultCallback.Update().Before("gorm:update").Register("mysql:lock_version", lockVersionCallback)
+	gorm.DefaultCallback.Update().After("gorm:update").Register("mysql:check_version", checkVersionCallback)
+}
+
+//WithOperator 返回带有操作人的ctx，用于填充审计字段
+func WithOperator(ctx context.Context, operator string) context.Context {
+	return context.WithValue(ctx, operatorKey{}, operator)
+}
+
+//OperatorFrom 获取ctx中的操作人
+func OperatorFrom(ctx context.Context) string {
+	operator, _ := ctx.Value(operatorKey{}).(string)
+	return operator
+}
+
+//WithDeleted 返回包含已逻辑删除记录的BaseOrm，在其上执行Delete为物理删除
+func (bo BaseOrm) WithDeleted() *BaseOrm {
+	return &BaseOrm{bo.DB.Unscoped()}
+}
+
+//WithDeleted 返回包含已逻辑删除记录的Tx，详见BaseOrm.WithDeleted
+func (t Tx) WithDeleted() *Tx {
+	return &Tx{t.DB.Unscoped()}
+}
+
+//WithDeleted 返回包含已逻辑删除记录的Repository，原Repository不受影响
+func (r *Repository[T]) WithDeleted() *Repository[T] {
+	return &Repository[T]{db: r.db.Unscoped()}
+}
+
+//operatorOf 获取db绑定的ctx中的操作人
+func operatorOf(db *gorm.DB) string {
+	return OperatorFrom(contextOf(db))
+}
+
+//auditCreateCallback 创建时填充为空的CreatedBy、UpdatedBy
+func auditCreateCallback(scope *gorm.Scope) {
+	operator := operatorOf(scope.DB())
+	if operator == "" || scope.HasError() {
+		return
+	}
+	for _, name := range []string{auditCreatedBy, auditUpdatedBy} {
+		if field, ok := scope.FieldByName(name); ok && field.IsBlank {
+			scope.Err(field.Set(operator))
+		}
+	}
+}
+
+//auditUpdateCallback 更新时填充UpdatedBy，UpdateColumn不会修改审计字段（与UpdatedAt一致）
+func auditUpdateCallback(scope *gorm.Scope) {
+	if _, ok := scope.Get(updateColumnOption); ok || scope.HasError() {
+		return
+	}
+	if operator := operatorOf(scope.DB()); operator != "" {
+		if _, ok := scope.FieldByName(auditUpdatedBy); ok {
+			scope.Err(scope.SetColumn(auditUpdatedBy, operator))
+		}
+	}
+}
+
+//versionField 获取实体中Version类型的字段
+func versionField(scope *gorm.Scope) (*gorm.Field, bool) {
+	if scope.IndirectValue().Kind() != reflect.Struct {
+		return nil, false
+	}
+	for _, field := range scope.Fields() {
+		if field.IsNormal && field.Struct.Type == versionType {
+			return field, true
+		}
+	}
+	return nil, false
+}
+
+//lockVersionCallback 以当前版本号作为更新条件，并将版本号加1
+func lockVersionCallback(scope *gorm.Scope) {
+	if _, ok := scope.Get(updateColumnOption); ok || scope.HasError() {
+		return
+	}
+	field, ok := versionField(scope)
+	if !ok {
+		return
+	}
+	current := field.Field.Interface().(Version)
+	scope.Search.Where(fmt.Sprintf("%s.%s = ?", scope.QuotedTableName(), scope.Quote(field.DBName)), current)
+	scope.InstanceSet(versionSetting, current)
+	scope.Err(scope.SetColumn(field, current+1))
+}
+
+//checkVersionCallback 没有更新到记录时说明版本号已经变化，恢复实体的版本号并返回ErrStaleObject
+func checkVersionCallback(scope *gorm.Scope) {
+	current, ok := scope.InstanceGet(versionSetting)
+	if !ok || scope.HasError() || scope.DB().RowsAffected > 0 {
+		return
+	}
+	if field, ok := versionField(scope); ok {
+		_ = field.Set(current)
+	}
+	scope.Err(ErrStaleObject)
+}
@@ -0,0 +1,148 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type Article struct {
+	ID        uint `gorm:"primary_key"`
+	Title     string
+	Views     int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	SoftDelete
+	Audit
+	Versioned
+}
+
+func newArticles(t *testing.T, titles ...string) (*BaseOrm, *Repository[Article]) {
+	orm := newTestOrm(t, &Article{})
+	repo := NewRepository[Article](orm)
+	for _, title := range titles {
+		if err := repo.Create(&Article{Title: title}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return orm, repo
+}
+
+func TestConventions_SoftDelete(t *testing.T) {
+	orm, repo := newArticles(t, "a", "b", "c")
+	if err := repo.Delete(&Article{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.FindByID(1); !IsRecordNotFound(err) {
+		t.Fatalf("deleted record should not be found, got %v", err)
+	}
+	var articles []Article
+	if err := orm.ListAll(&articles); err != nil || len(articles) != 2 {
+		t.Fatalf("ListAll: %d, %v", len(articles), err)
+	}
+	if count, _ := orm.Count(&Article{}, nil); count != 2 {
+		t.Fatalf("Count should skip deleted records, got %d", count)
+	}
+	tx := orm.Begin()
+	page, err := tx.ListWithPage(&articles, true, Pagination{Page: 1, PageSize: 10}, "id", nil)
+	tx.Rollback()
+	if err != nil || page.TotalCount != 3 {
+		t.Fatalf("ListWithPage with deleted: %+v, %v", page, err)
+	}
+	deleted, err := repo.WithDeleted().FindByID(1)
+	if err != nil || deleted.DeletedAt == nil {
+		t.Fatalf("WithDeleted should find the deleted record: %+v, %v", deleted, err)
+	}
+
+	result, err := repo.BulkUpdate([]Article{{ID: 1, Views: 9}, {ID: 2, Views: 9}}, 10, "Views")
+	if err != nil || result.RowsAffected != 1 {
+		t.Fatalf("BulkUpdate should skip deleted records: %+v, %v", result, err)
+	}
+	if n, err := repo.WithDeleted().DeleteByID(1); err != nil || n != 1 {
+		t.Fatalf("hard delete: %d, %v", n, err)
+	}
+	if count, _ := repo.WithDeleted().Count(nil); count != 2 {
+		t.Fatalf("record should be removed physically, count %d", count)
+	}
+}
+
+func TestConventions_Audit(t *testing.T) {
+	orm, repo := newArticles(t)
+	ctx := WithOperator(context.Background(), "alice")
+	article := &Article{Title: "a"}
+	if err := repo.WithContext(ctx).Create(article); err != nil || article.CreatedBy != "alice" || article.UpdatedBy != "alice" {
+		t.Fatalf("create: %+v, %v", article, err)
+	}
+	article.Title = "b"
+	if err := orm.WithContext(WithOperator(ctx, "bob")).Update(article); err != nil {
+		t.Fatal(err)
+	}
+	found, _ := repo.FindByID(article.ID)
+	if found.CreatedBy != "alice" || found.UpdatedBy != "bob" {
+		t.Fatalf("update should only change UpdatedBy: %+v", found)
+	}
+
+	err := orm.Transaction(WithOperator(ctx, "carol"), func(tx *Tx) error {
+		return tx.Create(&Article{Title: "c"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = orm.WithContext(ctx).BatchInsert([]*Article{{Title: "d"}, {Title: "e", Audit: Audit{CreatedBy: "dave"}}}, 10); err != nil {
+		t.Fatal(err)
+	}
+	articles, _ := repo.List(nil)
+	var creators []string
+	for _, a := range articles {
+		creators = append(creators, a.CreatedBy)
+	}
+	if joined := joinStrings(creators); joined != "alice,carol,alice,dave" {
+		t.Fatalf("unexpected creators %s", joined)
+	}
+}
+
+func TestConventions_OptimisticLock(t *testing.T) {
+	orm, repo := newArticles(t, "a")
+	first, _ := repo.FindByID(1)
+	second, _ := repo.FindByID(1)
+
+	first.Title = "first"
+	if err := repo.Update(&first); err != nil || first.Version != 1 {
+		t.Fatalf("update: %+v, %v", first, err)
+	}
+	second.Title = "second"
+	if err := repo.Update(&second); err != ErrStaleObject || second.Version != 0 {
+		t.Fatalf("expect stale object, got %v, version %d", err, second.Version)
+	}
+	if err := orm.Updates(&second, map[string]interface{}{"title": "second"}); err != ErrStaleObject {
+		t.Fatalf("Updates: expect stale object, got %v", err)
+	}
+	if err := orm.Save(&second); err != ErrStaleObject {
+		t.Fatalf("Save: expect stale object, got %v", err)
+	}
+	if found, _ := repo.FindByID(1); found.Title != "first" || found.Version != 1 {
+		t.Fatalf("stale updates should not be applied: %+v", found)
+	}
+
+	second, _ = repo.FindByID(1)
+	if err := repo.Updates(&second, map[string]interface{}{"views": 3}); err != nil || second.Version != 2 {
+		t.Fatalf("update after reload: %+v, %v", second, err)
+	}
+	if _, err := repo.BulkUpdate([]Article{{ID: 1, Views: 5}}, 10, "views"); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := repo.FindByID(1); found.Views != 5 || found.Version != 3 {
+		t.Fatalf("BulkUpdate should increase the version: %+v", found)
+	}
+}
+
+func joinStrings(values []string) string {
+	joined := ""
+	for i, v := range values {
+		if i > 0 {
+			joined += ","
+		}
+		joined += v
+	}
+	return joined
+}
@@ -0,0 +1,351 @@
+/*
+ @Desc 基于游标（keyset）的分页，使用上一页最后一条记录的排序列的值作为查询条件，不需要OFFSET，
+ 在大表上的性能不会随着页数增加而下降，并且在并发插入时也不会出现重复或者遗漏的记录。
+ 排序列的组合必须是唯一的（如：created_at + id），游标对调用方是不透明的字符串。
+ 排序列不能为NULL：NULL不能用比较符号比较，keyset条件会静默地跳过这些记录，因此指针以及sql.NullXxx类型的字段不能作为排序列。
+*/
+package mysql
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrInvalidCursor is returned when the cursor is malformed or was created with a different order.
+var ErrInvalidCursor = errors.New("mysql: invalid cursor")
+
+// CursorPagination is the request of a cursor paging query.
+type CursorPagination struct {
+	// Cursor is the NextCursor or PrevCursor of the previous response, empty for the first page.
+	Cursor string `json:"cursor"`
+	// PageSize is the max number of records of the page.
+	PageSize int `json:"pageSize"`
+	// OrderBy are the field names or columns to order by, "-" prefix means descending,
+	// the combination must be unique, e.g. []string{"-created_at", "id"}. Default to the primary key.
+	OrderBy []string `json:"orderBy"`
+	// WithTotal additionally counts the records matching the query.
+	WithTotal bool `json:"withTotal"`
+}
+
+// CursorPageData is the result of a cursor paging query, it has the same json shape as PageData plus the cursors.
+type CursorPageData struct {
+	PageSize   int         `json:"pageSize"`
+	TotalCount int         `json:"totalCount,omitempty"`
+	NextCursor string      `json:"nextCursor,omitempty"`
+	PrevCursor string      `json:"prevCursor,omitempty"`
+	Data       interface{} `json:"data"`
+}
+
+// CursorPage is the typed CursorPageData returned by Repository.
+type CursorPage[T any] struct {
+	PageSize   int    `json:"pageSize"`
+	TotalCount int    `json:"totalCount,omitempty"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+	Data       []T    `json:"data"`
+}
+
+type cursorColumn struct {
+	name   string
+	column string
+	desc   bool
+	typ    reflect.Type
+	index  []int //字段在模型中的索引路径，包含匿名嵌入以及gorm:"embedded"的结构体
+}
+
+//cursorToken 游标的内容，Order用于校验游标与本次查询的排序是否一致
+type cursorToken struct {
+	Order  string            `json:"o"`
+	Prev   bool              `json:"p,omitempty"`
+	Values []json.RawMessage `json:"v"`
+}
+
+/**
+ * 游标分页查询
+ * @param : result 查询结果，必须是slice的指针
+ * @param : pagination 游标分页器
+ * @param : query 查询条件，可以是*Spec，Spec中的排序和limit会被忽略
+ * @param : args 查询参数
+ * @return: 当前页的数据以及上一页、下一页的游标，没有上一页或下一页时游标为空
+ */
+func (bo BaseOrm) ListWithCursor(result interface{}, pagination CursorPagination, query interface{}, args ...interface{}) (*CursorPageData, error) {
+	return listWithCursor(bo.DB, result, pagination, query, args...)
+}
+
+//ListWithCursor 游标分页查询，详见BaseOrm.ListWithCursor
+func (t Tx) ListWithCursor(result interface{}, pagination CursorPagination, query interface{}, args ...interface{}) (*CursorPageData, error) {
+	return listWithCursor(t.DB, result, pagination, query, args...)
+}
+
+//Cursor 游标分页查询，详见BaseOrm.ListWithCursor
+func (r *Repository[T]) Cursor(pagination CursorPagination, query interface{}, args ...interface{}) (*CursorPage[T], error) {
+	data := make([]T, 0)
+	page, err := listWithCursor(r.db, &data, pagination, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &CursorPage[T]{
+		PageSize:   page.PageSize,
+		TotalCount: page.TotalCount,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+		Data:       data,
+	}, nil
+}
+
+func listWithCursor(db *gorm.DB, result interface{}, pagination CursorPagination, query interface{}, args ...interface{}) (*CursorPageData, error) {
+	resultValue := reflect.ValueOf(result)
+	if resultValue.Kind() != reflect.Ptr || resultValue.Elem().Kind() != reflect.Slice {
+		return nil, errors.New("the result only supports slice type")
+	}
+	if pagination.PageSize <= 0 {
+		return nil, fmt.Errorf("mysql: invalid page size %d", pagination.PageSize)
+	}
+	sliceType := resultValue.Elem().Type()
+	elemType := sliceType.Elem()
+	for elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	columns, err := cursorColumns(db, elemType, pagination.OrderBy)
+	if err != nil {
+		return nil, err
+	}
+	order := cursorOrder(columns)
+	page := &CursorPageData{PageSize: pagination.PageSize}
+
+	db = where(db, query, args...)
+	if pagination.WithTotal {
+		if err = db.Model(reflect.New(elemType).Interface()).Count(&page.TotalCount).Error; err != nil {
+			return nil, err
+		}
+	}
+	prev := false
+	if pagination.Cursor != "" {
+		token, values, err := decodeCursor(pagination.Cursor, order, columns)
+		if err != nil {
+			return nil, err
+		}
+		prev = token.Prev
+		sql, keysetArgs := keysetCondition(db, columns, values, prev)
+		db = db.Where(sql, keysetArgs...)
+	}
+	for _, c := range columns {
+		direction := ""
+		if c.desc != prev {
+			direction = " DESC"
+		}
+		db = db.Order(quoteColumn(db, c.column) + direction)
+	}
+	rows := reflect.New(sliceType)
+	if err = db.Limit(pagination.PageSize + 1).Find(rows.Interface()).Error; err != nil {
+		return nil, err
+	}
+	rows = rows.Elem()
+	more := rows.Len() > pagination.PageSize
+	if more {
+		rows = rows.Slice(0, pagination.PageSize)
+	}
+	if prev {
+		reverseSlice(rows)
+	}
+	if rows.Len() > 0 {
+		//向后翻页时如果是从游标开始的，那么一定存在上一页；向前翻页时一定存在下一页
+		hasNext, hasPrev := more, pagination.Cursor != ""
+		if prev {
+			hasNext, hasPrev = true, more
+		}
+		if hasNext {
+			if page.NextCursor, err = encodeCursor(order, false, columns, rows.Index(rows.Len()-1)); err != nil {
+				return nil, err
+			}
+		}
+		if hasPrev {
+			if page.PrevCursor, err = encodeCursor(order, true, columns, rows.Index(0)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	resultValue.Elem().Set(rows)
+	page.Data = result
+	return page, nil
+}
+
+//cursorColumns 解析排序列，默认使用主键排序
+func cursorColumns(db *gorm.DB, elemType reflect.Type, orderBy []string) ([]cursorColumn, error) {
+	scope := db.NewScope(reflect.New(elemType).Interface())
+	if len(orderBy) == 0 {
+		orderBy = []string{scope.PrimaryKey()}
+	}
+	fields := scope.GetModelStruct().StructFields
+	columns := make([]cursorColumn, 0, len(orderBy))
+	for _, order := range orderBy {
+		name := strings.TrimPrefix(order, "-")
+		var found *gorm.StructField
+		for _, field := range fields {
+			if !field.IsIgnored && (field.Name == name || field.DBName == name) {
+				found = field
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("mysql: unknown order column %q of model %v", name, elemType)
+		}
+		if isNullable(found.Struct.Type) {
+			return nil, fmt.Errorf("mysql: order column %q of model %v is nullable, it can not be used by the cursor", name, elemType)
+		}
+		index, ok := fieldIndex(elemType, found.Names)
+		if !ok {
+			return nil, fmt.Errorf("mysql: can not find order column %q in model %v", name, elemType)
+		}
+		columns = append(columns, cursorColumn{
+			name:   found.Name,
+			column: found.DBName,
+			desc:   strings.HasPrefix(order, "-"),
+			typ:    found.Struct.Type,
+			index:  index,
+		})
+	}
+	return columns, nil
+}
+
+//isNullable 指针以及sql.NullString这种带有Valid标记的Scanner可以保存NULL
+func isNullable(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		return true
+	}
+	if t.Kind() != reflect.Struct || !reflect.PtrTo(t).Implements(scannerType) {
+		return false
+	}
+	valid, ok := t.FieldByName("Valid")
+	return ok && valid.Type.Kind() == reflect.Bool
+}
+
+//fieldIndex 根据gorm的字段名路径（嵌入的结构体名称...字段名）获取字段的索引路径
+func fieldIndex(t reflect.Type, names []string) ([]int, bool) {
+	var index []int
+	for _, name := range names {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return nil, false
+		}
+		field, ok := t.FieldByName(name)
+		if !ok {
+			return nil, false
+		}
+		index = append(index, field.Index...)
+		t = field.Type
+	}
+	return index, len(index) > 0
+}
+
+//fieldByIndex 获取row中的字段，路径上的嵌入指针为nil时返回false
+func fieldByIndex(row reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && row.Kind() == reflect.Ptr {
+			if row.IsNil() {
+				return reflect.Value{}, false
+			}
+			row = row.Elem()
+		}
+		row = row.Field(x)
+	}
+	return row, true
+}
+
+func cursorOrder(columns []cursorColumn) string {
+	orders := make([]string, 0, len(columns))
+	for _, c := range columns {
+		if c.desc {
+			orders = append(orders, "-"+c.column)
+		} else {
+			orders = append(orders, c.column)
+		}
+	}
+	return strings.Join(orders, ",")
+}
+
+/**
+ * 生成keyset查询条件，如排序为a, b时向后翻页的条件为：(a > ?) OR (a = ? AND b > ?)
+ * @param : columns 排序列
+ * @param : values 游标中排序列的值
+ * @param : prev 是否是向前翻页，向前翻页时比较符号取反
+ * @return: 查询条件以及参数
+ */
+func keysetCondition(db *gorm.DB, columns []cursorColumn, values []interface{}, prev bool) (string, []interface{}) {
+	var (
+		ors  []string
+		args []interface{}
+	)
+	for i, c := range columns {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, quoteColumn(db, columns[j].column)+" = ?")
+			args = append(args, values[j])
+		}
+		op := " > ?"
+		if c.desc != prev {
+			op = " < ?"
+		}
+		ands = append(ands, quoteColumn(db, c.column)+op)
+		args = append(args, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+func encodeCursor(order string, prev bool, columns []cursorColumn, row reflect.Value) (string, error) {
+	row = reflect.Indirect(row)
+	token := cursorToken{Order: order, Prev: prev, Values: make([]json.RawMessage, 0, len(columns))}
+	for _, c := range columns {
+		field, ok := fieldByIndex(row, c.index)
+		if !ok {
+			return "", fmt.Errorf("mysql: order column %q is NULL, it can not be used by the cursor", c.name)
+		}
+		value, err := json.Marshal(field.Interface())
+		if err != nil {
+			return "", err
+		}
+		token.Values = append(token.Values, value)
+	}
+	data, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+//decodeCursor 解析游标，排序列的值会被还原成字段原本的类型
+func decodeCursor(cursor, order string, columns []cursorColumn) (*cursorToken, []interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, nil, ErrInvalidCursor
+	}
+	var token cursorToken
+	if err = json.Unmarshal(data, &token); err != nil || token.Order != order || len(token.Values) != len(columns) {
+		return nil, nil, ErrInvalidCursor
+	}
+	values := make([]interface{}, 0, len(columns))
+	for i, c := range columns {
+		value := reflect.New(c.typ)
+		if err = json.Unmarshal(token.Values[i], value.Interface()); err != nil {
+			return nil, nil, ErrInvalidCursor
+		}
+		values = append(values, value.Elem().Interface())
+	}
+	return &token, values, nil
+}
+
+func reverseSlice(slice reflect.Value) {
+	swap := reflect.Swapper(slice.Interface())
+	for i, j := 0, slice.Len()-1; i < j; i, j = i+1, j-1 {
+		swap(i, j)
+	}
+}
@@ -0,0 +1,103 @@
+package mysql
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRepository_Cursor(t *testing.T) {
+	repo := NewRepository[Product](newTestOrm(t, &Product{}))
+	//价格存在重复，排序为price降序、id升序
+	for i, price := range []int{10, 30, 20, 30, 10, 20, 40} {
+		if err := repo.Create(&Product{Name: fmt.Sprint("p", i+1), Price: price}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pagination := CursorPagination{PageSize: 3, OrderBy: []string{"-Price", "id"}, WithTotal: true}
+	expects := []string{"p7,p2,p4", "p3,p6,p1", "p5"}
+
+	var pages []*CursorPage[Product]
+	for i, want := range expects {
+		page, err := repo.Cursor(pagination, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total := 7
+		if i > 0 {
+			total = 8
+		}
+		if got := joinNames(page.Data); got != want || page.TotalCount != total {
+			t.Fatalf("page %d: got %s (total %d), want %s", i, got, page.TotalCount, want)
+		}
+		if (page.PrevCursor == "") != (i == 0) || (page.NextCursor == "") != (i == len(expects)-1) {
+			t.Fatalf("page %d: unexpected cursors %+v", i, page)
+		}
+		pages = append(pages, page)
+		pagination.Cursor = page.NextCursor
+		if i == 0 {
+			//翻页过程中插入的记录不会导致后面的页出现重复的记录
+			if err = repo.Create(&Product{Name: "new", Price: 50}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	pagination.Cursor = pages[2].PrevCursor
+	page, err := repo.Cursor(pagination, nil)
+	if err != nil || joinNames(page.Data) != "p3,p6,p1" || page.PrevCursor == "" || page.NextCursor == "" {
+		t.Fatalf("prev page: %+v, %v", page, err)
+	}
+	pagination.Cursor = page.PrevCursor
+	if page, err = repo.Cursor(pagination, nil); err != nil || joinNames(page.Data) != "p7,p2,p4" || page.PrevCursor == "" {
+		t.Fatalf("first page again: %+v, %v", page, err)
+	}
+	pagination.Cursor = page.PrevCursor
+	if page, err = repo.Cursor(pagination, nil); err != nil || joinNames(page.Data) != "new" || page.PrevCursor != "" {
+		t.Fatalf("page before the first page should contain the new record: %+v, %v", page, err)
+	}
+
+	pagination = CursorPagination{PageSize: 2}
+	page, err = repo.Cursor(pagination, NewSpec[Product]().Eq("price", 20))
+	if err != nil || joinNames(page.Data) != "p3,p6" || page.NextCursor != "" || page.TotalCount != 0 {
+		t.Fatalf("cursor with spec: %+v, %v", page, err)
+	}
+}
+
+func TestBaseOrm_ListWithCursor(t *testing.T) {
+	orm := newProducts(t)
+	var products []*Product
+	page, err := orm.ListWithCursor(&products, CursorPagination{PageSize: 3, OrderBy: []string{"-id"}}, "price > ?", 3)
+	if err != nil || len(products) != 3 || products[0].Name != "durian" || page.NextCursor != "" {
+		t.Fatalf("ListWithCursor: %+v, %v", page, err)
+	}
+
+	_, err = orm.ListWithCursor(&products, CursorPagination{PageSize: 3, Cursor: "bad"}, nil)
+	if err != ErrInvalidCursor {
+		t.Fatalf("expect invalid cursor, got %v", err)
+	}
+	page, _ = orm.ListWithCursor(&products, CursorPagination{PageSize: 1}, nil)
+	_, err = orm.ListWithCursor(&products, CursorPagination{PageSize: 1, Cursor: page.NextCursor, OrderBy: []string{"name"}}, nil)
+	if err != ErrInvalidCursor {
+		t.Fatalf("cursor of a different order should be rejected, got %v", err)
+	}
+	if _, err = orm.ListWithCursor(&products, CursorPagination{PageSize: 1, OrderBy: []string{"unknown"}}, nil); err == nil {
+		t.Fatal("unknown order column should be rejected")
+	}
+}
+
+func TestListWithCursor_EmbeddedAndNullable(t *testing.T) {
+	orm, repo := newArticles(t, "a1", "a2", "a3")
+	if err := orm.DB.Model(&Article{}).Where("title = ?", "a2").Update("created_by", "zz").Error; err != nil {
+		t.Fatal(err)
+	}
+	page, err := repo.Cursor(CursorPagination{PageSize: 2, OrderBy: []string{"-CreatedBy", "id"}}, nil)
+	if err != nil || len(page.Data) != 2 || page.Data[0].Title != "a2" || page.NextCursor == "" {
+		t.Fatalf("order by embedded column: %+v, %v", page, err)
+	}
+	if page, err = repo.Cursor(CursorPagination{PageSize: 2, OrderBy: []string{"-CreatedBy", "id"}, Cursor: page.NextCursor}, nil); err != nil || len(page.Data) != 1 || page.Data[0].Title != "a3" {
+		t.Fatalf("next page of embedded column: %+v, %v", page, err)
+	}
+	if _, err = repo.Cursor(CursorPagination{PageSize: 2, OrderBy: []string{"deleted_at", "id"}}, nil); err == nil {
+		t.Fatal("nullable order column should be rejected")
+	}
+}
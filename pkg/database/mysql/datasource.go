@@ -0,0 +1,376 @@
+/*
+ @Desc 多数据源以及读写分离。每个数据源包含一个主库和多个从库，读操作通过负载均衡分发到健康的从库，
+ 写操作和事务使用主库，从库的健康检查失败时会被摘除，恢复之后重新加入。
+
+	sources := mysql.NewDataSources()
+	ds, err := sources.Open(&mysql.DataSourceConfig{Name: "report", Primary: primary, Replicas: replicas})
+	...
+	err = ds.Writer().Create(&order)
+	ctx = mysql.UsePrimary(ctx) //刚写入的数据从主库读取，避免主从延迟
+	err = ds.Reader(ctx).FindById(order.ID, &order)
+
+ 没有健康的从库时读操作回退到主库。
+*/
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/common/log"
+
+	"github.com/yinjk/go-utils/pkg/utils/times"
+)
+
+const (
+	defaultHealthCheckInterval = 10 * time.Second
+	defaultHealthCheckTimeout  = 3 * time.Second
+)
+
+//ErrDataSourceNotFound 数据源没有注册
+var ErrDataSourceNotFound = errors.New("mysql: data source not found")
+
+type usePrimaryKey struct{}
+
+// DataSourceConfig a named data source with one primary and any number of replicas.
+type DataSourceConfig struct {
+	Name     string
+	Primary  *Config
+	Replicas []*Config
+}
+
+// Balancer picks a replica for a read, replicas only contains the healthy ones and is never empty.
+type Balancer interface {
+	Pick(replicas []*BaseOrm) *BaseOrm
+}
+
+// BalancerFunc adapts a function to Balancer.
+type BalancerFunc func(replicas []*BaseOrm) *BaseOrm
+
+//Pick 调用f选择从库
+func (f BalancerFunc) Pick(replicas []*BaseOrm) *BaseOrm {
+	return f(replicas)
+}
+
+//RoundRobin 轮询选择从库，默认的负载均衡策略
+func RoundRobin() Balancer {
+	var next uint64
+	return BalancerFunc(func(replicas []*BaseOrm) *BaseOrm {
+		return replicas[(atomic.AddUint64(&next, 1)-1)%uint64(len(replicas))]
+	})
+}
+
+//RandomBalancer 随机选择从库
+func RandomBalancer() Balancer {
+	return BalancerFunc(func(replicas []*BaseOrm) *BaseOrm {
+		return replicas[rand.Intn(len(replicas))]
+	})
+}
+
+//LeastInUse 选择正在使用的连接数最少的从库
+func LeastInUse() Balancer {
+	return BalancerFunc(func(replicas []*BaseOrm) *BaseOrm {
+		picked, least := replicas[0], sqlDBOf(replicas[0].DB).Stats().InUse
+		for _, r := range replicas[1:] {
+			if inUse := sqlDBOf(r.DB).Stats().InUse; inUse < least {
+				picked, least = r, inUse
+			}
+		}
+		return picked
+	})
+}
+
+type dataSourceOptions struct {
+	balancer            Balancer
+	healthCheckInterval time.Duration
+	healthCheckTimeout  time.Duration
+	clock               times.Clock
+}
+
+// DataSourceOption configures a DataSource.
+type DataSourceOption func(o *dataSourceOptions)
+
+// WithBalancer sets the load balancing strategy of the replicas, the default is RoundRobin.
+func WithBalancer(balancer Balancer) DataSourceOption {
+	return func(o *dataSourceOptions) {
+		o.balancer = balancer
+	}
+}
+
+// WithHealthCheck pings every replica each interval, a replica failing to answer within timeout is ejected
+// until a later ping succeeds. The default is every 10s with a 3s timeout, interval <= 0 disables it.
+func WithHealthCheck(interval, timeout time.Duration) DataSourceOption {
+	return func(o *dataSourceOptions) {
+		o.healthCheckInterval = interval
+		o.healthCheckTimeout = timeout
+	}
+}
+
+// WithHealthCheckClock sets the clock driving the health check interval, the default is times.SystemClock.
+// The ping timeout still uses the wall time.
+func WithHealthCheckClock(clock times.Clock) DataSourceOption {
+	return func(o *dataSourceOptions) {
+		o.clock = clock
+	}
+}
+
+//UsePrimary 返回一个强制从主库读取的ctx，用于写入之后立即读取的场景，避免读到从库中延迟的数据
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, usePrimaryKey{}, true)
+}
+
+//IsUsePrimary 判断ctx是否要求从主库读取
+func IsUsePrimary(ctx context.Context) bool {
+	use, _ := ctx.Value(usePrimaryKey{}).(bool)
+	return use
+}
+
+type replica struct {
+	orm     *BaseOrm
+	healthy int32
+}
+
+//DataSource 一个主库和多个从库组成的数据源
+type DataSource struct {
+	name     string
+	primary  *BaseOrm
+	replicas []*replica
+	healthy  atomic.Value //[]*BaseOrm，健康的从库
+	options  dataSourceOptions
+	stop     chan struct{}
+	closed   sync.Once
+}
+
+/**
+ * 创建数据源，健康检查开启时会启动一个后台的goroutine定时检查从库，Close时停止
+ * @param : name 数据源的名称
+ * @param : primary 主库
+ * @param : replicas 从库，可以为空
+ * @param : opts 选项，如：WithBalancer、WithHealthCheck、WithHealthCheckClock
+ * @return: 数据源
+ */
+func NewDataSource(name string, primary *BaseOrm, replicas []*BaseOrm, opts ...DataSourceOption) *DataSource {
+	ds := &DataSource{
+		name:    name,
+		primary: primary,
+		options: dataSourceOptions{
+			balancer:            RoundRobin(),
+			healthCheckInterval: defaultHealthCheckInterval,
+			healthCheckTimeout:  defaultHealthCheckTimeout,
+		},
+		stop: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(&ds.options)
+	}
+	ds.options.clock = times.ClockOrDefault(ds.options.clock)
+	for _, orm := range replicas {
+		ds.replicas = append(ds.replicas, &replica{orm: orm, healthy: 1})
+	}
+	ds.refresh()
+	if ds.options.healthCheckInterval > 0 && len(ds.replicas) > 0 {
+		go ds.healthCheck()
+	}
+	return ds
+}
+
+//Name 数据源的名称
+func (ds *DataSource) Name() string {
+	return ds.name
+}
+
+//Writer 返回主库，写操作和事务使用主库
+func (ds *DataSource) Writer() *BaseOrm {
+	return ds.primary
+}
+
+//Reader 返回执行读操作的库，ctx带有UsePrimary标记或者没有健康的从库时返回主库
+func (ds *DataSource) Reader(ctx context.Context) *BaseOrm {
+	if ctx != nil && IsUsePrimary(ctx) {
+		return ds.primary
+	}
+	healthy := ds.healthy.Load().([]*BaseOrm)
+	if len(healthy) == 0 {
+		return ds.primary
+	}
+	return ds.options.balancer.Pick(healthy)
+}
+
+//Begin 在主库上开启事务
+func (ds *DataSource) Begin() *Tx {
+	return ds.primary.Begin()
+}
+
+//Transaction 在主库上执行事务，参见BaseOrm.Transaction
+func (ds *DataSource) Transaction(ctx context.Context, fn func(tx *Tx) error, opts ...TxOption) error {
+	return ds.primary.Transaction(ctx, fn, opts...)
+}
+
+//Replicas 返回所有的从库以及是否健康
+func (ds *DataSource) Replicas() map[*BaseOrm]bool {
+	replicas := make(map[*BaseOrm]bool, len(ds.replicas))
+	for _, r := range ds.replicas {
+		replicas[r.orm] = atomic.LoadInt32(&r.healthy) == 1
+	}
+	return replicas
+}
+
+//CheckHealth 立即检查所有从库，ping失败的从库被摘除，ping成功的从库重新加入
+func (ds *DataSource) CheckHealth(ctx context.Context) {
+	changed := false
+	for _, r := range ds.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, ds.options.healthCheckTimeout)
+		err := sqlDBOf(r.orm.DB).PingContext(pingCtx)
+		cancel()
+		healthy := int32(1)
+		if err != nil {
+			healthy = 0
+		}
+		if atomic.SwapInt32(&r.healthy, healthy) == healthy {
+			continue
+		}
+		changed = true
+		if err != nil {
+			log.Warnf("data source %s: replica ejected: %v", ds.name, err)
+		} else {
+			log.Infof("data source %s: replica recovered", ds.name)
+		}
+	}
+	if changed {
+		ds.refresh()
+	}
+}
+
+//Close 停止健康检查并关闭主库和所有从库的连接
+func (ds *DataSource) Close() (err error) {
+	ds.closed.Do(func() {
+		close(ds.stop)
+		if err = ds.primary.Close(); err != nil {
+			log.Error(err)
+		}
+		for _, r := range ds.replicas {
+			if e := r.orm.Close(); e != nil {
+				log.Error(e)
+				err = e
+			}
+		}
+	})
+	return
+}
+
+func (ds *DataSource) healthCheck() {
+	ticker := ds.options.clock.NewTicker(ds.options.healthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ds.stop:
+			return
+		case <-ticker.C():
+			ds.CheckHealth(context.Background())
+		}
+	}
+}
+
+//refresh 重新计算健康的从库
+func (ds *DataSource) refresh() {
+	healthy := make([]*BaseOrm, 0, len(ds.replicas))
+	for _, r := range ds.replicas {
+		if atomic.LoadInt32(&r.healthy) == 1 {
+			healthy = append(healthy, r.orm)
+		}
+	}
+	ds.healthy.Store(healthy)
+}
+
+//DataSources 按名称管理多个数据源
+type DataSources struct {
+	mu      sync.RWMutex
+	sources map[string]*DataSource
+}
+
+//NewDataSources 创建数据源管理器
+func NewDataSources() *DataSources {
+	return &DataSources{sources: make(map[string]*DataSource)}
+}
+
+/**
+ * 根据配置连接主库（database不存在时尝试创建）和从库并注册数据源，任何一个库连接失败时关闭已经打开的连接并返回错误
+ * @param : c 数据源配置
+ * @param : opts 数据源选项
+ * @return: ds 注册的数据源
+ */
+func (m *DataSources) Open(c *DataSourceConfig, opts ...DataSourceOption) (ds *DataSource, err error) {
+	if c.Primary == nil {
+		return nil, fmt.Errorf("mysql: data source %s has no primary", c.Name)
+	}
+	if err = createDatabase(c.Primary); err != nil {
+		return nil, err
+	}
+	primary, err := Open(c.Primary)
+	if err != nil {
+		return nil, err
+	}
+	replicas := make([]*BaseOrm, 0, len(c.Replicas))
+	for _, rc := range c.Replicas {
+		r, err := Open(rc)
+		if err != nil {
+			_ = primary.Close()
+			for _, opened := range replicas {
+				_ = opened.Close()
+			}
+			return nil, err
+		}
+		replicas = append(replicas, r)
+	}
+	ds = NewDataSource(c.Name, primary, replicas, opts...)
+	m.Register(ds)
+	return ds, nil
+}
+
+//Register 注册数据源，同名的数据源会被替换并关闭
+func (m *DataSources) Register(ds *DataSource) {
+	m.mu.Lock()
+	old := m.sources[ds.name]
+	m.sources[ds.name] = ds
+	m.mu.Unlock()
+	if old != nil && old != ds {
+		_ = old.Close()
+	}
+}
+
+//Get 获取数据源，没有注册时返回ErrDataSourceNotFound
+func (m *DataSources) Get(name string) (*DataSource, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if ds, ok := m.sources[name]; ok {
+		return ds, nil
+	}
+	return nil, ErrDataSourceNotFound
+}
+
+//MustGet 获取数据源，没有注册时panic
+func (m *DataSources) MustGet(name string) *DataSource {
+	ds, err := m.Get(name)
+	if err != nil {
+		panic(fmt.Errorf("%v: %s", err, name))
+	}
+	return ds
+}
+
+//Close 关闭所有的数据源
+func (m *DataSources) Close() (err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, ds := range m.sources {
+		if e := ds.Close(); e != nil {
+			err = e
+		}
+		delete(m.sources, name)
+	}
+	return
+}
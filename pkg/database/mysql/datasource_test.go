@@ -0,0 +1,121 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yinjk/go-utils/pkg/utils/times"
+)
+
+func TestDataSource_Route(t *testing.T) {
+	primary, r1, r2 := newTestOrm(t, &Product{}), newTestOrm(t, &Product{}), newTestOrm(t, &Product{})
+	ds := NewDataSource("main", primary, []*BaseOrm{r1, r2}, WithHealthCheck(0, 0))
+	ctx := context.Background()
+
+	if ds.Writer() != primary || ds.Reader(UsePrimary(ctx)) != primary {
+		t.Fatal("writes and UsePrimary reads should use the primary")
+	}
+	picked := map[*BaseOrm]int{}
+	for i := 0; i < 4; i++ {
+		picked[ds.Reader(ctx)]++
+	}
+	if picked[r1] != 2 || picked[r2] != 2 {
+		t.Fatalf("round robin should spread reads over the replicas: %v", picked)
+	}
+
+	err := ds.Transaction(ctx, func(tx *Tx) error {
+		return tx.Create(&Product{Name: "apple"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := primary.Count(&Product{}, nil); count != 1 {
+		t.Fatalf("transaction should run on the primary, count %d", count)
+	}
+	if count, _ := ds.Reader(ctx).Count(&Product{}, nil); count != 0 {
+		t.Fatalf("reads should go to the replica, count %d", count)
+	}
+
+	single := NewDataSource("single", primary, nil, WithBalancer(LeastInUse()))
+	if single.Reader(ctx) != primary {
+		t.Fatal("data source without replicas should read from the primary")
+	}
+}
+
+func TestDataSource_HealthCheck(t *testing.T) {
+	primary, r1, r2 := newTestOrm(t), newTestOrm(t), newTestOrm(t)
+	ds := NewDataSource("main", primary, []*BaseOrm{r1, r2}, WithHealthCheck(0, 50*time.Millisecond))
+	ctx := context.Background()
+
+	//r1的连接池只有一个连接，连接被占用时ping超时
+	conn, err := r1.DB.DB().Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds.CheckHealth(ctx)
+	if replicas := ds.Replicas(); replicas[r1] || !replicas[r2] {
+		t.Fatalf("r1 should be ejected: %v", replicas)
+	}
+	for i := 0; i < 3; i++ {
+		if ds.Reader(ctx) != r2 {
+			t.Fatal("reads should skip the ejected replica")
+		}
+	}
+	_ = conn.Close()
+	ds.CheckHealth(ctx)
+	if replicas := ds.Replicas(); !replicas[r1] {
+		t.Fatalf("r1 should recover: %v", replicas)
+	}
+
+	_ = r1.Close()
+	_ = r2.Close()
+	ds.CheckHealth(ctx)
+	if ds.Reader(ctx) != primary {
+		t.Fatal("reads should fall back to the primary when all replicas are down")
+	}
+}
+
+func TestDataSource_HealthCheckInterval(t *testing.T) {
+	primary, r1, r2 := newTestOrm(t), newTestOrm(t), newTestOrm(t)
+	clock := times.NewFakeClock(time.Time{})
+	ds := NewDataSource("main", primary, []*BaseOrm{r1, r2}, WithHealthCheck(time.Minute, 50*time.Millisecond), WithHealthCheckClock(clock))
+	defer ds.Close()
+	conn, err := r1.SQLDB().Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	clock.BlockUntil(1) //等待健康检查注册ticker
+	if replicas := ds.Replicas(); !replicas[r1] {
+		t.Fatalf("replicas should not be checked before the interval: %v", replicas)
+	}
+	clock.Advance(time.Minute)
+	for i := 0; ds.Replicas()[r1]; i++ {
+		if i > 100 {
+			t.Fatal("the health check should eject r1 after the interval")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDataSources(t *testing.T) {
+	sources := NewDataSources()
+	ds := NewDataSource("main", newTestOrm(t), nil)
+	sources.Register(ds)
+	if got, err := sources.Get("main"); err != nil || got != ds {
+		t.Fatalf("Get: %v, %v", got, err)
+	}
+	if _, err := sources.Get("report"); err != ErrDataSourceNotFound {
+		t.Fatalf("expect not found, got %v", err)
+	}
+	if _, err := sources.Open(&DataSourceConfig{Name: "report"}); err == nil {
+		t.Fatal("data source without primary should be rejected")
+	}
+	if err := sources.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sources.Get("main"); err != ErrDataSourceNotFound {
+		t.Fatal("closed data sources should be removed")
+	}
+}
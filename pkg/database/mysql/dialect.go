@@ -0,0 +1,395 @@
+/*
+ @Desc 数据库方言，屏蔽mysql与sqlite等数据库之间的差异：创建database、建表选项、upsert语句、迁移锁等。
+ Config.Dialect指定使用的方言，默认为mysql，内置mysql、sqlite3两种方言，其它数据库可以通过RegisterDialect注册。
+ sqlite的内存数据库可以代替mysql用于单元测试，不需要启动mysql服务：
+
+	import _ "github.com/yinjk/go-utils/pkg/database/mysql/sqlite"
+
+	orm := mysql.NewMySQL(&mysql.Config{Dialect: mysql.DialectSQLite, DSN: ":memory:"})
+	orm.CreateTable(&User{})
+
+ sqlite使用cgo驱动（github.com/mattn/go-sqlite3），驱动需要通过匿名导入mysql/sqlite包单独注册，只使用mysql时不需要开启cgo。
+*/
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/prometheus/common/log"
+)
+
+const (
+	DialectMySQL  = "mysql"
+	DialectSQLite = "sqlite3"
+)
+
+//errLockUnsupported 方言不支持命名锁，迁移使用锁表代替
+var errLockUnsupported = errors.New("mysql: named lock is not supported by the dialect")
+
+var (
+	dialectsMu sync.RWMutex
+	dialects   = map[string]Dialect{}
+)
+
+// Dialect hides the differences between databases, the name is also the driver name passed to gorm.Open.
+type Dialect interface {
+	// Name returns the name of the dialect, it must be the same as the name of the gorm dialect.
+	Name() string
+	// CreateDatabase creates the database c.DataBase if it does not exist.
+	CreateDatabase(c *Config) error
+	// Configure tunes the connection pool after the pool settings of c are applied.
+	Configure(db *sql.DB, c *Config)
+	// TableOptions returns the options appended to CREATE TABLE.
+	TableOptions() string
+	// UpsertClause returns the clause appended to INSERT to update columns on a primary key conflict.
+	UpsertClause(quote func(string) string, primaryKeys, columns []string) string
+	// ListTables returns the names of the tables starting with prefix in the current database.
+	ListTables(db *gorm.DB, prefix string) ([]string, error)
+	// ListColumns returns the columns of the table in the order of their definition.
+	ListColumns(db *gorm.DB, table string) ([]Column, error)
+	// MaxPlaceholders returns the maximum number of placeholders in a statement, batch operations split batches by it.
+	MaxPlaceholders() int
+	// Lock acquires the named migration lock, ErrMigrationLocked on timeout, errLockUnsupported if not supported.
+	Lock(ctx context.Context, db *sql.DB, name string, timeout time.Duration) (unlock func(), err error)
+}
+
+func init() {
+	RegisterDialect(mysqlDialect{})
+	RegisterDialect(sqliteDialect{ansiDialect{name: DialectSQLite}})
+}
+
+//RegisterDialect 注册方言，同名的方言会被覆盖，对应的gorm方言和数据库驱动需要单独注册（import）
+func RegisterDialect(dialect Dialect) {
+	dialectsMu.Lock()
+	defer dialectsMu.Unlock()
+	dialects[dialect.Name()] = dialect
+}
+
+//GetDialect 获取注册的方言，name为空时返回mysql方言
+func GetDialect(name string) (Dialect, bool) {
+	if name == "" {
+		name = DialectMySQL
+	}
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+	dialect, ok := dialects[name]
+	return dialect, ok
+}
+
+//dialectOfConfig 获取配置的方言，方言没有注册时返回错误
+func dialectOfConfig(c *Config) (Dialect, error) {
+	dialect, ok := GetDialect(c.Dialect)
+	if !ok {
+		return nil, fmt.Errorf("mysql: unknown dialect %q", c.Dialect)
+	}
+	return dialect, nil
+}
+
+//createDatabase 如果database不存在，则尝试创建database
+func createDatabase(c *Config) error {
+	dialect, err := dialectOfConfig(c)
+	if err != nil {
+		return err
+	}
+	return dialect.CreateDatabase(c)
+}
+
+// Column describes a column of a table in the database.
+type Column struct {
+	Name string
+	// Type is the column type declared in the database in lower case, e.g. "bigint(20) unsigned", "varchar(64)".
+	Type          string
+	Nullable      bool
+	PrimaryKey    bool
+	AutoIncrement bool
+	Comment       string
+}
+
+//ListTables 查询当前数据库中以prefix开头的表，prefix为空时查询所有表
+func (bo BaseOrm) ListTables(prefix string) ([]string, error) {
+	return dialectOf(bo.DB).ListTables(bo.DB, prefix)
+}
+
+//ListColumns 查询表的所有列，表不存在时返回空
+func (bo BaseOrm) ListColumns(table string) ([]Column, error) {
+	return dialectOf(bo.DB).ListColumns(bo.DB, table)
+}
+
+//dialectOf 获取db使用的方言，没有注册时使用标准sql的方言
+func dialectOf(db *gorm.DB) Dialect {
+	name := db.Dialect().GetName()
+	if dialect, ok := GetDialect(name); ok {
+		return dialect
+	}
+	return ansiDialect{name: name}
+}
+
+//mysqlDialect mysql方言
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string {
+	return DialectMySQL
+}
+
+//CreateDatabase 连接名为mysql的数据库创建database
+func (mysqlDialect) CreateDatabase(c *Config) error {
+	dsn := strings.Replace(c.DSN, c.DataBase, "mysql", 1)
+	db, err := gorm.Open(DialectMySQL, dsn)
+	if err != nil {
+		log.Errorf("db dsn(%v) error: %v", dsn, err)
+		return err
+	}
+	defer func() { _ = db.Close() }() //数据库创建之后直接关闭该连接
+	if err = db.Exec("create database if not exists " + c.DataBase + " Character Set UTF8;").Error; err != nil {
+		log.Errorf("create database %v error(%v)", c.DataBase, err)
+		return err
+	}
+	return nil
+}
+
+func (mysqlDialect) Configure(*sql.DB, *Config) {}
+
+func (mysqlDialect) TableOptions() string {
+	return "ENGINE=InnoDB DEFAULT CHARSET=utf8"
+}
+
+func (mysqlDialect) UpsertClause(quote func(string) string, _, columns []string) string {
+	sets := make([]string, 0, len(columns))
+	for _, column := range columns {
+		sets = append(sets, fmt.Sprintf("%s = VALUES(%s)", quote(column), quote(column)))
+	}
+	return " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+}
+
+func (mysqlDialect) ListTables(db *gorm.DB, prefix string) ([]string, error) {
+	return listTables(db, "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name LIKE ?", prefix)
+}
+
+func (mysqlDialect) ListColumns(db *gorm.DB, table string) ([]Column, error) {
+	rows, err := db.Raw("SELECT column_name, column_type, is_nullable, column_key, extra, column_comment FROM information_schema.columns "+
+		"WHERE table_schema = DATABASE() AND table_name = ? ORDER BY ordinal_position", table).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var columns []Column
+	for rows.Next() {
+		var column Column
+		var nullable, key, extra string
+		if err = rows.Scan(&column.Name, &column.Type, &nullable, &key, &extra, &column.Comment); err != nil {
+			return nil, err
+		}
+		column.Type = strings.ToLower(column.Type)
+		column.Nullable = nullable == "YES"
+		column.PrimaryKey = key == "PRI"
+		column.AutoIncrement = strings.Contains(strings.ToLower(extra), "auto_increment")
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+//Lock 使用GET_LOCK加锁，连接断开时锁自动释放
+//MaxPlaceholders mysql预处理语句最多65535个占位符
+func (mysqlDialect) MaxPlaceholders() int {
+	return 65535
+}
+
+func (mysqlDialect) Lock(ctx context.Context, db *sql.DB, name string, timeout time.Duration) (func(), error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var locked sql.NullInt64
+	if err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, int(timeout.Seconds())).Scan(&locked); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if !locked.Valid || locked.Int64 != 1 {
+		_ = conn.Close()
+		return nil, ErrMigrationLocked
+	}
+	return func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", name); err != nil {
+			log.Errorf("release lock %s error: %v", name, err)
+		}
+		_ = conn.Close()
+	}, nil
+}
+
+//ansiDialect 标准sql的方言，用于没有注册的gorm方言，如postgres
+type ansiDialect struct {
+	name string
+}
+
+func (d ansiDialect) Name() string {
+	return d.name
+}
+
+func (ansiDialect) CreateDatabase(*Config) error {
+	return nil
+}
+
+func (ansiDialect) Configure(*sql.DB, *Config) {}
+
+func (ansiDialect) TableOptions() string {
+	return ""
+}
+
+func (ansiDialect) UpsertClause(quote func(string) string, primaryKeys, columns []string) string {
+	sets := make([]string, 0, len(columns))
+	for _, column := range columns {
+		sets = append(sets, fmt.Sprintf("%s = excluded.%s", quote(column), quote(column)))
+	}
+	quoted := make([]string, 0, len(primaryKeys))
+	for _, key := range primaryKeys {
+		quoted = append(quoted, quote(key))
+	}
+	return fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(quoted, ","), strings.Join(sets, ", "))
+}
+
+func (ansiDialect) ListTables(db *gorm.DB, prefix string) ([]string, error) {
+	return listTables(db, "SELECT table_name FROM information_schema.tables WHERE table_name LIKE ?", prefix)
+}
+
+//ListColumns 从information_schema查询列，主键从表约束中查询，默认值为序列的列视为自增列
+func (ansiDialect) ListColumns(db *gorm.DB, table string) ([]Column, error) {
+	primaryKeys := make(map[string]bool)
+	keys, err := db.Raw("SELECT kcu.column_name FROM information_schema.table_constraints tc "+
+		"JOIN information_schema.key_column_usage kcu ON tc.constraint_name = kcu.constraint_name AND tc.table_name = kcu.table_name "+
+		"WHERE tc.table_name = ? AND tc.constraint_type = 'PRIMARY KEY'", table).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer keys.Close()
+	for keys.Next() {
+		var name string
+		if err = keys.Scan(&name); err != nil {
+			return nil, err
+		}
+		primaryKeys[name] = true
+	}
+	rows, err := db.Raw("SELECT column_name, data_type, is_nullable, column_default FROM information_schema.columns "+
+		"WHERE table_name = ? ORDER BY ordinal_position", table).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var columns []Column
+	for rows.Next() {
+		var column Column
+		var nullable string
+		var def sql.NullString
+		if err = rows.Scan(&column.Name, &column.Type, &nullable, &def); err != nil {
+			return nil, err
+		}
+		column.Type = strings.ToLower(column.Type)
+		column.Nullable = nullable == "YES"
+		column.PrimaryKey = primaryKeys[column.Name]
+		column.AutoIncrement = strings.HasPrefix(def.String, "nextval(")
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+//MaxPlaceholders postgres的绑定参数个数上限
+func (ansiDialect) MaxPlaceholders() int {
+	return 65535
+}
+
+func (ansiDialect) Lock(context.Context, *sql.DB, string, time.Duration) (func(), error) {
+	return nil, errLockUnsupported
+}
+
+//sqliteDialect sqlite方言，database即数据库文件，打开时自动创建
+type sqliteDialect struct {
+	ansiDialect
+}
+
+//Configure 内存数据库的每个连接都是一个独立的数据库，只保留一个连接并且不过期
+func (sqliteDialect) Configure(db *sql.DB, c *Config) {
+	if isMemoryDSN(c.DSN) {
+		db.SetMaxOpenConns(1)
+		db.SetMaxIdleConns(1)
+		db.SetConnMaxLifetime(0)
+	}
+}
+
+//MaxPlaceholders sqlite 3.32之前SQLITE_MAX_VARIABLE_NUMBER默认为999，go-sqlite3内置的sqlite版本为3.31
+func (sqliteDialect) MaxPlaceholders() int {
+	return 999
+}
+
+func (sqliteDialect) ListTables(db *gorm.DB, prefix string) ([]string, error) {
+	return listTables(db, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite\\_%' ESCAPE '\\' AND name LIKE ?", prefix)
+}
+
+//ListColumns 使用PRAGMA table_info查询列，唯一的INTEGER主键是rowid的别名，视为自增列
+func (sqliteDialect) ListColumns(db *gorm.DB, table string) ([]Column, error) {
+	rows, err := db.Raw("PRAGMA table_info(" + db.Dialect().Quote(table) + ")").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var columns []Column
+	for rows.Next() {
+		var (
+			column  Column
+			cid, pk int
+			notNull bool
+			def     interface{}
+		)
+		if err = rows.Scan(&cid, &column.Name, &column.Type, &notNull, &def, &pk); err != nil {
+			return nil, err
+		}
+		column.Type = strings.ToLower(column.Type)
+		column.Nullable = !notNull && pk == 0
+		column.PrimaryKey = pk > 0
+		columns = append(columns, column)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	var primaryKeys []int
+	for i, column := range columns {
+		if column.PrimaryKey {
+			primaryKeys = append(primaryKeys, i)
+		}
+	}
+	if len(primaryKeys) == 1 && columns[primaryKeys[0]].Type == "integer" {
+		columns[primaryKeys[0]].AutoIncrement = true
+	}
+	return columns, nil
+}
+
+func isMemoryDSN(dsn string) bool {
+	return dsn == "" || strings.Contains(dsn, ":memory:") || strings.Contains(dsn, "mode=memory")
+}
+
+//listTables 查询以prefix开头的表并按名称排序，LIKE中的_是通配符，查询之后再精确过滤
+func listTables(db *gorm.DB, query, prefix string) ([]string, error) {
+	rows, err := db.Raw(query, prefix+"%").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, rows.Err()
+}
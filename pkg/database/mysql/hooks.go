@@ -0,0 +1,265 @@
+/*
+ @Desc 写操作钩子。Create、Save、Update、Updates、Delete、DeleteById等写操作执行前后调用注册的钩子，
+ 钩子与写操作在同一个事务中执行（写操作本身不在事务中时gorm会为它开启事务），钩子返回错误时写操作回滚：
+
+	orm.BeforeWrite(func(e *mysql.WriteEvent) error {
+		return validate(e.Value)
+	})
+	orm.AfterWrite(mysql.OutboxHook("order-events")) //变更事件与写操作在同一个事务中写入outbox表
+
+ BatchInsert、Upsert、BulkUpdate每执行一批之前和之后对该批的每条记录调用钩子，钩子与该批在同一个事务中执行，
+ 批量插入的记录没有回写自增主键，事件的PrimaryKey为nil。Exec执行的是原生sql，无法生成写事件，
+ 注册了钩子之后使用Exec执行INSERT、UPDATE、DELETE、REPLACE会返回ErrHooksBypassed。
+*/
+package mysql
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	hooksSetting      = "mysql:write_hooks"
+	primaryKeySetting = "mysql:primary_key"
+)
+
+// WriteOp is the kind of a write operation.
+type WriteOp string
+
+const (
+	OpCreate WriteOp = "create"
+	OpUpdate WriteOp = "update"
+	OpDelete WriteOp = "delete"
+	// OpUpsert is fired by Upsert, the record may be inserted or updated.
+	OpUpsert WriteOp = "upsert"
+)
+
+// ErrHooksBypassed is returned by Exec when a raw write would bypass the registered write hooks.
+var ErrHooksBypassed = errors.New("mysql: raw write statement bypasses the registered write hooks")
+
+// WriteEvent describes a write operation passed to the write hooks.
+type WriteEvent struct {
+	Op    WriteOp
+	Table string
+	// PrimaryKey is the primary key of the written record, it may be nil when deleting by conditions.
+	PrimaryKey interface{}
+	// Value is the model passed to the write operation.
+	Value interface{}
+	// Changes are the updated columns of an update, nil for create and delete.
+	Changes map[string]interface{}
+	// Ctx is the context bound by WithContext or Transaction.
+	Ctx context.Context
+	// Tx is the transaction of the write operation, writes through it commit or roll back together.
+	Tx *Tx
+}
+
+// WriteHook is called before or after a write operation, an error rolls the write back.
+type WriteHook func(e *WriteEvent) error
+
+type writeHooks struct {
+	mu     sync.RWMutex
+	before []WriteHook
+	after  []WriteHook
+}
+
+//钩子在gorm开启事务之后、提交事务之前执行
+func init() {
+	for op, processor := range map[WriteOp]*gorm.CallbackProcessor{
+		OpCreate: gorm.DefaultCallback.Create().After("gorm:begin_transaction"),
+		OpUpdate: gorm.DefaultCallback.Update().After("gorm:begin_transaction"),
+		OpDelete: gorm.DefaultCallback.Delete().After("gorm:begin_transaction"),
+	} {
+		processor.Register("mysql:before_write_hooks", hooksCallback(op, false))
+	}
+	for op, processor := range map[WriteOp]*gorm.CallbackProcessor{
+		OpCreate: gorm.DefaultCallback.Create().Before("gorm:commit_or_rollback_transaction"),
+		OpUpdate: gorm.DefaultCallback.Update().Before("gorm:commit_or_rollback_transaction"),
+		OpDelete: gorm.DefaultCallback.Delete().Before("gorm:commit_or_rollback_transaction"),
+	} {
+		processor.Register("mysql:after_write_hooks", hooksCallback(op, true))
+	}
+}
+
+//BeforeWrite 注册写操作之前执行的钩子，对bo以及之后从bo派生的BaseOrm、Tx、Repository生效
+func (bo *BaseOrm) BeforeWrite(hooks ...WriteHook) {
+	h := bo.hooks()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.before = append(h.before, hooks...)
+}
+
+//AfterWrite 注册写操作之后、事务提交之前执行的钩子，写操作失败时不会执行
+func (bo *BaseOrm) AfterWrite(hooks ...WriteHook) {
+	h := bo.hooks()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.after = append(h.after, hooks...)
+}
+
+func (bo *BaseOrm) hooks() *writeHooks {
+	if h, ok := bo.DB.Get(hooksSetting); ok {
+		return h.(*writeHooks)
+	}
+	h := &writeHooks{}
+	bo.DB = bo.DB.Set(hooksSetting, h)
+	return h
+}
+
+//hooksOf 返回db上注册的钩子
+func hooksOf(db *gorm.DB) (before, after []WriteHook) {
+	setting, ok := db.Get(hooksSetting)
+	if !ok {
+		return nil, nil
+	}
+	h := setting.(*writeHooks)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.before, h.after
+}
+
+//checkRawWrite 注册了钩子时拒绝执行原生的写语句
+func checkRawWrite(db *gorm.DB, sql string) error {
+	if before, after := hooksOf(db); len(before) == 0 && len(after) == 0 {
+		return nil
+	}
+	keyword := strings.ToUpper(strings.SplitN(strings.TrimSpace(sql), " ", 2)[0])
+	switch keyword {
+	case "INSERT", "UPDATE", "DELETE", "REPLACE":
+		return ErrHooksBypassed
+	}
+	return nil
+}
+
+/**
+ * 执行批量写的一批，注册了钩子时在事务中先对每条记录调用before钩子，执行write之后再调用after钩子，
+ * db已经在事务中时使用该事务，否则为这一批开启事务
+ * @param : db 批量写使用的gorm.DB
+ * @param : op 写操作的类型
+ * @param : batch 这一批的记录
+ * @param : columns 更新的列，用于生成事件的Changes，插入时为nil
+ * @param : write 执行这一批的sql
+ * @return: write的结果，钩子出错时Error为钩子的错误
+ */
+func writeBatch(db *gorm.DB, op WriteOp, batch []*gorm.Scope, columns []string, write func(db *gorm.DB) *gorm.DB) *gorm.DB {
+	before, after := hooksOf(db)
+	if len(before) == 0 && len(after) == 0 {
+		return write(db)
+	}
+	if _, ok := batch[0].Value.(*OutboxEvent); ok { //写outbox表时不再调用钩子，避免递归
+		return write(db)
+	}
+	ctx := contextOf(db)
+	tx, owned := db, false
+	if _, ok := db.CommonDB().(interface{ Commit() error }); !ok {
+		if tx = db.BeginTx(ctx, nil); tx.Error != nil {
+			return tx
+		}
+		if _, bound := boundContext(db); bound {
+			tx = withContext(tx, ctx)
+		}
+		owned = true
+	}
+	fail := func(err error) *gorm.DB {
+		if owned {
+			tx.Rollback()
+		}
+		result := tx.New()
+		result.Error = err
+		return result
+	}
+	events := make([]*WriteEvent, 0, len(batch))
+	for _, scope := range batch {
+		event := &WriteEvent{Op: op, Table: scope.TableName(), Value: scope.Value, Ctx: ctx, Tx: &Tx{withContext(tx.New(), ctx)}}
+		if !scope.PrimaryKeyZero() {
+			event.PrimaryKey = scope.PrimaryKeyValue()
+		}
+		if columns != nil {
+			event.Changes = make(map[string]interface{}, len(columns))
+			for _, column := range columns {
+				if field, ok := scope.FieldByName(column); ok {
+					event.Changes[column] = field.Field.Interface()
+				}
+			}
+		}
+		events = append(events, event)
+	}
+	for _, event := range events {
+		for _, hook := range before {
+			if err := hook(event); err != nil {
+				return fail(err)
+			}
+		}
+	}
+	exec := write(tx)
+	if exec.Error != nil {
+		return fail(exec.Error)
+	}
+	for _, event := range events {
+		for _, hook := range after {
+			if err := hook(event); err != nil {
+				return fail(err)
+			}
+		}
+	}
+	if owned {
+		if commit := tx.Commit(); commit.Error != nil {
+			return commit
+		}
+	}
+	return exec
+}
+
+func hooksCallback(op WriteOp, after bool) func(scope *gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		setting, ok := scope.Get(hooksSetting)
+		if !ok || scope.HasError() {
+			return
+		}
+		if _, ok = scope.Value.(*OutboxEvent); ok { //写outbox表时不再调用钩子，避免递归
+			return
+		}
+		h := setting.(*writeHooks)
+		h.mu.RLock()
+		hooks := h.before
+		if after {
+			hooks = h.after
+		}
+		h.mu.RUnlock()
+		if len(hooks) == 0 {
+			return
+		}
+		event := newWriteEvent(scope, op)
+		for _, hook := range hooks {
+			if scope.Err(hook(event)) != nil {
+				return
+			}
+		}
+	}
+}
+
+//newWriteEvent 根据scope创建写事件，Tx使用写操作所在的事务
+func newWriteEvent(scope *gorm.Scope, op WriteOp) *WriteEvent {
+	ctx := contextOf(scope.DB())
+	event := &WriteEvent{
+		Op:    op,
+		Table: scope.TableName(),
+		Value: scope.Value,
+		Ctx:   ctx,
+		Tx:    &Tx{withContext(scope.NewDB(), ctx)},
+	}
+	if !scope.PrimaryKeyZero() {
+		event.PrimaryKey = scope.PrimaryKeyValue()
+	} else if id, ok := scope.Get(primaryKeySetting); ok {
+		event.PrimaryKey = id
+	}
+	if op == OpUpdate {
+		if attrs, ok := scope.InstanceGet("gorm:update_attrs"); ok {
+			event.Changes, _ = attrs.(map[string]interface{})
+		}
+	}
+	return event
+}
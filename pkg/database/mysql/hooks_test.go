@@ -0,0 +1,105 @@
+package mysql
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestBaseOrm_WriteHooks(t *testing.T) {
+	orm := newTestOrm(t, &Product{})
+	var events []string
+	orm.BeforeWrite(func(e *WriteEvent) error {
+		if p, ok := e.Value.(*Product); ok && p.Name == "" && e.Op == OpCreate {
+			return errors.New("name is required")
+		}
+		return nil
+	})
+	orm.AfterWrite(func(e *WriteEvent) error {
+		events = append(events, fmt.Sprintf("%s %s %v %v", e.Op, e.Table, e.PrimaryKey, e.Changes["price"]))
+		if e.Tx == nil {
+			return errors.New("missing tx")
+		}
+		return nil
+	})
+
+	if err := orm.Create(&Product{Price: 1}); err == nil {
+		t.Fatal("before hook should reject the write")
+	}
+	apple := &Product{Name: "apple", Price: 5}
+	if err := orm.Create(apple); err != nil {
+		t.Fatal(err)
+	}
+	if err := orm.Updates(apple, map[string]interface{}{"price": 6}); err != nil {
+		t.Fatal(err)
+	}
+	if err := orm.DeleteById(apple.ID, &Product{}); err != nil {
+		t.Fatal(err)
+	}
+	if joined := joinStrings(events); joined != "create products 1 <nil>,update products 1 6,delete products 1 <nil>" {
+		t.Fatalf("unexpected events %s", joined)
+	}
+
+	//after钩子返回错误时写操作回滚
+	orm.AfterWrite(func(e *WriteEvent) error {
+		return errors.New("publish failed")
+	})
+	if err := NewRepository[Product](orm).Create(&Product{Name: "banana"}); err == nil {
+		t.Fatal("after hook error should fail the write")
+	}
+	if count, _ := orm.Count(&Product{}, nil); count != 0 {
+		t.Fatalf("write should be rolled back, count %d", count)
+	}
+}
+
+func TestBaseOrm_BatchWriteHooks(t *testing.T) {
+	orm := newTestOrm(t, &Product{})
+	var events []string
+	orm.BeforeWrite(func(e *WriteEvent) error {
+		if p, ok := e.Value.(*Product); ok && p.Name == "" {
+			return errors.New("name is required")
+		}
+		return nil
+	})
+	orm.AfterWrite(func(e *WriteEvent) error {
+		events = append(events, fmt.Sprintf("%s %s %v %v", e.Op, e.Table, e.PrimaryKey, e.Changes["price"]))
+		return nil
+	})
+
+	if _, err := orm.BatchInsert([]Product{{Name: "apple"}, {Price: 1}}, 10); err == nil {
+		t.Fatal("before hook should reject the batch")
+	}
+	if count, _ := orm.Count(&Product{}, nil); count != 0 {
+		t.Fatalf("rejected batch should not be written, count %d", count)
+	}
+	if _, err := orm.BatchInsert([]Product{{Name: "apple"}, {Name: "banana"}}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orm.BulkUpdate([]Product{{ID: 1, Name: "apple", Price: 3}}, 10, "Price"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orm.Upsert([]Product{{ID: 2, Name: "banana", Price: 4}}, 10); err != nil {
+		t.Fatal(err)
+	}
+	if joined := joinStrings(events); joined != "create products <nil> <nil>,create products <nil> <nil>,update products 1 3,upsert products 2 <nil>" {
+		t.Fatalf("unexpected events %s", joined)
+	}
+
+	//after钩子返回错误时这一批回滚
+	orm.AfterWrite(func(e *WriteEvent) error {
+		return errors.New("publish failed")
+	})
+	if _, err := orm.BatchInsert([]Product{{Name: "cherry"}}, 10); err == nil {
+		t.Fatal("after hook error should fail the batch")
+	}
+	if count, _ := orm.Count(&Product{}, nil); count != 2 {
+		t.Fatalf("batch should be rolled back, count %d", count)
+	}
+
+	if _, err := orm.Exec("UPDATE products SET price = 0"); !errors.Is(err, ErrHooksBypassed) {
+		t.Fatalf("raw write should be rejected, got %v", err)
+	}
+	if _, err := orm.Exec("CREATE INDEX idx_products_name ON products (name)"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,254 @@
+/*
+ @Desc 查询埋点：慢查询日志以及prometheus指标。
+   1. 耗时超过阈值的语句打印慢查询日志，参数只打印类型，不会打印参数的值
+   2. mysql_query_duration_seconds{operation,table}：语句耗时的直方图，operation为create、query、update、delete、row_query、exec
+   3. mysql_query_errors_total{operation,table,class}：按错误类型统计的错误数，ErrRecordNotFound不计入
+
+	orm.Instrument(mysql.SlowThreshold(200*time.Millisecond), mysql.WithRegisterer(prometheus.DefaultRegisterer))
+
+ gorm的Create、Find、Update、Delete、Raw等操作通过回调埋点，Exec、BatchInsert、Upsert、BulkUpdate单独埋点。
+*/
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"reflect"
+	"regexp"
+	"time"
+
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const (
+	instrumentSetting = "mysql:instrumenter"
+	instrumentStart   = "mysql:instrument_start"
+
+	mysqlErrDuplicateEntry = 1062
+	mysqlErrLockTimeout    = 1205
+)
+
+//从sql语句中解析表名
+var sqlTablePattern = regexp.MustCompile("(?i)\\b(?:from|into|update|join)\\s+[`\"]?([\\w.]+)")
+
+type instrumentOptions struct {
+	slowThreshold time.Duration
+	registerer    prometheus.Registerer
+	buckets       []float64
+}
+
+// InstrumentOption configures the query instrumentation installed by Instrument.
+type InstrumentOption func(o *instrumentOptions)
+
+// SlowThreshold logs the statements taking longer than threshold, 0 disables the slow query log.
+func SlowThreshold(threshold time.Duration) InstrumentOption {
+	return func(o *instrumentOptions) {
+		o.slowThreshold = threshold
+	}
+}
+
+// WithRegisterer registers the query metrics to registerer, metrics are disabled when it is nil.
+func WithRegisterer(registerer prometheus.Registerer) InstrumentOption {
+	return func(o *instrumentOptions) {
+		o.registerer = registerer
+	}
+}
+
+// WithBuckets sets the buckets of the latency histogram in seconds, the default is prometheus.DefBuckets.
+func WithBuckets(buckets []float64) InstrumentOption {
+	return func(o *instrumentOptions) {
+		o.buckets = buckets
+	}
+}
+
+type instrumenter struct {
+	slowThreshold time.Duration
+	duration      *prometheus.HistogramVec
+	errors        *prometheus.CounterVec
+}
+
+/**
+ * 开启查询埋点，埋点对bo以及之后从bo派生的BaseOrm、Tx、Repository生效，应该在创建BaseOrm之后立即调用
+ * @param : opts 埋点选项，如：SlowThreshold、WithRegisterer
+ * @return: 注册prometheus指标失败时返回错误，同名的指标已经注册时复用已有的指标
+ */
+func (bo *BaseOrm) Instrument(opts ...InstrumentOption) error {
+	options := &instrumentOptions{buckets: prometheus.DefBuckets}
+	for _, opt := range opts {
+		opt(options)
+	}
+	ins := &instrumenter{slowThreshold: options.slowThreshold}
+	if options.registerer != nil {
+		duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "mysql_query_duration_seconds",
+			Help:    "Latency of the sql statements by operation and table.",
+			Buckets: options.buckets,
+		}, []string{"operation", "table"})
+		errs := prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mysql_query_errors_total",
+			Help: "Failed sql statements by operation, table and error class.",
+		}, []string{"operation", "table", "class"})
+		var err error
+		if ins.duration, err = registerCollector(options.registerer, duration); err != nil {
+			return err
+		}
+		if ins.errors, err = registerCollector(options.registerer, errs); err != nil {
+			return err
+		}
+	}
+	bo.DB = bo.DB.Set(instrumentSetting, ins)
+	return nil
+}
+
+//registerCollector 注册指标，已经注册过时返回已有的指标
+func registerCollector[C prometheus.Collector](registerer prometheus.Registerer, collector C) (C, error) {
+	if err := registerer.Register(collector); err != nil {
+		var registered prometheus.AlreadyRegisteredError
+		if errors.As(err, &registered) {
+			if existing, ok := registered.ExistingCollector.(C); ok {
+				return existing, nil
+			}
+		}
+		return collector, err
+	}
+	return collector, nil
+}
+
+//埋点的回调对所有的gorm.DB生效，只记录调用过Instrument的BaseOrm（以及从它派生的BaseOrm、Tx）上的语句
+func init() {
+	callbacks := gorm.DefaultCallback
+	for operation, processor := range map[string]*gorm.CallbackProcessor{
+		"create":    callbacks.Create().Before("gorm:create"),
+		"query":     callbacks.Query().Before("gorm:query"),
+		"update":    callbacks.Update().Before("gorm:update"),
+		"delete":    callbacks.Delete().Before("gorm:delete"),
+		"row_query": callbacks.RowQuery().Before("gorm:row_query"),
+	} {
+		processor.Register("mysql:instrument_before_"+operation, instrumentStartCallback)
+	}
+	for operation, processor := range map[string]*gorm.CallbackProcessor{
+		"create":    callbacks.Create().After("gorm:create"),
+		"query":     callbacks.Query().After("gorm:query"),
+		"update":    callbacks.Update().After("gorm:update"),
+		"delete":    callbacks.Delete().After("gorm:delete"),
+		"row_query": callbacks.RowQuery().After("gorm:row_query"),
+	} {
+		processor.Register("mysql:instrument_after_"+operation, instrumentCallback(operation))
+	}
+}
+
+func instrumentStartCallback(scope *gorm.Scope) {
+	scope.InstanceSet(instrumentStart, time.Now())
+}
+
+func instrumentCallback(operation string) func(scope *gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		ins := instrumenterOf(scope.DB())
+		start, ok := scope.InstanceGet(instrumentStart)
+		if ins == nil || !ok || scope.SQL == "" { //没有开启埋点，或者被之前的回调跳过，没有执行sql
+			return
+		}
+		table := ""
+		if scope.Value != nil {
+			table = scope.TableName()
+		}
+		if table == "" {
+			table = tableOfSQL(scope.SQL)
+		}
+		ins.observe(operation, table, scope.SQL, scope.SQLVars, time.Since(start.(time.Time)), scope.DB().Error)
+	}
+}
+
+//observe 记录一次语句的执行
+func (ins *instrumenter) observe(operation, table, sql string, args []interface{}, elapsed time.Duration, err error) {
+	if ins.duration != nil {
+		ins.duration.WithLabelValues(operation, table).Observe(elapsed.Seconds())
+	}
+	if class := errorClass(err); class != "" && ins.errors != nil {
+		ins.errors.WithLabelValues(operation, table, class).Inc()
+	}
+	if ins.slowThreshold > 0 && elapsed > ins.slowThreshold {
+		log.Warnf("slow sql [%s %s] %v > %v: %s, args: %v", operation, table, elapsed, ins.slowThreshold, sql, redactArgs(args))
+	}
+}
+
+//instrumenterOf 获取db上的埋点，没有开启时返回nil
+func instrumenterOf(db *gorm.DB) *instrumenter {
+	if ins, ok := db.Get(instrumentSetting); ok {
+		return ins.(*instrumenter)
+	}
+	return nil
+}
+
+//execSQL 执行语句并埋点，gorm的Exec不会执行回调
+func execSQL(db *gorm.DB, sql string, args ...interface{}) *gorm.DB {
+	start := time.Now()
+	result := db.Exec(sql, args...)
+	if ins := instrumenterOf(db); ins != nil {
+		ins.observe("exec", tableOfSQL(sql), sql, args, time.Since(start), result.Error)
+	}
+	return result
+}
+
+//tableOfSQL 从sql语句中解析出第一个表名，解析不到时返回"unknown"
+func tableOfSQL(sql string) string {
+	if match := sqlTablePattern.FindStringSubmatch(sql); match != nil {
+		return match[1]
+	}
+	return "unknown"
+}
+
+//redactArgs 参数脱敏，只保留参数的类型
+func redactArgs(args []interface{}) []string {
+	redacted := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == nil {
+			redacted = append(redacted, "nil")
+			continue
+		}
+		redacted = append(redacted, reflect.TypeOf(arg).String())
+	}
+	return redacted
+}
+
+/**
+ * 错误分类，用于按类型统计错误
+ * @param : err 语句执行的错误
+ * @return: 错误类型：canceled、timeout、deadlock、lock_timeout、duplicate、connection、other，没有错误或者记录不存在时返回空字符串
+ */
+func errorClass(err error) string {
+	if errs, ok := err.(gorm.Errors); ok {
+		for _, e := range errs {
+			if class := errorClass(e); class != "" {
+				return class
+			}
+		}
+		return ""
+	}
+	var (
+		mysqlErr *mysqlDriver.MySQLError
+		netErr   net.Error
+	)
+	switch {
+	case err == nil || gorm.IsRecordNotFoundError(err):
+		return ""
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case IsDeadlock(err):
+		return "deadlock"
+	case errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrLockTimeout:
+		return "lock_timeout"
+	case errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry:
+		return "duplicate"
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, mysqlDriver.ErrInvalidConn), errors.As(err, &netErr):
+		return "connection"
+	}
+	return "other"
+}
@@ -0,0 +1,116 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	driver "github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// histogramCounts 返回指标中每组label的样本数，key为"operation/table"
+func histogramCounts(t *testing.T, registry *prometheus.Registry) (durations, errs map[string]uint64) {
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	durations, errs = map[string]uint64{}, map[string]uint64{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			key := labels["operation"] + "/" + labels["table"]
+			switch family.GetName() {
+			case "mysql_query_duration_seconds":
+				durations[key] = metric.GetHistogram().GetSampleCount()
+			case "mysql_query_errors_total":
+				errs[key+"/"+labels["class"]] = uint64(metric.GetCounter().GetValue())
+			}
+		}
+	}
+	return
+}
+
+func TestBaseOrm_Instrument(t *testing.T) {
+	orm := newTestOrm(t, &Product{})
+	registry := prometheus.NewRegistry()
+	if err := orm.Instrument(SlowThreshold(1), WithRegisterer(registry)); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewRepository[Product](orm)
+	if err := repo.Create(&Product{Name: "apple"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.List(nil); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	if err := orm.WithContext(context.Background()).FastQuery("SELECT name FROM products", &names); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orm.BatchInsert([]Product{{Name: "banana"}, {Name: "cherry"}}, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orm.Exec("UPDATE missing SET x = 1"); err == nil {
+		t.Fatal("expect error")
+	}
+	if _, err := repo.FindByID(100); !IsRecordNotFound(err) {
+		t.Fatalf("expect not found, got %v", err)
+	}
+
+	durations, errs := histogramCounts(t, registry)
+	want := map[string]uint64{"create/products": 1, "query/products": 2, "row_query/products": 1, "exec/products": 1, "exec/missing": 1}
+	for key, count := range want {
+		if durations[key] != count {
+			t.Fatalf("%s: expect %d samples, got %v", key, count, durations)
+		}
+	}
+	if len(errs) != 1 || errs["exec/missing/other"] != 1 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+
+	//其它BaseOrm复用已经注册的指标
+	other := newTestOrm(t, &Product{})
+	if err := other.Instrument(WithRegisterer(registry)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewRepository[Product](other).List(nil); err != nil {
+		t.Fatal(err)
+	}
+	if durations, _ = histogramCounts(t, registry); durations["query/products"] != 3 {
+		t.Fatalf("metrics should be shared: %v", durations)
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{gorm.ErrRecordNotFound, ""},
+		{context.Canceled, "canceled"},
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), "timeout"},
+		{&driver.MySQLError{Number: 1213, Message: "Deadlock found"}, "deadlock"},
+		{&driver.MySQLError{Number: 1205, Message: "Lock wait timeout"}, "lock_timeout"},
+		{&driver.MySQLError{Number: 1062, Message: "Duplicate entry"}, "duplicate"},
+		{driver.ErrInvalidConn, "connection"},
+		{gorm.Errors{gorm.ErrRecordNotFound, errors.New("boom")}, "other"},
+	}
+	for _, c := range cases {
+		if got := errorClass(c.err); got != c.want {
+			t.Errorf("errorClass(%v) = %q, want %q", c.err, got, c.want)
+		}
+	}
+	if got := fmt.Sprint(redactArgs([]interface{}{"secret", 1, nil})); got != "[string int nil]" {
+		t.Fatalf("unexpected redacted args %s", got)
+	}
+	if table := tableOfSQL("select * from `orders` o join users u on u.id = o.user_id"); table != "orders" {
+		t.Fatalf("unexpected table %s", table)
+	}
+}
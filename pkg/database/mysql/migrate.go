@@ -0,0 +1,453 @@
+/*
+ @Desc 版本化的数据库迁移，迁移可以是go函数，也可以是embed的sql文件（文件名格式：{version}_{name}.up.sql、{version}_{name}.down.sql），
+ 已执行的迁移记录在schema_migrations表中。执行迁移前会加锁（mysql使用GET_LOCK，其它数据库使用锁表），
+ 多个实例同时启动时只有一个实例会执行迁移。
+ 注意：每个迁移在一个事务中执行，但是mysql的DDL（CREATE、ALTER、DROP等）会隐式提交事务，不能回滚，
+ 包含DDL的迁移执行失败时，失败语句之前的DDL已经生效而迁移不会被记录为已执行，需要人工处理后再重新执行。
+ 因此建议每个迁移只包含一条DDL语句，执行失败时错误中会包含失败的语句序号。
+
+	//go:embed migrations/*.sql
+	var migrations embed.FS
+
+	migrator := mysql.NewMigrator(db)
+	if err := migrator.RegisterFS(migrations, "migrations"); err != nil {
+		panic(err)
+	}
+	applied, err := migrator.Up(ctx)
+*/
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/log"
+)
+
+const (
+	defaultMigrationTable       = "schema_migrations"
+	defaultMigrationLockTimeout = time.Minute
+)
+
+var (
+	// ErrMigrationLocked is returned when the migration lock can not be acquired before the lock timeout.
+	ErrMigrationLocked = errors.New("mysql: migration is locked by another instance")
+	// ErrIrreversible is returned when rolling back a migration without down.
+	ErrIrreversible = errors.New("mysql: migration is irreversible")
+
+	migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+)
+
+// Migration is a versioned schema change, either Go functions (Up/Down) or sql statements (UpSQL/DownSQL).
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *Tx) error
+	Down    func(tx *Tx) error
+	UpSQL   string
+	DownSQL string
+}
+
+// MigrationStatus is the state of a migration.
+type MigrationStatus struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+	// Missing means the migration was applied but is not registered any more.
+	Missing bool `json:"missing,omitempty"`
+	// Statements are the sql statements of a sql migration, only set by DryRun.
+	Statements []string `json:"statements,omitempty"`
+}
+
+// MigratorOption configures a Migrator.
+type MigratorOption func(m *Migrator)
+
+// MigrationTable changes the table recording the applied migrations, default to schema_migrations.
+func MigrationTable(table string) MigratorOption {
+	return func(m *Migrator) {
+		m.table = table
+	}
+}
+
+// MigrationLockTimeout changes how long to wait for the migration lock, default to 1 minute.
+func MigrationLockTimeout(timeout time.Duration) MigratorOption {
+	return func(m *Migrator) {
+		m.lockTimeout = timeout
+	}
+}
+
+// Migrator applies and rolls back migrations in version order.
+type Migrator struct {
+	db          *BaseOrm
+	table       string
+	lockTimeout time.Duration
+	migrations  map[int64]*Migration
+}
+
+//NewMigrator 创建Migrator
+func NewMigrator(db *BaseOrm, opts ...MigratorOption) *Migrator {
+	m := &Migrator{db: db, table: defaultMigrationTable, lockTimeout: defaultMigrationLockTimeout, migrations: make(map[int64]*Migration)}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+//Register 注册迁移，版本号不能重复
+func (m *Migrator) Register(migrations ...*Migration) error {
+	for _, migration := range migrations {
+		if _, ok := m.migrations[migration.Version]; ok {
+			return fmt.Errorf("mysql: duplicate migration version %d", migration.Version)
+		}
+		if migration.Up == nil && migration.UpSQL == "" {
+			return fmt.Errorf("mysql: migration %d has no up", migration.Version)
+		}
+		m.migrations[migration.Version] = migration
+	}
+	return nil
+}
+
+/**
+ * 注册dir目录下的sql迁移文件，文件名格式：{version}_{name}.up.sql、{version}_{name}.down.sql，down文件可以没有
+ * @param : fsys 文件系统，一般为embed.FS
+ * @param : dir 迁移文件所在的目录
+ * @return: 读取文件失败，或者文件名、版本号不合法
+ */
+func (m *Migrator) RegisterFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	migrations := make(map[int64]*Migration)
+	for _, entry := range entries {
+		matches := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || matches == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("mysql: invalid migration version of %s: %v", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		migration, ok := migrations[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matches[2]}
+			migrations[version] = migration
+		} else if migration.Name != matches[2] {
+			return fmt.Errorf("mysql: migration version %d has different names: %s, %s", version, migration.Name, matches[2])
+		}
+		if matches[3] == "up" {
+			migration.UpSQL = string(content)
+		} else {
+			migration.DownSQL = string(content)
+		}
+	}
+	for _, migration := range migrations {
+		if err = m.Register(migration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Status 返回所有迁移的状态，按版本号排序，包括已执行但未注册的迁移；迁移记录表不存在时所有迁移都未执行，不会创建该表
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	exists, err := m.hasTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]MigrationStatus)
+	if exists {
+		if applied, err = m.applied(ctx); err != nil {
+			return nil, err
+		}
+	}
+	var status []MigrationStatus
+	for _, migration := range m.sorted() {
+		s := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			s.Applied, s.AppliedAt = true, record.AppliedAt
+			delete(applied, migration.Version)
+		}
+		status = append(status, s)
+	}
+	for _, record := range applied {
+		record.Missing = true
+		status = append(status, record)
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Version < status[j].Version })
+	return status, nil
+}
+
+//DryRun 返回Up将要执行的迁移以及sql语句，不会修改数据库
+func (m *Migrator) DryRun(ctx context.Context) ([]MigrationStatus, error) {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []MigrationStatus
+	for _, s := range status {
+		if s.Applied || s.Missing {
+			continue
+		}
+		s.Statements = splitStatements(m.migrations[s.Version].UpSQL)
+		pending = append(pending, s)
+	}
+	return pending, nil
+}
+
+//Up 按版本号顺序执行所有未执行的迁移，返回本次执行的迁移
+func (m *Migrator) Up(ctx context.Context) ([]MigrationStatus, error) {
+	return m.UpTo(ctx, -1)
+}
+
+/**
+ * 按版本号顺序执行版本号不大于version的所有未执行的迁移，每个迁移在单独的事务中执行，
+ * 版本号小于已执行的最大版本号的迁移也会被执行；mysql的DDL会隐式提交事务，失败时已执行的DDL不会回滚
+ * @param : ctx 上下文
+ * @param : version 执行到的版本号，小于0表示执行所有迁移
+ * @return: 本次执行成功的迁移，以及执行失败的错误
+ */
+func (m *Migrator) UpTo(ctx context.Context, version int64) (done []MigrationStatus, err error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, migration := range m.sorted() {
+		if _, ok := applied[migration.Version]; ok || (version >= 0 && migration.Version > version) {
+			continue
+		}
+		log.Infof("apply migration %d_%s", migration.Version, migration.Name)
+		err = m.db.Transaction(ctx, func(tx *Tx) error {
+			if err := runMigration(tx, migration.Up, migration.UpSQL); err != nil {
+				return err
+			}
+			return tx.DB.Exec(fmt.Sprintf("INSERT INTO %s (version, name, applied_at) VALUES (?, ?, ?)", m.quotedTable()),
+				migration.Version, migration.Name, time.Now()).Error
+		}, WithRetry(0, 0))
+		if err != nil {
+			return done, fmt.Errorf("mysql: apply migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, MigrationStatus{Version: migration.Version, Name: migration.Name, Applied: true})
+	}
+	return done, nil
+}
+
+/**
+ * 按版本号倒序回滚最近执行的steps个迁移
+ * @param : ctx 上下文
+ * @param : steps 要回滚的迁移个数
+ * @return: 本次回滚成功的迁移，迁移没有down或者未注册时返回错误
+ */
+func (m *Migrator) Down(ctx context.Context, steps int) (done []MigrationStatus, err error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+	for i := 0; i < steps && i < len(versions); i++ {
+		migration, ok := m.migrations[versions[i]]
+		if !ok {
+			return done, fmt.Errorf("mysql: migration %d is not registered", versions[i])
+		}
+		if migration.Down == nil && migration.DownSQL == "" {
+			return done, fmt.Errorf("%w: %d_%s", ErrIrreversible, migration.Version, migration.Name)
+		}
+		log.Infof("rollback migration %d_%s", migration.Version, migration.Name)
+		err = m.db.Transaction(ctx, func(tx *Tx) error {
+			if err := runMigration(tx, migration.Down, migration.DownSQL); err != nil {
+				return err
+			}
+			return tx.DB.Exec(fmt.Sprintf("DELETE FROM %s WHERE version = ?", m.quotedTable()), migration.Version).Error
+		}, WithRetry(0, 0))
+		if err != nil {
+			return done, fmt.Errorf("mysql: rollback migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, MigrationStatus{Version: migration.Version, Name: migration.Name})
+	}
+	return done, nil
+}
+
+func runMigration(tx *Tx, fn func(tx *Tx) error, statements string) error {
+	if fn != nil {
+		return fn(tx)
+	}
+	split := splitStatements(statements)
+	for i, statement := range split {
+		if err := tx.DB.Exec(statement).Error; err != nil {
+			//mysql的DDL会隐式提交，序号之前的语句可能已经生效
+			return fmt.Errorf("statement %d of %d: %w", i+1, len(split), err)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) createTable(ctx context.Context) error {
+	_, err := sqlDBOf(m.db.DB).ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s "+
+		"(version BIGINT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at DATETIME NOT NULL)", m.quotedTable()))
+	return err
+}
+
+//hasTable 迁移记录表是否存在
+func (m *Migrator) hasTable(ctx context.Context) (bool, error) {
+	tables, err := dialectOf(m.db.DB).ListTables(withContext(m.db.DB, ctx), m.table)
+	if err != nil {
+		return false, err
+	}
+	for _, table := range tables {
+		if table == m.table {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+//applied 查询已执行的迁移
+func (m *Migrator) applied(ctx context.Context) (map[int64]MigrationStatus, error) {
+	rows, err := sqlDBOf(m.db.DB).QueryContext(ctx, fmt.Sprintf("SELECT version, name, applied_at FROM %s", m.quotedTable()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int64]MigrationStatus)
+	for rows.Next() {
+		var (
+			s         = MigrationStatus{Applied: true}
+			appliedAt time.Time
+		)
+		if err = rows.Scan(&s.Version, &s.Name, &appliedAt); err != nil {
+			return nil, err
+		}
+		s.AppliedAt = &appliedAt
+		applied[s.Version] = s
+	}
+	return applied, rows.Err()
+}
+
+func (m *Migrator) sorted() []*Migration {
+	migrations := make([]*Migration, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations
+}
+
+func (m *Migrator) quotedTable() string {
+	return m.db.Dialect().Quote(m.table)
+}
+
+/**
+ * 获取迁移锁，mysql使用GET_LOCK（连接断开时自动释放），其它数据库通过向锁表插入同一主键实现，
+ * 进程异常退出时锁表中的记录不会被删除，需要手动删除
+ * @param : ctx 上下文
+ * @return: unlock 释放锁的函数
+ */
+func (m *Migrator) lock(ctx context.Context) (unlock func(), err error) {
+	if err = m.createTable(ctx); err != nil {
+		return nil, err
+	}
+	unlock, err = dialectOf(m.db.DB).Lock(ctx, sqlDBOf(m.db.DB), "migrate:"+m.table, m.lockTimeout)
+	if err != errLockUnsupported {
+		return unlock, err
+	}
+	lockTable := m.db.Dialect().Quote(m.table + "_lock")
+	sqlDB := sqlDBOf(m.db.DB)
+	if _, err = sqlDB.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INT NOT NULL PRIMARY KEY, locked_at DATETIME NOT NULL)", lockTable)); err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(m.lockTimeout)
+	for {
+		if _, err = sqlDB.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (id, locked_at) VALUES (1, ?)", lockTable), time.Now()); err == nil {
+			return func() {
+				if _, err := sqlDB.Exec(fmt.Sprintf("DELETE FROM %s WHERE id = 1", lockTable)); err != nil {
+					log.Errorf("release migration lock error: %v", err)
+				}
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, ErrMigrationLocked
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+//splitStatements 将sql文件拆分成单条语句，忽略引号内以及注释中的分号
+func splitStatements(content string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+		quote      byte
+	)
+	flush := func() {
+		if statement := strings.TrimSpace(current.String()); statement != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case quote != 0:
+			current.WriteByte(c)
+			if c == '\\' && i+1 < len(content) {
+				i++
+				current.WriteByte(content[i])
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			current.WriteByte(c)
+		case c == '-' && strings.HasPrefix(content[i:], "--"), c == '#':
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+			current.WriteByte('\n')
+		case c == '/' && strings.HasPrefix(content[i:], "/*"):
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				i = len(content)
+			} else {
+				i += end + 3
+			}
+			current.WriteByte(' ')
+		case c == ';':
+			flush()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return statements
+}
@@ -0,0 +1,136 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var migrationFiles = fstest.MapFS{
+	"migrations/1_create_users.up.sql": {Data: []byte(`
+-- users table; created by migration
+CREATE TABLE users (id INTEGER PRIMARY KEY, name VARCHAR(64) NOT NULL);
+INSERT INTO users (name) VALUES ('a;b');`)},
+	"migrations/1_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+	"migrations/2_add_age.up.sql":        {Data: []byte("ALTER TABLE users ADD COLUMN age INT NOT NULL DEFAULT 0")},
+	"migrations/README.md":               {Data: []byte("ignored")},
+}
+
+func TestMigrator(t *testing.T) {
+	orm := newTestOrm(t)
+	ctx := context.Background()
+	migrator := NewMigrator(orm)
+	if err := migrator.RegisterFS(migrationFiles, "migrations"); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.Register(&Migration{Version: 3, Name: "seed", Up: func(tx *Tx) error {
+		return tx.DB.Exec("INSERT INTO users (name, age) VALUES ('seed', 18)").Error
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.Register(&Migration{Version: 3, Name: "dup", UpSQL: "SELECT 1"}); err == nil {
+		t.Fatal("duplicate version should be rejected")
+	}
+
+	plan, err := migrator.DryRun(ctx)
+	if err != nil || len(plan) != 3 || len(plan[0].Statements) != 2 || plan[0].Statements[1] != "INSERT INTO users (name) VALUES ('a;b')" {
+		t.Fatalf("unexpected plan %+v, %v", plan, err)
+	}
+	if orm.HasTable("users") || orm.HasTable(defaultMigrationTable) {
+		t.Fatal("dry run should not apply migrations or create the migrations table")
+	}
+	status, err := migrator.Status(ctx)
+	if err != nil || len(status) != 3 || status[0].Applied || orm.HasTable(defaultMigrationTable) {
+		t.Fatalf("all migrations should be pending before the first Up: %+v, %v", status, err)
+	}
+
+	done, err := migrator.UpTo(ctx, 2)
+	if err != nil || len(done) != 2 {
+		t.Fatalf("UpTo: %+v, %v", done, err)
+	}
+	if done, err = migrator.Up(ctx); err != nil || len(done) != 1 || done[0].Version != 3 {
+		t.Fatalf("Up: %+v, %v", done, err)
+	}
+	if done, err = migrator.Up(ctx); err != nil || len(done) != 0 {
+		t.Fatalf("Up should be idempotent: %+v, %v", done, err)
+	}
+	var count int
+	orm.Table("users").Where("age = ?", 18).Count(&count)
+	if count != 1 {
+		t.Fatalf("seed migration not applied, count %d", count)
+	}
+
+	status, err = migrator.Status(ctx)
+	if err != nil || len(status) != 3 || !status[2].Applied || status[2].AppliedAt == nil {
+		t.Fatalf("unexpected status %+v, %v", status, err)
+	}
+
+	if _, err = migrator.Down(ctx, 1); !errors.Is(err, ErrIrreversible) {
+		t.Fatalf("migration without down should be irreversible, got %v", err)
+	}
+}
+
+func TestMigrator_FailedAndDown(t *testing.T) {
+	orm := newTestOrm(t)
+	ctx := context.Background()
+	migrator := NewMigrator(orm, MigrationTable("versions"))
+	_ = migrator.RegisterFS(migrationFiles, "migrations")
+	_ = migrator.Register(&Migration{Version: 3, Name: "broken", UpSQL: "INSERT INTO users (name) VALUES ('x'); INSERT INTO unknown VALUES (1);"})
+
+	done, err := migrator.Up(ctx)
+	if err == nil || len(done) != 2 || !strings.Contains(err.Error(), "statement 2 of 2") {
+		t.Fatalf("expect the second statement of the third migration to fail: %+v, %v", done, err)
+	}
+	var count int
+	orm.Table("users").Where("name = ?", "x").Count(&count)
+	if count != 0 {
+		t.Fatal("failed migration should be rolled back")
+	}
+	status, _ := migrator.Status(ctx)
+	if status[2].Applied {
+		t.Fatalf("failed migration should not be recorded: %+v", status)
+	}
+
+	delete(migrator.migrations, 3)
+	if done, err = migrator.Down(ctx, 5); err == nil || len(done) != 0 {
+		t.Fatalf("migration 2 has no down: %+v, %v", done, err)
+	}
+	migrator.migrations[2].DownSQL = "SELECT 1"
+	if done, err = migrator.Down(ctx, 5); err != nil || len(done) != 2 || done[0].Version != 2 {
+		t.Fatalf("Down: %+v, %v", done, err)
+	}
+	if orm.HasTable("users") {
+		t.Fatal("users should be dropped")
+	}
+}
+
+func TestMigrator_Lock(t *testing.T) {
+	orm := newTestOrm(t)
+	ctx := context.Background()
+	migrator := NewMigrator(orm, MigrationLockTimeout(200*time.Millisecond))
+	_ = migrator.RegisterFS(migrationFiles, "migrations")
+
+	unlock, err := migrator.lock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = migrator.Up(ctx); err != ErrMigrationLocked {
+		t.Fatalf("expect locked, got %v", err)
+	}
+	unlock()
+	if done, err := migrator.Up(ctx); err != nil || len(done) != 2 {
+		t.Fatalf("Up after unlock: %+v, %v", done, err)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	statements := splitStatements("CREATE TABLE a (s VARCHAR(8) DEFAULT ';'); # comment;\n/* block; */ INSERT INTO a VALUES ('it\\'s;'); -- tail;\n;")
+	want := []string{"CREATE TABLE a (s VARCHAR(8) DEFAULT ';')", "INSERT INTO a VALUES ('it\\'s;')"}
+	if !reflect.DeepEqual(statements, want) {
+		t.Fatalf("got %q", statements)
+	}
+}
@@ -15,13 +15,12 @@ import (
 
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/mysql"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
-
-	"github.com/yinjk/go-utils/pkg/utils/collection/maps"
 )
 
 var (
-	tables = maps.NewSegmentHashMap()
+	tables = sync.Map{} //已经建过的表，key为tableKey
 	once   = sync.Once{}
 	db     *BaseOrm
 )
@@ -34,16 +33,39 @@ type Config struct {
 	Idle        int    // pool
 	IdleTimeout int    // connect max life time.
 	LogMode     bool   // is print log
+	Dialect     string // 数据库方言：mysql、sqlite3，默认为mysql
+
+	SlowThreshold time.Duration // 慢查询日志的阈值，0表示不打印慢查询日志
+	Metrics       bool          // 是否向prometheus.DefaultRegisterer注册查询指标
 }
 
-// NewMySQL new db and retry connection when has error.
+// NewMySQL new db and retry connection when has error, the database is specified by c.Dialect.
 func NewMySQL(c *Config) (db *BaseOrm) {
-	createDataBase(c) //如果数据库不存在，则尝试创建数据库
-	orm, err := gorm.Open("mysql", c.DSN)
+	if err := createDatabase(c); err != nil { //如果数据库不存在，则尝试创建数据库
+		panic(err)
+	}
+	db, err := Open(c)
 	if err != nil {
-		log.Errorf("db dsn(%v) error: %v", c.DSN, err)
 		panic(err)
 	}
+	return
+}
+
+/**
+ * 连接数据库并设置连接池，与NewMySQL不同的是不会尝试创建database，出错时返回错误而不是panic，用于连接只读的从库
+ * @param : c 数据库配置
+ * @return: db 数据库连接
+ */
+func Open(c *Config) (db *BaseOrm, err error) {
+	dialect, err := dialectOfConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	orm, err := gorm.Open(dialect.Name(), c.DSN)
+	if err != nil {
+		log.Errorf("db dsn(%v) error: %v", c.DSN, err)
+		return nil, err
+	}
 	db = &BaseOrm{orm}
 	log.Info("begin set max idle connection ", c.Idle)
 	db.DB.DB().SetMaxIdleConns(c.Idle)
@@ -57,11 +79,24 @@ func NewMySQL(c *Config) (db *BaseOrm) {
 	}
 	log.Info("begin set connection max life time, ", idleTimeout)
 	db.DB.DB().SetConnMaxLifetime(time.Duration(idleTimeout) * time.Minute)
+	dialect.Configure(db.DB.DB(), c)
 	if err = db.DB.DB().Ping(); err != nil {
 		log.Error(err)
-		panic(err)
+		_ = orm.Close()
+		return nil, err
 	}
 	db.DB.LogMode(c.LogMode)
+	db.DB = db.DB.Set(logModeSetting, c.LogMode)
+	if c.SlowThreshold > 0 || c.Metrics {
+		opts := []InstrumentOption{SlowThreshold(c.SlowThreshold)}
+		if c.Metrics {
+			opts = append(opts, WithRegisterer(prometheus.DefaultRegisterer))
+		}
+		if err = db.Instrument(opts...); err != nil {
+			_ = orm.Close()
+			return nil, err
+		}
+	}
 	return
 }
 
@@ -73,25 +108,11 @@ func Get(c *Config) *BaseOrm {
 	return db
 }
 
-/**
- * 尝试创建database，如果database不存在的话
- * @param : c 数据库配置
- * @return:
- * @author: yinjk
- * @time  : 2019/6/12 10:03
- */
-func createDataBase(c *Config) {
-	dsn := strings.Replace(c.DSN, c.DataBase, "mysql", 1) //连接名为mysql的数据库
-	db, err := gorm.Open("mysql", dsn)
-	if err != nil {
-		log.Errorf("db dsn(%v) error: %v", dsn, err)
-		panic(err)
-	}
-	defer func() { _ = db.Close() }() //数据库创建之后直接关闭该连接
-	if err = db.Exec("create database if not exists " + c.DataBase + " Character Set UTF8;").Error; err != nil {
-		log.Errorf("create database %v error(%v)", c.DataBase, err)
-		panic(err)
-	}
+//tableKey 表的缓存key，db保存在key中，避免关闭的*sql.DB的地址被复用
+type tableKey struct {
+	db    gorm.SQLCommon
+	beans reflect.Type
+	table string
 }
 
 type BaseOrm struct {
@@ -117,8 +138,15 @@ type PageData struct {
  * @time  : 2019/6/11 17:06
  */
 func (bo BaseOrm) Begin() (tx *Tx) {
-	db := bo.DB.Begin()
-	return &Tx{db}
+	ctx, bound := boundContext(bo.DB)
+	if !bound {
+		return &Tx{bo.DB.Begin()}
+	}
+	db := bo.DB.BeginTx(ctx, nil)
+	if db.Error != nil {
+		return &Tx{db}
+	}
+	return &Tx{withContext(db, ctx)}
 }
 
 //Commit 请勿执行该方法，已废弃，若要提交事物，请使用tx.Commit()
@@ -141,19 +169,42 @@ func (bo BaseOrm) Rollback() (tx *gorm.DB) {
  * @time  : 2019/6/11 17:10
  */
 func (bo BaseOrm) CreateTable(beans interface{}) {
-	beansType := reflect.TypeOf(beans)
-	if !tables.PutIfAbsent(beansType, true) { //返回false表示已经map中已经创建过该类型的表了，这里直接返回
-		return
+	if err := createTable(bo.DB, beans, ""); err != nil {
+		panic(err)
+	}
+}
+
+/**
+ * 表不存在时创建表，每个数据库中的每张表只会检查一次
+ * @param : db 数据库连接
+ * @param : beans 要创建的表的实体
+ * @param : table 表名，为空时使用实体的表名，分表时为物理表名
+ * @return: 建表失败的错误
+ */
+func createTable(db *gorm.DB, beans interface{}, table string) error {
+	//同一个类型在不同的数据库中需要分别建表
+	key := tableKey{db: unwrapSQL(db.CommonDB()), beans: reflect.TypeOf(beans), table: table}
+	if _, loaded := tables.LoadOrStore(key, true); loaded { //已经创建过该类型的表了，这里直接返回
+		return nil
 	}
 	//put成功，表示第一次创建，执行创建表语句
-	if !bo.HasTable(beans) {
-		if tables, ok := beans.(Tabler); ok {
-			log.Infof("create table %v", tables.TableName())
-		}
-		if err := bo.Set("gorm:table_options", "ENGINE=InnoDB DEFAULT CHARSET=utf8").CreateTable(beans).Error; err != nil && !strings.Contains(err.Error(), "already exists") {
-			panic(err)
-		}
+	if table != "" {
+		db = db.Table(table)
+	}
+	if table == "" && db.HasTable(beans) || table != "" && db.HasTable(table) {
+		return nil
+	}
+	if tables, ok := beans.(Tabler); ok && table == "" {
+		table = tables.TableName()
+	}
+	if table != "" {
+		log.Infof("create table %v", table)
 	}
+	if err := db.Set("gorm:table_options", dialectOf(db).TableOptions()).CreateTable(beans).Error; err != nil && !strings.Contains(err.Error(), "already exists") {
+		tables.Delete(key)
+		return err
+	}
+	return nil
 }
 
 //expand method
@@ -175,19 +226,19 @@ func (bo BaseOrm) ListWithPage(result interface{}, pagination Pagination, orderB
 		return nil, errors.New("the result only supports slice type")
 	}
 	db := bo.DB
+	db = where(db, query, args...)
+	countDB := db
 	if orderBy != "" {
 		db = db.Order(orderBy)
 	}
-	if query != nil && query != "" {
-		db = db.Where(query, args...)
-	}
+	db = orderBySpec(db, query)
 	if err = db.Offset((pagination.Page - 1) * pagination.PageSize).Limit(pagination.PageSize).Find(result).Error; err != nil {
 		log.Errorf("BaseOrm.ListWithPage db.Find error(%v):", err)
 		return nil, err
 	}
 	//查询分页数据集的总数
 	value := reflect.New(typ.Elem()).Interface()
-	if err = db.Model(value).Count(&pagination.TotalCount).Error; err != nil {
+	if err = countDB.Model(value).Count(&pagination.TotalCount).Error; err != nil {
 		log.Errorf("page query BaseOrm.ListWithPage db.Count error(%v):", err)
 		return nil, err
 	}
@@ -206,163 +257,26 @@ func (bo BaseOrm) Query(query string, args ...interface{}) (rows *sql.Rows, err
 	return bo.DB.Raw(query, args...).Rows()
 }
 
+//StructFieldMap 已废弃，FastQuery的列与字段的映射见rowmapper.go
+//Deprecated
 type StructFieldMap map[string]reflect.StructField
 
-func (s StructFieldMap) getField(name string) (reflect.StructField, bool) {
-	field, ok := s[name]
-	if ok {
-		return field, ok
-	}
-	// if not found, try covert mysql field name from snake case to camel case
-	name = strings.ReplaceAll(name, "_", "")
-	field, ok = s[name]
-	if ok {
-		return field, ok
-	}
-	// if still not found, it maybe a join query，try analyze it.
-	//if strings.Contains(name, ".") {
-	//	strings.Split(name, ".")
-	//}
-	return field, ok
-}
-
 /**
- * 执行sql快速查询,该方法相比Query使用更简单，不需要自己手动去遍历rows了
+ * 执行sql快速查询,该方法相比Query使用更简单，不需要自己手动去遍历rows了，
+ * 支持join查询映射到嵌套结构体、json列、NULL值等，映射规则详见rowmapper.go
  * @param : query 查询语句
- * @param : scanner 查询结果映射
+ * @param : value 查询结果映射，slice或者结构体的指针
  * @param : args 查询参数
  * @return: result 查询结果
  * @author: yinjk
  * @time  : 2019/6/10 11:07
  */
 func (bo BaseOrm) FastQuery(query string, value interface{}, args ...interface{}) (err error) {
-	var (
-		targetTypeMap = make(StructFieldMap)
-		targetValue   = reflect.ValueOf(value)
-	)
-	if targetValue.Kind() != reflect.Ptr {
-		return errors.New("FastQuery must accept a ptr type")
-	}
-	targetValue = reflect.Indirect(targetValue)
-	if targetValue.Kind() == reflect.Slice {
-		rows, err := bo.Query(query, args...)
-		if err != nil {
-			return err
-		}
-		dbTypes, err := rows.ColumnTypes()
-		if err != nil {
-			return err
-		}
-		scanValues := make([]interface{}, 0)
-		keys := make([]string, 0)
-		slice := reflect.MakeSlice(targetValue.Type(), 0, 0)
-		switch targetValue.Type().Elem().Kind() {
-		case reflect.Map:
-			for _, v := range dbTypes {
-				if targetValue.Type().Elem().Elem().Kind() == reflect.Interface {
-					i := reflect.New(v.ScanType()).Interface()
-					scanValues = append(scanValues, i)
-				} else {
-					i := reflect.New(targetValue.Type().Elem().Elem()).Interface()
-					scanValues = append(scanValues, i)
-				}
-				keys = append(keys, v.Name())
-			}
-			for rows.Next() {
-				refMap := reflect.MakeMap(targetValue.Type().Elem())
-				if err := rows.Scan(scanValues...); err != nil {
-					return err
-				}
-				for i, v := range scanValues {
-					refMap.SetMapIndex(reflect.ValueOf(keys[i]), reflect.ValueOf(v).Elem())
-				}
-				slice = reflect.Append(slice, refMap)
-			}
-		case reflect.Struct:
-			for i := 0; i < targetValue.Type().Elem().NumField(); i++ {
-				fieldName := targetValue.Type().Field(i).Name
-				structField := targetValue.Field(i).Type()
-				// If the body contains a nested structure, then need to traverse the fields of the inner structure
-				if structField.Kind() == reflect.Struct && structField.Name() == fieldName {
-					for j := 0; j < structField.NumField(); j++ {
-						targetTypeMap[strings.ToUpper(structField.Field(j).Name)] = structField.Field(j)
-					}
-					continue
-				}
-				targetTypeMap[strings.ToUpper(fieldName)] = targetValue.Type().Elem().Field(i)
-			}
-			for _, v := range dbTypes {
-				field, ok := targetTypeMap.getField(strings.ToUpper(v.Name()))
-				if !ok { //target中没有这个字段，设置一个虚拟字段用来占位
-					var ignoreField interface{}
-					scanValues = append(scanValues, &ignoreField)
-					keys = append(keys, strings.ToUpper(v.Name()))
-					continue
-				}
-				i := reflect.New(field.Type).Interface()
-				//fmt.Println(v.ScanType().Kind() == reflect.Uint32)
-				//i := reflect.New(v.ScanType()).Interface()
-				scanValues = append(scanValues, i)
-				keys = append(keys, strings.ToUpper(v.Name()))
-			}
-			for rows.Next() {
-				row := reflect.New(targetValue.Type().Elem()).Interface()
-				refStu := reflect.ValueOf(row).Elem()
-				if err = rows.Scan(scanValues...); err != nil {
-					return err
-				}
-				for i, v := range scanValues {
-					field, ok := targetTypeMap.getField(keys[i])
-					if !ok { //结构体中没有该字段，说明是一个用来占位的虚拟字段，直接跳过
-						continue
-					}
-					// if this field is ptr type
-					if refStu.FieldByIndex(field.Index).Kind() == reflect.Ptr {
-						refStu.FieldByIndex(field.Index).Set(reflect.ValueOf(&v))
-					} else {
-						refStu.FieldByIndex(field.Index).Set(reflect.ValueOf(v).Elem())
-					}
-				}
-				slice = reflect.Append(slice, refStu)
-			}
-		case reflect.Slice:
-			for _, v := range dbTypes {
-				if targetValue.Type().Elem().Elem().Kind() == reflect.Interface {
-					i := reflect.New(v.ScanType()).Interface()
-					scanValues = append(scanValues, i)
-				} else {
-					i := reflect.New(targetValue.Type().Elem().Elem()).Interface()
-					scanValues = append(scanValues, i)
-				}
-			}
-			for rows.Next() {
-				refSlice := reflect.MakeSlice(targetValue.Type().Elem(), len(scanValues), len(scanValues))
-				if err := rows.Scan(scanValues...); err != nil {
-					return err
-				}
-				for i, v := range scanValues {
-					refSlice.Index(i).Set(reflect.ValueOf(v).Elem())
-				}
-				slice = reflect.Append(slice, refSlice)
-			}
-		default:
-			for rows.Next() {
-				i := reflect.New(targetValue.Type().Elem()).Interface()
-				if err = rows.Scan(i); err != nil {
-					return err
-				}
-				slice = reflect.Append(slice, reflect.ValueOf(i).Elem())
-			}
-		}
-		targetValue.Set(slice)
-	} else {
-		return errors.New("FastQuery only support a slice type")
-	}
-	return nil
+	return fastQuery(bo.DB, query, value, args...)
 }
 
 /**
- * 执行sql，不包括返回结果，一般用于增删改
+ * 执行sql，不包括返回结果，一般用于增删改；注册了写钩子时不能执行INSERT、UPDATE、DELETE、REPLACE，返回ErrHooksBypassed
  * @param : sql 查询语句
  * @param : args 查询参数
  * @return: rowsAffected 影响行数
@@ -370,7 +284,10 @@ func (bo BaseOrm) FastQuery(query string, value interface{}, args ...interface{}
  * @time  : 2019/6/10 11:11
  */
 func (bo BaseOrm) Exec(sql string, args ...interface{}) (rowsAffected int64, err error) {
-	exec := bo.DB.Exec(sql, args...)
+	if err = checkRawWrite(bo.DB, sql); err != nil {
+		return 0, err
+	}
+	exec := execSQL(bo.DB, sql, args...)
 	return exec.RowsAffected, exec.Error
 }
 
@@ -382,7 +299,7 @@ func (bo BaseOrm) Exec(sql string, args ...interface{}) (rowsAffected int64, err
  * @time  : 2019/6/10 11:11
  */
 func (bo BaseOrm) DeleteById(id interface{}, beans interface{}) error {
-	return bo.Delete(beans, "id=?", id).Error
+	return bo.Set(primaryKeySetting, id).Delete(beans, "id=?", id).Error
 }
 
 /**
@@ -439,7 +356,10 @@ func (bo BaseOrm) Updates(bean interface{}, args map[string]interface{}) error {
  * @time  : 2019/6/10 11:47
  */
 func (bo BaseOrm) FindIn(field string, value interface{}, beans interface{}) error {
-	return bo.DB.Where(field+" in(?)", value).Find(beans).Error
+	if err := checkColumn(field); err != nil {
+		return err
+	}
+	return bo.DB.Where(quoteColumn(bo.DB, field)+" in(?)", value).Find(beans).Error
 }
 
 func (bo BaseOrm) FindById(id interface{}, beans interface{}) error {
@@ -453,18 +373,26 @@ func (bo BaseOrm) FindById(id interface{}, beans interface{}) error {
 }
 
 func (bo BaseOrm) FindOneEq(beans interface{}, fieldName string, value interface{}) (err error) {
-	return bo.DB.Where(fieldName+" = ?", value).First(beans).Error
+	if err := checkColumn(fieldName); err != nil {
+		return err
+	}
+	return bo.DB.Where(quoteColumn(bo.DB, fieldName)+" = ?", value).First(beans).Error
 }
-func (bo BaseOrm) FindOneCondition(bean interface{}, condition string, params ...interface{}) (err error) {
-	return bo.DB.Where(condition, params...).First(bean).Error
+//FindOneCondition 查询满足条件的第一条记录，condition可以是sql条件、map、结构体或者*Spec
+func (bo BaseOrm) FindOneCondition(bean interface{}, condition interface{}, params ...interface{}) (err error) {
+	return scoped(bo.DB, condition, params...).First(bean).Error
 }
 
-func (bo BaseOrm) FindCondition(bean interface{}, condition string, params ...interface{}) (err error) {
-	return bo.DB.Where(condition, params...).Find(bean).Error
+//FindCondition 查询满足条件的所有记录，condition可以是sql条件、map、结构体或者*Spec
+func (bo BaseOrm) FindCondition(bean interface{}, condition interface{}, params ...interface{}) (err error) {
+	return scoped(bo.DB, condition, params...).Find(bean).Error
 }
 
 func (bo BaseOrm) FindOneLike(fieldName string, value interface{}, beans interface{}) (err error) {
-	if err = bo.DB.Where(fieldName+" like ?", value).First(beans).Error; err != nil {
+	if err := checkColumn(fieldName); err != nil {
+		return err
+	}
+	if err = bo.DB.Where(quoteColumn(bo.DB, fieldName)+" like ?", value).First(beans).Error; err != nil {
 		log.Error(err)
 	}
 	return
@@ -518,28 +446,37 @@ func (bo BaseOrm) ListMap(sql string, params ...interface{}) (data []map[string]
 }
 
 func (bo BaseOrm) Count(beans interface{}, query interface{}, args ...interface{}) (count int, err error) {
-	err = bo.DB.Model(beans).Where(query, args...).Count(&count).Error
+	err = where(bo.DB.Model(beans), query, args...).Count(&count).Error
 	return
 }
 
 func (bo BaseOrm) ListBy(beans interface{}, fields *[]string, query interface{}, args ...interface{}) error {
 	if len(*fields) == 0 {
-		return bo.DB.Where(query, args...).Find(beans).Error
+		return scoped(bo.DB, query, args...).Find(beans).Error
 	} else {
-		return bo.DB.Select(*fields).Where(query, args...).Find(beans).Error
+		return scoped(bo.DB.Select(*fields), query, args...).Find(beans).Error
 	}
 }
 
 func (bo BaseOrm) ListEq(fieldName string, value interface{}, beans interface{}) error {
-	return bo.DB.Where(fieldName+" = ?", value).Find(beans).Error
+	if err := checkColumn(fieldName); err != nil {
+		return err
+	}
+	return bo.DB.Where(quoteColumn(bo.DB, fieldName)+" = ?", value).Find(beans).Error
 }
 
 func (bo BaseOrm) ListNotEq(fieldName string, value interface{}, beans interface{}) error {
-	return bo.DB.Where(fieldName+" != ?", value).Find(beans).Error
+	if err := checkColumn(fieldName); err != nil {
+		return err
+	}
+	return bo.DB.Where(quoteColumn(bo.DB, fieldName)+" != ?", value).Find(beans).Error
 }
 
 func (bo BaseOrm) ListLike(fieldName string, value interface{}, beans interface{}) error {
-	return bo.DB.Where(fieldName+" like ?", value).Find(beans).Error
+	if err := checkColumn(fieldName); err != nil {
+		return err
+	}
+	return bo.DB.Where(quoteColumn(bo.DB, fieldName)+" like ?", value).Find(beans).Error
 }
 
 func (bo BaseOrm) ListAll(beans interface{}) error {
@@ -586,19 +523,19 @@ func (t Tx) ListWithPage(result interface{}, deleted bool, pagination Pagination
 	if deleted {
 		db = t.Unscoped()
 	}
+	db = where(db, query, args...)
+	countDB := db
 	if orderBy != "" {
 		db = db.Order(orderBy)
 	}
-	if query != nil && query != "" {
-		db = db.Where(query, args...)
-	}
+	db = orderBySpec(db, query)
 	if err = db.Offset((pagination.Page - 1) * pagination.PageSize).Limit(pagination.PageSize).Find(result).Error; err != nil {
 		log.Errorf("BaseOrm.ListWithPage db.Find error(%v):", err)
 		return nil, err
 	}
 	//查询分页数据集的总数
 	value := reflect.New(typ.Elem()).Interface()
-	if err = db.Model(value).Count(&pagination.TotalCount).Error; err != nil {
+	if err = countDB.Model(value).Count(&pagination.TotalCount).Error; err != nil {
 		log.Errorf("page query BaseOrm.ListWithPage db.Count error(%v):", err)
 		return nil, err
 	}
@@ -609,118 +546,21 @@ func (t Tx) Query(query string, args ...interface{}) (rows *sql.Rows, err error)
 	return t.DB.Raw(query, args...).Rows()
 }
 
+//FastQuery 执行sql快速查询，详见BaseOrm.FastQuery
 func (t Tx) FastQuery(query string, value interface{}, args ...interface{}) (err error) {
-	var (
-		targetTypeMap = make(map[string]reflect.StructField)
-		targetValue   = reflect.ValueOf(value)
-	)
-	if targetValue.Kind() != reflect.Ptr {
-		return errors.New("FastQuery must accept a ptr type")
-	}
-	targetValue = reflect.Indirect(targetValue)
-	if targetValue.Kind() == reflect.Slice {
-		rows, err := t.Query(query, args...)
-		if err != nil {
-			return err
-		}
-		dbTypes, err := rows.ColumnTypes()
-		if err != nil {
-			return err
-		}
-		scanValues := make([]interface{}, 0)
-		keys := make([]string, 0)
-		slice := reflect.MakeSlice(targetValue.Type(), 0, 0)
-		switch targetValue.Type().Elem().Kind() {
-		case reflect.Map:
-			for _, v := range dbTypes {
-				if targetValue.Type().Elem().Elem().Kind() == reflect.Interface {
-					i := reflect.New(v.ScanType()).Interface()
-					scanValues = append(scanValues, i)
-				} else {
-					i := reflect.New(targetValue.Type().Elem().Elem()).Interface()
-					scanValues = append(scanValues, i)
-				}
-				keys = append(keys, v.Name())
-			}
-			for rows.Next() {
-				refMap := reflect.MakeMap(targetValue.Type().Elem())
-				if err := rows.Scan(scanValues...); err != nil {
-					return err
-				}
-				for i, v := range scanValues {
-					refMap.SetMapIndex(reflect.ValueOf(keys[i]), reflect.ValueOf(v).Elem())
-				}
-				slice = reflect.Append(slice, refMap)
-			}
-		case reflect.Struct:
-			for i := 0; i < targetValue.Type().Elem().NumField(); i++ {
-				fieldName := targetValue.Type().Elem().Field(i).Name
-				targetTypeMap[strings.ToUpper(fieldName)] = targetValue.Type().Elem().Field(i)
-			}
-			for _, v := range dbTypes {
-				if _, ok := targetTypeMap[strings.ToUpper(v.Name())]; !ok { //target中没有这个字段
-					continue
-				}
-				i := reflect.New(targetTypeMap[strings.ToUpper(v.Name())].Type).Interface()
-				//fmt.Println(v.ScanType().Kind() == reflect.Uint32)
-				//i := reflect.New(v.ScanType()).Interface()
-				scanValues = append(scanValues, i)
-				keys = append(keys, strings.ToUpper(v.Name()))
-			}
-			for rows.Next() {
-				row := reflect.New(targetValue.Type().Elem()).Interface()
-				refStu := reflect.ValueOf(row).Elem()
-				if err = rows.Scan(scanValues...); err != nil {
-					return err
-				}
-				for i, v := range scanValues {
-					refStu.FieldByIndex(targetTypeMap[keys[i]].Index).Set(reflect.ValueOf(v).Elem())
-				}
-				slice = reflect.Append(slice, refStu)
-			}
-		case reflect.Slice:
-			for _, v := range dbTypes {
-				if targetValue.Type().Elem().Elem().Kind() == reflect.Interface {
-					i := reflect.New(v.ScanType()).Interface()
-					scanValues = append(scanValues, i)
-				} else {
-					i := reflect.New(targetValue.Type().Elem().Elem()).Interface()
-					scanValues = append(scanValues, i)
-				}
-			}
-			for rows.Next() {
-				refSlice := reflect.MakeSlice(targetValue.Type().Elem(), len(scanValues), len(scanValues))
-				if err := rows.Scan(scanValues...); err != nil {
-					return err
-				}
-				for i, v := range scanValues {
-					refSlice.Index(i).Set(reflect.ValueOf(v).Elem())
-				}
-				slice = reflect.Append(slice, refSlice)
-			}
-		default:
-			for rows.Next() {
-				i := reflect.New(targetValue.Type().Elem()).Interface()
-				if err = rows.Scan(i); err != nil {
-					return err
-				}
-				slice = reflect.Append(slice, reflect.ValueOf(i).Elem())
-			}
-		}
-		targetValue.Set(slice)
-	} else {
-		return errors.New("FastQuery only support a slice type")
-	}
-	return nil
+	return fastQuery(t.DB, query, value, args...)
 }
 
 func (t Tx) Exec(sql string, args ...interface{}) (rowsAffected int64, err error) {
-	exec := t.DB.Exec(sql, args...)
+	if err = checkRawWrite(t.DB, sql); err != nil {
+		return 0, err
+	}
+	exec := execSQL(t.DB, sql, args...)
 	return exec.RowsAffected, exec.Error
 }
 
 func (t Tx) DeleteById(id interface{}, base interface{}) error {
-	return t.Delete(base, "id=?", id).Error
+	return t.Set(primaryKeySetting, id).Delete(base, "id=?", id).Error
 }
 
 func (t Tx) Create(val interface{}) error {
@@ -763,7 +603,10 @@ func (t Tx) Updates(bean interface{}, args map[string]interface{}) error {
  * @time  : 2019/6/10 11:47
  */
 func (t Tx) FindIn(field string, value interface{}, beans interface{}) error {
-	return t.DB.Where(field+" in(?)", value).Find(beans).Error
+	if err := checkColumn(field); err != nil {
+		return err
+	}
+	return t.DB.Where(quoteColumn(t.DB, field)+" in(?)", value).Find(beans).Error
 }
 
 func (t Tx) FindById(id interface{}, beans interface{}) error {
@@ -777,7 +620,10 @@ func (t Tx) FindById(id interface{}, beans interface{}) error {
 }
 
 func (t Tx) FindOneEq(beans interface{}, fieldName string, value interface{}) (err error) {
-	if err = t.DB.Where(fieldName+" = ?", value).First(beans).Error; err != nil {
+	if err := checkColumn(fieldName); err != nil {
+		return err
+	}
+	if err = t.DB.Where(quoteColumn(t.DB, fieldName)+" = ?", value).First(beans).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil
 		}
@@ -786,12 +632,21 @@ func (t Tx) FindOneEq(beans interface{}, fieldName string, value interface{}) (e
 	return nil
 }
 
-func (t Tx) FindCondition(condition string, bean interface{}, params ...interface{}) (err error) {
-	return t.DB.Where(condition, params...).Find(bean).Error
+//FindOneCondition 查询满足条件的第一条记录，condition可以是sql条件、map、结构体或者*Spec
+func (t Tx) FindOneCondition(bean interface{}, condition interface{}, params ...interface{}) (err error) {
+	return scoped(t.DB, condition, params...).First(bean).Error
+}
+
+//FindCondition 查询满足条件的所有记录，condition可以是sql条件、map、结构体或者*Spec，参数顺序与BaseOrm.FindCondition一致
+func (t Tx) FindCondition(bean interface{}, condition interface{}, params ...interface{}) (err error) {
+	return scoped(t.DB, condition, params...).Find(bean).Error
 }
 
 func (t Tx) FindOneLike(fieldName string, value interface{}, beans interface{}) (err error) {
-	if err = t.DB.Where(fieldName+" like ?", value).First(beans).Error; err != nil {
+	if err := checkColumn(fieldName); err != nil {
+		return err
+	}
+	if err = t.DB.Where(quoteColumn(t.DB, fieldName)+" like ?", value).First(beans).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil
 		}
@@ -800,24 +655,30 @@ func (t Tx) FindOneLike(fieldName string, value interface{}, beans interface{})
 }
 
 func (t Tx) Count(beans interface{}, query interface{}, args ...interface{}) (count int, err error) {
-	err = t.DB.Model(beans).Where(query, args...).Count(&count).Error
+	err = where(t.DB.Model(beans), query, args...).Count(&count).Error
 	return
 }
 
 func (t Tx) ListBy(beans interface{}, fields *[]string, query interface{}, args ...interface{}) error {
 	if len(*fields) == 0 {
-		return t.DB.Where(query, args...).Find(beans).Error
+		return scoped(t.DB, query, args...).Find(beans).Error
 	} else {
-		return t.DB.Select(*fields).Where(query, args...).Find(beans).Error
+		return scoped(t.DB.Select(*fields), query, args...).Find(beans).Error
 	}
 }
 
 func (t Tx) ListEq(fieldName string, value interface{}, beans interface{}) error {
-	return t.DB.Where(fieldName+" = ?", value).Find(beans).Error
+	if err := checkColumn(fieldName); err != nil {
+		return err
+	}
+	return t.DB.Where(quoteColumn(t.DB, fieldName)+" = ?", value).Find(beans).Error
 }
 
 func (t Tx) ListLike(fieldName string, value interface{}, beans interface{}) error {
-	return t.DB.Where(fieldName+" like ?", value).Find(beans).Error
+	if err := checkColumn(fieldName); err != nil {
+		return err
+	}
+	return t.DB.Where(quoteColumn(t.DB, fieldName)+" like ?", value).Find(beans).Error
 }
 
 func (t Tx) ListAll(beans interface{}) error {
@@ -15,12 +15,9 @@ var config Config
 
 func init() {
 	config = Config{
-		DataBase:    "test",
-		DSN:         "root:123456@tcp(localhost:3306)/test?timeout=120s&parseTime=true&loc=Local&charset=utf8mb4,utf8",
-		Active:      100,
-		Idle:        100,
-		IdleTimeout: 240,
-		LogMode:     false,
+		DSN:     ":memory:",
+		LogMode: false,
+		Dialect: DialectSQLite,
 	}
 }
 
@@ -31,13 +28,118 @@ type User struct {
 	Age int
 }
 
-func TestBaseOrm_FastQuery(t *testing.T) {
+func newUsers(t *testing.T) *BaseOrm {
 	db := NewMySQL(&config)
-	//var result []map[interface{}]string
-	//var result [][]interface{}
+	t.Cleanup(func() { _ = db.Close() })
+	db.CreateTable(&User{})
+	for _, user := range []*User{{Name: "tom", Age: 18}, {Name: "jerry", Age: 16}, {Name: "spike", Age: 30}} {
+		if err := db.Create(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestBaseOrm_FastQuery(t *testing.T) {
+	db := newUsers(t)
 	var result []User
-	if err := db.FastQuery("select distinct id, name as name, sex, age from user", &result); err != nil {
-		panic(err)
+	if err := db.FastQuery("select distinct id, name as name, age from users order by id", &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 3 || result[0].Name != "tom" || result[2].Age != 30 {
+		t.Fatalf("unexpected users %+v", result)
+	}
+}
+
+func TestBaseOrm_CreateTable(t *testing.T) {
+	db := newUsers(t)
+	db.CreateTable(&User{}) //已经建过的表直接跳过
+	other := NewMySQL(&config)
+	defer other.Close()
+	other.CreateTable(&User{})
+	if !other.HasTable(&User{}) {
+		t.Fatal("the table should be created in every database")
+	}
+}
+
+func TestBaseOrm_ListWithPage(t *testing.T) {
+	db := newUsers(t)
+	var users []User
+	page, err := db.ListWithPage(&users, Pagination{Page: 2, PageSize: 1}, "age desc", "age > ?", 10)
+	if err != nil || page.TotalCount != 3 || len(users) != 1 || users[0].Name != "tom" {
+		t.Fatalf("ListWithPage: %+v, %+v, %v", page, users, err)
+	}
+}
+
+func TestBaseOrm_ListMap(t *testing.T) {
+	db := newUsers(t)
+	data, err := db.ListMap("select name, age from users where age < ? order by age", 20)
+	if err != nil || len(data) != 2 {
+		t.Fatalf("ListMap: %v, %v", data, err)
+	}
+	if data[0]["name"] != "jerry" || data[0]["age"] != int64(16) {
+		t.Fatalf("unexpected row %v", data[0])
+	}
+	value, err := db.FindAloneRecord("select count(*) from users")
+	if err != nil || value != int64(3) {
+		t.Fatalf("FindAloneRecord: %v, %v", value, err)
+	}
+}
+
+func TestBaseOrm_Begin(t *testing.T) {
+	db := newUsers(t)
+	tx := db.Begin()
+	if err := tx.Create(&User{Name: "tyke"}); err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+	tx = db.Begin()
+	if _, err := tx.Exec("update users set age = age + 1"); err != nil {
+		t.Fatal(err)
+	}
+	tx.Commit()
+	var users []User
+	if err := db.ListAll(&users); err != nil || len(users) != 3 || users[0].Age != 19 {
+		t.Fatalf("ListAll after commit: %+v, %v", users, err)
+	}
+}
+
+func TestOpen_UnknownDialect(t *testing.T) {
+	if _, err := Open(&Config{Dialect: "oracle"}); err == nil {
+		t.Fatal("unknown dialect should be rejected")
+	}
+	quote := func(s string) string { return "`" + s + "`" }
+	mysql, _ := GetDialect("")
+	if clause := mysql.UpsertClause(quote, []string{"id"}, []string{"name"}); clause != " ON DUPLICATE KEY UPDATE `name` = VALUES(`name`)" {
+		t.Fatalf("unexpected mysql upsert clause %q", clause)
+	}
+	sqlite, _ := GetDialect(DialectSQLite)
+	if clause := sqlite.UpsertClause(quote, []string{"id"}, []string{"name"}); clause != " ON CONFLICT (`id`) DO UPDATE SET `name` = excluded.`name`" {
+		t.Fatalf("unexpected sqlite upsert clause %q", clause)
+	}
+}
+
+func TestBaseOrm_ListColumns(t *testing.T) {
+	db := newUsers(t)
+	db.CreateTable(&Article{})
+	tables, err := db.ListTables("")
+	if err != nil || joinStrings(tables) != "articles,users" {
+		t.Fatalf("ListTables: %v, %v", tables, err)
+	}
+	columns, err := db.ListColumns("articles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var described []string
+	for _, c := range columns {
+		described = append(described, fmt.Sprintf("%s %s %v %v %v", c.Name, c.Type, c.Nullable, c.PrimaryKey, c.AutoIncrement))
+	}
+	if joined := joinStrings(described); joined != "id integer false true true,title varchar(255) true false false,views integer true false false,"+
+		"created_at datetime true false false,updated_at datetime true false false,deleted_at datetime true false false,created_by varchar(64) true false false,updated_by varchar(64) true false false,"+
+		"version bigint false false false" {
+		t.Fatalf("unexpected columns %s", joined)
+	}
+	if columns, err = db.ListColumns("unknown"); err != nil || len(columns) != 0 {
+		t.Fatalf("unknown table: %v, %v", columns, err)
 	}
-	fmt.Println(result)
 }
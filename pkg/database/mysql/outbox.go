@@ -0,0 +1,405 @@
+/*
+ @Desc 事务性outbox。业务数据与待发布的事件在同一个事务中写入outbox_events表，由OutboxRelay轮询outbox表并投递到
+ sink（redis stream、http webhook、channel等），进程在提交和发布之间退出也不会丢失事件：
+
+	orm.CreateTable(&mysql.OutboxEvent{})
+	err := orm.Transaction(ctx, func(tx *mysql.Tx) error {
+		if err := tx.Create(&order); err != nil {
+			return err
+		}
+		return mysql.AddOutbox(tx, "order-created", fmt.Sprintf("order:%d", order.ID), order)
+	})
+
+	relay, err := mysql.NewOutboxRelay(orm, mysql.RedisStreamSink(pool, "orders", 10000))
+	go relay.Run(ctx)
+
+ 投递语义为至少一次（at-least-once）：投递成功但标记失败时会重复投递，消费方需要使用事件的Key去重。
+ 投递失败的事件按照指数退避重试，多个relay实例通过递增Attempts抢占事件，同一个事件同时只会被一个实例投递。
+*/
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/jinzhu/gorm"
+	"github.com/prometheus/common/log"
+
+	"github.com/yinjk/go-utils/pkg/utils/httpclient"
+	"github.com/yinjk/go-utils/pkg/utils/times"
+)
+
+const (
+	outboxTable            = "outbox_events"
+	outboxMaxErrorLength   = 512
+	defaultRelayBatchSize  = 100
+	defaultRelayInterval   = time.Second
+	defaultRelayBackoff    = time.Second
+	defaultRelayMaxBackoff = 5 * time.Minute
+	defaultSinkTimeout     = 10 * time.Second
+)
+
+// OutboxEvent is a row of the outbox table.
+type OutboxEvent struct {
+	ID    uint64 `gorm:"primary_key" json:"id"`
+	Topic string `gorm:"size:128;not null" json:"topic"`
+	// Key identifies the event for the consumers to dedupe, it is unique in the outbox table.
+	Key     string `gorm:"size:191;not null;unique_index" json:"key"`
+	Payload string `gorm:"type:text" json:"payload"`
+	// Attempts is the number of delivery attempts, also used to claim the event.
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"index" json:"-"`
+	DeliveredAt   *time.Time `gorm:"index" json:"deliveredAt,omitempty"`
+	LastError     string     `gorm:"size:512" json:"-"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+//TableName outbox表名
+func (OutboxEvent) TableName() string {
+	return outboxTable
+}
+
+// ChangeEvent is the payload written by OutboxHook.
+type ChangeEvent struct {
+	Op         WriteOp                `json:"op"`
+	Table      string                 `json:"table"`
+	PrimaryKey interface{}            `json:"primaryKey,omitempty"`
+	Data       interface{}            `json:"data"`
+	Changes    map[string]interface{} `json:"changes,omitempty"`
+}
+
+/**
+ * 写入一条outbox事件，db为Tx时与事务中的其它写操作一起提交或回滚
+ * @param : db *Tx、*BaseOrm等
+ * @param : topic 事件的主题，relay根据主题选择sink
+ * @param : key 事件的去重key，为空时随机生成，同一个key只能写入一次
+ * @param : payload 事件内容，序列化成json，json.RawMessage原样写入
+ * @return: 序列化或者写入失败的错误
+ */
+func AddOutbox(db Executor, topic, key string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		key = newEventKey()
+	}
+	event := &OutboxEvent{Topic: topic, Key: key, Payload: string(data), NextAttemptAt: time.Now()}
+	return db.Gorm().Create(event).Error
+}
+
+//OutboxHook 将写操作作为ChangeEvent写入outbox的钩子，通过AfterWrite注册
+func OutboxHook(topic string) WriteHook {
+	return func(e *WriteEvent) error {
+		return AddOutbox(e.Tx, topic, "", &ChangeEvent{
+			Op:         e.Op,
+			Table:      e.Table,
+			PrimaryKey: e.PrimaryKey,
+			Data:       e.Value,
+			Changes:    e.Changes,
+		})
+	}
+}
+
+func newEventKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// OutboxSink delivers the outbox events, an error makes the event retried later.
+type OutboxSink interface {
+	Deliver(ctx context.Context, event *OutboxEvent) error
+}
+
+// OutboxSinkFunc adapts a function to OutboxSink.
+type OutboxSinkFunc func(ctx context.Context, event *OutboxEvent) error
+
+//Deliver 调用f投递事件
+func (f OutboxSinkFunc) Deliver(ctx context.Context, event *OutboxEvent) error {
+	return f(ctx, event)
+}
+
+//ChannelSink 将事件发送到ch，用于测试或者进程内消费，ch已满时阻塞直到ctx结束
+func ChannelSink(ch chan<- *OutboxEvent) OutboxSink {
+	return OutboxSinkFunc(func(ctx context.Context, event *OutboxEvent) error {
+		select {
+		case ch <- event:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// RedisConnPool is implemented by *redis.Pool of redigo.
+type RedisConnPool interface {
+	Get() redis.Conn
+}
+
+//sinkTimeout ctx剩余的时间，ctx没有截止时间时为defaultSinkTimeout
+func sinkTimeout(ctx context.Context) time.Duration {
+	if deadline, ok := ctx.Deadline(); ok {
+		return time.Until(deadline)
+	}
+	return defaultSinkTimeout
+}
+
+//RedisStreamSink 通过XADD将事件写入redis stream，字段为id、topic、key、payload，maxLen大于0时近似裁剪stream的长度；
+//命令的超时时间为ctx剩余的时间，ctx没有截止时间时为10秒
+func RedisStreamSink(pool RedisConnPool, stream string, maxLen int64) OutboxSink {
+	return OutboxSinkFunc(func(ctx context.Context, event *OutboxEvent) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		conn := pool.Get()
+		defer func() { _ = conn.Close() }()
+		args := redis.Args{stream}
+		if maxLen > 0 {
+			args = args.Add("MAXLEN", "~", maxLen)
+		}
+		args = args.Add("*", "id", event.ID, "topic", event.Topic, "key", event.Key, "payload", event.Payload)
+		timeout := sinkTimeout(ctx)
+		if timeout <= 0 {
+			return context.DeadlineExceeded
+		}
+		_, err := redis.DoWithTimeout(conn, timeout, "XADD", args...)
+		return err
+	})
+}
+
+//webhookBody webhook的请求体，payload为原始的json
+type webhookBody struct {
+	ID        uint64          `json:"id"`
+	Topic     string          `json:"topic"`
+	Key       string          `json:"key"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+//WebhookSink 通过httpclient将事件POST到url，请求头Idempotency-Key为事件的key，响应码不是2xx时视为投递失败；
+//请求随ctx取消，最长等待10秒
+func WebhookSink(url string, header httpclient.Header) OutboxSink {
+	return OutboxSinkFunc(func(ctx context.Context, event *OutboxEvent) error {
+		body, err := json.Marshal(&webhookBody{
+			ID:        event.ID,
+			Topic:     event.Topic,
+			Key:       event.Key,
+			Payload:   json.RawMessage(event.Payload),
+			CreatedAt: event.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		h := httpclient.Header{}
+		for k, v := range header {
+			h[k] = v
+		}
+		h.Set(httpclient.ContentTypeKey, httpclient.ContentTypeJson)
+		h.Set("Idempotency-Key", event.Key)
+		var message string
+		result, err := httpclient.ExecuteContext(ctx, defaultSinkTimeout, http.MethodPost, url, h, bytes.NewReader(body), &message)
+		if err != nil {
+			return err
+		}
+		if result.Code < 200 || result.Code >= 300 {
+			if len(message) > outboxMaxErrorLength {
+				message = message[:outboxMaxErrorLength]
+			}
+			return fmt.Errorf("mysql: webhook %s responded %d: %s", url, result.Code, message)
+		}
+		return nil
+	})
+}
+
+// RelayOption configures an OutboxRelay.
+type RelayOption func(r *OutboxRelay)
+
+//RelayBatchSize 每次轮询最多投递的事件数，默认100，小于等于0时使用默认值
+func RelayBatchSize(size int) RelayOption {
+	return func(r *OutboxRelay) {
+		if size <= 0 {
+			size = defaultRelayBatchSize
+		}
+		r.batchSize = size
+	}
+}
+
+//RelayInterval 没有待投递的事件时的轮询间隔，默认1秒
+func RelayInterval(interval time.Duration) RelayOption {
+	return func(r *OutboxRelay) {
+		r.interval = interval
+	}
+}
+
+//RelayBackoff 投递失败之后的重试间隔，从base开始每次翻倍，最大为max，默认1秒到5分钟
+func RelayBackoff(base, max time.Duration) RelayOption {
+	return func(r *OutboxRelay) {
+		r.backoff, r.maxBackoff = base, max
+	}
+}
+
+//RelayMaxAttempts 最大投递次数，超过之后不再重试，0表示一直重试
+func RelayMaxAttempts(attempts int) RelayOption {
+	return func(r *OutboxRelay) {
+		r.maxAttempts = attempts
+	}
+}
+
+//RelayClock 轮询间隔、退避以及投递时间使用的时钟，默认为times.SystemClock，测试中可以使用times.FakeClock
+func RelayClock(clock times.Clock) RelayOption {
+	return func(r *OutboxRelay) {
+		r.clock = clock
+	}
+}
+
+//RelayRoute topic的事件投递到sink，没有路由的topic投递到默认的sink
+func RelayRoute(topic string, sink OutboxSink) RelayOption {
+	return func(r *OutboxRelay) {
+		r.routes[topic] = sink
+	}
+}
+
+// OutboxRelay polls the outbox table and delivers the pending events to the sinks.
+type OutboxRelay struct {
+	db          *BaseOrm
+	sink        OutboxSink
+	routes      map[string]OutboxSink
+	batchSize   int
+	interval    time.Duration
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	maxAttempts int
+	clock       times.Clock
+}
+
+/**
+ * 创建outbox的relay，outbox表不存在时自动创建
+ * @param : db outbox表所在的数据库
+ * @param : sink 默认的sink
+ * @param : opts relay选项，如：RelayRoute、RelayBackoff
+ * @return: 创建outbox表失败时返回错误
+ */
+func NewOutboxRelay(db *BaseOrm, sink OutboxSink, opts ...RelayOption) (*OutboxRelay, error) {
+	r := &OutboxRelay{
+		db:         db,
+		sink:       sink,
+		routes:     make(map[string]OutboxSink),
+		batchSize:  defaultRelayBatchSize,
+		interval:   defaultRelayInterval,
+		backoff:    defaultRelayBackoff,
+		maxBackoff: defaultRelayMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.clock = times.ClockOrDefault(r.clock)
+	if err := createTable(db.DB, &OutboxEvent{}, ""); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+//Run 持续轮询并投递事件，直到ctx结束，返回ctx.Err()
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	for {
+		delivered, err := r.RelayOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Errorf("relay outbox events error: %v", err)
+		}
+		if err == nil && delivered >= r.batchSize { //还有待投递的事件，立即继续
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-r.clock.After(r.interval):
+		}
+	}
+}
+
+/**
+ * 轮询一次outbox表，按id顺序投递到期的事件，投递失败的事件按退避时间重试，不会阻塞之后的事件
+ * @param : ctx 上下文
+ * @return: delivered 本次投递成功的事件数，err 查询或者更新outbox失败的错误，投递失败不会返回错误
+ */
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (delivered int, err error) {
+	db := withContext(r.db.DB, ctx)
+	//没有投递过的事件立即投递，不依赖写入时的next_attempt_at
+	query := db.Where("delivered_at IS NULL AND (attempts = 0 OR next_attempt_at <= ?)", r.clock.Now())
+	if r.maxAttempts > 0 {
+		query = query.Where("attempts < ?", r.maxAttempts)
+	}
+	var events []*OutboxEvent
+	if err = query.Order("id").Limit(r.batchSize).Find(&events).Error; err != nil {
+		return 0, err
+	}
+	for _, event := range events {
+		if ctx.Err() != nil {
+			return delivered, ctx.Err()
+		}
+		//递增Attempts抢占事件，其它实例已经抢占时跳过，抢占之后进程退出时在退避时间之后重试
+		claim := db.Model(&OutboxEvent{}).Where("id = ? AND attempts = ?", event.ID, event.Attempts).UpdateColumns(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": r.clock.Now().Add(r.backoffOf(event.Attempts + 1)),
+		})
+		if claim.Error != nil {
+			return delivered, claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+		event.Attempts++
+		if deliverErr := r.sinkOf(event.Topic).Deliver(ctx, event); deliverErr != nil {
+			log.Warnf("deliver outbox event %d(%s) attempt %d error: %v", event.ID, event.Key, event.Attempts, deliverErr)
+			message := deliverErr.Error()
+			if len(message) > outboxMaxErrorLength {
+				message = message[:outboxMaxErrorLength]
+			}
+			if err = db.Model(event).UpdateColumn("last_error", message).Error; err != nil {
+				return delivered, err
+			}
+			continue
+		}
+		now := r.clock.Now()
+		if err = db.Model(event).UpdateColumns(map[string]interface{}{"delivered_at": &now, "last_error": ""}).Error; err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+//Purge 删除before之前投递成功的事件，返回删除的事件数
+func (r *OutboxRelay) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result := withContext(r.db.DB, ctx).Where("delivered_at IS NOT NULL AND delivered_at < ?", before).Delete(&OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *OutboxRelay) sinkOf(topic string) OutboxSink {
+	if sink, ok := r.routes[topic]; ok {
+		return sink
+	}
+	return r.sink
+}
+
+//backoffOf 第attempt次投递失败之后的重试间隔
+func (r *OutboxRelay) backoffOf(attempt int) time.Duration {
+	backoff := r.backoff
+	for i := 1; i < attempt && backoff < r.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > r.maxBackoff {
+		backoff = r.maxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,247 @@
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/yinjk/go-utils/pkg/utils/times"
+)
+
+func TestOutbox_Relay(t *testing.T) {
+	orm := newTestOrm(t, &Product{})
+	orm.AfterWrite(OutboxHook("products"))
+	ch := make(chan *OutboxEvent, 10)
+	relay, err := NewOutboxRelay(orm, ChannelSink(ch), RelayBackoff(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	err = orm.Transaction(ctx, func(tx *Tx) error {
+		if err := tx.Create(&Product{Name: "apple"}); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	if err == nil {
+		t.Fatal("expect rollback")
+	}
+	if count, _ := orm.Count(&OutboxEvent{}, nil); count != 0 {
+		t.Fatalf("outbox should be rolled back with the write, count %d", count)
+	}
+	err = orm.Transaction(ctx, func(tx *Tx) error {
+		if err := tx.Create(&Product{Name: "banana", Price: 3}); err != nil {
+			return err
+		}
+		return AddOutbox(tx, "audit", "audit:1", map[string]string{"action": "create"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = AddOutbox(orm, "audit", "audit:1", nil); err == nil {
+		t.Fatal("duplicate key should be rejected")
+	}
+
+	if delivered, err := relay.RelayOnce(ctx); err != nil || delivered != 2 {
+		t.Fatalf("RelayOnce: %d, %v", delivered, err)
+	}
+	first, second := <-ch, <-ch
+	var change ChangeEvent
+	if err = json.Unmarshal([]byte(first.Payload), &change); err != nil || change.Op != OpCreate || change.Table != "products" {
+		t.Fatalf("unexpected change event %s, %v", first.Payload, err)
+	}
+	if second.Key != "audit:1" || second.Payload != `{"action":"create"}` {
+		t.Fatalf("unexpected event %+v", second)
+	}
+	if delivered, _ := relay.RelayOnce(ctx); delivered != 0 {
+		t.Fatal("delivered events should not be delivered again")
+	}
+	if purged, err := relay.Purge(ctx, time.Now().Add(time.Minute)); err != nil || purged != 2 {
+		t.Fatalf("Purge: %d, %v", purged, err)
+	}
+}
+
+func TestOutbox_Retry(t *testing.T) {
+	orm := newTestOrm(t)
+	failures := 2
+	var keys []string
+	flaky := OutboxSinkFunc(func(ctx context.Context, event *OutboxEvent) error {
+		keys = append(keys, event.Key)
+		if failures > 0 {
+			failures--
+			return errors.New("sink unavailable")
+		}
+		return nil
+	})
+	dead := OutboxSinkFunc(func(ctx context.Context, event *OutboxEvent) error {
+		return errors.New("always fails")
+	})
+	relay, err := NewOutboxRelay(orm, flaky, RelayBackoff(0, 0), RelayMaxAttempts(3), RelayRoute("dead", dead))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	_ = AddOutbox(orm, "order", "order:1", 1)
+	_ = AddOutbox(orm, "dead", "dead:1", 1)
+	for i := 0; i < 5; i++ {
+		if _, err = relay.RelayOnce(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if joined := joinStrings(keys); joined != "order:1,order:1,order:1" {
+		t.Fatalf("event should be delivered at least once after retries: %s", joined)
+	}
+	var events []OutboxEvent
+	_ = orm.Order("id").Find(&events).Error
+	if events[0].DeliveredAt == nil || events[0].Attempts != 3 || events[0].LastError != "" {
+		t.Fatalf("unexpected delivered event %+v", events[0])
+	}
+	if events[1].DeliveredAt != nil || events[1].Attempts != 3 || events[1].LastError != "always fails" {
+		t.Fatalf("dead event should stop after max attempts: %+v", events[1])
+	}
+
+	//退避时间未到时不会重试
+	relay.backoff, relay.maxBackoff, relay.maxAttempts = time.Hour, time.Hour, 0
+	if delivered, _ := relay.RelayOnce(ctx); delivered != 0 {
+		t.Fatal("dead event should wait for the backoff")
+	}
+	if delivered, _ := relay.RelayOnce(ctx); delivered != 0 {
+		t.Fatal("event should not be retried before the backoff")
+	}
+
+	//批量大小不是正数时使用默认值，否则Run会一直空转
+	for _, size := range []int{0, -1} {
+		if relay, err = NewOutboxRelay(orm, flaky, RelayBatchSize(size)); err != nil || relay.batchSize != defaultRelayBatchSize {
+			t.Fatalf("RelayBatchSize(%d) should fall back to the default, got %d, %v", size, relay.batchSize, err)
+		}
+	}
+}
+
+func TestOutbox_Backoff(t *testing.T) {
+	orm := newTestOrm(t)
+	clock := times.NewFakeClock(time.Time{})
+	failures := 2
+	delivered := make(chan string, 1)
+	sink := OutboxSinkFunc(func(ctx context.Context, event *OutboxEvent) error {
+		if failures > 0 {
+			failures--
+			return errors.New("sink unavailable")
+		}
+		delivered <- event.Key
+		return nil
+	})
+	relay, err := NewOutboxRelay(orm, sink, RelayBackoff(time.Minute, time.Hour), RelayClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	_ = AddOutbox(orm, "order", "order:1", 1)
+	//第一次失败之后等待1分钟，第二次失败之后等待2分钟
+	for i, step := range []struct {
+		advance  time.Duration
+		attempts int
+	}{{0, 1}, {59 * time.Second, 1}, {time.Second, 2}, {time.Minute, 2}, {time.Minute, 3}} {
+		clock.Advance(step.advance)
+		if _, err = relay.RelayOnce(ctx); err != nil {
+			t.Fatal(err)
+		}
+		var event OutboxEvent
+		if err = orm.First(&event).Error; err != nil || event.Attempts != step.attempts {
+			t.Fatalf("step %d: expect %d attempts, got %+v, %v", i, step.attempts, event, err)
+		}
+	}
+	if key := <-delivered; key != "order:1" {
+		t.Fatalf("unexpected event %s", key)
+	}
+
+	//Run在轮询间隔之后再次轮询
+	ctx, cancel := context.WithCancel(ctx)
+	stopped := make(chan error, 1)
+	go func() { stopped <- relay.Run(ctx) }()
+	clock.BlockUntil(1)
+	_ = AddOutbox(orm, "order", "order:2", 2)
+	clock.Advance(defaultRelayInterval)
+	select {
+	case key := <-delivered:
+		if key != "order:2" {
+			t.Fatalf("unexpected event %s", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run should relay the new event after the interval")
+	}
+	cancel()
+	if err = <-stopped; err != context.Canceled {
+		t.Fatalf("Run should return ctx.Err(), got %v", err)
+	}
+}
+
+func TestOutbox_Sinks(t *testing.T) {
+	event := &OutboxEvent{ID: 7, Topic: "orders", Key: "order:7", Payload: `{"id":7}`}
+	ctx := context.Background()
+
+	var (
+		idempotencyKey string
+		body           webhookBody
+	)
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey = r.Header.Get("Idempotency-Key")
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	webhook := WebhookSink(server.URL, nil)
+	if err := webhook.Deliver(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+	if idempotencyKey != "order:7" || body.Topic != "orders" || string(body.Payload) != `{"id":7}` {
+		t.Fatalf("unexpected webhook request %s %+v", idempotencyKey, body)
+	}
+	status = http.StatusInternalServerError
+	if err := webhook.Deliver(ctx, event); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Fatalf("non 2xx response should fail the delivery, got %v", err)
+	}
+
+	//webhook随ctx取消
+	block := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer slow.Close()
+	defer close(block)
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := WebhookSink(slow.URL, nil).Deliver(timeout, event); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("webhook should stop at the ctx deadline, got %v", err)
+	}
+
+	mr := miniredis.RunT(t)
+	pool := &redis.Pool{Dial: func() (redis.Conn, error) { return redis.Dial("tcp", mr.Addr()) }}
+	defer pool.Close()
+	canceled, cancelNow := context.WithCancel(ctx)
+	cancelNow()
+	if err := RedisStreamSink(pool, "outbox", 100).Deliver(canceled, event); !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled ctx should not be delivered, got %v", err)
+	}
+	if err := RedisStreamSink(pool, "outbox", 100).Deliver(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := mr.Stream("outbox")
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("stream entries: %v, %v", entries, err)
+	}
+	if values := strings.Join(entries[0].Values, " "); values != `id 7 topic orders key order:7 payload {"id":7}` {
+		t.Fatalf("unexpected stream entry %s", values)
+	}
+}
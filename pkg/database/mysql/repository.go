@@ -0,0 +1,220 @@
+/*
+ @Desc 泛型Repository，在BaseOrm或者Tx的基础上提供强类型的增删改查，
+ 同一个Repository既可以在事务外使用，也可以通过WithTx绑定到事务中使用，不需要再为Tx重复编写DAO方法。
+*/
+package mysql
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// ErrRecordNotFound is returned by the Repository finders when no record matches.
+	ErrRecordNotFound = gorm.ErrRecordNotFound
+	// ErrMissingPrimaryKey is returned when updating or deleting an entity whose primary key is blank,
+	// which would otherwise update or delete the whole table.
+	ErrMissingPrimaryKey = errors.New("mysql: entity primary key is blank")
+)
+
+// Executor is implemented by both BaseOrm and Tx, a Repository works the same on either of them.
+type Executor interface {
+	Gorm() *gorm.DB
+}
+
+//Gorm 返回底层的gorm.DB
+func (bo BaseOrm) Gorm() *gorm.DB {
+	return bo.DB
+}
+
+//Gorm 返回底层的gorm.DB
+func (t Tx) Gorm() *gorm.DB {
+	return t.DB
+}
+
+//IsRecordNotFound 判断err是否是记录不存在的错误
+func IsRecordNotFound(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound) || gorm.IsRecordNotFoundError(err)
+}
+
+// Page is the typed result of a paging query.
+type Page[T any] struct {
+	Pagination
+	Data []T `json:"data"`
+}
+
+// Repository provides typed CRUD for the table of the struct type T (T must be a struct, not a pointer).
+//
+// The query arguments of the finders are the same as gorm's Where: a sql string with args, a map or a struct,
+// a nil or empty query matches all the records.
+type Repository[T any] struct {
+	db *gorm.DB
+}
+
+//NewRepository 创建T类型的Repository，db可以是*BaseOrm、BaseOrm、*Tx或者Tx
+func NewRepository[T any](db Executor) *Repository[T] {
+	return &Repository[T]{db: db.Gorm()}
+}
+
+//WithTx 返回一个绑定到tx上的Repository，原Repository不受影响
+func (r *Repository[T]) WithTx(tx *Tx) *Repository[T] {
+	return &Repository[T]{db: tx.DB}
+}
+
+//DB 返回Repository当前使用的gorm.DB，用于Repository不支持的复杂查询
+func (r *Repository[T]) DB() *gorm.DB {
+	return r.db
+}
+
+/**
+ * 通过主键查询
+ * @param : id 主键的值
+ * @return: 查询到的记录，记录不存在时返回ErrRecordNotFound
+ */
+func (r *Repository[T]) FindByID(id interface{}) (result T, err error) {
+	err = r.db.Where(r.primaryKey()+" = ?", id).First(&result).Error
+	return
+}
+
+//FindOne 查询满足条件的第一条记录，记录不存在时返回ErrRecordNotFound
+func (r *Repository[T]) FindOne(query interface{}, args ...interface{}) (result T, err error) {
+	err = scoped(r.db, query, args...).First(&result).Error
+	return
+}
+
+//List 查询满足条件的所有记录
+func (r *Repository[T]) List(query interface{}, args ...interface{}) ([]T, error) {
+	result := make([]T, 0)
+	if err := scoped(r.db, query, args...).Find(&result).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+/**
+ * 分页查询
+ * @param : pagination 分页器，Page从1开始
+ * @param : orderBy 排序规则，格式为"列名 [asc|desc], ..."，为空时不排序
+ * @param : query 查询条件
+ * @param : args 查询参数
+ * @return: 当前页的数据以及满足条件的记录总数，orderBy中的列名不合法时返回错误
+ */
+func (r *Repository[T]) Page(pagination Pagination, orderBy string, query interface{}, args ...interface{}) (*Page[T], error) {
+	if pagination.Page < 1 {
+		pagination.Page = 1
+	}
+	//与Sharding.Page一样校验列名并加引号，不会将原始的排序拼接到sql中
+	orders := make([]string, 0)
+	for _, order := range parseOrderBy(orderBy) {
+		quoted, err := quoteOrder(r.db, order)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, quoted)
+	}
+	db := where(r.db, query, args...)
+	if err := db.Model(new(T)).Count(&pagination.TotalCount).Error; err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		db = db.Order(order)
+	}
+	db = orderBySpec(db, query)
+	data := make([]T, 0)
+	if err := db.Offset((pagination.Page - 1) * pagination.PageSize).Limit(pagination.PageSize).Find(&data).Error; err != nil {
+		return nil, err
+	}
+	return &Page[T]{Pagination: pagination, Data: data}, nil
+}
+
+//Count 查询满足条件的记录数
+func (r *Repository[T]) Count(query interface{}, args ...interface{}) (count int, err error) {
+	err = where(r.db.Model(new(T)), query, args...).Count(&count).Error
+	return
+}
+
+//Exists 判断是否存在满足条件的记录
+func (r *Repository[T]) Exists(query interface{}, args ...interface{}) (bool, error) {
+	var count int
+	if err := where(r.db.Model(new(T)), query, args...).Limit(1).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+//Create 插入一条记录，自增主键会回写到entity中
+func (r *Repository[T]) Create(entity *T) error {
+	return r.db.Create(entity).Error
+}
+
+//Save 主键为空时插入，否则全量更新
+func (r *Repository[T]) Save(entity *T) error {
+	return r.db.Save(entity).Error
+}
+
+//Update 通过主键更新entity中的非零值字段
+func (r *Repository[T]) Update(entity *T) error {
+	if r.db.NewScope(entity).PrimaryKeyZero() {
+		return ErrMissingPrimaryKey
+	}
+	return r.db.Model(entity).Updates(entity).Error
+}
+
+//Updates 通过主键更新fields中的字段，key为字段名或列名
+func (r *Repository[T]) Updates(entity *T, fields map[string]interface{}) error {
+	if r.db.NewScope(entity).PrimaryKeyZero() {
+		return ErrMissingPrimaryKey
+	}
+	return r.db.Model(entity).Updates(fields).Error
+}
+
+//Delete 通过主键删除entity，T包含DeletedAt字段时为逻辑删除
+func (r *Repository[T]) Delete(entity *T) error {
+	if r.db.NewScope(entity).PrimaryKeyZero() {
+		return ErrMissingPrimaryKey
+	}
+	return r.db.Delete(entity).Error
+}
+
+//DeleteByID 通过主键删除记录，返回删除的行数
+func (r *Repository[T]) DeleteByID(id interface{}) (int64, error) {
+	db := r.db.Set(primaryKeySetting, id).Where(r.primaryKey()+" = ?", id).Delete(new(T))
+	return db.RowsAffected, db.Error
+}
+
+func (r *Repository[T]) primaryKey() string {
+	scope := r.db.NewScope(new(T))
+	return scope.Quote(scope.PrimaryKey())
+}
+
+//where 添加查询条件，query为nil或空字符串时不添加，query为*Spec时只添加其中的查询条件
+func where(db *gorm.DB, query interface{}, args ...interface{}) *gorm.DB {
+	if spec, ok := query.(*Spec); ok {
+		if spec == nil {
+			return db
+		}
+		return spec.applyWhere(db)
+	}
+	if query == nil || query == "" {
+		return db
+	}
+	return db.Where(query, args...)
+}
+
+//scoped 与where相同，query为*Spec时还会添加其中的排序和limit
+func scoped(db *gorm.DB, query interface{}, args ...interface{}) *gorm.DB {
+	db = where(db, query, args...)
+	if spec, ok := query.(*Spec); ok && spec != nil {
+		db = spec.applyPaging(spec.applyOrder(db))
+	}
+	return db
+}
+
+//orderBySpec query为*Spec时添加其中的排序
+func orderBySpec(db *gorm.DB, query interface{}) *gorm.DB {
+	if spec, ok := query.(*Spec); ok && spec != nil {
+		db = spec.applyOrder(db)
+	}
+	return db
+}
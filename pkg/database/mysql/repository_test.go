@@ -0,0 +1,136 @@
+package mysql
+
+import (
+	"testing"
+
+	_ "github.com/yinjk/go-utils/pkg/database/mysql/sqlite"
+)
+
+type Product struct {
+	ID    uint `gorm:"primary_key"`
+	Name  string
+	Price int
+	Stock int
+}
+
+//newTestOrm 使用sqlite内存数据库代替mysql，内存数据库只保留一个连接，保证所有查询访问同一个数据库
+func newTestOrm(t *testing.T, beans ...interface{}) *BaseOrm {
+	orm, err := Open(&Config{Dialect: DialectSQLite, DSN: ":memory:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = orm.Close() })
+	for _, bean := range beans {
+		if err = orm.DB.CreateTable(bean).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return orm
+}
+
+func TestRepository_CRUD(t *testing.T) {
+	repo := NewRepository[Product](newTestOrm(t, &Product{}))
+	for _, p := range []Product{{Name: "apple", Price: 5, Stock: 10}, {Name: "banana", Price: 3}, {Name: "cherry", Price: 20, Stock: 1}} {
+		if err := repo.Create(&p); err != nil || p.ID == 0 {
+			t.Fatalf("create failed: %v, %+v", err, p)
+		}
+	}
+	apple, err := repo.FindByID(1)
+	if err != nil || apple.Name != "apple" {
+		t.Fatalf("FindByID: %+v, %v", apple, err)
+	}
+	if _, err = repo.FindByID(100); !IsRecordNotFound(err) {
+		t.Fatalf("expect not found, got %v", err)
+	}
+	cherry, err := repo.FindOne("name = ?", "cherry")
+	if err != nil || cherry.Price != 20 {
+		t.Fatalf("FindOne: %+v, %v", cherry, err)
+	}
+	list, err := repo.List(map[string]interface{}{"stock": 0})
+	if err != nil || len(list) != 1 || list[0].Name != "banana" {
+		t.Fatalf("List: %+v, %v", list, err)
+	}
+	if all, _ := repo.List(nil); len(all) != 3 {
+		t.Fatalf("List all: %+v", all)
+	}
+	if count, err := repo.Count("price > ?", 4); err != nil || count != 2 {
+		t.Fatalf("Count: %d, %v", count, err)
+	}
+	if ok, err := repo.Exists("name = ?", "durian"); err != nil || ok {
+		t.Fatalf("Exists: %v, %v", ok, err)
+	}
+
+	apple.Price = 6
+	if err = repo.Update(&apple); err != nil {
+		t.Fatal(err)
+	}
+	if err = repo.Updates(&cherry, map[string]interface{}{"stock": 0}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := repo.FindByID(1); got.Price != 6 || got.Stock != 10 {
+		t.Fatalf("Update should only change non-zero fields: %+v", got)
+	}
+	if got, _ := repo.FindByID(cherry.ID); got.Stock != 0 {
+		t.Fatalf("Updates failed: %+v", got)
+	}
+	if err = repo.Update(&Product{Price: 1}); err != ErrMissingPrimaryKey {
+		t.Fatalf("update without primary key should be rejected, got %v", err)
+	}
+	if err = repo.Delete(&Product{}); err != ErrMissingPrimaryKey {
+		t.Fatalf("delete without primary key should be rejected, got %v", err)
+	}
+	if err = repo.Delete(&apple); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := repo.DeleteByID(2); err != nil || n != 1 {
+		t.Fatalf("DeleteByID: %d, %v", n, err)
+	}
+	if count, _ := repo.Count(nil); count != 1 {
+		t.Fatalf("expect 1 record left, got %d", count)
+	}
+}
+
+func TestRepository_Page(t *testing.T) {
+	repo := NewRepository[Product](newTestOrm(t, &Product{}))
+	for i := 1; i <= 5; i++ {
+		if err := repo.Create(&Product{Name: "p", Price: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	page, err := repo.Page(Pagination{Page: 2, PageSize: 2}, "price desc", "price > ?", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.TotalCount != 5 || len(page.Data) != 2 || page.Data[0].Price != 3 || page.Data[1].Price != 2 {
+		t.Fatalf("unexpected page %+v", page)
+	}
+	if page, err = repo.Page(Pagination{Page: 1, PageSize: 2}, "stock, price DESC", nil); err != nil || page.Data[0].Price != 5 {
+		t.Fatalf("unexpected page %+v, %v", page, err)
+	}
+	for _, orderBy := range []string{"price; DROP TABLE products", "(SELECT 1) desc", "price desc, 1=1"} {
+		if _, err = repo.Page(Pagination{Page: 1, PageSize: 2}, orderBy, nil); err == nil {
+			t.Fatalf("invalid order %q should be rejected", orderBy)
+		}
+	}
+}
+
+func TestRepository_WithTx(t *testing.T) {
+	orm := newTestOrm(t, &Product{})
+	repo := NewRepository[Product](orm)
+
+	tx := orm.Begin()
+	if err := repo.WithTx(tx).Create(&Product{Name: "rollback"}); err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+	tx = orm.Begin()
+	if err := NewRepository[Product](tx).Create(&Product{Name: "commit"}); err != nil {
+		t.Fatal(err)
+	}
+	tx.Commit()
+
+	list, err := repo.List(nil)
+	if err != nil || len(list) != 1 || list[0].Name != "commit" {
+		t.Fatalf("unexpected records %+v, %v", list, err)
+	}
+}
@@ -0,0 +1,377 @@
+/*
+ @Desc 查询结果到结构体的映射，BaseOrm.FastQuery和Tx.FastQuery共用。
+ 列名与字段的对应规则：
+   1. 字段的列名为db tag，没有db tag时使用gorm的column tag，都没有时使用字段名的蛇形命名，db:"-"表示忽略该字段
+   2. 匹配时忽略大小写，匹配不到时再忽略下划线，如：列user_name、USERNAME都可以匹配到字段UserName
+   3. 嵌套结构体字段的列名为"前缀.列名"，前缀为字段的列名，join查询时使用别名映射，如：select o.id as `order.id`
+      匿名嵌入的结构体没有前缀，除非它有db tag
+   4. 实现了sql.Scanner的字段直接交给Scanner处理，指针以及sql.Null*字段可以接收NULL，其它字段遇到NULL时保持零值
+   5. map、slice、结构体字段如果直接匹配到了列（或者db tag带有json选项，如：db:"attrs,json"），会把列的值作为json解析
+ 列与字段的对应关系按照"结构体类型+查询的列"缓存，相同的查询只会解析一次。
+*/
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+//结构体嵌套的最大深度，防止自引用的结构体无限递归
+const maxNestedDepth = 5
+
+var (
+	scannerType  = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType     = reflect.TypeOf(time.Time{})
+	bytesType    = reflect.TypeOf([]byte(nil))
+	rawBytesType = reflect.TypeOf(sql.RawBytes(nil))
+
+	structColumnsCache sync.Map //reflect.Type -> *structColumns
+	rowPlanCache       sync.Map //rowPlanKey -> []*fieldTarget
+)
+
+type scanKind int
+
+const (
+	scanDirect   scanKind = iota //直接Scan到字段中：Scanner、指针
+	scanNullable                 //Scan到**T中，NULL时保持零值
+	scanJSON                     //Scan到[]byte中再作为json解析
+)
+
+type fieldTarget struct {
+	index []int
+	typ   reflect.Type
+	kind  scanKind
+}
+
+type structColumns struct {
+	exact      map[string]*fieldTarget //小写的列名
+	normalized map[string]*fieldTarget //小写并且去掉下划线的列名
+}
+
+type rowPlanKey struct {
+	typ     reflect.Type
+	columns string
+}
+
+/**
+ * 执行查询并将结果映射到value中，value可以是以下类型的指针：
+ * []struct、[]*struct、[]map[string]T、[][]T、[]T（T为基础类型，只取第一列）、struct（只取第一行，没有记录时返回ErrRecordNotFound）
+ * @param : db 执行查询的db
+ * @param : query 查询语句
+ * @param : value 查询结果
+ * @param : args 查询参数
+ * @return:
+ */
+func fastQuery(db *gorm.DB, query string, value interface{}, args ...interface{}) error {
+	targetValue := reflect.ValueOf(value)
+	if targetValue.Kind() != reflect.Ptr || targetValue.IsNil() {
+		return errors.New("FastQuery must accept a ptr type")
+	}
+	targetValue = targetValue.Elem()
+	if targetValue.Kind() != reflect.Slice && !isMappedStruct(targetValue.Type()) {
+		return errors.New("FastQuery only support a slice or struct type")
+	}
+	rows, err := db.Raw(query, args...).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if targetValue.Kind() == reflect.Struct {
+		if !rows.Next() {
+			if err = rows.Err(); err != nil {
+				return err
+			}
+			return gorm.ErrRecordNotFound
+		}
+		row, err := newRowMapper(rows, targetValue.Type())
+		if err != nil {
+			return err
+		}
+		return row.scan(rows, targetValue)
+	}
+	slice, err := scanSlice(rows, targetValue.Type())
+	if err != nil {
+		return err
+	}
+	targetValue.Set(slice)
+	return nil
+}
+
+func scanSlice(rows *sql.Rows, sliceType reflect.Type) (reflect.Value, error) {
+	slice := reflect.MakeSlice(sliceType, 0, 0)
+	scan, err := newRowScanner(rows, sliceType.Elem())
+	if err != nil {
+		return slice, err
+	}
+	for rows.Next() {
+		elem, err := scan()
+		if err != nil {
+			return slice, err
+		}
+		slice = reflect.Append(slice, elem)
+	}
+	return slice, rows.Err()
+}
+
+/**
+ * 创建逐行扫描的函数，每次调用将rows的当前行扫描成一个elemType类型的值，elemType支持的类型同FastQuery中slice的元素类型
+ * @param : rows 查询结果
+ * @param : elemType 每一行对应的类型
+ * @return: 扫描当前行的函数
+ */
+func newRowScanner(rows *sql.Rows, elemType reflect.Type) (func() (reflect.Value, error), error) {
+	structType := elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if isMappedStruct(structType) {
+		mapper, err := newRowMapper(rows, structType)
+		if err != nil {
+			return nil, err
+		}
+		return func() (reflect.Value, error) {
+			row := reflect.New(structType)
+			if err := mapper.scan(rows, row.Elem()); err != nil {
+				return row, err
+			}
+			if elemType.Kind() == reflect.Ptr {
+				return row, nil
+			}
+			return row.Elem(), nil
+		}, nil
+	}
+	dbTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	scanValues := make([]interface{}, 0, len(dbTypes))
+	switch elemType.Kind() {
+	case reflect.Map, reflect.Slice:
+		for _, v := range dbTypes {
+			if elemType.Elem().Kind() == reflect.Interface {
+				scanType := v.ScanType()
+				if scanType == nil { //部分驱动无法确定表达式列的类型
+					scanType = elemType.Elem()
+				} else if scanType == rawBytesType { //RawBytes在下一次Scan时会被覆盖
+					scanType = bytesType
+				}
+				scanValues = append(scanValues, reflect.New(scanType).Interface())
+			} else {
+				scanValues = append(scanValues, reflect.New(elemType.Elem()).Interface())
+			}
+		}
+		return func() (reflect.Value, error) {
+			if err := rows.Scan(scanValues...); err != nil {
+				return reflect.Value{}, err
+			}
+			if elemType.Kind() == reflect.Map {
+				refMap := reflect.MakeMap(elemType)
+				for i, v := range scanValues {
+					refMap.SetMapIndex(reflect.ValueOf(dbTypes[i].Name()), reflect.ValueOf(v).Elem())
+				}
+				return refMap, nil
+			}
+			refSlice := reflect.MakeSlice(elemType, len(scanValues), len(scanValues))
+			for i, v := range scanValues {
+				refSlice.Index(i).Set(reflect.ValueOf(v).Elem())
+			}
+			return refSlice, nil
+		}, nil
+	default:
+		//只取第一列，其余的列丢弃
+		var discard interface{}
+		for range dbTypes {
+			scanValues = append(scanValues, &discard)
+		}
+		return func() (reflect.Value, error) {
+			i := reflect.New(elemType)
+			if len(scanValues) > 0 {
+				scanValues[0] = i.Interface()
+			}
+			if err := rows.Scan(scanValues...); err != nil {
+				return reflect.Value{}, err
+			}
+			return i.Elem(), nil
+		}, nil
+	}
+}
+
+type rowMapper struct {
+	plan []*fieldTarget
+}
+
+//newRowMapper 获取查询的列与结构体字段的对应关系，结果按照结构体类型和列名缓存
+func newRowMapper(rows *sql.Rows, structType reflect.Type) (*rowMapper, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	key := rowPlanKey{typ: structType, columns: strings.Join(columns, "\x00")}
+	if plan, ok := rowPlanCache.Load(key); ok {
+		return &rowMapper{plan: plan.([]*fieldTarget)}, nil
+	}
+	fields := getStructColumns(structType)
+	plan := make([]*fieldTarget, len(columns))
+	for i, column := range columns {
+		column = strings.ToLower(column)
+		if target, ok := fields.exact[column]; ok {
+			plan[i] = target
+		} else if target, ok = fields.normalized[strings.ReplaceAll(column, "_", "")]; ok {
+			plan[i] = target
+		}
+	}
+	rowPlanCache.Store(key, plan)
+	return &rowMapper{plan: plan}, nil
+}
+
+//scan 将当前行映射到row中，row必须是可寻址的结构体
+func (m *rowMapper) scan(rows *sql.Rows, row reflect.Value) error {
+	var discard interface{}
+	dest := make([]interface{}, len(m.plan))
+	holders := make([]reflect.Value, len(m.plan))
+	for i, target := range m.plan {
+		switch {
+		case target == nil:
+			dest[i] = &discard
+		case target.kind == scanDirect:
+			dest[i] = fieldByIndexAlloc(row, target.index).Addr().Interface()
+		case target.kind == scanJSON:
+			holders[i] = reflect.New(bytesType)
+			dest[i] = holders[i].Interface()
+		default:
+			holders[i] = reflect.New(reflect.PtrTo(target.typ))
+			dest[i] = holders[i].Interface()
+		}
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return err
+	}
+	for i, target := range m.plan {
+		if target == nil || target.kind == scanDirect {
+			continue
+		}
+		value := holders[i].Elem()
+		if target.kind == scanJSON {
+			if value.Len() == 0 {
+				continue
+			}
+			field := fieldByIndexAlloc(row, target.index)
+			if err := json.Unmarshal(value.Bytes(), field.Addr().Interface()); err != nil {
+				return fmt.Errorf("mysql: decode json column into %v failed: %v", target.typ, err)
+			}
+			continue
+		}
+		if !value.IsNil() {
+			fieldByIndexAlloc(row, target.index).Set(value.Elem())
+		}
+	}
+	return nil
+}
+
+func getStructColumns(t reflect.Type) *structColumns {
+	if columns, ok := structColumnsCache.Load(t); ok {
+		return columns.(*structColumns)
+	}
+	columns := &structColumns{exact: make(map[string]*fieldTarget), normalized: make(map[string]*fieldTarget)}
+	collectColumns(t, "", nil, columns, 0)
+	structColumnsCache.Store(t, columns)
+	return columns
+}
+
+func collectColumns(t reflect.Type, prefix string, index []int, columns *structColumns, depth int) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("db")
+		name, option := tag, ""
+		if j := strings.IndexByte(tag, ','); j >= 0 {
+			name, option = tag[:j], tag[j+1:]
+		}
+		if name == "-" || (field.PkgPath != "" && !field.Anonymous) {
+			continue
+		}
+		if name == "" {
+			name = columnName(field)
+		}
+		fieldIndex := append(index[:len(index):len(index)], i)
+		fieldType := field.Type
+		baseType := fieldType
+		if baseType.Kind() == reflect.Ptr {
+			baseType = baseType.Elem()
+		}
+		if option != "json" && isMappedStruct(baseType) && !isScanner(fieldType) && depth < maxNestedDepth {
+			childPrefix := prefix
+			if !field.Anonymous || tag != "" {
+				childPrefix = prefix + name + "."
+				//嵌套结构体也可以直接对应一个json列
+				columns.add(prefix+name, &fieldTarget{index: fieldIndex, typ: fieldType, kind: scanJSON})
+			}
+			collectColumns(baseType, childPrefix, fieldIndex, columns, depth+1)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		target := &fieldTarget{index: fieldIndex, typ: fieldType, kind: scanNullable}
+		switch {
+		case isScanner(fieldType) || fieldType.Kind() == reflect.Ptr:
+			target.kind = scanDirect
+		case option == "json" || ((baseType.Kind() == reflect.Map || baseType.Kind() == reflect.Slice) && baseType != bytesType):
+			target.kind = scanJSON
+		}
+		columns.add(prefix+name, target)
+	}
+}
+
+//add 外层以及先声明的字段优先
+func (c *structColumns) add(column string, target *fieldTarget) {
+	column = strings.ToLower(column)
+	if _, ok := c.exact[column]; !ok {
+		c.exact[column] = target
+	}
+	normalized := strings.ReplaceAll(column, "_", "")
+	if _, ok := c.normalized[normalized]; !ok {
+		c.normalized[normalized] = target
+	}
+}
+
+//columnName 字段对应的列名，优先使用gorm的column tag
+func columnName(field reflect.StructField) string {
+	for _, setting := range strings.Split(field.Tag.Get("gorm"), ";") {
+		kv := strings.SplitN(setting, ":", 2)
+		if len(kv) == 2 && strings.EqualFold(strings.TrimSpace(kv[0]), "column") {
+			return strings.TrimSpace(kv[1])
+		}
+	}
+	return gorm.ToColumnName(field.Name)
+}
+
+//isMappedStruct 是否是需要按字段映射的结构体，time.Time以及Scanner等当作单个值处理
+func isMappedStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && !isScanner(t)
+}
+
+func isScanner(t reflect.Type) bool {
+	return t.Implements(scannerType) || reflect.PtrTo(t).Implements(scannerType)
+}
+
+//fieldByIndexAlloc 按照index获取字段，路径上的nil指针会被初始化
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
@@ -0,0 +1,136 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type Customer struct {
+	ID   int
+	Name string
+}
+
+type OrderMeta struct {
+	Channel string   `json:"channel"`
+	Tags    []string `json:"tags"`
+}
+
+//Level 自定义Scanner，数据库中存储为字符串
+type Level int
+
+func (l *Level) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*l = 0
+	case string:
+		*l = Level(len(v))
+	case []byte:
+		*l = Level(len(v))
+	default:
+		return fmt.Errorf("unsupported level %v", src)
+	}
+	return nil
+}
+
+type CustomerOrder struct {
+	ID     int
+	Amount sql.NullFloat64
+	Remark *string
+	Meta   OrderMeta
+	Extra  map[string]int `db:"extra"`
+}
+
+type OrderView struct {
+	Customer
+	Order   *CustomerOrder `db:"order"`
+	Total   int            `db:"total_amount"`
+	Level   Level
+	Ignored string `db:"-"`
+	Note    string
+}
+
+func newOrderOrm(t *testing.T) *BaseOrm {
+	orm := newTestOrm(t)
+	for _, statement := range splitStatements(`
+		CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT);
+		CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER, amount REAL, remark TEXT, meta TEXT, extra TEXT);
+		INSERT INTO customers VALUES (1, 'zhangshan'), (2, 'lisi');
+		INSERT INTO orders VALUES (10, 1, 9.5, 'fast', '{"channel":"app","tags":["a","b"]}', '{"x":1}');`) {
+		if err := orm.DB.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return orm
+}
+
+func TestFastQuery_Join(t *testing.T) {
+	orm := newOrderOrm(t)
+	query := `SELECT c.id, c.name, o.id AS "order.id", o.amount AS "order.amount", o.remark AS "order.remark",
+		o.meta AS "order.meta", o.extra AS "Order.Extra", NULL AS note, 5 AS TOTALAMOUNT, 'abc' AS level,
+		'x' AS ignored, 1 AS unknown
+		FROM customers c LEFT JOIN orders o ON o.customer_id = c.id ORDER BY c.id`
+	for i := 0; i < 2; i++ { //第二次使用缓存的映射关系
+		var views []*OrderView
+		if err := orm.FastQuery(query, &views); err != nil {
+			t.Fatal(err)
+		}
+		if len(views) != 2 {
+			t.Fatalf("unexpected views %+v", views)
+		}
+		first, second := views[0], views[1]
+		remark := "fast"
+		want := &OrderView{
+			Customer: Customer{ID: 1, Name: "zhangshan"},
+			Order: &CustomerOrder{
+				ID:     10,
+				Amount: sql.NullFloat64{Float64: 9.5, Valid: true},
+				Remark: &remark,
+				Meta:   OrderMeta{Channel: "app", Tags: []string{"a", "b"}},
+				Extra:  map[string]int{"x": 1},
+			},
+			Total: 5,
+			Level: 3,
+		}
+		if !reflect.DeepEqual(first, want) {
+			t.Fatalf("got %+v %+v\nwant %+v %+v", first, first.Order, want, want.Order)
+		}
+		if second.Name != "lisi" || second.Order.ID != 0 || second.Order.Amount.Valid || second.Order.Remark != nil || second.Order.Extra != nil {
+			t.Fatalf("NULL columns should be zero values: %+v %+v", second, second.Order)
+		}
+	}
+}
+
+func TestFastQuery_Targets(t *testing.T) {
+	orm := newOrderOrm(t)
+	var customer Customer
+	if err := orm.FastQuery("SELECT id, name AS NAME FROM customers WHERE id = ?", &customer, 2); err != nil || customer.Name != "lisi" {
+		t.Fatalf("struct target: %+v, %v", customer, err)
+	}
+	if err := orm.FastQuery("SELECT id FROM customers WHERE id = 100", &customer); !IsRecordNotFound(err) {
+		t.Fatalf("expect not found, got %v", err)
+	}
+	var names []string
+	if err := orm.FastQuery("SELECT name, id FROM customers ORDER BY id", &names); err != nil || strings.Join(names, ",") != "zhangshan,lisi" {
+		t.Fatalf("scalar target: %v, %v", names, err)
+	}
+	var maps []map[string]interface{}
+	if err := orm.FastQuery("SELECT id, name FROM customers ORDER BY id", &maps); err != nil || len(maps) != 2 || maps[1]["name"] != "lisi" {
+		t.Fatalf("map target: %v, %v", maps, err)
+	}
+	var rows [][]string
+	if err := orm.FastQuery("SELECT name, remark FROM customers c JOIN orders o ON o.customer_id = c.id", &rows); err != nil || len(rows) != 1 || rows[0][1] != "fast" {
+		t.Fatalf("slice target: %v, %v", rows, err)
+	}
+	tx := orm.Begin()
+	defer tx.Rollback()
+	var orders []CustomerOrder
+	if err := tx.FastQuery("SELECT id, amount, meta FROM orders", &orders); err != nil || len(orders) != 1 || orders[0].Meta.Channel != "app" {
+		t.Fatalf("Tx.FastQuery: %+v, %v", orders, err)
+	}
+	if err := orm.FastQuery("SELECT 1", customer); err == nil {
+		t.Fatal("non pointer should be rejected")
+	}
+}
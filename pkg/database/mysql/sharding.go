@@ -0,0 +1,655 @@
+/*
+ @Desc 分表分库。根据分片键（实体的字段或者ctx中的值）选择物理表（逻辑表名_后缀），可选地再选择数据库：
+   1. HashSharding：按哈希取模，如user_0 ~ user_15
+   2. RangeSharding：按数值区间，如order_0（id < 1000000）、order_1（id < 2000000）...
+   3. TimeSharding：按时间格式化，如metrics_202007
+   4. ValueSharding：直接使用分片键的值，如多租户的table_<tenant>
+ 物理表不存在时通过CreateTable的注册表自动创建。跨分片的查询并发查询所有已存在的分表（scatter-gather），
+ 按照排序规则合并结果之后再分页。
+
+	sharding, err := mysql.NewSharding[Metric](orm, "CreatedAt", mysql.TimeSharding("200601"))
+	err = sharding.Create(ctx, &metric)             //写入metrics_202007
+	repo, err := sharding.Shard(ctx, time.Now())    //当月的分表，可以使用Repository的所有方法
+	metrics, err := sharding.List(ctx, mysql.NewSpec[Metric]().Gte("value", 10).OrderByDesc("value").Limit(20))
+
+ 多租户时分片键放在ctx中：ctx = mysql.WithShardKey(ctx, tenant)，实体的分片键字段为零值时使用ctx中的分片键。
+*/
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+//ErrMissingShardKey 实体和ctx中都没有分片键
+var ErrMissingShardKey = errors.New("mysql: missing sharding key")
+
+//分片后缀拼接在表名中，只允许字母、数字、下划线，防止sql注入
+var shardSuffixPattern = regexp.MustCompile(`^\w+$`)
+
+type shardKey struct{}
+
+// ShardStrategy maps a sharding key to the suffix of a physical table, or to the name of a database.
+type ShardStrategy interface {
+	Shard(key interface{}) (string, error)
+}
+
+// ShardEnumerator is implemented by the strategies knowing all of their shards, e.g. HashSharding.
+type ShardEnumerator interface {
+	Shards() []string
+}
+
+// ShardFunc adapts a function to ShardStrategy.
+type ShardFunc func(key interface{}) (string, error)
+
+//Shard 调用f计算分片
+func (f ShardFunc) Shard(key interface{}) (string, error) {
+	return f(key)
+}
+
+type hashSharding int
+
+//HashSharding 按哈希取模分为shards片，后缀为0 ~ shards-1，整数直接取模，其它类型使用字符串的fnv哈希
+func HashSharding(shards int) ShardStrategy {
+	return hashSharding(shards)
+}
+
+func (h hashSharding) Shard(key interface{}) (string, error) {
+	if h <= 0 {
+		return "", errors.New("mysql: the number of hash shards must be positive")
+	}
+	n := uint64(h)
+	v := reflect.Indirect(reflect.ValueOf(key))
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := v.Int() % int64(n)
+		if i < 0 {
+			i += int64(n)
+		}
+		return strconv.FormatInt(i, 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint()%n, 10), nil
+	case reflect.Invalid:
+		return "", ErrMissingShardKey
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(fmt.Sprint(v.Interface())))
+	return strconv.FormatUint(uint64(hash.Sum32())%n, 10), nil
+}
+
+func (h hashSharding) Shards() []string {
+	shards := make([]string, 0, int(h))
+	for i := 0; i < int(h); i++ {
+		shards = append(shards, strconv.Itoa(i))
+	}
+	return shards
+}
+
+type rangeSharding int64
+
+//RangeSharding 按数值区间分片，每size个连续的值为一片，后缀为key / size，key必须是非负整数
+func RangeSharding(size int64) ShardStrategy {
+	return rangeSharding(size)
+}
+
+func (r rangeSharding) Shard(key interface{}) (string, error) {
+	if r <= 0 {
+		return "", errors.New("mysql: the size of range shards must be positive")
+	}
+	v := reflect.Indirect(reflect.ValueOf(key))
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() >= 0 {
+			return strconv.FormatInt(v.Int()/int64(r), 10), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint()/uint64(r), 10), nil
+	}
+	return "", fmt.Errorf("mysql: range sharding key must be a non-negative integer, got %v", key)
+}
+
+type timeSharding string
+
+//TimeSharding 按时间分片，后缀为key.Format(layout)，如：按月分表使用"200601"，按天分表使用"20060102"
+func TimeSharding(layout string) ShardStrategy {
+	return timeSharding(layout)
+}
+
+func (t timeSharding) Shard(key interface{}) (string, error) {
+	switch v := key.(type) {
+	case time.Time:
+		return v.Format(string(t)), nil
+	case *time.Time:
+		if v != nil {
+			return v.Format(string(t)), nil
+		}
+	}
+	return "", fmt.Errorf("mysql: time sharding key must be a time.Time, got %v", key)
+}
+
+//ValueSharding 直接使用分片键的值作为后缀，如多租户按租户分表
+func ValueSharding() ShardStrategy {
+	return ShardFunc(func(key interface{}) (string, error) {
+		if key == nil {
+			return "", ErrMissingShardKey
+		}
+		return fmt.Sprint(key), nil
+	})
+}
+
+//WithShardKey 返回带有分片键的ctx，实体中没有分片键时使用
+func WithShardKey(ctx context.Context, key interface{}) context.Context {
+	return context.WithValue(ctx, shardKey{}, key)
+}
+
+//ShardKeyFrom 获取ctx中的分片键
+func ShardKeyFrom(ctx context.Context) interface{} {
+	return ctx.Value(shardKey{})
+}
+
+type shardingOptions struct {
+	databases  map[string]*BaseOrm
+	dbStrategy ShardStrategy
+}
+
+// ShardingOption configures a Sharding.
+type ShardingOption func(o *shardingOptions)
+
+//ShardDatabases 分库，strategy根据分片键计算数据库的名称，databases为名称到数据库的映射
+func ShardDatabases(strategy ShardStrategy, databases map[string]*BaseOrm) ShardingOption {
+	return func(o *shardingOptions) {
+		o.dbStrategy, o.databases = strategy, databases
+	}
+}
+
+// Sharding routes the records of the model T to the physical tables "<table>_<suffix>".
+type Sharding[T any] struct {
+	shardingOptions
+	db       *BaseOrm
+	table    string
+	field    string
+	strategy ShardStrategy
+	within   []interface{}
+}
+
+//shard 一个物理表
+type shard struct {
+	db    *BaseOrm
+	table string
+}
+
+/**
+ * 创建T的分表，逻辑表名为T的表名
+ * @param : db 不分库时使用的数据库
+ * @param : field 分片键的字段名或列名，为空时只使用ctx中的分片键
+ * @param : strategy 分表策略
+ * @param : opts 分表选项，如：ShardDatabases
+ * @return: 分片键字段不存在时返回错误
+ */
+func NewSharding[T any](db *BaseOrm, field string, strategy ShardStrategy, opts ...ShardingOption) (*Sharding[T], error) {
+	s := &Sharding[T]{db: db, strategy: strategy}
+	for _, opt := range opts {
+		opt(&s.shardingOptions)
+	}
+	scope := db.NewScope(new(T))
+	s.table = scope.TableName()
+	if field != "" {
+		f, ok := scope.FieldByName(field)
+		if !ok || !f.IsNormal {
+			return nil, fmt.Errorf("mysql: unknown sharding field %q of table %s", field, s.table)
+		}
+		s.field = f.Name
+	}
+	return s, nil
+}
+
+//Within 返回只包含keys所在分片的Sharding，用于缩小跨分片查询的范围，如只查询最近3个月的分表
+func (s *Sharding[T]) Within(keys ...interface{}) *Sharding[T] {
+	within := *s
+	within.within = keys
+	return &within
+}
+
+//Locate 计算分片键所在的数据库以及物理表名
+func (s *Sharding[T]) Locate(key interface{}) (db *BaseOrm, table string, err error) {
+	suffix, err := s.strategy.Shard(key)
+	if err != nil {
+		return nil, "", err
+	}
+	if !shardSuffixPattern.MatchString(suffix) {
+		return nil, "", fmt.Errorf("mysql: invalid shard suffix %q of key %v", suffix, key)
+	}
+	db, err = s.database(key)
+	return db, s.table + "_" + suffix, err
+}
+
+func (s *Sharding[T]) database(key interface{}) (*BaseOrm, error) {
+	if s.dbStrategy == nil {
+		return s.db, nil
+	}
+	name, err := s.dbStrategy.Shard(key)
+	if err != nil {
+		return nil, err
+	}
+	db, ok := s.databases[name]
+	if !ok {
+		return nil, fmt.Errorf("mysql: shard database %q of key %v not found", name, key)
+	}
+	return db, nil
+}
+
+/**
+ * 返回分片键所在分表的Repository，分表不存在时自动创建
+ * @param : ctx 上下文
+ * @param : key 分片键，为nil时使用ctx中的分片键
+ * @return: 绑定到物理表以及ctx的Repository
+ */
+func (s *Sharding[T]) Shard(ctx context.Context, key interface{}) (*Repository[T], error) {
+	if key == nil {
+		if key = ShardKeyFrom(ctx); key == nil {
+			return nil, ErrMissingShardKey
+		}
+	}
+	db, table, err := s.Locate(key)
+	if err != nil {
+		return nil, err
+	}
+	if err = createTable(db.DB, new(T), table); err != nil {
+		return nil, err
+	}
+	return s.repository(ctx, shard{db: db, table: table}), nil
+}
+
+//Route 返回entity所在分表的Repository，entity的分片键字段为零值时使用ctx中的分片键
+func (s *Sharding[T]) Route(ctx context.Context, entity *T) (*Repository[T], error) {
+	if s.field != "" {
+		if field, ok := s.db.NewScope(entity).FieldByName(s.field); ok && !field.IsBlank {
+			return s.Shard(ctx, field.Field.Interface())
+		}
+	}
+	return s.Shard(ctx, nil)
+}
+
+//Create 将entity插入到所在的分表中
+func (s *Sharding[T]) Create(ctx context.Context, entity *T) error {
+	repo, err := s.Route(ctx, entity)
+	if err != nil {
+		return err
+	}
+	return repo.Create(entity)
+}
+
+//Count 所有分片中满足条件的记录数
+func (s *Sharding[T]) Count(ctx context.Context, query interface{}, args ...interface{}) (int, error) {
+	var total int64
+	err := s.scatter(ctx, func(_ int, repo *Repository[T]) error {
+		count, err := repo.Count(query, args...)
+		atomic.AddInt64(&total, int64(count))
+		return err
+	})
+	return int(total), err
+}
+
+/**
+ * 跨分片查询，并发查询所有分片之后按照query（*Spec）中的排序合并，再应用query中的limit和offset
+ * @param : ctx 上下文
+ * @param : query 查询条件，与Repository.List相同
+ * @param : args 查询参数
+ * @return: 合并之后的记录，query没有排序时按分片的顺序返回
+ */
+func (s *Sharding[T]) List(ctx context.Context, query interface{}, args ...interface{}) ([]T, error) {
+	limit, offset := -1, 0
+	var orders []string
+	if spec, ok := query.(*Spec); ok && spec != nil {
+		limit, orders = spec.limit, spec.orders
+		if spec.offset > 0 {
+			offset = spec.offset
+		}
+	}
+	fetch := -1
+	if limit >= 0 {
+		fetch = offset + limit
+	}
+	results, err := s.gather(ctx, func(db *gorm.DB) *gorm.DB {
+		return orderBySpec(where(db, query, args...), query).Limit(fetch)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pageOf(s.merge(results, orders), offset, limit), nil
+}
+
+/**
+ * 跨分片分页查询，每个分片查询前page*pageSize条记录，合并排序之后取当前页
+ * @param : ctx 上下文
+ * @param : pagination 分页器，Page从1开始，页数越大每个分片需要查询的记录越多
+ * @param : orderBy 排序规则，格式为"列名 [desc], 列名 [desc]"，为空时按query（*Spec）中的排序
+ * @param : query 查询条件
+ * @param : args 查询参数
+ * @return: 当前页的数据以及所有分片满足条件的记录总数
+ */
+func (s *Sharding[T]) Page(ctx context.Context, pagination Pagination, orderBy string, query interface{}, args ...interface{}) (*Page[T], error) {
+	if pagination.Page < 1 {
+		pagination.Page = 1
+	}
+	total, err := s.Count(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	pagination.TotalCount = total
+	orders := parseOrderBy(orderBy)
+	if spec, ok := query.(*Spec); ok && spec != nil {
+		orders = append(orders, spec.orders...)
+	}
+	quoted := make([]string, 0, len(orders))
+	for _, order := range orders {
+		q, err := quoteOrder(s.db.DB, order)
+		if err != nil {
+			return nil, err
+		}
+		quoted = append(quoted, q)
+	}
+	offset := (pagination.Page - 1) * pagination.PageSize
+	results, err := s.gather(ctx, func(db *gorm.DB) *gorm.DB {
+		db = where(db, query, args...)
+		for _, order := range quoted {
+			db = db.Order(order)
+		}
+		return db.Limit(offset + pagination.PageSize)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Page[T]{Pagination: pagination, Data: pageOf(s.merge(results, orders), offset, pagination.PageSize)}, nil
+}
+
+//gather 在所有分片上执行查询，返回每个分片的结果
+func (s *Sharding[T]) gather(ctx context.Context, build func(db *gorm.DB) *gorm.DB) ([][]T, error) {
+	var (
+		mu      sync.Mutex
+		results = make(map[int][]T)
+	)
+	err := s.scatter(ctx, func(i int, repo *Repository[T]) error {
+		data := make([]T, 0)
+		if err := build(repo.db).Find(&data).Error; err != nil {
+			return err
+		}
+		mu.Lock()
+		results[i] = data
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ordered := make([][]T, len(results))
+	for i, data := range results {
+		ordered[i] = data
+	}
+	return ordered, nil
+}
+
+//scatter 并发地在所有分片上执行fn，返回第一个错误
+func (s *Sharding[T]) scatter(ctx context.Context, fn func(i int, repo *Repository[T]) error) error {
+	shards, err := s.shards(ctx)
+	if err != nil {
+		return err
+	}
+	errs := make([]error, len(shards))
+	var wg sync.WaitGroup
+	for i, sh := range shards {
+		wg.Add(1)
+		go func(i int, sh shard) {
+			defer wg.Done()
+			errs[i] = fn(i, s.repository(ctx, sh))
+		}(i, sh)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/**
+ * 获取跨分片查询的分片：Within指定了分片键时为分片键所在的分片，否则为所有数据库中已经存在的分表，
+ * 策略实现了ShardEnumerator时只包含其中的分片
+ * @param : ctx 上下文
+ * @return: 按数据库、表名排序的分片
+ */
+func (s *Sharding[T]) shards(ctx context.Context) ([]shard, error) {
+	var shards []shard
+	if s.within != nil {
+		seen := make(map[shard]bool)
+		for _, key := range s.within {
+			db, table, err := s.Locate(key)
+			if err != nil {
+				return nil, err
+			}
+			sh := shard{db: db, table: table}
+			if !seen[sh] && db.HasTable(table) {
+				seen[sh] = true
+				shards = append(shards, sh)
+			}
+		}
+		return shards, nil
+	}
+	var known map[string]bool
+	if enumerator, ok := s.strategy.(ShardEnumerator); ok {
+		known = make(map[string]bool)
+		for _, suffix := range enumerator.Shards() {
+			known[s.table+"_"+suffix] = true
+		}
+	}
+	dbs := []*BaseOrm{s.db}
+	if s.dbStrategy != nil {
+		names := make([]string, 0, len(s.databases))
+		for name := range s.databases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		dbs = dbs[:0]
+		for _, name := range names {
+			dbs = append(dbs, s.databases[name])
+		}
+	}
+	prefix := s.table + "_"
+	for _, db := range dbs {
+		names, err := dialectOf(db.DB).ListTables(withContext(db.DB, ctx), prefix)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if suffix := strings.TrimPrefix(name, prefix); shardSuffixPattern.MatchString(suffix) && (known == nil || known[name]) {
+				shards = append(shards, shard{db: db, table: name})
+			}
+		}
+	}
+	return shards, nil
+}
+
+func (s *Sharding[T]) repository(ctx context.Context, sh shard) *Repository[T] {
+	return &Repository[T]{db: withContext(sh.db.DB, ctx).Table(sh.table)}
+}
+
+//merge 合并各个分片的结果，orders为空时按分片的顺序拼接，否则稳定排序
+func (s *Sharding[T]) merge(results [][]T, orders []string) []T {
+	merged := make([]T, 0)
+	for _, data := range results {
+		merged = append(merged, data...)
+	}
+	if len(orders) == 0 || len(merged) < 2 {
+		return merged
+	}
+	columns := make([]string, len(orders))
+	desc := make([]bool, len(orders))
+	for i, order := range orders {
+		columns[i] = order
+		if j := strings.IndexByte(order, ' '); j > 0 {
+			columns[i], desc[i] = order[:j], strings.EqualFold(strings.TrimSpace(order[j:]), "desc")
+		}
+		if j := strings.LastIndexByte(columns[i], '.'); j >= 0 {
+			columns[i] = columns[i][j+1:]
+		}
+	}
+	keys := make([][]interface{}, len(merged))
+	for i := range merged {
+		scope := s.db.NewScope(&merged[i])
+		keys[i] = make([]interface{}, len(columns))
+		for j, column := range columns {
+			if field, ok := scope.FieldByName(column); ok {
+				keys[i][j] = field.Field.Interface()
+			}
+		}
+	}
+	index := make([]int, len(merged))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(a, b int) bool {
+		for j := range columns {
+			c := compareValues(keys[index[a]][j], keys[index[b]][j])
+			if desc[j] {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	sorted := make([]T, len(merged))
+	for i, k := range index {
+		sorted[i] = merged[k]
+	}
+	return sorted
+}
+
+//parseOrderBy 解析"列名 [asc|desc], ..."格式的排序规则，转换成Spec中的排序格式
+func parseOrderBy(orderBy string) []string {
+	var orders []string
+	for _, part := range strings.Split(orderBy, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		column := strings.Trim(fields[0], "`\"")
+		if len(fields) > 1 && strings.EqualFold(fields[1], "desc") {
+			column += " DESC"
+		}
+		orders = append(orders, column)
+	}
+	return orders
+}
+
+//quoteOrder 对Spec格式的排序中的列名加引号，列名不合法时返回错误，不会将原始的排序拼接到sql中
+func quoteOrder(db *gorm.DB, order string) (string, error) {
+	column, direction := order, ""
+	if i := strings.IndexByte(order, ' '); i > 0 {
+		column, direction = order[:i], order[i:]
+	}
+	if err := checkColumn(column); err != nil {
+		return "", err
+	}
+	if direction != "" && !strings.EqualFold(direction, " DESC") && !strings.EqualFold(direction, " ASC") {
+		return "", fmt.Errorf("mysql: invalid order %q", order)
+	}
+	return quoteColumn(db, column) + direction, nil
+}
+
+//pageOf 截取offset之后的limit条记录，limit小于0时不限制
+func pageOf[T any](data []T, offset, limit int) []T {
+	if offset >= len(data) {
+		return make([]T, 0)
+	}
+	data = data[offset:]
+	if limit >= 0 && limit < len(data) {
+		data = data[:limit]
+	}
+	return data
+}
+
+/**
+ * 比较两个排序列的值，nil最小，支持整数、浮点数、字符串、布尔、时间以及它们的指针，其它类型按字符串比较
+ * @param : a 值a
+ * @param : b 值b
+ * @return: a < b时返回-1，a == b时返回0，a > b时返回1
+ */
+func compareValues(a, b interface{}) int {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	for va.Kind() == reflect.Ptr && !va.IsNil() {
+		va = va.Elem()
+	}
+	for vb.Kind() == reflect.Ptr && !vb.IsNil() {
+		vb = vb.Elem()
+	}
+	nilA, nilB := !va.IsValid() || va.Kind() == reflect.Ptr, !vb.IsValid() || vb.Kind() == reflect.Ptr
+	switch {
+	case nilA && nilB:
+		return 0
+	case nilA:
+		return -1
+	case nilB:
+		return 1
+	}
+	if ta, ok := va.Interface().(time.Time); ok {
+		if tb, ok := vb.Interface().(time.Time); ok {
+			return compareOrdered(ta.UnixNano(), tb.UnixNano())
+		}
+	}
+	switch va.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if vb.CanInt() {
+			return compareOrdered(va.Int(), vb.Int())
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if vb.CanUint() {
+			return compareOrdered(va.Uint(), vb.Uint())
+		}
+	case reflect.Float32, reflect.Float64:
+		if vb.CanFloat() {
+			return compareOrdered(va.Float(), vb.Float())
+		}
+	case reflect.String:
+		if vb.Kind() == reflect.String {
+			return compareOrdered(va.String(), vb.String())
+		}
+	case reflect.Bool:
+		if vb.Kind() == reflect.Bool {
+			return compareOrdered(boolToInt(va.Bool()), boolToInt(vb.Bool()))
+		}
+	}
+	return compareOrdered(fmt.Sprint(va.Interface()), fmt.Sprint(vb.Interface()))
+}
+
+func compareOrdered[V int | int64 | uint64 | float64 | string](a, b V) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
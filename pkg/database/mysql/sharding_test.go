@@ -0,0 +1,159 @@
+package mysql
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type Metric struct {
+	ID        uint `gorm:"primary_key"`
+	Tenant    string
+	Value     int
+	CreatedAt time.Time
+}
+
+func month(m time.Month, day int) time.Time {
+	return time.Date(2020, m, day, 0, 0, 0, 0, time.Local)
+}
+
+func metricValues(metrics []Metric) []int {
+	values := make([]int, 0, len(metrics))
+	for _, m := range metrics {
+		values = append(values, m.Value)
+	}
+	return values
+}
+
+func TestSharding_Time(t *testing.T) {
+	orm := newTestOrm(t)
+	ctx := context.Background()
+	sharding, err := NewSharding[Metric](orm, "created_at", TimeSharding("200601"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewSharding[Metric](orm, "Unknown", TimeSharding("200601")); err == nil {
+		t.Fatal("unknown sharding field should be rejected")
+	}
+	for i, m := range []*Metric{
+		{Value: 5, CreatedAt: month(6, 1)},
+		{Value: 1, CreatedAt: month(7, 1)},
+		{Value: 9, CreatedAt: month(7, 2)},
+		{Value: 3, CreatedAt: month(8, 1)},
+		{Value: 7, CreatedAt: month(8, 2)},
+	} {
+		if err = sharding.Create(ctx, m); err != nil {
+			t.Fatalf("create metric %d: %v", i, err)
+		}
+	}
+	for _, table := range []string{"metrics_202006", "metrics_202007", "metrics_202008"} {
+		if !orm.HasTable(table) {
+			t.Fatalf("table %s should be created", table)
+		}
+	}
+	repo, err := sharding.Shard(ctx, month(7, 15))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := repo.Count(nil); count != 2 {
+		t.Fatalf("metrics_202007 should contain 2 records, got %d", count)
+	}
+
+	metrics, err := sharding.List(ctx, NewSpec[Metric]().Gte("value", 2).OrderByDesc("value").Offset(1).Limit(2))
+	if err != nil || joinInts(metricValues(metrics)) != "7,5" {
+		t.Fatalf("List: %v, %v", metricValues(metrics), err)
+	}
+	if count, err := sharding.Count(ctx, "value > ?", 2); err != nil || count != 4 {
+		t.Fatalf("Count: %d, %v", count, err)
+	}
+	page, err := sharding.Page(ctx, Pagination{Page: 2, PageSize: 2}, "value", nil)
+	if err != nil || page.TotalCount != 5 || joinInts(metricValues(page.Data)) != "5,7" {
+		t.Fatalf("Page: %+v, %v", page, err)
+	}
+	if _, err = sharding.Page(ctx, Pagination{Page: 1, PageSize: 2}, "(select 1)", nil); err == nil {
+		t.Fatal("invalid order column should be rejected")
+	}
+	metrics, err = sharding.Within(month(7, 1), month(8, 1), month(9, 1)).List(ctx, NewSpec[Metric]().OrderBy("value"))
+	if err != nil || joinInts(metricValues(metrics)) != "1,3,7,9" {
+		t.Fatalf("Within: %v, %v", metricValues(metrics), err)
+	}
+}
+
+func TestSharding_Tenant(t *testing.T) {
+	db0, db1 := newTestOrm(t), newTestOrm(t)
+	databases := map[string]*BaseOrm{"0": db0, "1": db1}
+	byTenant := ShardFunc(func(key interface{}) (string, error) {
+		if key == "acme" {
+			return "0", nil
+		}
+		return "1", nil
+	})
+	sharding, err := NewSharding[Metric](db0, "Tenant", ValueSharding(), ShardDatabases(byTenant, databases))
+	if err != nil {
+		t.Fatal(err)
+	}
+	acme := WithShardKey(context.Background(), "acme")
+	if err = sharding.Create(acme, &Metric{Value: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = sharding.Create(context.Background(), &Metric{Tenant: "globex", Value: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err = sharding.Create(context.Background(), &Metric{Value: 3}); err != ErrMissingShardKey {
+		t.Fatalf("expect missing shard key, got %v", err)
+	}
+	if !db0.HasTable("metrics_acme") || db0.HasTable("metrics_globex") || !db1.HasTable("metrics_globex") {
+		t.Fatal("tenants should be routed to their databases")
+	}
+	repo, err := sharding.Shard(acme, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics, _ := repo.List(nil); len(metrics) != 1 || metrics[0].Value != 1 {
+		t.Fatalf("acme metrics: %+v", metrics)
+	}
+	metrics, err := sharding.List(context.Background(), NewSpec[Metric]().OrderByDesc("value"))
+	if err != nil || joinInts(metricValues(metrics)) != "2,1" {
+		t.Fatalf("scatter-gather over databases: %v, %v", metricValues(metrics), err)
+	}
+	if _, _, err = sharding.Locate("a;drop table metrics"); err == nil {
+		t.Fatal("invalid shard suffix should be rejected")
+	}
+}
+
+func TestShardStrategies(t *testing.T) {
+	hash := HashSharding(4)
+	for key, want := range map[interface{}]string{7: "3", int64(-1): "3", uint8(8): "0"} {
+		if got, err := hash.Shard(key); err != nil || got != want {
+			t.Fatalf("hash(%v) = %s, %v, want %s", key, got, err, want)
+		}
+	}
+	a, _ := hash.Shard("tenant-a")
+	if again, _ := hash.Shard("tenant-a"); again != a {
+		t.Fatal("hash sharding should be stable")
+	}
+	if shards := hash.(ShardEnumerator).Shards(); joinStrings(shards) != "0,1,2,3" {
+		t.Fatalf("unexpected shards %v", shards)
+	}
+	if got, err := RangeSharding(1000).Shard(uint(2500)); err != nil || got != "2" {
+		t.Fatalf("range: %s, %v", got, err)
+	}
+	if _, err := RangeSharding(1000).Shard(-1); err == nil {
+		t.Fatal("negative range key should be rejected")
+	}
+	if _, err := TimeSharding("200601").Shard("2020-07"); err == nil {
+		t.Fatal("time sharding should reject non time keys")
+	}
+	if compareValues(nil, 1) >= 0 || compareValues(month(7, 1), month(6, 1)) <= 0 || compareValues("b", "a") <= 0 {
+		t.Fatal("unexpected compare result")
+	}
+}
+
+func joinInts(values []int) string {
+	strs := make([]string, 0, len(values))
+	for _, v := range values {
+		strs = append(strs, strconv.Itoa(v))
+	}
+	return joinStrings(strs)
+}
@@ -0,0 +1,305 @@
+/*
+ @Desc 类型安全的查询条件构造器，列名会根据模型的结构体字段进行校验（支持字段名和列名），
+ 构造出的Spec可以直接作为BaseOrm、Tx以及Repository的查询条件使用，如：
+
+	spec := mysql.NewSpec[Product]().
+		Eq("Name", name).
+		Between("price", 10, 20).
+		Or(func(s *mysql.Spec) { s.IsNull("stock").Eq("stock", 0) }).
+		OrderByDesc("id").
+		Limit(10)
+	err := db.FindCondition(&products, spec)
+*/
+package mysql
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+var columnPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Spec is a composable query condition built against the fields of a model.
+// An unknown column makes the Spec invalid: applying it matches nothing and the query returns the error.
+type Spec struct {
+	model   reflect.Type
+	columns map[string]string
+	or      bool
+	conds   []condition
+	orders  []string
+	limit   int
+	offset  int
+	err     error
+}
+
+type condition struct {
+	column string
+	op     string
+	args   []interface{}
+	group  *Spec
+}
+
+//NewSpec 创建模型T的查询条件，T必须是gorm模型结构体
+func NewSpec[T any]() *Spec {
+	var model T
+	s := &Spec{model: reflect.TypeOf(model), columns: make(map[string]string), limit: -1, offset: -1}
+	for _, field := range (&gorm.Scope{Value: &model}).GetModelStruct().StructFields {
+		if field.IsIgnored {
+			continue
+		}
+		s.columns[field.Name] = field.DBName
+		s.columns[field.DBName] = field.DBName
+	}
+	return s
+}
+
+//Eq column = value
+func (s *Spec) Eq(column string, value interface{}) *Spec {
+	return s.add(column, "= ?", value)
+}
+
+//Ne column <> value
+func (s *Spec) Ne(column string, value interface{}) *Spec {
+	return s.add(column, "<> ?", value)
+}
+
+//Gt column > value
+func (s *Spec) Gt(column string, value interface{}) *Spec {
+	return s.add(column, "> ?", value)
+}
+
+//Gte column >= value
+func (s *Spec) Gte(column string, value interface{}) *Spec {
+	return s.add(column, ">= ?", value)
+}
+
+//Lt column < value
+func (s *Spec) Lt(column string, value interface{}) *Spec {
+	return s.add(column, "< ?", value)
+}
+
+//Lte column <= value
+func (s *Spec) Lte(column string, value interface{}) *Spec {
+	return s.add(column, "<= ?", value)
+}
+
+//In column IN (values...)，values为slice，为空时不匹配任何记录
+func (s *Spec) In(column string, values interface{}) *Spec {
+	if isEmptySlice(values) {
+		s.resolve(column)
+		s.conds = append(s.conds, condition{op: "1 = 0"})
+		return s
+	}
+	return s.add(column, "IN (?)", values)
+}
+
+//NotIn column NOT IN (values...)，values为slice，为空时匹配所有记录（在Or中也是恒为真的条件）
+func (s *Spec) NotIn(column string, values interface{}) *Spec {
+	if isEmptySlice(values) {
+		s.resolve(column)
+		s.conds = append(s.conds, condition{op: "1 = 1"})
+		return s
+	}
+	return s.add(column, "NOT IN (?)", values)
+}
+
+//Between column BETWEEN from AND to
+func (s *Spec) Between(column string, from, to interface{}) *Spec {
+	return s.add(column, "BETWEEN ? AND ?", from, to)
+}
+
+//Like column LIKE pattern，pattern需要自己添加通配符
+func (s *Spec) Like(column string, pattern string) *Spec {
+	return s.add(column, "LIKE ?", pattern)
+}
+
+//IsNull column IS NULL
+func (s *Spec) IsNull(column string) *Spec {
+	return s.add(column, "IS NULL")
+}
+
+//IsNotNull column IS NOT NULL
+func (s *Spec) IsNotNull(column string) *Spec {
+	return s.add(column, "IS NOT NULL")
+}
+
+//And 添加一组使用AND连接的条件，整组条件会被括号括起来，组内不能使用OrderBy、Limit以及Offset，否则Spec不合法
+func (s *Spec) And(group func(s *Spec)) *Spec {
+	return s.group(false, group)
+}
+
+//Or 添加一组使用OR连接的条件，整组条件会被括号括起来，与其它条件之间使用AND连接，组内同样不能使用OrderBy、Limit以及Offset
+func (s *Spec) Or(group func(s *Spec)) *Spec {
+	return s.group(true, group)
+}
+
+//OrderBy 按照column升序排序
+func (s *Spec) OrderBy(column string) *Spec {
+	if name, ok := s.resolve(column); ok {
+		s.orders = append(s.orders, name)
+	}
+	return s
+}
+
+//OrderByDesc 按照column降序排序
+func (s *Spec) OrderByDesc(column string) *Spec {
+	if name, ok := s.resolve(column); ok {
+		s.orders = append(s.orders, name+" DESC")
+	}
+	return s
+}
+
+//Limit 最多查询n条记录
+func (s *Spec) Limit(n int) *Spec {
+	s.limit = n
+	return s
+}
+
+//Offset 跳过前n条记录
+func (s *Spec) Offset(n int) *Spec {
+	s.offset = n
+	return s
+}
+
+//Err 返回构造条件时遇到的第一个错误
+func (s *Spec) Err() error {
+	return s.err
+}
+
+//Apply 将查询条件、排序以及limit添加到db上
+func (s *Spec) Apply(db *gorm.DB) *gorm.DB {
+	return s.applyPaging(s.applyOrder(s.applyWhere(db)))
+}
+
+/**
+ * 只添加查询条件，用于count等不需要排序、分页的查询
+ * @param : db 要添加条件的db
+ * @return: 添加条件之后的db，Spec不合法时返回的db带有错误并且不会匹配任何记录
+ */
+func (s *Spec) applyWhere(db *gorm.DB) *gorm.DB {
+	if s.err != nil {
+		db = db.Where("1 = 0")
+		db.Error = s.err
+		return db
+	}
+	sql, args := s.build(db.Dialect().Quote)
+	if sql == "" {
+		return db
+	}
+	return db.Where(sql, args...)
+}
+
+func (s *Spec) applyOrder(db *gorm.DB) *gorm.DB {
+	for _, order := range s.orders {
+		column, direction := order, ""
+		if i := strings.IndexByte(order, ' '); i > 0 {
+			column, direction = order[:i], order[i:]
+		}
+		db = db.Order(quoteColumn(db, column) + direction)
+	}
+	return db
+}
+
+func (s *Spec) applyPaging(db *gorm.DB) *gorm.DB {
+	if s.limit >= 0 {
+		db = db.Limit(s.limit)
+	}
+	if s.offset >= 0 {
+		db = db.Offset(s.offset)
+	}
+	return db
+}
+
+func (s *Spec) build(quote func(string) string) (string, []interface{}) {
+	var (
+		parts []string
+		args  []interface{}
+	)
+	for _, c := range s.conds {
+		switch {
+		case c.group != nil:
+			sql, groupArgs := c.group.build(quote)
+			if sql == "" {
+				continue
+			}
+			parts = append(parts, "("+sql+")")
+			args = append(args, groupArgs...)
+		case c.column == "":
+			parts = append(parts, c.op)
+		default:
+			parts = append(parts, quoteWith(quote, c.column)+" "+c.op)
+			args = append(args, c.args...)
+		}
+	}
+	separator := " AND "
+	if s.or {
+		separator = " OR "
+	}
+	return strings.Join(parts, separator), args
+}
+
+func (s *Spec) add(column, op string, args ...interface{}) *Spec {
+	if name, ok := s.resolve(column); ok {
+		s.conds = append(s.conds, condition{column: name, op: op, args: args})
+	}
+	return s
+}
+
+func (s *Spec) group(or bool, build func(s *Spec)) *Spec {
+	child := &Spec{model: s.model, columns: s.columns, or: or, limit: -1, offset: -1}
+	build(child)
+	if child.err != nil {
+		s.setErr(child.err)
+	}
+	//组内的排序以及分页无法生效，返回错误而不是静默地丢弃
+	if len(child.orders) > 0 || child.limit >= 0 || child.offset >= 0 {
+		s.setErr(fmt.Errorf("mysql: OrderBy, Limit and Offset are not allowed in And/Or groups of model %v", s.model))
+	}
+	s.conds = append(s.conds, condition{group: child})
+	return s
+}
+
+//resolve 将字段名或列名转换成列名，列不存在时记录错误
+func (s *Spec) resolve(column string) (string, bool) {
+	name, ok := s.columns[column]
+	if !ok {
+		s.setErr(fmt.Errorf("mysql: unknown column %q of model %v", column, s.model))
+	}
+	return name, ok
+}
+
+func (s *Spec) setErr(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+//checkColumn 校验拼接到sql中的列名，防止sql注入
+func checkColumn(column string) error {
+	if !columnPattern.MatchString(column) {
+		return fmt.Errorf("mysql: invalid column name %q", column)
+	}
+	return nil
+}
+
+//quoteColumn 使用数据库方言对列名加引号，支持table.column的形式
+func quoteColumn(db *gorm.DB, column string) string {
+	return quoteWith(db.Dialect().Quote, column)
+}
+
+func quoteWith(quote func(string) string, column string) string {
+	parts := strings.Split(column, ".")
+	for i, part := range parts {
+		parts[i] = quote(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+func isEmptySlice(values interface{}) bool {
+	v := reflect.ValueOf(values)
+	return !v.IsValid() || ((v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Len() == 0)
+}
@@ -0,0 +1,117 @@
+package mysql
+
+import (
+	"strings"
+	"testing"
+)
+
+func newProducts(t *testing.T) *BaseOrm {
+	orm := newTestOrm(t, &Product{})
+	for _, p := range []Product{
+		{Name: "apple", Price: 5, Stock: 10},
+		{Name: "banana", Price: 3},
+		{Name: "cherry", Price: 20, Stock: 1},
+		{Name: "durian", Price: 50, Stock: 2},
+	} {
+		if err := orm.Create(&p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return orm
+}
+
+func names(products []Product) []string {
+	result := make([]string, 0, len(products))
+	for _, p := range products {
+		result = append(result, p.Name)
+	}
+	return result
+}
+
+func TestSpec_Finders(t *testing.T) {
+	orm := newProducts(t)
+	cases := []struct {
+		spec *Spec
+		want string
+	}{
+		{NewSpec[Product]().Eq("Name", "apple"), "apple"},
+		{NewSpec[Product]().Ne("name", "apple").OrderBy("price"), "banana,cherry,durian"},
+		{NewSpec[Product]().In("id", []int{1, 3}).OrderByDesc("ID"), "cherry,apple"},
+		{NewSpec[Product]().In("id", []int{}), ""},
+		{NewSpec[Product]().NotIn("id", nil).Limit(2).Offset(1).OrderBy("id"), "banana,cherry"},
+		{NewSpec[Product]().Between("price", 4, 20).Like("name", "%e%").OrderBy("id"), "apple,cherry"},
+		{NewSpec[Product]().Gt("price", 3).Lte("stock", 2).OrderBy("id"), "cherry,durian"},
+		{NewSpec[Product]().Lt("price", 10).Or(func(s *Spec) {
+			s.Eq("stock", 10).And(func(s *Spec) { s.Lt("price", 4).Eq("stock", 0) })
+		}).OrderBy("id"), "apple,banana"},
+		{NewSpec[Product]().Or(func(s *Spec) { s.Eq("name", "apple").Eq("name", "banana") }).IsNotNull("price").OrderBy("id"), "apple,banana"},
+		{NewSpec[Product]().Or(func(s *Spec) { s.Eq("name", "apple").NotIn("id", []int{}) }).OrderBy("id"), "apple,banana,cherry,durian"},
+	}
+	for i, c := range cases {
+		var result []Product
+		if err := orm.FindCondition(&result, c.spec); err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if got := joinNames(result); got != c.want {
+			t.Errorf("case %d: got %q, want %q", i, got, c.want)
+		}
+	}
+
+	count, err := orm.Count(&Product{}, NewSpec[Product]().IsNull("name"))
+	if err != nil || count != 0 {
+		t.Fatalf("Count: %d, %v", count, err)
+	}
+	var products []Product
+	page, err := orm.ListWithPage(&products, Pagination{Page: 1, PageSize: 2}, "", NewSpec[Product]().Gt("price", 3).OrderByDesc("price"))
+	if err != nil || page.TotalCount != 3 || joinNames(products) != "durian,cherry" {
+		t.Fatalf("ListWithPage: %+v, %v, %v", page, names(products), err)
+	}
+	tx := orm.Begin()
+	defer tx.Rollback()
+	products = nil
+	if err = tx.FindCondition(&products, NewSpec[Product]().Eq("name", "banana")); err != nil || joinNames(products) != "banana" {
+		t.Fatalf("Tx.FindCondition: %v, %v", names(products), err)
+	}
+	var product Product
+	if err = tx.FindOneCondition(&product, NewSpec[Product]().Gt("price", 3).OrderBy("price")); err != nil || product.Name == "" {
+		t.Fatalf("Tx.FindOneCondition: %+v, %v", product, err)
+	}
+}
+
+func TestSpec_InvalidColumn(t *testing.T) {
+	orm := newProducts(t)
+	spec := NewSpec[Product]().Eq("name", "apple").Or(func(s *Spec) { s.Eq("name; drop table products", 1) })
+	if spec.Err() == nil {
+		t.Fatal("unknown column should be reported")
+	}
+	var products []Product
+	if err := orm.FindCondition(&products, spec); err == nil || len(products) != 0 {
+		t.Fatalf("invalid spec should fail without querying: %v, %v", names(products), err)
+	}
+	if count, err := orm.Count(&Product{}, spec); err == nil || count != 0 {
+		t.Fatalf("invalid spec should not count all records: %d, %v", count, err)
+	}
+	//组内的排序以及分页无法生效，Spec不合法
+	for _, group := range []func(s *Spec){
+		func(s *Spec) { s.Eq("name", "apple").OrderBy("price") },
+		func(s *Spec) { s.Eq("name", "apple").Limit(1) },
+		func(s *Spec) { s.Eq("name", "apple").Offset(1) },
+	} {
+		if spec := NewSpec[Product]().Or(group); spec.Err() == nil {
+			t.Fatal("OrderBy, Limit and Offset in a group should be rejected")
+		}
+		if spec := NewSpec[Product]().And(group); spec.Err() == nil {
+			t.Fatal("OrderBy, Limit and Offset in a group should be rejected")
+		}
+	}
+	if err := orm.ListEq("name = name or 1", 1, &products); err == nil {
+		t.Fatal("column name should be validated")
+	}
+	if err := orm.ListEq("name", "apple", &products); err != nil || joinNames(products) != "apple" {
+		t.Fatalf("ListEq: %v, %v", names(products), err)
+	}
+}
+
+func joinNames(products []Product) string {
+	return strings.Join(names(products), ",")
+}
@@ -0,0 +1,13 @@
+/*
+ @Desc 注册sqlite3的数据库驱动。mysql包内置了sqlite3方言（mysql.DialectSQLite），但是不会导入驱动，
+ 驱动（github.com/mattn/go-sqlite3）使用cgo，只有需要sqlite时才匿名导入该包，例如在单元测试中使用内存数据库代替mysql：
+
+	import _ "github.com/yinjk/go-utils/pkg/database/mysql/sqlite"
+
+	orm, err := mysql.Open(&mysql.Config{Dialect: mysql.DialectSQLite, DSN: ":memory:"})
+*/
+package sqlite
+
+import (
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
@@ -0,0 +1,161 @@
+/*
+ @Desc 流式查询，逐行读取查询结果，避免导出大表时将整个结果集加载到内存中。
+ QueryIterator以迭代器的方式读取：
+
+	it, err := mysql.QueryIterator[*User](ctx, orm, "SELECT * FROM users")
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	for it.Next() {
+		user := it.Value()
+		...
+	}
+	return it.Err()
+
+ QueryStream将结果转换成Stream，终止操作（ForEach、FindFirst、AnyMatch等）结束后查询结果会被关闭，即使终止操作提前返回
+*/
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"sync"
+
+	"github.com/jinzhu/gorm"
+
+	list "github.com/yinjk/go-utils/pkg/utils/collection/stream"
+)
+
+type contextQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+//RowIterator 查询结果的迭代器，非并发安全
+type RowIterator[T any] struct {
+	rows  *sql.Rows
+	scan  func() (reflect.Value, error)
+	value T
+	err   error
+}
+
+/**
+ * 执行查询并返回结果的迭代器，每次调用Next时才会读取并扫描下一行，T支持的类型同FastQuery中slice的元素类型。
+ * 迭代结束或者出错时查询结果会自动关闭，提前结束迭代时必须调用Close，ctx取消时查询结果由database/sql关闭，Err返回ctx的错误
+ * @param : ctx 上下文
+ * @param : db BaseOrm或者Tx
+ * @param : query 查询语句
+ * @param : args 查询参数
+ * @return: 迭代器
+ */
+func QueryIterator[T any](ctx context.Context, db Executor, query string, args ...interface{}) (*RowIterator[T], error) {
+	rows, err := queryContext(ctx, db.Gorm(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	scan, err := newRowScanner(rows, reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+	return &RowIterator[T]{rows: rows, scan: scan}, nil
+}
+
+//Next 读取下一行，没有更多的记录或者出错时返回false
+func (it *RowIterator[T]) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if !it.rows.Next() {
+		it.err = it.rows.Err()
+		_ = it.rows.Close()
+		return false
+	}
+	value, err := it.scan()
+	if err != nil {
+		it.err = err
+		_ = it.rows.Close()
+		return false
+	}
+	it.value = value.Interface().(T)
+	return true
+}
+
+//Value 返回当前行
+func (it *RowIterator[T]) Value() T {
+	return it.value
+}
+
+//Err 返回迭代过程中的错误
+func (it *RowIterator[T]) Err() error {
+	return it.err
+}
+
+//Close 关闭查询结果，可以重复调用
+func (it *RowIterator[T]) Close() error {
+	return it.rows.Close()
+}
+
+/**
+ * 执行查询并将结果转换成Stream，数据在终止操作执行时才会逐行读取。T必须是可比较的类型，
+ * 结构体中包含slice、map等字段时使用结构体指针。返回的Stream必须执行一次终止操作（或者取消ctx），否则查询结果不会被关闭
+ * @param : ctx 上下文
+ * @param : db BaseOrm或者Tx
+ * @param : query 查询语句
+ * @param : args 查询参数
+ * @return: stream 查询结果的流
+ * @return: errFunc 返回读取过程中的错误，需要在终止操作结束之后调用
+ */
+func QueryStream[T comparable](ctx context.Context, db Executor, query string, args ...interface{}) (stream list.Stream[T], errFunc func() error, err error) {
+	it, err := QueryIterator[T](ctx, db, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		ch       = make(chan T)
+		stop     = make(chan struct{})
+		finished = make(chan struct{})
+		once     sync.Once
+	)
+	go func() {
+		defer close(finished)
+		defer it.Close()
+		defer close(ch)
+		for it.Next() {
+			if err := ctx.Err(); err != nil {
+				it.err = err
+				return
+			}
+			select {
+			case ch <- it.Value():
+			case <-stop: //终止操作提前结束
+				return
+			case <-ctx.Done():
+				it.err = ctx.Err()
+				return
+			}
+		}
+	}()
+	stream = list.StreamOfChan(ch, func() {
+		once.Do(func() { close(stop) })
+		<-finished
+	})
+	return stream, func() error {
+		<-finished
+		return it.Err()
+	}, nil
+}
+
+//queryContext 执行原生查询，参数的处理与gorm的Raw相同，db支持QueryContext时查询可以被ctx取消
+func queryContext(ctx context.Context, db *gorm.DB, query string, args ...interface{}) (*sql.Rows, error) {
+	scope := db.Raw(query, args...).NewScope(nil)
+	scope.Raw(scope.CombinedConditionSql())
+	if scope.HasError() {
+		return nil, scope.DB().Error
+	}
+	if querier, ok := scope.SQLDB().(contextQuerier); ok {
+		return querier.QueryContext(ctx, scope.SQL, scope.SQLVars...)
+	}
+	return scope.SQLDB().Query(scope.SQL, scope.SQLVars...)
+}
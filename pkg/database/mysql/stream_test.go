@@ -0,0 +1,82 @@
+package mysql
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	list "github.com/yinjk/go-utils/pkg/utils/collection/stream"
+)
+
+func TestQueryIterator(t *testing.T) {
+	orm := newProducts(t)
+	it, err := QueryIterator[Product](context.Background(), orm, "SELECT * FROM products WHERE name IN (?) ORDER BY id", []string{"apple", "cherry"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var products []Product
+	for it.Next() {
+		products = append(products, it.Value())
+	}
+	if it.Err() != nil || joinNames(products) != "apple,cherry" || products[1].Price != 20 {
+		t.Fatalf("unexpected products %+v, %v", products, it.Err())
+	}
+
+	//提前结束迭代，连接池只有一个连接，查询结果没有关闭时后面的查询会阻塞
+	names, err := QueryIterator[string](context.Background(), orm, "SELECT name FROM products ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !names.Next() || names.Value() != "apple" {
+		t.Fatalf("unexpected name %s, %v", names.Value(), names.Err())
+	}
+	_ = names.Close()
+	var count int
+	if err = orm.DB.Raw("SELECT COUNT(*) FROM products").Row().Scan(&count); err != nil || count != 4 {
+		t.Fatalf("count after close: %d, %v", count, err)
+	}
+}
+
+func TestQueryStream(t *testing.T) {
+	orm := newProducts(t)
+	stream, errFunc, err := QueryStream[*Product](context.Background(), orm, "SELECT * FROM products ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inStock := stream.Filter(func(p *Product) bool { return p.Stock > 0 }).ToArray()
+	if err = errFunc(); err != nil || len(inStock) != 3 || inStock[2].Name != "durian" {
+		t.Fatalf("unexpected products %+v, %v", inStock, err)
+	}
+
+	//终止操作提前返回时关闭查询结果
+	stream, errFunc, err = QueryStream[*Product](context.Background(), orm, "SELECT * FROM products ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first := stream.FindFirst(); first.Name != "apple" || errFunc() != nil {
+		t.Fatalf("unexpected first %+v", first)
+	}
+	names, errFunc, err := QueryStream[string](context.Background(), orm, "SELECT name FROM products ORDER BY price DESC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upper := list.Map(names.Limit(2), strings.ToUpper).ToArray()
+	if err = errFunc(); err != nil || strings.Join(upper, ",") != "DURIAN,CHERRY" {
+		t.Fatalf("unexpected names %v, %v", upper, err)
+	}
+
+	//ctx取消时停止读取
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, errFunc, err = QueryStream[*Product](ctx, orm, "SELECT * FROM products ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if count := stream.Count(); count != 0 || errFunc() != context.Canceled {
+		t.Fatalf("expect canceled, got %v after %d rows", errFunc(), count)
+	}
+	var total int
+	if err = orm.DB.Raw("SELECT COUNT(*) FROM products").Row().Scan(&total); err != nil || total != 4 {
+		t.Fatalf("count after cancel: %d, %v", total, err)
+	}
+}
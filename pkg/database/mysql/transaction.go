@@ -0,0 +1,176 @@
+/*
+ @Desc 闭包式事务，fn返回nil时提交，返回错误或者panic时回滚（panic会在回滚之后重新抛出），
+ 在事务中再次调用Transaction时使用SAVEPOINT实现嵌套事务，外层事务遇到死锁时自动重试。
+
+	err := db.Transaction(ctx, func(tx *mysql.Tx) error {
+		if err := tx.Create(&order); err != nil {
+			return err
+		}
+		return tx.Transaction(ctx, func(tx *mysql.Tx) error { //嵌套事务，失败只回滚到savepoint
+			return tx.Create(&log)
+		})
+	}, mysql.WithIsolation(sql.LevelRepeatableRead))
+*/
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	driver "github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+	"github.com/prometheus/common/log"
+)
+
+const (
+	mysqlErrDeadlock = 1213
+
+	defaultTxRetries = 3
+	defaultTxBackoff = 20 * time.Millisecond
+)
+
+//savepoint名称的序号，保证同一个事务中的savepoint不会重名
+var savepointSeq uint64
+
+type txOptions struct {
+	sql.TxOptions
+	maxRetries int
+	backoff    time.Duration
+	retryable  func(err error) bool
+}
+
+// TxOption configures a transaction started by Transaction.
+type TxOption func(o *txOptions)
+
+// WithIsolation sets the isolation level of the transaction.
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(o *txOptions) {
+		o.Isolation = level
+	}
+}
+
+// ReadOnly starts a read-only transaction.
+func ReadOnly() TxOption {
+	return func(o *txOptions) {
+		o.ReadOnly = true
+	}
+}
+
+// WithRetry retries the whole transaction at most maxRetries times when it fails with a deadlock,
+// the n-th retry waits n*backoff. The default is 3 retries with 20ms backoff, 0 disables retrying.
+func WithRetry(maxRetries int, backoff time.Duration) TxOption {
+	return func(o *txOptions) {
+		o.maxRetries = maxRetries
+		o.backoff = backoff
+	}
+}
+
+// RetryOn replaces the deadlock check deciding whether a failed transaction is retried.
+func RetryOn(retryable func(err error) bool) TxOption {
+	return func(o *txOptions) {
+		o.retryable = retryable
+	}
+}
+
+//IsDeadlock 判断err是否是mysql的死锁错误（Error 1213），外层事务遇到该错误时会自动重试
+func IsDeadlock(err error) bool {
+	if errs, ok := err.(gorm.Errors); ok {
+		for _, e := range errs {
+			if IsDeadlock(e) {
+				return true
+			}
+		}
+		return false
+	}
+	var mysqlErr *driver.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlErrDeadlock
+	}
+	return err != nil && strings.Contains(err.Error(), "Deadlock found")
+}
+
+/**
+ * 在事务中执行fn，fn返回nil时提交事务，返回错误或者panic时回滚事务，panic会在回滚之后重新抛出
+ * @param : ctx 事务的上下文，ctx取消时事务会被回滚
+ * @param : fn 在事务中执行的函数，fn中的所有数据库操作都必须使用参数tx
+ * @param : opts 事务选项，如：WithIsolation、ReadOnly、WithRetry
+ * @return: fn返回的错误，或者开启、提交事务时的错误
+ */
+func (bo BaseOrm) Transaction(ctx context.Context, fn func(tx *Tx) error, opts ...TxOption) error {
+	options := &txOptions{maxRetries: defaultTxRetries, backoff: defaultTxBackoff, retryable: IsDeadlock}
+	for _, opt := range opts {
+		opt(options)
+	}
+	for attempt := 1; ; attempt++ {
+		err := bo.transaction(ctx, fn, options)
+		if err == nil || attempt > options.maxRetries || !options.retryable(err) {
+			return err
+		}
+		log.Warnf("transaction failed, retry %d/%d: %v", attempt, options.maxRetries, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * options.backoff):
+		}
+	}
+}
+
+func (bo BaseOrm) transaction(ctx context.Context, fn func(tx *Tx) error, options *txOptions) (err error) {
+	db := bo.DB.BeginTx(ctx, &options.TxOptions)
+	if db.Error != nil {
+		return db.Error
+	}
+	tx := &Tx{withContext(db, ctx)}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.rollback()
+			panic(r)
+		}
+	}()
+	if err = fn(tx); err != nil {
+		tx.rollback()
+		return err
+	}
+	return db.Commit().Error
+}
+
+/**
+ * 在当前事务中开启嵌套事务，使用SAVEPOINT实现，fn返回错误或者panic时只回滚到savepoint，
+ * 外层事务可以继续执行，嵌套事务会随外层事务一起提交，opts在嵌套事务中无效
+ * @param : ctx 上下文，ctx已经取消时直接返回ctx的错误
+ * @param : fn 在嵌套事务中执行的函数
+ * @return: fn返回的错误，或者创建、释放savepoint时的错误
+ */
+func (t Tx) Transaction(ctx context.Context, fn func(tx *Tx) error, opts ...TxOption) (err error) {
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	savepoint := fmt.Sprintf("sp_%d", atomic.AddUint64(&savepointSeq, 1))
+	if err = t.DB.Exec("SAVEPOINT " + savepoint).Error; err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.DB.Exec("ROLLBACK TO SAVEPOINT " + savepoint)
+			panic(r)
+		}
+	}()
+	if err = fn(t.WithContext(ctx)); err != nil {
+		if rbErr := t.DB.Exec("ROLLBACK TO SAVEPOINT " + savepoint).Error; rbErr != nil {
+			log.Errorf("rollback to savepoint %s error: %v", savepoint, rbErr)
+		}
+		return err
+	}
+	return t.DB.Exec("RELEASE SAVEPOINT " + savepoint).Error
+}
+
+func (t Tx) rollback() {
+	if err := t.DB.Rollback().Error; err != nil && !errors.Is(err, sql.ErrTxDone) {
+		log.Errorf("rollback transaction error: %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	driver "github.com/go-sql-driver/mysql"
+)
+
+func TestBaseOrm_Transaction(t *testing.T) {
+	orm := newTestOrm(t, &Product{})
+	ctx := context.Background()
+	repo := NewRepository[Product](orm)
+
+	if err := orm.Transaction(ctx, func(tx *Tx) error {
+		return tx.Create(&Product{Name: "commit"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+	failed := errors.New("failed")
+	if err := orm.Transaction(ctx, func(tx *Tx) error {
+		if err := tx.Create(&Product{Name: "rollback"}); err != nil {
+			return err
+		}
+		return failed
+	}); err != failed {
+		t.Fatalf("expect fn error, got %v", err)
+	}
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Fatalf("panic should be re-thrown, got %v", r)
+			}
+		}()
+		_ = orm.Transaction(ctx, func(tx *Tx) error {
+			_ = tx.Create(&Product{Name: "panic"})
+			panic("boom")
+		})
+	}()
+
+	list, err := repo.List(nil)
+	if err != nil || joinNames(list) != "commit" {
+		t.Fatalf("unexpected records %v, %v", names(list), err)
+	}
+}
+
+func TestTx_Transaction(t *testing.T) {
+	orm := newTestOrm(t, &Product{})
+	ctx := context.Background()
+	err := orm.Transaction(ctx, func(tx *Tx) error {
+		if err := tx.Create(&Product{Name: "outer"}); err != nil {
+			return err
+		}
+		if err := tx.Transaction(ctx, func(tx *Tx) error {
+			_ = tx.Create(&Product{Name: "inner-rollback"})
+			return errors.New("inner failed")
+		}); err == nil {
+			return errors.New("inner error should be returned")
+		}
+		func() {
+			defer func() { _ = recover() }()
+			_ = tx.Transaction(ctx, func(tx *Tx) error {
+				_ = tx.Create(&Product{Name: "inner-panic"})
+				panic("boom")
+			})
+		}()
+		return tx.Transaction(ctx, func(tx *Tx) error {
+			return tx.Transaction(ctx, func(tx *Tx) error {
+				return tx.Create(&Product{Name: "inner-commit"})
+			})
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := NewRepository[Product](orm).List(NewSpec[Product]().OrderBy("id"))
+	if err != nil || joinNames(list) != "outer,inner-commit" {
+		t.Fatalf("unexpected records %v, %v", names(list), err)
+	}
+}
+
+func TestBaseOrm_TransactionRetry(t *testing.T) {
+	orm := newTestOrm(t, &Product{})
+	ctx := context.Background()
+	deadlock := &driver.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+
+	attempts := 0
+	err := orm.Transaction(ctx, func(tx *Tx) error {
+		attempts++
+		if err := tx.Create(&Product{Name: fmt.Sprint(attempts)}); err != nil {
+			return err
+		}
+		if attempts < 3 {
+			return deadlock
+		}
+		return nil
+	}, WithRetry(3, time.Millisecond))
+	if err != nil || attempts != 3 {
+		t.Fatalf("expect success after 3 attempts, got %d, %v", attempts, err)
+	}
+	if list, _ := NewRepository[Product](orm).List(nil); joinNames(list) != "3" {
+		t.Fatalf("failed attempts should be rolled back: %v", names(list))
+	}
+
+	attempts = 0
+	err = orm.Transaction(ctx, func(tx *Tx) error {
+		attempts++
+		return deadlock
+	}, WithRetry(2, time.Millisecond))
+	if !IsDeadlock(err) || attempts != 3 {
+		t.Fatalf("expect deadlock after 3 attempts, got %d, %v", attempts, err)
+	}
+
+	attempts = 0
+	_ = orm.Transaction(ctx, func(tx *Tx) error {
+		attempts++
+		return errors.New("not retryable")
+	})
+	if attempts != 1 {
+		t.Fatalf("only deadlocks should be retried, got %d attempts", attempts)
+	}
+
+	attempts = 0
+	_ = orm.Transaction(ctx, func(tx *Tx) error {
+		attempts++
+		return errors.New("busy")
+	}, WithRetry(1, time.Millisecond), RetryOn(func(err error) bool { return err.Error() == "busy" }))
+	if attempts != 2 {
+		t.Fatalf("custom retryable errors should be retried, got %d attempts", attempts)
+	}
+}
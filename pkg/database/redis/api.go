@@ -1,10 +1,14 @@
 /**
- * redis 通用操作接口
+ * redis 通用操作接口，按照redis的数据类型分为多组接口，Client实现了所有的接口
  * @author yinjk
  * @create 2019-02-11 14:16
  */
 package redis
 
+import "time"
+
+var _ API = Client{}
+
 type API interface {
 
 	/**
@@ -23,16 +27,16 @@ type API interface {
 	 * @author: yinjk
 	 * @time  : 2019/2/13 14:53
 	 */
-	Multi()
+	Multi() error
 
 	/**
-	 * 执行事物块内的所有语句
+	 * 执行事物块内的所有语句，Multi之后的命令只返回QUEUED，命令的结果由Exec按顺序返回
 	 * @param :
-	 * @return:
+	 * @return: 每个命令的结果，执行失败的命令对应的结果为redis.Error；被WATCH中止时返回ErrTxAborted
 	 * @author: yinjk
 	 * @time  : 2019/2/13 14:53
 	 */
-	Exec()
+	Exec() ([]interface{}, error)
 
 	/**
 	 * 取消事物，放弃事物块内的所有语句
@@ -41,7 +45,42 @@ type API interface {
 	 * @author: yinjk
 	 * @time  : 2019/2/13 14:53
 	 */
-	Discard()
+	Discard() error
+
+	//Pipeline 创建管道，排队的命令在Exec时一次发送
+	Pipeline() *Pipeline
+
+	//TxPipeline 创建事务管道，排队的命令在Exec时使用MULTI/EXEC原子执行
+	TxPipeline() *Pipeline
+
+	/**
+	 * 基于WATCH的乐观锁事务，被监视的key在EXEC之前被修改时重新执行fn
+	 * @param : fn 通过Client读取数据并在tx中排队写命令
+	 * @param : maxRetries 最多重试的次数
+	 * @param : keys 需要监视的key
+	 * @return: 重试次数用完时返回ErrTxAborted
+	 */
+	Watch(fn func(tx *Pipeline) error, maxRetries int, keys ...string) error
+
+	//Eval 使用EVALSHA执行lua脚本，脚本未加载时回退到EVAL
+	Eval(script *Script, keysAndArgs ...interface{}) *Reply
+
+	//ScriptLoad 预先加载lua脚本
+	ScriptLoad(scripts ...*Script) error
+
+	KeysAPI
+	StringsAPI
+	HashesAPI
+	ListsAPI
+	SetsAPI
+	SortedSetsAPI
+	BitmapsAPI
+	HyperLogLogAPI
+	GeoAPI
+}
+
+// KeysAPI contains the commands working on keys of any type.
+type KeysAPI interface {
 
 	/**
 	 * 删除一个或多个key
@@ -50,7 +89,7 @@ type API interface {
 	 * @author: yinjk
 	 * @time  : 2019/2/13 10:39
 	 */
-	DeleteKey(keys ...interface{}) int
+	DeleteKey(keys ...interface{}) (int, error)
 
 	/**
 	 * key是否在redis中存在
@@ -59,64 +98,158 @@ type API interface {
 	 * @author: yinjk
 	 * @time  : 2019/2/13 10:40
 	 */
-	Exists(key string) bool
+	Exists(key string) (bool, error)
+
+	/**
+	 * @description: 设置key过期时间
+	 * @param key
+	 * @param time 过期时间，单位秒
+	 * @return
+	 */
+	Expire(key string, time int) error
+
+	//PExpire 设置key的过期时间，精确到毫秒，key不存在时返回false
+	PExpire(key string, ttl time.Duration) (bool, error)
+
+	//ExpireAt 设置key在指定的时间过期，key不存在时返回false
+	ExpireAt(key string, at time.Time) (bool, error)
+
+	//Persist 移除key的过期时间，key不存在或者没有过期时间时返回false
+	Persist(key string) (bool, error)
+
+	//TTL 获取key的剩余过期时间，没有过期时间时返回NoTTL，key不存在时返回ErrNil
+	TTL(key string) (time.Duration, error)
+
+	//Type 获取key的类型：string、list、set、zset、hash、stream，key不存在时返回none
+	Type(key string) (string, error)
+
+	//Rename 重命名key，newKey存在时会被覆盖
+	Rename(key, newKey string) error
+
+	//RenameNX newKey不存在时重命名key
+	RenameNX(key, newKey string) (bool, error)
+
+	//Scan 迭代当前数据库中的key，match为空时匹配所有key，count为每次迭代返回数量的提示值，小于等于0时使用redis的默认值
+	Scan(match string, count int) *ScanIterator
+}
+
+// StringsAPI contains the commands of the string type.
+type StringsAPI interface {
 
 	/**
 	 * 获取一个string类型的值
 	 * @param : key
-	 * @return:
+	 * @return: key不存在时返回ErrNil
 	 * @author: yinjk
 	 * @time  : 2019/2/13 10:40
 	 */
-	GetString(key string) string
+	GetString(key string) (string, error)
 
 	/**
 	 * 获取一个int类型的值
 	 * @param : key
-	 * @return:
+	 * @return: key不存在时返回ErrNil
 	 * @author: yinjk
 	 * @time  : 2019/2/13 10:41
 	 */
-	GetInt(key string) (value int)
+	GetInt(key string) (int, error)
+
+	//GetInt64 获取一个int64类型的值
+	GetInt64(key string) (int64, error)
+
+	//GetFloat64 获取一个float64类型的值
+	GetFloat64(key string) (float64, error)
+
+	//GetBytes 获取一个[]byte类型的值
+	GetBytes(key string) ([]byte, error)
+
+	//GetSet 设置新的值并返回旧的值，key不存在时返回ErrNil
+	GetSet(key string, value interface{}) (string, error)
+
+	//GetDel 获取并删除key，key不存在时返回ErrNil
+	GetDel(key string) (string, error)
+
+	//MGet 获取多个key的值，不存在的key对应的值为空字符串
+	MGet(keys ...string) ([]string, error)
 
 	/**
 	 * 设置一个key-value值
 	 * @param :
-	 * @return: 是否设置成功
+	 * @return:
 	 * @author: yinjk
 	 * @time  : 2019/2/13 10:41
 	 */
-	Set(key, value interface{}) bool
+	Set(key, value interface{}) error
 
 	/**
-	 * @description: 设置key过期时间
-	 * @param
-	 * @return
-	 * @author: wzl
-	 * @time  : 2019/8/23 16:12
+	 * 设置一个key-value值，支持过期时间以及NX、XX等选项
+	 * @param : key 键
+	 * @param : value 值
+	 * @param : opts 选项：ExpireAfter、IfNotExists、IfExists、KeepTTL
+	 * @return: NX、XX的条件不满足时返回false
 	 */
-	Expire(key string, time int) bool
+	SetWithOptions(key, value interface{}, opts ...SetOption) (bool, error)
+
+	//SetNX key不存在时设置值，返回是否设置成功
+	SetNX(key, value interface{}) (bool, error)
+
+	//SetEX 设置值以及过期时间
+	SetEX(key, value interface{}, ttl time.Duration) error
+
+	//MSet 同时设置多个key-value值
+	MSet(pairs map[string]interface{}) error
+
+	//Incr 将值加1，返回加1之后的值，key不存在时从0开始
+	Incr(key string) (int64, error)
+
+	//IncrBy 将值加上n，返回相加之后的值
+	IncrBy(key string, n int64) (int64, error)
+
+	//IncrByFloat 将值加上浮点数f，返回相加之后的值
+	IncrByFloat(key string, f float64) (float64, error)
+
+	//Decr 将值减1，返回减1之后的值
+	Decr(key string) (int64, error)
+
+	//DecrBy 将值减去n，返回相减之后的值
+	DecrBy(key string, n int64) (int64, error)
+
+	//Append 在值的末尾追加字符串，返回追加之后的长度
+	Append(key string, value interface{}) (int, error)
+
+	//StrLen 获取值的长度
+	StrLen(key string) (int, error)
+}
+
+// HashesAPI contains the commands of the hash type.
+type HashesAPI interface {
 
 	/**
 	 * 向一个map中设置一个value值
 	 * @param : key map的键
 	 * @param : filed map中的字段
 	 * @param : value map中字段的值
-	 * @return: 是否设置成功
+	 * @return:
 	 * @author: yinjk
 	 * @time  : 2019/2/13 10:42
 	 */
-	HSet(key, field string, value interface{}) bool
+	HSet(key, field string, value interface{}) error
+
+	//HSetNX 字段不存在时设置字段的值，返回是否设置成功
+	HSetNX(key, field string, value interface{}) (bool, error)
 
 	/**
 	 * 从map中获取一个字段的值
 	 * @param : key 要获取map的键
 	 * @param : field 要获取map的字段
-	 * @return: 获取的值
+	 * @return: 获取的值，字段不存在时返回ErrNil
 	 * @author: yinjk
 	 * @time  : 2019/2/13 10:43
 	 */
-	HGet(key, field string) string
+	HGet(key, field string) (string, error)
+
+	//HMGet 获取多个字段的值，不存在的字段对应的值为空字符串
+	HMGet(key string, fields ...string) ([]string, error)
 
 	/**
 	 * 获取一整个map所有字段的值
@@ -125,19 +258,151 @@ type API interface {
 	 * @author: yinjk
 	 * @time  : 2019/2/13 10:44
 	 */
-	HGetAll(key string) map[string]string
+	HGetAll(key string) (map[string]string, error)
 
 	/**
 	 * 设置整个map的值
 	 * @param : key map的键
-	 * @return: map的数据
+	 * @param : data map的数据
+	 * @return:
 	 * @author: yinjk
 	 * @time  : 2019/2/13 10:45
 	 */
-	HmSet(key string, data map[string]interface{})
+	HmSet(key string, data map[string]interface{}) error
+
+	//HDel 删除一个或多个字段，返回删除的字段个数
+	HDel(key string, fields ...string) (int, error)
+
+	//HExists 字段是否存在
+	HExists(key, field string) (bool, error)
+
+	//HIncrBy 将字段的值加上n，返回相加之后的值
+	HIncrBy(key, field string, n int64) (int64, error)
+
+	//HIncrByFloat 将字段的值加上浮点数f，返回相加之后的值
+	HIncrByFloat(key, field string, f float64) (float64, error)
+
+	//HKeys 获取所有的字段
+	HKeys(key string) ([]string, error)
+
+	//HVals 获取所有字段的值
+	HVals(key string) ([]string, error)
+
+	//HLen 获取字段的个数
+	HLen(key string) (int, error)
+
+	//HScan 迭代map的字段，ScanIterator.Member为字段，ScanIterator.Value为字段的值
+	HScan(key, match string, count int) *ScanIterator
+}
+
+// ListsAPI contains the commands of the list type.
+type ListsAPI interface {
+
+	//LPush 在列表头部插入一个或多个值，返回插入之后列表的长度
+	LPush(key string, values ...interface{}) (int, error)
+
+	//RPush 在列表尾部插入一个或多个值，返回插入之后列表的长度
+	RPush(key string, values ...interface{}) (int, error)
+
+	//LPop 移除并返回列表的第一个元素，列表为空时返回ErrNil
+	LPop(key string) (string, error)
+
+	//RPop 移除并返回列表的最后一个元素，列表为空时返回ErrNil
+	RPop(key string) (string, error)
+
+	/**
+	 * 移除并返回第一个非空列表的第一个元素，所有列表都为空时阻塞直到有元素插入或者超时
+	 * @param : timeout 超时时间，向上取整到秒，0表示一直阻塞
+	 * @param : keys 列表的键
+	 * @return: 元素所在列表的键以及元素的值，超时返回ErrNil
+	 */
+	BLPop(timeout time.Duration, keys ...string) (key, value string, err error)
+
+	//BRPop 同BLPop，移除并返回列表的最后一个元素
+	BRPop(timeout time.Duration, keys ...string) (key, value string, err error)
+
+	//RPopLPush 移除source的最后一个元素并插入到destination的头部，返回该元素，source为空时返回ErrNil
+	RPopLPush(source, destination string) (string, error)
+
+	//LLen 获取列表的长度
+	LLen(key string) (int, error)
+
+	//LRange 获取列表指定区间内的元素，start、stop从0开始，-1表示最后一个元素
+	LRange(key string, start, stop int) ([]string, error)
+
+	//LIndex 获取列表中下标为index的元素，index超出范围时返回ErrNil
+	LIndex(key string, index int) (string, error)
+
+	//LSet 设置列表中下标为index的元素
+	LSet(key string, index int, value interface{}) error
+
+	//LInsertBefore 在pivot之前插入value，返回插入之后列表的长度，pivot不存在时返回-1
+	LInsertBefore(key string, pivot, value interface{}) (int, error)
+
+	//LInsertAfter 在pivot之后插入value，返回插入之后列表的长度，pivot不存在时返回-1
+	LInsertAfter(key string, pivot, value interface{}) (int, error)
+
+	//LRem 移除count个等于value的元素，count大于0时从头部开始、小于0时从尾部开始、等于0时移除所有，返回移除的个数
+	LRem(key string, count int, value interface{}) (int, error)
+
+	//LTrim 只保留列表指定区间内的元素
+	LTrim(key string, start, stop int) error
+}
+
+// SetsAPI contains the commands of the set type.
+type SetsAPI interface {
+
+	//SAdd 向集合添加一个或多个成员，返回新添加的成员个数
+	SAdd(key string, members ...interface{}) (int, error)
+
+	//SRem 移除集合中的一个或多个成员，返回移除的成员个数
+	SRem(key string, members ...interface{}) (int, error)
+
+	//SMembers 获取集合的所有成员
+	SMembers(key string) ([]string, error)
+
+	//SIsMember 判断member是否是集合的成员
+	SIsMember(key string, member interface{}) (bool, error)
+
+	//SCard 获取集合的成员个数
+	SCard(key string) (int, error)
+
+	//SPop 移除并返回集合中的一个随机成员，集合为空时返回ErrNil
+	SPop(key string) (string, error)
+
+	//SRandMember 随机返回集合中的count个成员，count为负数时可能返回重复的成员
+	SRandMember(key string, count int) ([]string, error)
+
+	//SMove 将member从source移动到destination
+	SMove(source, destination string, member interface{}) (bool, error)
+
+	//SInter 返回多个集合的交集
+	SInter(keys ...string) ([]string, error)
+
+	//SUnion 返回多个集合的并集
+	SUnion(keys ...string) ([]string, error)
+
+	//SDiff 返回第一个集合与其它集合的差集
+	SDiff(keys ...string) ([]string, error)
+
+	//SInterStore 将多个集合的交集保存到destination，返回交集的成员个数
+	SInterStore(destination string, keys ...string) (int, error)
+
+	//SUnionStore 将多个集合的并集保存到destination，返回并集的成员个数
+	SUnionStore(destination string, keys ...string) (int, error)
+
+	//SDiffStore 将第一个集合与其它集合的差集保存到destination，返回差集的成员个数
+	SDiffStore(destination string, keys ...string) (int, error)
+
+	//SScan 迭代集合的成员
+	SScan(key, match string, count int) *ScanIterator
+}
+
+// SortedSetsAPI contains the commands of the sorted set type.
+type SortedSetsAPI interface {
 
 	/**
-	 * 想有序集合中追加一个值
+	 * 向有序集合中追加一个值
 	 * @param : key 有序集合的键
 	 * @param : score 要追加的值的排序分数
 	 * @param : value 要追加的值
@@ -145,7 +410,10 @@ type API interface {
 	 * @author: yinjk
 	 * @time  : 2019/2/13 10:46
 	 */
-	ZAdd(key string, score int, value interface{}) error
+	ZAdd(key string, score float64, value interface{}) error
+
+	//ZAddValues 向有序集合中追加多个值，返回新添加的成员个数
+	ZAddValues(key string, values ...ZSetValue) (int, error)
 
 	/**
 	 * 获取有序集合中的元素个数
@@ -154,17 +422,29 @@ type API interface {
 	 * @author: yinjk
 	 * @time  : 2019/2/13 13:48
 	 */
-	ZCard(key string) int
+	ZCard(key string) (int, error)
+
+	//ZCount 获取分数在[min, max]之间的成员个数，min、max的格式同ZRangeByScore
+	ZCount(key, min, max string) (int, error)
 
 	/**
 	 * 获取有序集合中某个值的排序分数
 	 * @param : key 有序集合的键
 	 * @param : member 要获取分数的值
-	 * @return: 分数
+	 * @return: 分数，member不存在时返回ErrNil
 	 * @author: yinjk
 	 * @time  : 2019/2/13 10:47
 	 */
-	ZScore(key, member string) int
+	ZScore(key, member string) (float64, error)
+
+	//ZIncrBy 将member的分数加上increment，返回相加之后的分数
+	ZIncrBy(key string, increment float64, member interface{}) (float64, error)
+
+	//ZRank 获取member的排名（分数从低到高，从0开始），member不存在时返回ErrNil
+	ZRank(key string, member interface{}) (int, error)
+
+	//ZRevRank 获取member的排名（分数从高到低，从0开始），member不存在时返回ErrNil
+	ZRevRank(key string, member interface{}) (int, error)
 
 	/**
 	 * 移除有序集合中的一个或多个成员
@@ -174,47 +454,56 @@ type API interface {
 	 * @author: yinjk
 	 * @time  : 2019/2/11 15:25
 	 */
-	ZRemove(key string, member ...interface{}) int
+	ZRemove(key string, member ...interface{}) (int, error)
+
+	//ZRemRangeByRank 移除排名在[start, stop]之间的成员，返回移除的个数
+	ZRemRangeByRank(key string, start, stop int) (int, error)
+
+	//ZRemRangeByScore 移除分数在[min, max]之间的成员，返回移除的个数
+	ZRemRangeByScore(key, min, max string) (int, error)
 
 	/**
-	 * 向有序集合中追加数据
+	 * 向有序集合中追加数据，分数为数据的下标
 	 * @param : key 有序集合的键
 	 * @param : values 要追加的values
-	 * @return: 是否最佳成功
+	 * @return:
 	 * @author: yinjk
 	 * @time  : 2019/2/11 16:45
 	 */
-	ZAddSet(key string, values []interface{}) bool
+	ZAddSet(key string, values []interface{}) error
+
+	//ZAddSetWithScore 向有序集合中追加数据，分数由genScore生成
+	ZAddSetWithScore(key string, values []interface{}, genScore func(value interface{}) float64) error
 
 	/**
-	 * 清空有序集合并重新添加一组数据（分数按照 0 1 2 3... 排序）
+	 * 清空有序集合并重新添加一组数据（分数按照 0 1 2 3... 排序），清空与添加在同一个事务中执行
 	 * @param : key 有序集合的key
 	 * @param : values 要添加的数据
-	 * @return: 是否添加成功
+	 * @return:
 	 * @author: yinjk
 	 * @time  : 2019/2/11 16:42
 	 */
-	ZClearAndAddSet(key string, values []interface{}) bool
+	ZClearAndAddSet(key string, values []interface{}) error
 
 	/**
 	 * 获取有序集合中的最大分数
 	 * @param : 有序集合的key
-	 * @return: 最大的分数
+	 * @return: 最大的分数，有序集合为空时返回-1
 	 * @author: yinjk
 	 * @time  : 2019/2/11 16:42
 	 */
-	ZGetMaxScore(key string) int
+	ZGetMaxScore(key string) (float64, error)
 
 	/**
 	 * 通过索引区间返回有序集合成指定区间内的成员，分数从低到高
 	 * @param : key 有序集合的键
-	 * @param : cronjob 从第几个元素开始（从0开始计数）
-	 * @param : end 到第几个元素结束（-1 表示到最后）
+	 * @param : start 从第几个元素开始（从0开始计数）
+	 * @param : stop 到第几个元素结束（-1 表示到最后）
 	 * @return: 有序集合
 	 * @author: yinjk
 	 * @time  : 2019/2/11 16:31
 	 */
-	ZRange(key string, start, stop int) []ZSetValue
+	ZRange(key string, start, stop int) ([]ZSetValue, error)
 
 	/**
 	 * 返回有序集中指定区间内的成员，通过索引，分数从高到底，相当于ZRange倒序
@@ -223,5 +512,91 @@ type API interface {
 	 * @author: yinjk
 	 * @time  : 2019/2/11 16:38
 	 */
-	ZRevRange(key string, start, stop int) []ZSetValue
+	ZRevRange(key string, start, stop int) ([]ZSetValue, error)
+
+	/**
+	 * 返回分数在[min, max]之间的成员，分数从低到高
+	 * @param : key 有序集合的键
+	 * @param : min 最小分数，"-inf"表示负无穷，以"("开头表示不包含，如"(1.5"
+	 * @param : max 最大分数，"+inf"表示正无穷
+	 * @param : offset 跳过的成员个数
+	 * @param : count 返回的成员个数，小于0时返回所有
+	 * @return: 有序集合
+	 */
+	ZRangeByScore(key, min, max string, offset, count int) ([]ZSetValue, error)
+
+	//ZRevRangeByScore 同ZRangeByScore，分数从高到低，注意max在前
+	ZRevRangeByScore(key, max, min string, offset, count int) ([]ZSetValue, error)
+
+	//ZPopMin 移除并返回分数最低的count个成员
+	ZPopMin(key string, count int) ([]ZSetValue, error)
+
+	//ZPopMax 移除并返回分数最高的count个成员
+	ZPopMax(key string, count int) ([]ZSetValue, error)
+
+	//ZScan 迭代有序集合的成员，ScanIterator.Score为成员的分数
+	ZScan(key, match string, count int) *ScanIterator
+}
+
+// BitmapsAPI contains the bit commands of the string type.
+type BitmapsAPI interface {
+
+	//SetBit 设置offset位的值，返回该位原来的值
+	SetBit(key string, offset int64, on bool) (bool, error)
+
+	//GetBit 获取offset位的值
+	GetBit(key string, offset int64) (bool, error)
+
+	//BitCount 统计值为1的位数
+	BitCount(key string) (int64, error)
+
+	//BitCountRange 统计字节区间[start, end]内值为1的位数
+	BitCountRange(key string, start, end int64) (int64, error)
+
+	//BitPos 返回第一个值为bit的位，不存在时返回-1
+	BitPos(key string, bit bool) (int64, error)
+
+	//BitOp 对多个key执行位运算（BitAnd、BitOr、BitXor、BitNot）并将结果保存到destination，返回结果的字节数
+	BitOp(op, destination string, keys ...string) (int64, error)
+}
+
+// HyperLogLogAPI contains the commands of the HyperLogLog type.
+type HyperLogLogAPI interface {
+
+	//PFAdd 添加元素，基数估计值发生变化时返回true
+	PFAdd(key string, elements ...interface{}) (bool, error)
+
+	//PFCount 返回多个HyperLogLog并集的基数估计值
+	PFCount(keys ...string) (int64, error)
+
+	//PFMerge 将多个HyperLogLog合并到destination
+	PFMerge(destination string, keys ...string) error
+}
+
+// GeoAPI contains the commands of the geospatial index.
+type GeoAPI interface {
+
+	//GeoAdd 添加一个或多个位置，返回新添加的位置个数
+	GeoAdd(key string, locations ...GeoLocation) (int, error)
+
+	//GeoPos 获取位置的经纬度，不存在的成员对应的值为nil
+	GeoPos(key string, members ...string) ([]*GeoLocation, error)
+
+	//GeoDist 获取两个位置之间的距离，unit为GeoMeters、GeoKilometers、GeoMiles、GeoFeet，任意一个位置不存在时返回ErrNil
+	GeoDist(key, member1, member2, unit string) (float64, error)
+
+	/**
+	 * 查询中心点指定半径内的位置，按照距离从近到远排序
+	 * @param : key 位置集合的键
+	 * @param : longitude 中心点经度
+	 * @param : latitude 中心点纬度
+	 * @param : radius 半径
+	 * @param : unit 半径以及返回距离的单位
+	 * @param : count 返回的位置个数，小于等于0时返回所有
+	 * @return: 位置以及与中心点的距离
+	 */
+	GeoRadius(key string, longitude, latitude, radius float64, unit string, count int) ([]GeoLocation, error)
+
+	//GeoRadiusByMember 同GeoRadius，以member的位置为中心点
+	GeoRadiusByMember(key, member string, radius float64, unit string, count int) ([]GeoLocation, error)
 }
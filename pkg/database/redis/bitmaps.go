@@ -0,0 +1,60 @@
+/**
+ * redis bitmap以及HyperLogLog的操作
+ */
+package redis
+
+import (
+	"github.com/gomodule/redigo/redis"
+)
+
+//BitOp的位运算
+const (
+	BitAnd = "AND"
+	BitOr  = "OR"
+	BitXor = "XOR"
+	BitNot = "NOT"
+)
+
+func (c Client) SetBit(key string, offset int64, on bool) (bool, error) {
+	return redis.Bool(c.conn.Do("SETBIT", key, offset, bit(on)))
+}
+
+func (c Client) GetBit(key string, offset int64) (bool, error) {
+	return redis.Bool(c.conn.Do("GETBIT", key, offset))
+}
+
+func (c Client) BitCount(key string) (int64, error) {
+	return redis.Int64(c.conn.Do("BITCOUNT", key))
+}
+
+func (c Client) BitCountRange(key string, start, end int64) (int64, error) {
+	return redis.Int64(c.conn.Do("BITCOUNT", key, start, end))
+}
+
+func (c Client) BitPos(key string, on bool) (int64, error) {
+	return redis.Int64(c.conn.Do("BITPOS", key, bit(on)))
+}
+
+func (c Client) BitOp(op, destination string, keys ...string) (int64, error) {
+	return redis.Int64(c.conn.Do("BITOP", redis.Args{}.Add(op, destination).AddFlat(keys)...))
+}
+
+func (c Client) PFAdd(key string, elements ...interface{}) (bool, error) {
+	return redis.Bool(c.conn.Do("PFADD", redis.Args{}.Add(key).Add(elements...)...))
+}
+
+func (c Client) PFCount(keys ...string) (int64, error) {
+	return redis.Int64(c.conn.Do("PFCOUNT", redis.Args{}.AddFlat(keys)...))
+}
+
+func (c Client) PFMerge(destination string, keys ...string) error {
+	_, err := c.conn.Do("PFMERGE", redis.Args{}.Add(destination).AddFlat(keys)...)
+	return err
+}
+
+func bit(on bool) int {
+	if on {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,53 @@
+package redis
+
+import (
+	"testing"
+)
+
+func TestClient_SetBit(t *testing.T) {
+	client, _ := newClient(t)
+	for _, day := range []int64{1, 3, 9} {
+		if old, err := client.SetBit("sign:202008", day, true); err != nil || old {
+			t.Fatalf("SetBit: %v, %v", old, err)
+		}
+	}
+	if on, _ := client.GetBit("sign:202008", 3); !on {
+		t.Fatal("bit 3 should be set")
+	}
+	if n, _ := client.BitCount("sign:202008"); n != 3 {
+		t.Fatalf("BitCount: %d", n)
+	}
+	if n, _ := client.BitCountRange("sign:202008", 1, 1); n != 1 {
+		t.Fatalf("BitCountRange: %d", n)
+	}
+	if pos, _ := client.BitPos("sign:202008", true); pos != 1 {
+		t.Fatalf("BitPos: %d", pos)
+	}
+	_, _ = client.SetBit("sign:202009", 3, true)
+	if _, err := client.BitOp(BitAnd, "both", "sign:202008", "sign:202009"); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := client.BitCount("both"); n != 1 {
+		t.Fatalf("BitOp AND: %d", n)
+	}
+}
+
+func TestClient_PFAdd(t *testing.T) {
+	client, _ := newClient(t)
+	if changed, err := client.PFAdd("uv:1", "tom", "jerry", "tom"); err != nil || !changed {
+		t.Fatalf("PFAdd: %v, %v", changed, err)
+	}
+	if changed, _ := client.PFAdd("uv:1", "tom"); changed {
+		t.Fatal("adding an existing element should not change the estimate")
+	}
+	_, _ = client.PFAdd("uv:2", "spike", "jerry")
+	if n, _ := client.PFCount("uv:1"); n != 2 {
+		t.Fatalf("PFCount: %d", n)
+	}
+	if err := client.PFMerge("uv", "uv:1", "uv:2"); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := client.PFCount("uv"); n != 3 {
+		t.Fatalf("PFMerge: %d", n)
+	}
+}
@@ -0,0 +1,331 @@
+/**
+ * 对象的编解码：ObjectStore使用可插拔的Codec（JSON、gob、msgpack）将对象保存为字符串，
+ * 可选地压缩超过阈值的数据，所有的key都加上服务的命名空间前缀；
+ * 结构体与hash之间的转换使用convert.Mapper，字段名取redis或json标签
+ */
+package redis
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/ugorji/go/codec"
+	"github.com/yinjk/go-utils/pkg/utils/convert"
+)
+
+// Codec marshals the objects stored in redis.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	//JSONCodec 使用encoding/json编码，其它语言的客户端也可以读取
+	JSONCodec Codec = jsonCodec{}
+	//GobCodec 使用encoding/gob编码，只适用于Go的客户端
+	GobCodec Codec = gobCodec{}
+	//MsgpackCodec 使用msgpack二进制编码，比JSON更紧凑
+	MsgpackCodec Codec = msgpackCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+var msgpackHandle = &codec.MsgpackHandle{WriteExt: true}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v interface{}) (data []byte, err error) {
+	err = codec.NewEncoderBytes(&data, msgpackHandle).Encode(v)
+	return data, err
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	return codec.NewDecoderBytes(data, msgpackHandle).Decode(v)
+}
+
+//开启压缩之后，保存的数据的第一个字节标记是否压缩
+const (
+	flagPlain byte = iota
+	flagGzip
+)
+
+// ObjectStore stores objects in redis with a codec, an optional compression and a key namespace.
+type ObjectStore struct {
+	pool          *Pool
+	codec         Codec
+	prefix        string
+	compressAbove int
+	mapper        *convert.Mapper
+}
+
+// StoreOption configures an ObjectStore.
+type StoreOption func(s *ObjectStore)
+
+//StoreCodec 对象的编码方式，默认JSONCodec
+func StoreCodec(c Codec) StoreOption {
+	return func(s *ObjectStore) {
+		s.codec = c
+	}
+}
+
+//StoreNamespace key的命名空间，所有的key加上<namespace>:前缀，一般为服务名
+func StoreNamespace(namespace string) StoreOption {
+	return func(s *ObjectStore) {
+		s.prefix = namespace + ":"
+	}
+}
+
+//StoreCompression 编码之后超过threshold字节的数据使用gzip压缩；开启之后的数据带有压缩标记，同一个命名空间需要使用相同的设置
+func StoreCompression(threshold int) StoreOption {
+	return func(s *ObjectStore) {
+		s.compressAbove = threshold
+	}
+}
+
+//NewObjectStore 创建对象存储，默认使用JSON编码、不压缩、没有命名空间
+func NewObjectStore(pool *Pool, opts ...StoreOption) *ObjectStore {
+	s := &ObjectStore{
+		pool:  pool,
+		codec: JSONCodec,
+		mapper: convert.NewMapper(convert.MapperConfig{
+			TagNames:    []string{"redis", "json"},
+			WeaklyTyped: true,
+			Hooks:       []convert.DecodeHook{decodeHashField},
+		}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+//Key 加上命名空间前缀的key
+func (s *ObjectStore) Key(key string) string {
+	return s.prefix + key
+}
+
+//Delete 删除命名空间中的key，返回删除的个数
+func (s *ObjectStore) Delete(keys ...string) (int, error) {
+	client, err := s.pool.Get()
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = s.Key(key)
+	}
+	return client.DeleteKey(args...)
+}
+
+//encode 编码对象，开启压缩时加上压缩标记
+func (s *ObjectStore) encode(v interface{}) ([]byte, error) {
+	data, err := s.codec.Marshal(v)
+	if err != nil || s.compressAbove <= 0 {
+		return data, err
+	}
+	if len(data) <= s.compressAbove {
+		return append([]byte{flagPlain}, data...), nil
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(flagGzip)
+	w := gzip.NewWriter(&buf)
+	if _, err = w.Write(data); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *ObjectStore) decode(data []byte, v interface{}) error {
+	if s.compressAbove > 0 {
+		if len(data) == 0 {
+			return fmt.Errorf("redis: missing compression flag")
+		}
+		switch data[0] {
+		case flagPlain:
+			data = data[1:]
+		case flagGzip:
+			r, err := gzip.NewReader(bytes.NewReader(data[1:]))
+			if err != nil {
+				return err
+			}
+			if data, err = io.ReadAll(r); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("redis: unknown compression flag %d", data[0])
+		}
+	}
+	return s.codec.Unmarshal(data, v)
+}
+
+/**
+ * 编码并保存对象
+ * @param : s 对象存储
+ * @param : key 不带命名空间的key
+ * @param : value 要保存的对象
+ * @param : expiration 过期时间，0表示不过期
+ * @return:
+ */
+func SetObject[T any](s *ObjectStore, key string, value T, expiration time.Duration) error {
+	data, err := s.encode(value)
+	if err != nil {
+		return err
+	}
+	client, err := s.pool.Get()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	var opts []SetOption
+	if expiration > 0 {
+		opts = append(opts, ExpireAfter(expiration))
+	}
+	_, err = client.SetWithOptions(s.Key(key), data, opts...)
+	return err
+}
+
+//GetObject 读取并解码对象，key不存在时返回ErrNil
+func GetObject[T any](s *ObjectStore, key string) (value T, err error) {
+	client, err := s.pool.Get()
+	if err != nil {
+		return value, err
+	}
+	defer client.Close()
+	data, err := client.GetBytes(s.Key(key))
+	if err != nil {
+		return value, err
+	}
+	err = s.decode(data, &value)
+	return value, err
+}
+
+/**
+ * 将结构体保存为hash，结构体的每个字段为hash的一个field，字段名取redis或json标签，覆盖原有的hash；
+ * 数字、字符串、bool保存为字符串，time.Time保存为RFC3339格式，嵌套的结构体、slice、map保存为JSON，nil字段不保存
+ * @param : s 对象存储
+ * @param : key 不带命名空间的key
+ * @param : value 结构体或结构体指针
+ * @return:
+ */
+func HSetStruct[T any](s *ObjectStore, key string, value T) error {
+	fields, err := s.mapper.Encode(value)
+	if err != nil {
+		return err
+	}
+	args := redis.Args{s.Key(key)}
+	for name, field := range fields {
+		if field == nil {
+			continue
+		}
+		encoded, err := encodeHashField(field)
+		if err != nil {
+			return fmt.Errorf("redis: encode field %s: %v", name, err)
+		}
+		args = args.Add(name, encoded)
+	}
+	client, err := s.pool.Get()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	tx := client.TxPipeline()
+	tx.Do("DEL", s.Key(key))
+	if len(args) > 1 {
+		tx.Do("HMSET", args...)
+	}
+	_, err = tx.Exec()
+	return err
+}
+
+//HGetStruct 读取hash并转换为结构体，字符串按照字段的类型转换，key不存在时返回ErrNil
+func HGetStruct[T any](s *ObjectStore, key string) (value T, err error) {
+	client, err := s.pool.Get()
+	if err != nil {
+		return value, err
+	}
+	defer client.Close()
+	fields, err := client.HGetAll(s.Key(key))
+	if err != nil {
+		return value, err
+	}
+	if len(fields) == 0 {
+		return value, ErrNil
+	}
+	data := make(map[string]interface{}, len(fields))
+	for name, field := range fields {
+		data[name] = field
+	}
+	err = s.mapper.Decode(data, &value)
+	return value, err
+}
+
+//encodeHashField 将convert.Mapper编码的字段值转换为hash的字符串
+func encodeHashField(field interface{}) (interface{}, error) {
+	switch v := field.(type) {
+	case string, []byte, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	}
+	data, err := json.Marshal(field)
+	return string(data), err
+}
+
+//decodeHashField 将hash中保存为JSON的字段解码为结构体、slice或者map
+func decodeHashField(from, to reflect.Type, data interface{}) (interface{}, error) {
+	text, ok := data.(string)
+	if !ok || from == nil || from.Kind() != reflect.String {
+		return data, nil
+	}
+	for to.Kind() == reflect.Ptr {
+		to = to.Elem()
+	}
+	switch {
+	case to.Kind() == reflect.Slice && to.Elem().Kind() == reflect.Uint8:
+		return []byte(text), nil
+	case to.Kind() == reflect.Struct && to != reflect.TypeOf(time.Time{}),
+		to.Kind() == reflect.Slice, to.Kind() == reflect.Array, to.Kind() == reflect.Map:
+		result := reflect.New(to)
+		if err := json.Unmarshal([]byte(text), result.Interface()); err != nil {
+			return nil, err
+		}
+		return result.Elem().Interface(), nil
+	}
+	return data, nil
+}
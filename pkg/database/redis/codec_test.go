@@ -0,0 +1,166 @@
+package redis
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+type profile struct {
+	Name     string
+	Age      int
+	Tags     []string
+	Scores   map[string]float64
+	Birthday time.Time
+	Address  *address
+}
+
+type address struct {
+	City   string
+	Street string
+}
+
+func TestObjectStore_SetObject(t *testing.T) {
+	user := profile{
+		Name:     "tom",
+		Age:      18,
+		Tags:     []string{"vip", "new"},
+		Scores:   map[string]float64{"math": 90.5},
+		Birthday: time.Date(2002, 3, 4, 5, 6, 7, 0, time.UTC),
+		Address:  &address{City: "chengdu", Street: strings.Repeat("tianfu ", 20)},
+	}
+	codecs := map[string]Codec{"json": JSONCodec, "gob": GobCodec, "msgpack": MsgpackCodec}
+	for name, codec := range codecs {
+		for _, threshold := range []int{0, 64} {
+			mr := miniredis.RunT(t)
+			store := NewObjectStore(newPool(t, mr), StoreCodec(codec), StoreNamespace("user-service"), StoreCompression(threshold))
+			if err := SetObject(store, "user:1", user, time.Minute); err != nil {
+				t.Fatalf("%s/%d SetObject: %v", name, threshold, err)
+			}
+			if !mr.Exists("user-service:user:1") || mr.TTL("user-service:user:1") != time.Minute {
+				t.Fatalf("%s/%d the key should be in the namespace with the expiration", name, threshold)
+			}
+			got, err := GetObject[profile](store, "user:1")
+			if err != nil || !reflect.DeepEqual(got, user) {
+				t.Fatalf("%s/%d GetObject: %+v, %v", name, threshold, got, err)
+			}
+			raw, _ := mr.Get("user-service:user:1")
+			if threshold > 0 && raw[0] != flagGzip {
+				t.Fatalf("%s/%d the large value should be compressed", name, threshold)
+			}
+
+			if err = SetObject(store, "ids", []int64{1, 2, 3}, 0); err != nil {
+				t.Fatal(err)
+			}
+			ids, err := GetObject[[]int64](store, "ids")
+			if err != nil || !reflect.DeepEqual(ids, []int64{1, 2, 3}) {
+				t.Fatalf("%s/%d GetObject of a slice: %v, %v", name, threshold, ids, err)
+			}
+			if raw, _ = mr.Get("user-service:ids"); threshold > 0 && raw[0] != flagPlain {
+				t.Fatalf("%s/%d the small value should not be compressed", name, threshold)
+			}
+			if mr.TTL("user-service:ids") != 0 {
+				t.Fatal("the object should not expire without an expiration")
+			}
+		}
+	}
+}
+
+func TestObjectStore_GetObject(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := NewObjectStore(newPool(t, mr), StoreNamespace("svc"))
+	if _, err := GetObject[profile](store, "missing"); err != ErrNil {
+		t.Fatalf("GetObject of a missing key should return ErrNil, got %v", err)
+	}
+	_ = mr.Set("svc:broken", "{")
+	if _, err := GetObject[profile](store, "broken"); err == nil {
+		t.Fatal("GetObject of an invalid value should fail")
+	}
+	_ = SetObject(store, "a", 1, 0)
+	_ = SetObject(store, "b", 2, 0)
+	if n, err := store.Delete("a", "b", "c"); err != nil || n != 2 {
+		t.Fatalf("Delete: %d, %v", n, err)
+	}
+	compressed := NewObjectStore(newPool(t, mr), StoreNamespace("svc"), StoreCompression(10))
+	_ = SetObject(store, "plain", "value", 0)
+	if _, err := GetObject[string](compressed, "plain"); err == nil {
+		t.Fatal("a value without the compression flag should fail")
+	}
+}
+
+type member struct {
+	ID        int64             `redis:"id"`
+	Name      string            `json:"name"`
+	VIP       bool              `redis:"vip"`
+	Balance   float64           `redis:"balance"`
+	JoinedAt  time.Time         `redis:"joined_at"`
+	Nickname  *string           `redis:"nickname"`
+	Address   address           `redis:"address"`
+	Roles     []string          `redis:"roles"`
+	Labels    map[string]string `redis:"labels"`
+	Avatar    []byte            `redis:"avatar"`
+	Password  string            `redis:"-"`
+	UpdatedAt *time.Time        `redis:"updated_at,omitempty"`
+}
+
+func TestObjectStore_HSetStruct(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := NewObjectStore(newPool(t, mr), StoreNamespace("svc"))
+	nickname := "tommy"
+	m := member{
+		ID:       1,
+		Name:     "tom",
+		VIP:      true,
+		Balance:  10.25,
+		JoinedAt: time.Date(2020, 8, 3, 16, 0, 0, 123, time.UTC),
+		Nickname: &nickname,
+		Address:  address{City: "chengdu"},
+		Roles:    []string{"admin"},
+		Labels:   map[string]string{"level": "3"},
+		Avatar:   []byte{1, 2, 3},
+		Password: "secret",
+	}
+	if err := HSetStruct(store, "member:1", &m); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"id": "1", "name": "tom", "vip": "true", "balance": "10.25", "joined_at": "2020-08-03T16:00:00.000000123Z",
+		"nickname": "tommy", "address": `{"City":"chengdu","Street":""}`, "roles": `["admin"]`, "labels": `{"level":"3"}`,
+		"avatar": "\x01\x02\x03",
+	}
+	for field, value := range expected {
+		if got := mr.HGet("svc:member:1", field); got != value {
+			t.Fatalf("field %s: %q", field, got)
+		}
+	}
+	if keys, _ := mr.HKeys("svc:member:1"); len(keys) != len(expected) {
+		t.Fatalf("unexpected fields %v", keys)
+	}
+	got, err := HGetStruct[member](store, "member:1")
+	m.Password = ""
+	if err != nil || !reflect.DeepEqual(got, m) {
+		t.Fatalf("HGetStruct: %+v, %v", got, err)
+	}
+
+	//重新保存时覆盖原有的字段
+	m.Nickname, m.Roles = nil, nil
+	if err = HSetStruct(store, "member:1", m); err != nil {
+		t.Fatal(err)
+	}
+	if mr.HGet("svc:member:1", "nickname") != "" || mr.HGet("svc:member:1", "roles") != "" {
+		t.Fatal("the nil fields should be removed")
+	}
+	if got, _ = HGetStruct[member](store, "member:1"); !reflect.DeepEqual(got, m) {
+		t.Fatalf("HGetStruct after overwrite: %+v", got)
+	}
+	if _, err = HGetStruct[member](store, "missing"); err != ErrNil {
+		t.Fatalf("HGetStruct of a missing key should return ErrNil, got %v", err)
+	}
+	mr.HSet("svc:member:2", "id", "abc")
+	if _, err = HGetStruct[member](store, "member:2"); err == nil {
+		t.Fatal("HGetStruct should return the conversion error")
+	}
+}
@@ -0,0 +1,93 @@
+/**
+ * redis geo的操作，位置保存在sorted set中
+ */
+package redis
+
+import (
+	"github.com/gomodule/redigo/redis"
+)
+
+//距离的单位
+const (
+	GeoMeters     = "m"
+	GeoKilometers = "km"
+	GeoMiles      = "mi"
+	GeoFeet       = "ft"
+)
+
+// GeoLocation is a named position in a geospatial index.
+type GeoLocation struct {
+	Name      string
+	Longitude float64
+	Latitude  float64
+	// Dist is the distance to the center of GeoRadius in the unit of the query.
+	Dist float64
+}
+
+func (c Client) GeoAdd(key string, locations ...GeoLocation) (int, error) {
+	args := redis.Args{}.Add(key)
+	for _, l := range locations {
+		args = args.Add(l.Longitude, l.Latitude, l.Name)
+	}
+	return redis.Int(c.conn.Do("GEOADD", args...))
+}
+
+func (c Client) GeoPos(key string, members ...string) ([]*GeoLocation, error) {
+	positions, err := redis.Positions(c.conn.Do("GEOPOS", redis.Args{}.Add(key).AddFlat(members)...))
+	if err != nil {
+		return nil, err
+	}
+	locations := make([]*GeoLocation, len(positions))
+	for i, p := range positions {
+		if p != nil {
+			locations[i] = &GeoLocation{Name: members[i], Longitude: p[0], Latitude: p[1]}
+		}
+	}
+	return locations, nil
+}
+
+func (c Client) GeoDist(key, member1, member2, unit string) (float64, error) {
+	return redis.Float64(c.conn.Do("GEODIST", key, member1, member2, unit))
+}
+
+func (c Client) GeoRadius(key string, longitude, latitude, radius float64, unit string, count int) ([]GeoLocation, error) {
+	return geoLocations(c.conn.Do("GEORADIUS", geoRadiusArgs(redis.Args{}.Add(key, longitude, latitude), radius, unit, count)...))
+}
+
+func (c Client) GeoRadiusByMember(key, member string, radius float64, unit string, count int) ([]GeoLocation, error) {
+	return geoLocations(c.conn.Do("GEORADIUSBYMEMBER", geoRadiusArgs(redis.Args{}.Add(key, member), radius, unit, count)...))
+}
+
+func geoRadiusArgs(args redis.Args, radius float64, unit string, count int) redis.Args {
+	args = args.Add(radius, unit, "WITHCOORD", "WITHDIST")
+	if count > 0 {
+		args = args.Add("COUNT", count)
+	}
+	return args.Add("ASC")
+}
+
+//geoLocations 解析WITHCOORD WITHDIST的结果，每个位置的格式为：[name dist [longitude latitude]]
+func geoLocations(reply interface{}, err error) ([]GeoLocation, error) {
+	items, err := redis.Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	locations := make([]GeoLocation, len(items))
+	for i, item := range items {
+		var (
+			l     = &locations[i]
+			coord []interface{}
+		)
+		fields, err := redis.Values(item, nil)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = redis.Scan(fields, &l.Name, &l.Dist, &coord); err != nil {
+			return nil, err
+		}
+		if _, err = redis.Scan(coord, &l.Longitude, &l.Latitude); err != nil {
+			return nil, err
+		}
+	}
+	return locations, nil
+}
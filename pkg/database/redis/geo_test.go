@@ -0,0 +1,40 @@
+package redis
+
+import (
+	"math"
+	"testing"
+)
+
+func TestClient_GeoRadius(t *testing.T) {
+	client, _ := newClient(t)
+	n, err := client.GeoAdd("shops",
+		GeoLocation{Name: "tianfu", Longitude: 104.0657, Latitude: 30.6595},
+		GeoLocation{Name: "chunxi", Longitude: 104.0810, Latitude: 30.6570},
+		GeoLocation{Name: "beijing", Longitude: 116.4074, Latitude: 39.9042},
+	)
+	if err != nil || n != 3 {
+		t.Fatalf("GeoAdd: %d, %v", n, err)
+	}
+	positions, err := client.GeoPos("shops", "tianfu", "missing")
+	if err != nil || len(positions) != 2 || positions[1] != nil || math.Abs(positions[0].Longitude-104.0657) > 1e-4 {
+		t.Fatalf("GeoPos: %v, %v", positions, err)
+	}
+	dist, err := client.GeoDist("shops", "tianfu", "chunxi", GeoKilometers)
+	if err != nil || dist < 1 || dist > 2 {
+		t.Fatalf("GeoDist: %v, %v", dist, err)
+	}
+	if _, err = client.GeoDist("shops", "tianfu", "missing", GeoMeters); err != ErrNil {
+		t.Fatalf("GeoDist of a missing member should return ErrNil, got %v", err)
+	}
+	locations, err := client.GeoRadius("shops", 104.07, 30.66, 5, GeoKilometers, 0)
+	if err != nil || len(locations) != 2 || locations[0].Name != "tianfu" || locations[1].Name != "chunxi" {
+		t.Fatalf("GeoRadius: %+v, %v", locations, err)
+	}
+	if locations[0].Dist <= 0 || locations[0].Dist > locations[1].Dist || math.Abs(locations[1].Latitude-30.6570) > 1e-4 {
+		t.Fatalf("unexpected locations %+v", locations)
+	}
+	locations, err = client.GeoRadiusByMember("shops", "chunxi", 5000, GeoKilometers, 2)
+	if err != nil || len(locations) != 2 || locations[0].Name != "chunxi" || locations[0].Dist != 0 {
+		t.Fatalf("GeoRadiusByMember: %+v, %v", locations, err)
+	}
+}
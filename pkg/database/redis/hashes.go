@@ -0,0 +1,44 @@
+/**
+ * redis hash类型的操作
+ */
+package redis
+
+import (
+	"github.com/gomodule/redigo/redis"
+)
+
+func (c Client) HSetNX(key, field string, value interface{}) (bool, error) {
+	return redis.Bool(c.conn.Do("HSETNX", key, field, value))
+}
+
+func (c Client) HMGet(key string, fields ...string) ([]string, error) {
+	return redis.Strings(c.conn.Do("HMGET", redis.Args{}.Add(key).AddFlat(fields)...))
+}
+
+func (c Client) HDel(key string, fields ...string) (int, error) {
+	return redis.Int(c.conn.Do("HDEL", redis.Args{}.Add(key).AddFlat(fields)...))
+}
+
+func (c Client) HExists(key, field string) (bool, error) {
+	return redis.Bool(c.conn.Do("HEXISTS", key, field))
+}
+
+func (c Client) HIncrBy(key, field string, n int64) (int64, error) {
+	return redis.Int64(c.conn.Do("HINCRBY", key, field, n))
+}
+
+func (c Client) HIncrByFloat(key, field string, f float64) (float64, error) {
+	return redis.Float64(c.conn.Do("HINCRBYFLOAT", key, field, f))
+}
+
+func (c Client) HKeys(key string) ([]string, error) {
+	return redis.Strings(c.conn.Do("HKEYS", key))
+}
+
+func (c Client) HVals(key string) ([]string, error) {
+	return redis.Strings(c.conn.Do("HVALS", key))
+}
+
+func (c Client) HLen(key string) (int, error) {
+	return redis.Int(c.conn.Do("HLEN", key))
+}
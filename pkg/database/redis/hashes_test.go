@@ -0,0 +1,44 @@
+package redis
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestClient_HIncrBy(t *testing.T) {
+	client, _ := newClient(t)
+	_ = client.HSet("user:1", "name", "tom")
+	if ok, err := client.HSetNX("user:1", "name", "jerry"); err != nil || ok {
+		t.Fatalf("HSetNX on an existing field: %v, %v", ok, err)
+	}
+	if ok, _ := client.HSetNX("user:1", "age", 18); !ok {
+		t.Fatal("HSetNX on a missing field should succeed")
+	}
+	if n, err := client.HIncrBy("user:1", "age", 2); err != nil || n != 20 {
+		t.Fatalf("HIncrBy: %d, %v", n, err)
+	}
+	if f, _ := client.HIncrByFloat("user:1", "balance", 0.5); f != 0.5 {
+		t.Fatalf("HIncrByFloat: %v", f)
+	}
+	if values, _ := client.HMGet("user:1", "name", "missing", "age"); strings.Join(values, ",") != "tom,,20" {
+		t.Fatalf("HMGet: %q", values)
+	}
+	keys, _ := client.HKeys("user:1")
+	sort.Strings(keys)
+	if strings.Join(keys, ",") != "age,balance,name" {
+		t.Fatalf("HKeys: %v", keys)
+	}
+	if values, _ := client.HVals("user:1"); len(values) != 3 {
+		t.Fatalf("HVals: %v", values)
+	}
+	if n, _ := client.HDel("user:1", "balance", "missing"); n != 1 {
+		t.Fatalf("HDel: %d", n)
+	}
+	if exists, _ := client.HExists("user:1", "balance"); exists {
+		t.Fatal("balance should be deleted")
+	}
+	if n, _ := client.HLen("user:1"); n != 2 {
+		t.Fatalf("HLen: %d", n)
+	}
+}
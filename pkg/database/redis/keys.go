@@ -0,0 +1,53 @@
+/**
+ * redis key的通用操作：过期时间、类型、重命名
+ */
+package redis
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+//NoTTL key没有设置过期时间
+const NoTTL time.Duration = -1
+
+func (c Client) PExpire(key string, ttl time.Duration) (bool, error) {
+	return redis.Bool(c.conn.Do("PEXPIRE", key, ttl.Milliseconds()))
+}
+
+func (c Client) ExpireAt(key string, at time.Time) (bool, error) {
+	return redis.Bool(c.conn.Do("PEXPIREAT", key, at.UnixNano()/int64(time.Millisecond)))
+}
+
+func (c Client) Persist(key string) (bool, error) {
+	return redis.Bool(c.conn.Do("PERSIST", key))
+}
+
+//TTL 使用PTTL查询，-2表示key不存在，-1表示没有过期时间
+func (c Client) TTL(key string) (time.Duration, error) {
+	ttl, err := redis.Int64(c.conn.Do("PTTL", key))
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case ttl == -2:
+		return 0, ErrNil
+	case ttl < 0:
+		return NoTTL, nil
+	}
+	return time.Duration(ttl) * time.Millisecond, nil
+}
+
+func (c Client) Type(key string) (string, error) {
+	return redis.String(c.conn.Do("TYPE", key))
+}
+
+func (c Client) Rename(key, newKey string) error {
+	_, err := c.conn.Do("RENAME", key, newKey)
+	return err
+}
+
+func (c Client) RenameNX(key, newKey string) (bool, error) {
+	return redis.Bool(c.conn.Do("RENAMENX", key, newKey))
+}
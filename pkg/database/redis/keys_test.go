@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClient_TTL(t *testing.T) {
+	client, mr := newClient(t)
+	_ = client.Set("k", "v")
+	if ttl, err := client.TTL("k"); err != nil || ttl != NoTTL {
+		t.Fatalf("TTL without expiration: %v, %v", ttl, err)
+	}
+	if _, err := client.TTL("missing"); err != ErrNil {
+		t.Fatalf("TTL of a missing key should return ErrNil, got %v", err)
+	}
+	if ok, err := client.PExpire("k", 1500*time.Millisecond); err != nil || !ok {
+		t.Fatalf("PExpire: %v, %v", ok, err)
+	}
+	if ttl, _ := client.TTL("k"); ttl != 1500*time.Millisecond {
+		t.Fatalf("unexpected ttl %v", ttl)
+	}
+	if ok, _ := client.Persist("k"); !ok || mr.TTL("k") != 0 {
+		t.Fatal("Persist should remove the expiration")
+	}
+	if ok, err := client.ExpireAt("k", time.Now().Add(time.Hour)); err != nil || !ok {
+		t.Fatalf("ExpireAt: %v, %v", ok, err)
+	}
+	if ok, _ := client.PExpire("missing", time.Second); ok {
+		t.Fatal("expire a missing key should return false")
+	}
+	if err := client.Expire("k", 10); err != nil || mr.TTL("k") != 10*time.Second {
+		t.Fatalf("Expire: %v, %v", mr.TTL("k"), err)
+	}
+	mr.FastForward(11 * time.Second)
+	if exists, _ := client.Exists("k"); exists {
+		t.Fatal("key should be expired")
+	}
+}
+
+func TestClient_Rename(t *testing.T) {
+	client, _ := newClient(t)
+	_, _ = client.LPush("list", "a")
+	_ = client.Set("other", "v")
+	if typ, err := client.Type("list"); err != nil || typ != "list" {
+		t.Fatalf("Type: %s, %v", typ, err)
+	}
+	if typ, _ := client.Type("missing"); typ != "none" {
+		t.Fatalf("Type of a missing key: %s", typ)
+	}
+	if ok, err := client.RenameNX("list", "other"); err != nil || ok {
+		t.Fatalf("RenameNX should not overwrite: %v, %v", ok, err)
+	}
+	if err := client.Rename("list", "queue"); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := client.Exists("queue"); !exists {
+		t.Fatal("key should be renamed")
+	}
+}
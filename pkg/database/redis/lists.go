@@ -0,0 +1,83 @@
+/**
+ * redis list类型的操作，包括阻塞的BLPop、BRPop
+ */
+package redis
+
+import (
+	"math"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func (c Client) LPush(key string, values ...interface{}) (int, error) {
+	return redis.Int(c.conn.Do("LPUSH", redis.Args{}.Add(key).Add(values...)...))
+}
+
+func (c Client) RPush(key string, values ...interface{}) (int, error) {
+	return redis.Int(c.conn.Do("RPUSH", redis.Args{}.Add(key).Add(values...)...))
+}
+
+func (c Client) LPop(key string) (string, error) {
+	return redis.String(c.conn.Do("LPOP", key))
+}
+
+func (c Client) RPop(key string) (string, error) {
+	return redis.String(c.conn.Do("RPOP", key))
+}
+
+func (c Client) BLPop(timeout time.Duration, keys ...string) (key, value string, err error) {
+	return c.blockingPop("BLPOP", timeout, keys)
+}
+
+func (c Client) BRPop(timeout time.Duration, keys ...string) (key, value string, err error) {
+	return c.blockingPop("BRPOP", timeout, keys)
+}
+
+//blockingPop 超时时redis返回nil，redis.Strings返回ErrNil
+func (c Client) blockingPop(cmd string, timeout time.Duration, keys []string) (key, value string, err error) {
+	seconds := int64(math.Ceil(timeout.Seconds()))
+	values, err := redis.Strings(c.conn.Do(cmd, redis.Args{}.AddFlat(keys).Add(seconds)...))
+	if err != nil {
+		return "", "", err
+	}
+	return values[0], values[1], nil
+}
+
+func (c Client) RPopLPush(source, destination string) (string, error) {
+	return redis.String(c.conn.Do("RPOPLPUSH", source, destination))
+}
+
+func (c Client) LLen(key string) (int, error) {
+	return redis.Int(c.conn.Do("LLEN", key))
+}
+
+func (c Client) LRange(key string, start, stop int) ([]string, error) {
+	return redis.Strings(c.conn.Do("LRANGE", key, start, stop))
+}
+
+func (c Client) LIndex(key string, index int) (string, error) {
+	return redis.String(c.conn.Do("LINDEX", key, index))
+}
+
+func (c Client) LSet(key string, index int, value interface{}) error {
+	_, err := c.conn.Do("LSET", key, index, value)
+	return err
+}
+
+func (c Client) LInsertBefore(key string, pivot, value interface{}) (int, error) {
+	return redis.Int(c.conn.Do("LINSERT", key, "BEFORE", pivot, value))
+}
+
+func (c Client) LInsertAfter(key string, pivot, value interface{}) (int, error) {
+	return redis.Int(c.conn.Do("LINSERT", key, "AFTER", pivot, value))
+}
+
+func (c Client) LRem(key string, count int, value interface{}) (int, error) {
+	return redis.Int(c.conn.Do("LREM", key, count, value))
+}
+
+func (c Client) LTrim(key string, start, stop int) error {
+	_, err := c.conn.Do("LTRIM", key, start, stop)
+	return err
+}
@@ -0,0 +1,68 @@
+package redis
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_LPush(t *testing.T) {
+	client, _ := newClient(t)
+	if n, err := client.RPush("list", "b", "c"); err != nil || n != 2 {
+		t.Fatalf("RPush: %d, %v", n, err)
+	}
+	if n, _ := client.LPush("list", "a"); n != 3 {
+		t.Fatalf("LPush: %d", n)
+	}
+	if n, _ := client.LInsertAfter("list", "c", "d"); n != 4 {
+		t.Fatalf("LInsertAfter: %d", n)
+	}
+	if n, _ := client.LInsertBefore("list", "missing", "x"); n != -1 {
+		t.Fatalf("LInsertBefore a missing pivot: %d", n)
+	}
+	if err := client.LSet("list", 1, "B"); err != nil {
+		t.Fatal(err)
+	}
+	if values, _ := client.LRange("list", 0, -1); strings.Join(values, ",") != "a,B,c,d" {
+		t.Fatalf("LRange: %v", values)
+	}
+	if value, _ := client.LIndex("list", -1); value != "d" {
+		t.Fatalf("LIndex: %s", value)
+	}
+	if _, err := client.LIndex("list", 10); err != ErrNil {
+		t.Fatalf("LIndex out of range should return ErrNil, got %v", err)
+	}
+	_, _ = client.RPush("list", "a")
+	if n, _ := client.LRem("list", 0, "a"); n != 2 {
+		t.Fatalf("LRem: %d", n)
+	}
+	if err := client.LTrim("list", 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := client.LLen("list"); n != 2 {
+		t.Fatalf("LLen: %d", n)
+	}
+	if value, _ := client.LPop("list"); value != "B" {
+		t.Fatalf("LPop: %s", value)
+	}
+	if value, _ := client.RPopLPush("list", "done"); value != "c" {
+		t.Fatalf("RPopLPush: %s", value)
+	}
+	if _, err := client.RPop("list"); err != ErrNil {
+		t.Fatalf("RPop of an empty list should return ErrNil, got %v", err)
+	}
+}
+
+func TestClient_BLPop(t *testing.T) {
+	client, _ := newClient(t)
+	_, _ = client.RPush("jobs:high", "j1", "j2")
+	if key, value, err := client.BLPop(time.Second, "jobs:low", "jobs:high"); err != nil || key != "jobs:high" || value != "j1" {
+		t.Fatalf("BLPop: %s %s %v", key, value, err)
+	}
+	if key, value, err := client.BRPop(time.Second, "jobs:high"); err != nil || key != "jobs:high" || value != "j2" {
+		t.Fatalf("BRPop: %s %s %v", key, value, err)
+	}
+	if _, _, err := client.BLPop(100*time.Millisecond, "jobs:high"); err != ErrNil {
+		t.Fatalf("BLPop timeout should return ErrNil, got %v", err)
+	}
+}
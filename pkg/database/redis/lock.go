@@ -0,0 +1,312 @@
+/**
+ * 基于redis的分布式锁：
+ *   1. 加锁使用SET key token NX PX lease，token为随机值，只有持有者才能续期以及释放
+ *   2. 释放以及续期使用lua脚本先比较token再删除/续期，不会误删其它持有者的锁
+ *   3. 持有锁期间看门狗每lease/3续期一次，持有者异常退出后锁在lease后自动过期
+ *   4. 传入多个相互独立的Pool时使用Redlock算法，超过半数节点加锁成功才算成功，各节点并发访问，
+ *      每个节点的命令超时时间为lease/10（最少50毫秒），单个节点无响应不会拖住加锁、续期以及释放
+ */
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/common/log"
+
+	"github.com/yinjk/go-utils/pkg/utils/times"
+)
+
+const (
+	defaultLockLease = 30 * time.Second
+	defaultLockRetry = 100 * time.Millisecond
+	//minLockLease 最小租期，PX以毫秒为单位，租期还需要大于加锁耗时以及时钟漂移
+	minLockLease = 10 * time.Millisecond
+	//minNodeTimeout 单个节点命令的最小超时时间，节点响应慢导致剩余租期不足时TryLock会失败，不会误判为加锁成功
+	minNodeTimeout = 50 * time.Millisecond
+)
+
+var (
+	//ErrLockNotObtained 锁已经被其它持有者持有
+	ErrLockNotObtained = errors.New("redis: lock not obtained")
+	//ErrLockNotHeld 释放的锁已经过期或者被其它持有者持有
+	ErrLockNotHeld = errors.New("redis: lock not held")
+)
+
+var (
+	releaseScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+	renewScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+// Lock is a distributed lock on one or several independent redis instances.
+// A Lock is held by one goroutine at a time and can be locked again after Unlock.
+type Lock struct {
+	pools    []*Pool
+	key      string
+	lease    time.Duration
+	retry    time.Duration
+	watchdog bool
+	clock    times.Clock
+
+	mu    sync.Mutex
+	token string
+	stop  chan struct{}
+	lost  chan struct{}
+	done  sync.WaitGroup
+}
+
+// LockOption configures a Lock.
+type LockOption func(l *Lock)
+
+//LockLease 锁的租期，默认30秒，最小10毫秒，看门狗每lease/3续期一次
+func LockLease(lease time.Duration) LockOption {
+	return func(l *Lock) {
+		l.lease = lease
+	}
+}
+
+//LockRetryInterval Lock获取锁失败后重试的间隔，默认100毫秒，小于等于0时使用默认值
+func LockRetryInterval(interval time.Duration) LockOption {
+	return func(l *Lock) {
+		l.retry = interval
+	}
+}
+
+//LockWithoutWatchdog 不自动续期，锁在租期后过期，适用于执行时间确定的任务
+func LockWithoutWatchdog() LockOption {
+	return func(l *Lock) {
+		l.watchdog = false
+	}
+}
+
+//LockClock 看门狗以及重试使用的时钟，默认为times.SystemClock，测试中可以使用times.FakeClock
+func LockClock(clock times.Clock) LockOption {
+	return func(l *Lock) {
+		l.clock = clock
+	}
+}
+
+//NewLock 创建单个redis上的分布式锁
+func NewLock(pool *Pool, key string, opts ...LockOption) *Lock {
+	return NewRedLock([]*Pool{pool}, key, opts...)
+}
+
+/**
+ * 创建Redlock，pools为相互独立的redis（不是同一个集群的主从），超过半数加锁成功并且剩余租期大于0时获取锁成功
+ * @param : pools 独立的redis连接池，建议使用奇数个
+ * @param : key 锁的key
+ * @param : opts 租期、重试间隔、时钟以及是否自动续期，租期小于10毫秒时使用10毫秒
+ * @return: 分布式锁
+ */
+func NewRedLock(pools []*Pool, key string, opts ...LockOption) *Lock {
+	l := &Lock{pools: pools, key: key, lease: defaultLockLease, retry: defaultLockRetry, watchdog: true}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.lease < minLockLease {
+		log.Warnf("redis: lock %s lease %v is too short, use %v", key, l.lease, minLockLease)
+		l.lease = minLockLease
+	}
+	if l.retry <= 0 {
+		l.retry = defaultLockRetry
+	}
+	l.clock = times.ClockOrDefault(l.clock)
+	return l
+}
+
+//Key 锁的key
+func (l *Lock) Key() string {
+	return l.key
+}
+
+//quorum 获取锁需要成功的节点数
+func (l *Lock) quorum() int {
+	return len(l.pools)/2 + 1
+}
+
+/**
+ * 尝试获取锁，不等待
+ * @return: ok 是否获取成功，锁被其它持有者持有时返回false, nil
+ * @return: err 超过半数节点无法访问时返回错误
+ */
+func (l *Lock) TryLock() (ok bool, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.token != "" {
+		return false, fmt.Errorf("redis: lock %s is already held by this Lock", l.key)
+	}
+	token, err := newLockToken()
+	if err != nil {
+		return false, err
+	}
+	start := l.clock.Now()
+	acquired, failed, lastErr := l.each(func(conn redis.Conn) (bool, error) {
+		reply, err := redis.String(conn.Do("SET", l.key, token, "NX", "PX", l.lease.Milliseconds()))
+		if err == ErrNil {
+			return false, nil
+		}
+		return err == nil && reply == "OK", err
+	})
+	//扣除加锁耗时以及各节点的时钟漂移后，剩余的租期仍然有效才算加锁成功
+	drift := l.lease/100 + 2*time.Millisecond
+	if acquired >= l.quorum() && l.lease-l.clock.Since(start)-drift > 0 {
+		l.token, l.stop, l.lost = token, make(chan struct{}), make(chan struct{})
+		if l.watchdog {
+			l.done.Add(1)
+			go l.renew(token, l.stop, l.lost)
+		}
+		return true, nil
+	}
+	l.release(token)
+	if failed > len(l.pools)-l.quorum() {
+		return false, lastErr
+	}
+	return false, nil
+}
+
+//Lock 获取锁，锁被其它持有者持有时每隔重试间隔重试一次，直到获取成功、出错或者ctx结束
+func (l *Lock) Lock(ctx context.Context) error {
+	timer := l.clock.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %v", ErrLockNotObtained, ctx.Err())
+		case <-timer.C():
+		}
+		ok, err := l.TryLock()
+		if err != nil || ok {
+			return err
+		}
+		timer.Reset(l.retry)
+	}
+}
+
+//Unlock 停止看门狗并释放锁，锁已经过期或者被其它持有者持有时返回ErrLockNotHeld
+func (l *Lock) Unlock() error {
+	l.mu.Lock()
+	token, stop := l.token, l.stop
+	l.token = ""
+	l.mu.Unlock()
+	if token == "" {
+		return ErrLockNotHeld
+	}
+	close(stop)
+	l.done.Wait()
+	released, failed, lastErr := l.release(token)
+	if released >= l.quorum() {
+		return nil
+	}
+	if failed > len(l.pools)-l.quorum() {
+		return lastErr
+	}
+	return ErrLockNotHeld
+}
+
+//Lost 锁丢失（续期失败）时关闭的channel，持有者应当停止受锁保护的工作；未持有锁时返回nil
+func (l *Lock) Lost() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.token == "" {
+		return nil
+	}
+	return l.lost
+}
+
+//release 删除所有节点上token对应的锁
+func (l *Lock) release(token string) (released, failed int, lastErr error) {
+	return l.each(func(conn redis.Conn) (bool, error) {
+		n, err := redis.Int(releaseScript.Do(conn, l.key, token))
+		return n == 1, err
+	})
+}
+
+//renew 看门狗，每lease/3续期一次，超过半数节点续期失败时认为锁已丢失并关闭lost
+func (l *Lock) renew(token string, stop, lost chan struct{}) {
+	defer l.done.Done()
+	ticker := l.clock.NewTicker(l.lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C():
+		}
+		renewed, _, err := l.each(func(conn redis.Conn) (bool, error) {
+			n, err := redis.Int(renewScript.Do(conn, l.key, token, l.lease.Milliseconds()))
+			return n == 1, err
+		})
+		if renewed < l.quorum() {
+			log.Errorf("redis: lock %s lost, renewed on %d of %d instances, last error: %v",
+				l.key, renewed, len(l.pools), err)
+			close(lost)
+			return
+		}
+	}
+}
+
+//each 在所有节点上并发执行fn，每个节点的命令超时时间为lease/10（最少50毫秒），返回成功以及出错的节点数
+func (l *Lock) each(fn func(conn redis.Conn) (bool, error)) (succeeded, failed int, lastErr error) {
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	timeout := l.lease / 10
+	if timeout < minNodeTimeout {
+		timeout = minNodeTimeout
+	}
+	for _, pool := range l.pools {
+		wg.Add(1)
+		go func(pool *Pool) {
+			defer wg.Done()
+			conn := pool.pool.Get()
+			ok, err := fn(timeoutConn{Conn: conn, timeout: timeout})
+			_ = conn.Close()
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed++
+				lastErr = err
+			} else if ok {
+				succeeded++
+			}
+		}(pool)
+	}
+	wg.Wait()
+	return succeeded, failed, lastErr
+}
+
+//timeoutConn 每条命令都带有读超时的连接，连接不支持超时时使用连接本身的超时设置
+type timeoutConn struct {
+	redis.Conn
+	timeout time.Duration
+}
+
+func (c timeoutConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	if _, ok := c.Conn.(redis.ConnWithTimeout); !ok {
+		return c.Conn.Do(commandName, args...)
+	}
+	return redis.DoWithTimeout(c.Conn, c.timeout, commandName, args...)
+}
+
+func newLockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
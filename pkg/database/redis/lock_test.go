@@ -0,0 +1,239 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/yinjk/go-utils/pkg/utils/times"
+)
+
+func newPool(t *testing.T, mr *miniredis.Miniredis) *Pool {
+	pool := GetRedisPool(&Config{Addr: mr.Addr(), MaxIdle: 2})
+	t.Cleanup(func() { _ = pool.Close() })
+	return pool
+}
+
+func TestLock_TryLock(t *testing.T) {
+	mr := miniredis.RunT(t)
+	pool := newPool(t, mr)
+	first := NewLock(pool, "lock:job", LockLease(10*time.Second))
+	second := NewLock(pool, "lock:job")
+	if ok, err := first.TryLock(); err != nil || !ok {
+		t.Fatalf("TryLock: %v, %v", ok, err)
+	}
+	if mr.TTL("lock:job") != 10*time.Second {
+		t.Fatalf("unexpected lease %v", mr.TTL("lock:job"))
+	}
+	if ok, err := second.TryLock(); err != nil || ok {
+		t.Fatalf("TryLock on a held lock: %v, %v", ok, err)
+	}
+	if _, err := first.TryLock(); err == nil {
+		t.Fatal("TryLock on a lock held by itself should fail")
+	}
+	if err := first.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists("lock:job") {
+		t.Fatal("Unlock should delete the key")
+	}
+	if err := first.Unlock(); err != ErrLockNotHeld {
+		t.Fatalf("Unlock twice should return ErrLockNotHeld, got %v", err)
+	}
+	if ok, _ := second.TryLock(); !ok {
+		t.Fatal("the released lock should be obtained")
+	}
+	_ = second.Unlock()
+}
+
+func TestLock_LeaseExpired(t *testing.T) {
+	mr := miniredis.RunT(t)
+	pool := newPool(t, mr)
+	first := NewLock(pool, "lock:job", LockLease(time.Second), LockWithoutWatchdog())
+	second := NewLock(pool, "lock:job", LockLease(time.Minute))
+	if ok, _ := first.TryLock(); !ok {
+		t.Fatal("TryLock should succeed")
+	}
+	mr.FastForward(2 * time.Second)
+	if ok, _ := second.TryLock(); !ok {
+		t.Fatal("the expired lock should be obtained")
+	}
+	if err := first.Unlock(); err != ErrLockNotHeld {
+		t.Fatalf("Unlock an expired lock should return ErrLockNotHeld, got %v", err)
+	}
+	if !mr.Exists("lock:job") || mr.TTL("lock:job") != time.Minute {
+		t.Fatal("Unlock an expired lock should not delete the lock of another holder")
+	}
+	if err := second.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLock_Watchdog(t *testing.T) {
+	mr := miniredis.RunT(t)
+	pool := newPool(t, mr)
+	clock := times.NewFakeClock(time.Time{})
+	lock := NewLock(pool, "lock:job", LockLease(300*time.Millisecond), LockClock(clock))
+	if ok, _ := lock.TryLock(); !ok {
+		t.Fatal("TryLock should succeed")
+	}
+	clock.BlockUntil(1) //等待看门狗注册ticker
+	mr.FastForward(250 * time.Millisecond)
+	clock.Advance(100 * time.Millisecond)
+	waitFor(t, "the watchdog to renew the lease", func() bool {
+		return mr.TTL("lock:job") > 100*time.Millisecond
+	})
+	select {
+	case <-lock.Lost():
+		t.Fatal("the lock should not be lost")
+	default:
+	}
+
+	//锁过期后被其它持有者获取，看门狗续期失败
+	mr.Set("lock:job", "another")
+	clock.Advance(100 * time.Millisecond)
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("Lost should be closed when the renewal fails")
+	}
+	if err := lock.Unlock(); err != ErrLockNotHeld {
+		t.Fatalf("Unlock a lost lock should return ErrLockNotHeld, got %v", err)
+	}
+	if value, _ := mr.Get("lock:job"); value != "another" {
+		t.Fatalf("the lock of another holder should be kept, got %s", value)
+	}
+}
+
+func TestLock_Lock(t *testing.T) {
+	mr := miniredis.RunT(t)
+	pool := newPool(t, mr)
+	holder := NewLock(pool, "lock:job")
+	if ok, _ := holder.TryLock(); !ok {
+		t.Fatal("TryLock should succeed")
+	}
+	clock := times.NewFakeClock(time.Time{})
+	waiter := NewLock(pool, "lock:job", LockRetryInterval(10*time.Millisecond), LockClock(clock))
+	lock := func(ctx context.Context) chan error {
+		result := make(chan error, 1)
+		go func() { result <- waiter.Lock(ctx) }()
+		return result
+	}
+	receive := func(result chan error) error {
+		select {
+		case err := <-result:
+			return err
+		case <-time.After(time.Second):
+			t.Fatal("Lock should return")
+			return nil
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := lock(ctx)
+	clock.BlockUntil(1) //第一次获取失败，等待重试
+	cancel()
+	if err := receive(result); !errors.Is(err, ErrLockNotObtained) {
+		t.Fatalf("Lock should fail after ctx is done, got %v", err)
+	}
+
+	result = lock(context.Background())
+	clock.BlockUntil(1)
+	_ = holder.Unlock()
+	clock.Advance(10 * time.Millisecond)
+	if err := receive(result); err != nil {
+		t.Fatal(err)
+	}
+	_ = waiter.Unlock()
+}
+
+func TestRedLock(t *testing.T) {
+	servers := []*miniredis.Miniredis{miniredis.RunT(t), miniredis.RunT(t), miniredis.RunT(t)}
+	pools := make([]*Pool, len(servers))
+	for i, mr := range servers {
+		pools[i] = newPool(t, mr)
+	}
+
+	//其它持有者持有了多数节点上的锁
+	servers[1].Set("lock:job", "another")
+	servers[2].Set("lock:job", "another")
+	lock := NewRedLock(pools, "lock:job")
+	if ok, err := lock.TryLock(); err != nil || ok {
+		t.Fatalf("TryLock without the quorum: %v, %v", ok, err)
+	}
+	if servers[0].Exists("lock:job") {
+		t.Fatal("the minority locks should be released")
+	}
+	servers[1].Del("lock:job")
+
+	//一个节点不可用时仍然满足多数
+	servers[2].Close()
+	if ok, err := lock.TryLock(); err != nil || !ok {
+		t.Fatalf("TryLock with the quorum: %v, %v", ok, err)
+	}
+	if !servers[0].Exists("lock:job") || !servers[1].Exists("lock:job") {
+		t.Fatal("the lock should be set on the available instances")
+	}
+	if err := lock.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	servers[1].Close()
+	if _, err := lock.TryLock(); err == nil {
+		t.Fatal("TryLock should fail when most instances are unavailable")
+	}
+}
+
+func TestRedLock_UnresponsiveNode(t *testing.T) {
+	//接受连接但是从不响应的节点
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() { _, _ = io.Copy(io.Discard, conn) }()
+		}
+	}()
+	hung := GetRedisPool(&Config{Addr: listener.Addr().String(), MaxIdle: 2})
+	t.Cleanup(func() { _ = hung.Close() })
+	pools := []*Pool{newPool(t, miniredis.RunT(t)), newPool(t, miniredis.RunT(t)), hung}
+
+	lock := NewRedLock(pools, "lock:job", LockLease(time.Second), LockWithoutWatchdog())
+	start := time.Now()
+	if ok, err := lock.TryLock(); err != nil || !ok {
+		t.Fatalf("TryLock with the quorum: %v, %v", ok, err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("unresponsive node should time out, TryLock took %v", elapsed)
+	}
+	if err := lock.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLock_ShortLease(t *testing.T) {
+	mr := miniredis.RunT(t)
+	lock := NewLock(newPool(t, mr), "lock:job", LockLease(time.Nanosecond), LockRetryInterval(0))
+	if ok, err := lock.TryLock(); err != nil || !ok {
+		t.Fatalf("TryLock: %v, %v", ok, err)
+	}
+	if mr.TTL("lock:job") != minLockLease {
+		t.Fatalf("lease should be raised to %v, got %v", minLockLease, mr.TTL("lock:job"))
+	}
+	if err := lock.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if lock.retry != defaultLockRetry {
+		t.Fatalf("invalid retry interval should use the default, got %v", lock.retry)
+	}
+}
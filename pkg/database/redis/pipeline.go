@@ -0,0 +1,347 @@
+/**
+ * 管道、事务以及lua脚本：
+ *   1. Pipeline将命令排队，Exec时一次发送并按顺序读取所有的回复，只需要一次网络往返
+ *   2. TxPipeline在Exec时使用MULTI/EXEC包裹排队的命令，命令原子执行
+ *   3. Watch基于WATCH实现乐观锁事务，被监视的key在提交前被修改时重新执行
+ *   4. Script使用EVALSHA执行，脚本未加载（NOSCRIPT）时使用EVAL执行并加载
+ */
+package redis
+
+import (
+	"errors"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+//zAddBatchSize 批量添加有序集合时每个ZADD命令的成员数，避免单个命令过大长时间阻塞redis
+const zAddBatchSize = 1000
+
+var (
+	//ErrTxAborted 被WATCH监视的key在EXEC之前被修改，事务没有执行
+	ErrTxAborted = errors.New("redis: transaction aborted, watched keys changed")
+
+	errReplyNotReady = errors.New("redis: reply is not ready before Exec")
+)
+
+// Reply is the reply of a command, the reply of a queued command is ready after Exec.
+type Reply struct {
+	value interface{}
+	err   error
+}
+
+//Value 回复的原始值，命令执行失败时返回redis的错误
+func (r *Reply) Value() (interface{}, error) {
+	return r.value, r.err
+}
+
+func (r *Reply) Err() error {
+	return r.err
+}
+
+func (r *Reply) String() (string, error) {
+	return redis.String(r.value, r.err)
+}
+
+func (r *Reply) Int() (int, error) {
+	return redis.Int(r.value, r.err)
+}
+
+func (r *Reply) Int64() (int64, error) {
+	return redis.Int64(r.value, r.err)
+}
+
+func (r *Reply) Float64() (float64, error) {
+	return redis.Float64(r.value, r.err)
+}
+
+func (r *Reply) Bool() (bool, error) {
+	return redis.Bool(r.value, r.err)
+}
+
+func (r *Reply) Bytes() ([]byte, error) {
+	return redis.Bytes(r.value, r.err)
+}
+
+func (r *Reply) Strings() ([]string, error) {
+	return redis.Strings(r.value, r.err)
+}
+
+func (r *Reply) StringMap() (map[string]string, error) {
+	return redis.StringMap(r.value, r.err)
+}
+
+func (r *Reply) Values() ([]interface{}, error) {
+	return redis.Values(r.value, r.err)
+}
+
+//ZSetValues 解析WITHSCORES的回复
+func (r *Reply) ZSetValues() ([]ZSetValue, error) {
+	return zSetValues(r.value, r.err)
+}
+
+// Script is a Lua script evaluated by EVALSHA.
+type Script struct {
+	keyCount int
+	script   *redis.Script
+}
+
+//NewScript 创建lua脚本，keyCount为脚本中KEYS的个数，执行时keysAndArgs的前keyCount个参数为KEYS，其余的为ARGV
+func NewScript(keyCount int, src string) *Script {
+	return &Script{keyCount: keyCount, script: redis.NewScript(keyCount, src)}
+}
+
+func (s *Script) Hash() string {
+	return s.script.Hash()
+}
+
+//Eval 使用EVALSHA执行脚本，脚本未加载时使用EVAL执行，redis会缓存EVAL执行过的脚本
+func (c Client) Eval(script *Script, keysAndArgs ...interface{}) *Reply {
+	value, err := script.script.Do(c.conn, keysAndArgs...)
+	return &Reply{value: value, err: err}
+}
+
+//ScriptLoad 预先加载脚本，之后的EVALSHA不需要回退到EVAL
+func (c Client) ScriptLoad(scripts ...*Script) error {
+	for _, script := range scripts {
+		if err := script.script.Load(c.conn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type command struct {
+	name   string
+	args   []interface{}
+	script *Script
+	reply  *Reply
+}
+
+// Pipeline queues commands and sends them in one round trip on Exec. It is not safe for concurrent use.
+type Pipeline struct {
+	conn redis.Conn
+	tx   bool
+	cmds []command
+}
+
+//Pipeline 创建管道，管道使用Client的连接，Exec之前不会发送命令
+func (c Client) Pipeline() *Pipeline {
+	return &Pipeline{conn: c.conn}
+}
+
+//TxPipeline 创建事务管道，Exec时使用MULTI/EXEC原子执行排队的命令
+func (c Client) TxPipeline() *Pipeline {
+	return &Pipeline{conn: c.conn, tx: true}
+}
+
+//Do 命令排队，返回的Reply在Exec之后可用
+func (p *Pipeline) Do(cmd string, args ...interface{}) *Reply {
+	reply := &Reply{err: errReplyNotReady}
+	p.cmds = append(p.cmds, command{name: cmd, args: args, reply: reply})
+	return reply
+}
+
+//Eval 脚本排队，Exec时先加载没有缓存的脚本，保证排队的EVALSHA不会返回NOSCRIPT
+func (p *Pipeline) Eval(script *Script, keysAndArgs ...interface{}) *Reply {
+	reply := &Reply{err: errReplyNotReady}
+	args := redis.Args{script.Hash(), script.keyCount}.Add(keysAndArgs...)
+	p.cmds = append(p.cmds, command{name: "EVALSHA", args: args, script: script, reply: reply})
+	return reply
+}
+
+//Len 排队的命令数
+func (p *Pipeline) Len() int {
+	return len(p.cmds)
+}
+
+//Discard 放弃排队的命令
+func (p *Pipeline) Discard() {
+	p.cmds = nil
+}
+
+/**
+ * 发送排队的命令并按顺序读取回复，Exec之后管道被清空，可以继续排队
+ * @return: replies 按排队顺序的回复，每个命令的错误保存在对应的Reply中
+ * @return: err 第一个执行失败的命令的错误或者连接的错误，事务被WATCH中止时返回ErrTxAborted
+ */
+func (p *Pipeline) Exec() (replies []*Reply, err error) {
+	cmds := p.cmds
+	p.cmds = nil
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+	replies = make([]*Reply, len(cmds))
+	for i, cmd := range cmds {
+		replies[i] = cmd.reply
+	}
+	if err = p.loadScripts(cmds); err == nil {
+		if p.tx {
+			err = p.execTx(cmds)
+		} else {
+			err = p.exec(cmds)
+		}
+	}
+	if err != nil {
+		for _, reply := range replies {
+			if reply.err == errReplyNotReady {
+				reply.err = err
+			}
+		}
+		return replies, err
+	}
+	for _, reply := range replies {
+		if reply.err != nil {
+			return replies, reply.err
+		}
+	}
+	return replies, nil
+}
+
+func (p *Pipeline) exec(cmds []command) error {
+	for _, cmd := range cmds {
+		if err := p.conn.Send(cmd.name, cmd.args...); err != nil {
+			return err
+		}
+	}
+	if err := p.conn.Flush(); err != nil {
+		return err
+	}
+	for _, cmd := range cmds {
+		value, err := p.conn.Receive()
+		if _, ok := err.(redis.Error); err != nil && !ok {
+			return err //连接错误，之后的回复都无法读取
+		}
+		cmd.reply.value, cmd.reply.err = value, err
+	}
+	return nil
+}
+
+//execTx 命令在排队时出错（如参数个数错误）时redis放弃整个事务，EXEC返回EXECABORT
+func (p *Pipeline) execTx(cmds []command) error {
+	if err := p.conn.Send("MULTI"); err != nil {
+		return err
+	}
+	for _, cmd := range cmds {
+		if err := p.conn.Send(cmd.name, cmd.args...); err != nil {
+			return err
+		}
+	}
+	if err := p.conn.Send("EXEC"); err != nil {
+		return err
+	}
+	if err := p.conn.Flush(); err != nil {
+		return err
+	}
+	//MULTI出错时（如已经在事务中）仍然需要读取所有的回复，保证连接可以继续使用
+	_, multiErr := p.conn.Receive()
+	if _, ok := multiErr.(redis.Error); multiErr != nil && !ok {
+		return multiErr
+	}
+	for _, cmd := range cmds {
+		_, err := p.conn.Receive() //QUEUED
+		if e, ok := err.(redis.Error); ok {
+			cmd.reply.err = e
+		} else if err != nil {
+			return err
+		}
+	}
+	values, err := redis.Values(p.conn.Receive())
+	if multiErr != nil {
+		return multiErr
+	}
+	if err == ErrNil {
+		return ErrTxAborted
+	}
+	if err != nil {
+		return err
+	}
+	if len(values) != len(cmds) {
+		return redis.Error("redis: unexpected EXEC reply")
+	}
+	for i, cmd := range cmds {
+		if e, ok := values[i].(redis.Error); ok {
+			cmd.reply.err = e
+		} else {
+			cmd.reply.value, cmd.reply.err = values[i], nil
+		}
+	}
+	return nil
+}
+
+//loadScripts 使用SCRIPT EXISTS检查排队的脚本，加载没有缓存的脚本
+func (p *Pipeline) loadScripts(cmds []command) error {
+	var (
+		scripts []*Script
+		hashes  = make(map[string]bool)
+	)
+	for _, cmd := range cmds {
+		if cmd.script != nil && !hashes[cmd.script.Hash()] {
+			hashes[cmd.script.Hash()] = true
+			scripts = append(scripts, cmd.script)
+		}
+	}
+	if len(scripts) == 0 {
+		return nil
+	}
+	args := make(redis.Args, 0, len(scripts)+1).Add("EXISTS")
+	for _, script := range scripts {
+		args = args.Add(script.Hash())
+	}
+	exists, err := redis.Ints(p.conn.Do("SCRIPT", args...))
+	if err != nil {
+		return err
+	}
+	for i, script := range scripts {
+		if i < len(exists) && exists[i] == 1 {
+			continue
+		}
+		if err = script.script.Load(p.conn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/**
+ * 乐观锁事务：WATCH监视keys后执行fn，fn通过Client读取数据并在tx中排队写命令，
+ * 排队的命令在EXEC时原子执行；keys在EXEC之前被其它客户端修改时事务中止并重新执行fn
+ * @param : fn 读取数据并排队写命令，返回错误时放弃事务并返回该错误，没有排队命令时不执行EXEC
+ * @param : maxRetries 事务中止后最多重试的次数
+ * @param : keys 需要监视的key
+ * @return: 重试次数用完时返回ErrTxAborted
+ */
+func (c Client) Watch(fn func(tx *Pipeline) error, maxRetries int, keys ...string) error {
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if _, err := c.conn.Do("WATCH", redis.Args{}.AddFlat(keys)...); err != nil {
+			return err
+		}
+		tx := c.TxPipeline()
+		if err := fn(tx); err != nil || tx.Len() == 0 {
+			if _, unwatchErr := c.conn.Do("UNWATCH"); err == nil {
+				err = unwatchErr
+			}
+			return err
+		}
+		if _, err := tx.Exec(); err != ErrTxAborted {
+			return err
+		}
+	}
+	return ErrTxAborted
+}
+
+//zAddBatches 将values拆分为多个ZADD命令的参数，score生成第i个value的分数
+func zAddBatches(key string, values []interface{}, score func(i int, value interface{}) float64) []redis.Args {
+	var batches []redis.Args
+	for start := 0; start < len(values); start += zAddBatchSize {
+		end := start + zAddBatchSize
+		if end > len(values) {
+			end = len(values)
+		}
+		args := make(redis.Args, 0, 2*(end-start)+1).Add(key)
+		for i := start; i < end; i++ {
+			args = args.Add(score(i, values[i]), values[i])
+		}
+		batches = append(batches, args)
+	}
+	return batches
+}
@@ -0,0 +1,203 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestPipeline_Exec(t *testing.T) {
+	client, _ := newClient(t)
+	pipe := client.Pipeline()
+	set := pipe.Do("SET", "name", "tom")
+	incr := pipe.Do("INCR", "counter")
+	bad := pipe.Do("INCR", "name")
+	get := pipe.Do("GET", "name")
+	if _, err := get.String(); err == nil {
+		t.Fatal("the reply should not be ready before Exec")
+	}
+	if pipe.Len() != 4 {
+		t.Fatalf("Len: %d", pipe.Len())
+	}
+	replies, err := pipe.Exec()
+	if err == nil || !strings.Contains(err.Error(), "not an integer") || len(replies) != 4 {
+		t.Fatalf("Exec should return the error of the failed command: %v", err)
+	}
+	if value, _ := set.String(); value != "OK" {
+		t.Fatalf("SET: %s", value)
+	}
+	if n, _ := incr.Int64(); n != 1 {
+		t.Fatalf("INCR: %d", n)
+	}
+	if bad.Err() == nil {
+		t.Fatal("INCR on a string should fail")
+	}
+	if value, _ := replies[3].String(); value != "tom" {
+		t.Fatalf("GET: %s", value)
+	}
+	if replies, err = pipe.Exec(); err != nil || replies != nil {
+		t.Fatal("Exec an empty pipeline should do nothing")
+	}
+	pipe.Do("SET", "discarded", 1)
+	pipe.Discard()
+	if _, _ = pipe.Exec(); mustExists(client, "discarded") {
+		t.Fatal("discarded commands should not be sent")
+	}
+}
+
+func mustExists(client *Client, key string) bool {
+	exists, _ := client.Exists(key)
+	return exists
+}
+
+func TestTxPipeline_Exec(t *testing.T) {
+	client, _ := newClient(t)
+	_ = client.Set("name", "tom")
+	tx := client.TxPipeline()
+	incr := tx.Do("INCRBY", "counter", 2)
+	bad := tx.Do("INCR", "name")
+	zRange := tx.Do("ZRANGE", "rank", 0, -1, "WITHSCORES")
+	tx.Do("ZADD", "rank", 1.5, "a")
+	if _, err := tx.Exec(); err == nil || bad.Err() == nil {
+		t.Fatalf("the runtime error should be returned: %v", err)
+	}
+	if n, _ := incr.Int(); n != 2 {
+		t.Fatalf("INCRBY: %d", n)
+	}
+	if values, err := zRange.ZSetValues(); err != nil || len(values) != 0 {
+		t.Fatalf("ZRANGE: %v, %v", values, err)
+	}
+	if score, _ := client.ZScore("rank", "a"); score != 1.5 {
+		t.Fatal("the commands after a runtime error should still be executed")
+	}
+
+	//排队时出错的事务整体放弃
+	tx.Do("SET", "aborted", 1)
+	wrong := tx.Do("GET")
+	if _, err := tx.Exec(); err == nil || wrong.Err() == nil || mustExists(client, "aborted") {
+		t.Fatalf("the transaction should be aborted: %v", err)
+	}
+	if err := client.Set("after", 1); err != nil {
+		t.Fatalf("the connection should be usable after an aborted transaction: %v", err)
+	}
+}
+
+func TestClient_Multi(t *testing.T) {
+	client, _ := newClient(t)
+	if err := client.Multi(); err != nil {
+		t.Fatal(err)
+	}
+	_ = client.Set("a", 1)
+	_, _ = client.Incr("a")
+	_, _ = client.GetString("a")
+	results, err := client.Exec()
+	if err != nil || fmt.Sprintf("%s", results) != "[OK %!s(int64=2) 2]" {
+		t.Fatalf("Exec: %s, %v", results, err)
+	}
+	_ = client.Multi()
+	_ = client.Set("b", 1)
+	if err = client.Discard(); err != nil || mustExists(client, "b") {
+		t.Fatalf("Discard: %v", err)
+	}
+}
+
+func TestClient_Watch(t *testing.T) {
+	client, mr := newClient(t)
+	_ = client.Set("balance", 100)
+	attempts := 0
+	err := client.Watch(func(tx *Pipeline) error {
+		attempts++
+		balance, err := client.GetInt("balance")
+		if err != nil {
+			return err
+		}
+		if attempts == 1 {
+			mr.Set("balance", "50") //其它客户端在提交之前修改了余额
+		}
+		tx.Do("SET", "balance", balance-30)
+		return nil
+	}, 3, "balance")
+	if err != nil || attempts != 2 {
+		t.Fatalf("Watch: %d attempts, %v", attempts, err)
+	}
+	if balance, _ := client.GetInt("balance"); balance != 20 {
+		t.Fatalf("unexpected balance %d", balance)
+	}
+
+	err = client.Watch(func(tx *Pipeline) error {
+		mr.Set("balance", "0")
+		tx.Do("SET", "balance", 1)
+		return nil
+	}, 1, "balance")
+	if err != ErrTxAborted {
+		t.Fatalf("Watch should return ErrTxAborted after the retries, got %v", err)
+	}
+
+	insufficient := errors.New("insufficient balance")
+	if err = client.Watch(func(tx *Pipeline) error { return insufficient }, 3, "balance"); err != insufficient {
+		t.Fatalf("Watch should return the error of fn, got %v", err)
+	}
+	mr.Set("balance", "10")
+	if err = client.Watch(func(tx *Pipeline) error {
+		tx.Do("INCR", "balance")
+		return nil
+	}, 0, "balance"); err != nil {
+		t.Fatal("the key modified before WATCH should not abort the transaction")
+	}
+}
+
+func TestScript(t *testing.T) {
+	client, mr := newClient(t)
+	script := NewScript(1, `return redis.call("INCRBY", KEYS[1], ARGV[1])`)
+	if n, err := client.Eval(script, "counter", 2).Int(); err != nil || n != 2 {
+		t.Fatalf("Eval: %d, %v", n, err)
+	}
+	mr.FlushAll()
+	_, _ = client.conn.Do("SCRIPT", "FLUSH")
+	if n, err := client.Eval(script, "counter", 3).Int(); err != nil || n != 3 {
+		t.Fatalf("Eval should fall back to EVAL: %d, %v", n, err)
+	}
+
+	_, _ = client.conn.Do("SCRIPT", "FLUSH")
+	pipe := client.Pipeline()
+	first := pipe.Eval(script, "counter", 1)
+	second := pipe.Eval(script, "counter", 1)
+	if _, err := pipe.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := second.Int(); n != 5 || first.Err() != nil {
+		t.Fatalf("pipelined Eval: %d", n)
+	}
+	if err := client.ScriptLoad(script); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := redis.Ints(client.conn.Do("SCRIPT", "EXISTS", script.Hash())); exists[0] != 1 {
+		t.Fatal("the script should be loaded")
+	}
+}
+
+func TestClient_ZAddSetBatches(t *testing.T) {
+	client, _ := newClient(t)
+	values := make([]interface{}, 2*zAddBatchSize+500)
+	for i := range values {
+		values[i] = fmt.Sprintf("member-%d", i)
+	}
+	if err := client.ZAddSet("big", values); err != nil {
+		t.Fatal(err)
+	}
+	if card, _ := client.ZCard("big"); card != len(values) {
+		t.Fatalf("ZCard: %d", card)
+	}
+	if score, _ := client.ZScore("big", "member-2400"); score != 2400 {
+		t.Fatalf("ZScore: %v", score)
+	}
+	if err := client.ZClearAndAddSet("big", values[:zAddBatchSize+1]); err != nil {
+		t.Fatal(err)
+	}
+	if card, _ := client.ZCard("big"); card != zAddBatchSize+1 {
+		t.Fatalf("ZCard after ZClearAndAddSet: %d", card)
+	}
+}
@@ -0,0 +1,279 @@
+/**
+ * 发布订阅：Subscriber为每个channel/pattern注册一个handler，
+ * 连接断开后自动重连并重新订阅，handler在syncs.TaskExecutor中执行，等待队列已满时在接收消息的协程中执行；
+ * 订阅期间定期发送PING，超过两个PING间隔没有收到任何回复时认为连接已经失效并重连。
+ * 重连间隔以及PING间隔使用Subscriber的时钟，连接的读超时仍然是真实时间
+ */
+package redis
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/common/log"
+	"github.com/yinjk/go-utils/pkg/utils/syncs"
+	"github.com/yinjk/go-utils/pkg/utils/times"
+)
+
+const (
+	defaultReconnectInterval = time.Second
+	defaultPingInterval      = 30 * time.Second
+)
+
+// Message is a message received from a channel.
+type Message struct {
+	Channel string
+	Pattern string //通过PSubscribe订阅时匹配的pattern
+	Data    []byte
+}
+
+// MessageHandler handles the messages of a channel or pattern.
+type MessageHandler func(msg *Message)
+
+//Publish 发布消息，返回收到消息的订阅者数
+func (c Client) Publish(channel string, message interface{}) (receivers int, err error) {
+	return redis.Int(c.conn.Do("PUBLISH", channel, message))
+}
+
+// Subscriber dispatches the messages of the subscribed channels and patterns to their handlers.
+type Subscriber struct {
+	pool      *Pool
+	executor  *syncs.TaskExecutor
+	reconnect time.Duration
+	ping      time.Duration
+	clock     times.Clock
+
+	mu       sync.Mutex
+	channels map[string]MessageHandler
+	patterns map[string]MessageHandler
+	conn     *redis.PubSubConn //当前的订阅连接，没有运行时为nil
+}
+
+// SubscriberOption configures a Subscriber.
+type SubscriberOption func(s *Subscriber)
+
+//SubscriberReconnectInterval 连接断开后重连的间隔，默认1秒
+func SubscriberReconnectInterval(interval time.Duration) SubscriberOption {
+	return func(s *Subscriber) {
+		s.reconnect = interval
+	}
+}
+
+//SubscriberPingInterval 订阅连接发送PING的间隔，默认30秒，小于等于0时不发送PING，半开的连接不会被发现
+func SubscriberPingInterval(interval time.Duration) SubscriberOption {
+	return func(s *Subscriber) {
+		s.ping = interval
+	}
+}
+
+//SubscriberClock 重连以及PING使用的时钟，默认为times.SystemClock，测试中可以使用times.FakeClock
+func SubscriberClock(clock times.Clock) SubscriberOption {
+	return func(s *Subscriber) {
+		s.clock = clock
+	}
+}
+
+//NewSubscriber 创建订阅者，executor为nil时handler在接收消息的协程中依次执行
+func NewSubscriber(pool *Pool, executor *syncs.TaskExecutor, opts ...SubscriberOption) *Subscriber {
+	s := &Subscriber{
+		pool:      pool,
+		executor:  executor,
+		reconnect: defaultReconnectInterval,
+		ping:      defaultPingInterval,
+		channels:  make(map[string]MessageHandler),
+		patterns:  make(map[string]MessageHandler),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.clock = times.ClockOrDefault(s.clock)
+	return s
+}
+
+//Subscribe 订阅channel，重复订阅时替换handler；Run之前订阅的channel在Run连接之后订阅
+func (s *Subscriber) Subscribe(channel string, handler MessageHandler) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.channels[channel] = handler
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Subscribe(channel)
+}
+
+//PSubscribe 订阅pattern，如news.*
+func (s *Subscriber) PSubscribe(pattern string, handler MessageHandler) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.patterns[pattern] = handler
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.PSubscribe(pattern)
+}
+
+//Unsubscribe 取消订阅channel
+func (s *Subscriber) Unsubscribe(channels ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, channel := range channels {
+		delete(s.channels, channel)
+	}
+	if s.conn == nil || len(channels) == 0 {
+		return nil
+	}
+	return s.conn.Unsubscribe(redis.Args{}.AddFlat(channels)...)
+}
+
+//PUnsubscribe 取消订阅pattern
+func (s *Subscriber) PUnsubscribe(patterns ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, pattern := range patterns {
+		delete(s.patterns, pattern)
+	}
+	if s.conn == nil || len(patterns) == 0 {
+		return nil
+	}
+	return s.conn.PUnsubscribe(redis.Args{}.AddFlat(patterns)...)
+}
+
+//Run 接收并分发消息直到ctx结束，返回ctx.Err()；连接断开后每隔重连间隔重连一次，重连之后重新订阅所有的channel和pattern
+func (s *Subscriber) Run(ctx context.Context) error {
+	for {
+		err := s.receive(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Errorf("redis: subscriber disconnected, reconnect in %v: %v", s.reconnect, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.clock.After(s.reconnect):
+		}
+	}
+}
+
+//receive 建立连接、订阅并接收消息，连接出错时返回错误
+func (s *Subscriber) receive(ctx context.Context) error {
+	//订阅的连接不能放回连接池复用，使用单独的连接
+	c, err := s.pool.pool.Dial()
+	if err != nil {
+		return err
+	}
+	conn := &redis.PubSubConn{Conn: c}
+	if err := s.attach(conn); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	//ctx结束或者PING失败时关闭连接，使阻塞的Receive返回
+	done := make(chan struct{})
+	defer close(done)
+	go s.keepalive(ctx, conn, done)
+	for {
+		var reply interface{}
+		if s.ping > 0 {
+			reply = conn.ReceiveWithTimeout(2 * s.ping)
+		} else {
+			reply = conn.Receive()
+		}
+		switch reply := reply.(type) {
+		case redis.Message:
+			s.dispatch(&Message{Channel: reply.Channel, Pattern: reply.Pattern, Data: reply.Data})
+		case redis.Subscription, redis.Pong:
+		case error:
+			return reply
+		}
+	}
+}
+
+//keepalive 每隔PING间隔发送一次PING，直到ctx结束或者receive返回，然后关闭conn
+func (s *Subscriber) keepalive(ctx context.Context, conn *redis.PubSubConn, done chan struct{}) {
+	defer s.detach(conn)
+	var tick <-chan time.Time
+	if s.ping > 0 {
+		ticker := s.clock.NewTicker(s.ping)
+		defer ticker.Stop()
+		tick = ticker.C()
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-tick:
+		}
+		//与Subscribe等方法一样在锁中写连接
+		s.mu.Lock()
+		err := conn.Ping("")
+		s.mu.Unlock()
+		if err != nil {
+			log.Errorf("redis: subscriber ping error: %v", err)
+			return
+		}
+	}
+}
+
+//attach 将conn设置为当前连接并订阅所有的channel和pattern
+func (s *Subscriber) attach(conn *redis.PubSubConn) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.channels) > 0 {
+		if err := conn.Subscribe(keysOf(s.channels)...); err != nil {
+			return err
+		}
+	}
+	if len(s.patterns) > 0 {
+		if err := conn.PSubscribe(keysOf(s.patterns)...); err != nil {
+			return err
+		}
+	}
+	s.conn = conn
+	return nil
+}
+
+//detach 关闭conn，conn仍然是当前连接时清除当前连接
+func (s *Subscriber) detach(conn *redis.PubSubConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == conn {
+		s.conn = nil
+	}
+	_ = conn.Close()
+}
+
+//dispatch 找到消息的handler并在executor中执行，executor已满时在当前协程中执行，handler的panic不会影响其它消息
+func (s *Subscriber) dispatch(msg *Message) {
+	s.mu.Lock()
+	handler := s.channels[msg.Channel]
+	if msg.Pattern != "" {
+		handler = s.patterns[msg.Pattern]
+	}
+	s.mu.Unlock()
+	if handler == nil {
+		return
+	}
+	run := func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Errorf("redis: handler of channel %s panic: %v", msg.Channel, r)
+			}
+		}()
+		handler(msg)
+	}
+	//与StreamConsumer一样不使用executor的拒绝策略，拒绝策略可能丢弃消息或者阻塞接收
+	if s.executor == nil || !s.executor.TryExecute(run) {
+		run()
+	}
+}
+
+func keysOf(handlers map[string]MessageHandler) []interface{} {
+	keys := make([]interface{}, 0, len(handlers))
+	for key := range handlers {
+		keys = append(keys, key)
+	}
+	return keys
+}
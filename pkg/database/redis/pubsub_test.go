@@ -0,0 +1,185 @@
+package redis
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/yinjk/go-utils/pkg/utils/syncs"
+	"github.com/yinjk/go-utils/pkg/utils/times"
+)
+
+//waitFor 等待条件成立，超时时测试失败
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func receiveMessage(t *testing.T, messages chan *Message) *Message {
+	t.Helper()
+	select {
+	case msg := <-messages:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the message")
+		return nil
+	}
+}
+
+func TestSubscriber_Run(t *testing.T) {
+	mr := miniredis.RunT(t)
+	pool := newPool(t, mr)
+	executor := syncs.NewTaskExecutor(2, 10, nil)
+	defer executor.Shutdown()
+	messages := make(chan *Message, 10)
+	handler := func(msg *Message) { messages <- msg }
+
+	subscriber := NewSubscriber(pool, executor, SubscriberReconnectInterval(10*time.Millisecond))
+	_ = subscriber.Subscribe("news", handler)
+	_ = subscriber.PSubscribe("order.*", handler)
+	_ = subscriber.Subscribe("panic", func(msg *Message) { panic("boom") })
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- subscriber.Run(ctx) }()
+	waitFor(t, "subscription", func() bool { return mr.PubSubNumSub("news")["news"] == 1 && mr.PubSubNumPat() == 1 })
+
+	client, _ := pool.Get()
+	defer client.Close()
+	_, _ = client.Publish("panic", "ignored")
+	if n, err := client.Publish("news", "hello"); err != nil || n != 1 {
+		t.Fatalf("Publish: %d, %v", n, err)
+	}
+	if msg := receiveMessage(t, messages); msg.Channel != "news" || string(msg.Data) != "hello" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+	_, _ = client.Publish("order.created", "1")
+	if msg := receiveMessage(t, messages); msg.Channel != "order.created" || msg.Pattern != "order.*" || string(msg.Data) != "1" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+
+	//运行之后订阅以及取消订阅
+	_ = subscriber.Subscribe("late", handler)
+	_ = subscriber.Unsubscribe("news")
+	waitFor(t, "resubscription", func() bool {
+		return mr.PubSubNumSub("late")["late"] == 1 && mr.PubSubNumSub("news")["news"] == 0
+	})
+
+	//断开连接之后重连并重新订阅
+	mr.Close()
+	if err := mr.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "reconnection", func() bool { return mr.PubSubNumSub("late")["late"] == 1 && mr.PubSubNumPat() == 1 })
+	client, _ = pool.Get()
+	defer client.Close()
+	_, _ = client.Publish("late", "again")
+	if msg := receiveMessage(t, messages); msg.Channel != "late" || string(msg.Data) != "again" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+
+	cancel()
+	select {
+	case err := <-stopped:
+		if err != context.Canceled {
+			t.Fatalf("Run should return ctx.Err(), got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run should return after ctx is done")
+	}
+}
+
+func TestSubscriber_HalfOpen(t *testing.T) {
+	//接受连接但是从不响应的节点，模拟半开的连接
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	var accepted int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&accepted, 1)
+			go func() { _, _ = io.Copy(io.Discard, conn) }()
+		}
+	}()
+	pool := GetRedisPool(&Config{Addr: listener.Addr().String(), MaxIdle: 1})
+	defer pool.Close()
+	subscriber := NewSubscriber(pool, nil, SubscriberPingInterval(20*time.Millisecond), SubscriberReconnectInterval(time.Millisecond))
+	_ = subscriber.Subscribe("news", func(msg *Message) {})
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- subscriber.Run(ctx) }()
+	waitFor(t, "reconnect", func() bool {
+		return atomic.LoadInt32(&accepted) >= 2
+	})
+	cancel()
+	if err := <-stopped; err != context.Canceled {
+		t.Fatalf("Run should return ctx.Err(), got %v", err)
+	}
+}
+
+func TestSubscriber_Ping(t *testing.T) {
+	mr := miniredis.RunT(t)
+	pool := newPool(t, mr)
+	received := make(chan *Message, 1)
+	clock := times.NewFakeClock(time.Time{})
+	subscriber := NewSubscriber(pool, nil, SubscriberPingInterval(time.Minute), SubscriberClock(clock))
+	_ = subscriber.Subscribe("news", func(msg *Message) { received <- msg })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = subscriber.Run(ctx) }()
+	waitFor(t, "subscription", func() bool {
+		return len(mr.PubSubChannels("")) == 1
+	})
+	//每个PING间隔发送一次PING，PING的回复使连接在没有消息时保持可用
+	clock.BlockUntil(1)
+	commands := mr.CommandCount()
+	clock.Advance(time.Minute)
+	waitFor(t, "ping", func() bool { return mr.CommandCount() > commands })
+	mr.Publish("news", "hello")
+	if msg := receiveMessage(t, received); string(msg.Data) != "hello" {
+		t.Fatalf("unexpected message %s", msg.Data)
+	}
+}
+
+func TestSubscriber_ExecutorFull(t *testing.T) {
+	mr := miniredis.RunT(t)
+	pool := newPool(t, mr)
+	//拒绝策略直接丢弃任务，唯一的协程以及等待队列都被占满
+	executor := syncs.NewTaskExecutor(1, 1, func(f func()) {})
+	defer executor.Shutdown()
+	release, busy := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	executor.Execute(func() {
+		close(busy)
+		<-release
+	})
+	<-busy
+	executor.Execute(func() { <-release })
+
+	messages := make(chan *Message, 1)
+	subscriber := NewSubscriber(pool, executor)
+	_ = subscriber.Subscribe("news", func(msg *Message) { messages <- msg })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = subscriber.Run(ctx) }()
+	waitFor(t, "subscription", func() bool { return mr.PubSubNumSub("news")["news"] == 1 })
+	mr.Publish("news", "hello")
+	if msg := receiveMessage(t, messages); string(msg.Data) != "hello" {
+		t.Fatalf("the message should be handled in the receiving goroutine, got %s", msg.Data)
+	}
+}
@@ -6,12 +6,16 @@
 package redis
 
 import (
-	"github.com/yinjk/go-utils/pkg/utils/convert"
+	"strconv"
+	"time"
+
 	"github.com/gomodule/redigo/redis"
 	"github.com/prometheus/common/log"
-	"time"
 )
 
+//ErrNil key、字段、成员不存在时返回的错误
+var ErrNil = redis.ErrNil
+
 type Config struct {
 	Addr              string
 	Password          string
@@ -37,15 +41,21 @@ func GetRedisPool(config *Config) (pool *Pool) {
 				log.Error(err)
 				return nil, err
 			}
-			// 鉴权 登陆
-			if _, err = c.Do("AUTH", config.Password); err != nil {
-				log.Error(err)
-				return nil, err
+			// 鉴权 登陆，没有设置密码的redis不需要鉴权
+			if config.Password != "" {
+				if _, err = c.Do("AUTH", config.Password); err != nil {
+					log.Error(err)
+					_ = c.Close()
+					return nil, err
+				}
 			}
-			// 选择db
-			if _, err = c.Do("SELECT", config.Database); err != nil {
-				log.Error(err)
-				return nil, err
+			// 选择db，未指定时使用默认的db 0
+			if config.Database != "" {
+				if _, err = c.Do("SELECT", config.Database); err != nil {
+					log.Error(err)
+					_ = c.Close()
+					return nil, err
+				}
 			}
 			return c, nil
 		},
@@ -75,9 +85,13 @@ func (c Client) Multi() (err error) {
 	return err
 }
 
-func (c Client) Exec() (err error) {
-	_, err = c.conn.Do("EXEC") //执行事物
-	return err
+//Exec 执行事物，返回事物中每个命令的结果，事务被WATCH中止时返回ErrTxAborted
+func (c Client) Exec() (results []interface{}, err error) {
+	results, err = redis.Values(c.conn.Do("EXEC"))
+	if err == ErrNil {
+		return nil, ErrTxAborted
+	}
+	return results, err
 }
 
 func (c Client) Discard() (err error) {
@@ -128,7 +142,7 @@ func (c Client) HmSet(key string, data map[string]interface{}) (err error) {
 	return err
 }
 
-func (c Client) ZAdd(key string, score int, value interface{}) error {
+func (c Client) ZAdd(key string, score float64, value interface{}) error {
 	_, err := c.conn.Do("zadd", key, score, value)
 	return err
 }
@@ -137,8 +151,8 @@ func (c Client) ZCard(key string) (value int, err error) {
 	return redis.Int(c.conn.Do("ZCARD", key))
 }
 
-func (c Client) ZScore(key, member string) (i int, err error) {
-	return redis.Int(c.conn.Do("ZSCORE", key, member))
+func (c Client) ZScore(key, member string) (score float64, err error) {
+	return redis.Float64(c.conn.Do("ZSCORE", key, member))
 }
 
 /**
@@ -157,106 +171,69 @@ func (c Client) ZRemove(key string, member ...interface{}) (i int, err error) {
 }
 
 func (c Client) ZAddSet(key string, values []interface{}) (err error) {
-	zSetArgs := make([]interface{}, len(values)*2+1) //拼装 zadd的参数
-	zSetArgs[0] = key
-	for i, v := range values {
-		zSetArgs[2*i+1] = i
-		zSetArgs[2*i+2] = v
-	}
-	_, err = c.conn.Do("zadd", zSetArgs...)
-	return err
+	return c.zAddBatches(c.Pipeline(), key, values, func(i int, _ interface{}) float64 {
+		return float64(i)
+	})
 }
 
-func (c Client) ZAddSetWithScore(key string, values []interface{}, genScore func(value interface{}) int) bool {
-	zSetArgs := make([]interface{}, len(values)*2+1) //拼装 zadd的参数
-	zSetArgs[0] = key
-	for i, v := range values {
-		zSetArgs[2*i+1] = genScore(v)
-		zSetArgs[2*i+2] = v
-	}
-	_, err := c.conn.Do("zadd", zSetArgs...)
-	if err != nil {
-		panic(err)
-		return false
-	}
-	return true
+func (c Client) ZAddSetWithScore(key string, values []interface{}, genScore func(value interface{}) float64) (err error) {
+	return c.zAddBatches(c.Pipeline(), key, values, func(_ int, value interface{}) float64 {
+		return genScore(value)
+	})
 }
 
 func (c Client) ZClearAndAddSet(key string, values []interface{}) (err error) {
-	//开启redis事物
-	if err = c.Multi(); err != nil {
-		return
-	}
-	//先清空zSet
-	if _, err = c.DeleteKey(key); err != nil {
-		_ = c.Discard()
-		return
+	//清空与添加在同一个事务中执行
+	tx := c.TxPipeline()
+	tx.Do("DEL", key)
+	return c.zAddBatches(tx, key, values, func(i int, _ interface{}) float64 {
+		return float64(i)
+	})
+}
+
+//zAddBatches 大量的数据拆分为多个ZADD命令，通过管道一次发送
+func (c Client) zAddBatches(p *Pipeline, key string, values []interface{}, score func(i int, value interface{}) float64) error {
+	for _, args := range zAddBatches(key, values, score) {
+		p.Do("ZADD", args...)
 	}
-	//添加set
-	result := c.ZAddSet(key, values)
-	//执行事物
-	if err = c.Exec(); err != nil {
-		_ = c.Discard()
-		return
-	}
-	return result
+	_, err := p.Exec()
+	return err
 }
 
-func (c Client) ZGetMaxScore(key string) (score int, err error) {
+func (c Client) ZGetMaxScore(key string) (score float64, err error) {
 	values, err := redis.Strings(c.conn.Do("zrevrange", key, 0, 0, "withscores"))
 	if err != nil || len(values) != 2 {
 		return -1, err
 	}
-	return convert.StringToInt(values[1]), nil
+	return strconv.ParseFloat(values[1], 64)
 }
 
+// ZSetValue is a member of a sorted set with its score.
 type ZSetValue struct {
-	Score int
+	Score float64
 	Value string
 }
 
-func (c Client) ZRange(key string, start, stop int) []ZSetValue {
-	//返回的values数组为值和分数，格式如：[value1 score1 value2 score2 ...]
-	values, err := redis.Strings(c.conn.Do("zrange", key, start, stop, "withscores"))
-	if err != nil {
-		return nil
-	}
-	//将values封装成ZSetValue类型数组
-	isScore := false
-	result := make([]ZSetValue, len(values)/2)
-	var zSetValue ZSetValue
-	for i, v := range values {
-		if isScore {
-			zSetValue.Score = convert.StringToInt(v)
-			result[(i-1)/2] = zSetValue
-			isScore = false
-		} else {
-			zSetValue = ZSetValue{Value: v}
-			isScore = true
-		}
-	}
-	return result
+func (c Client) ZRange(key string, start, stop int) ([]ZSetValue, error) {
+	return zSetValues(c.conn.Do("ZRANGE", key, start, stop, "WITHSCORES"))
+}
+
+func (c Client) ZRevRange(key string, start, stop int) ([]ZSetValue, error) {
+	return zSetValues(c.conn.Do("ZREVRANGE", key, start, stop, "WITHSCORES"))
 }
 
-func (c Client) ZRevRange(key string, start, stop int) []ZSetValue {
-	//返回的values数组为值和分数，格式如：[value1 score1 value2 score2 ...]
-	values, err := redis.Strings(c.conn.Do("ZREVRANGE", key, start, stop, "withscores"))
+//zSetValues 返回的values数组为值和分数，格式如：[value1 score1 value2 score2 ...]，将values封装成ZSetValue类型数组
+func zSetValues(reply interface{}, err error) ([]ZSetValue, error) {
+	values, err := redis.Strings(reply, err)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	//将values封装成ZSetValue类型数组
-	isScore := false
 	result := make([]ZSetValue, len(values)/2)
-	var zSetValue ZSetValue
-	for i, v := range values {
-		if isScore {
-			zSetValue.Score = convert.StringToInt(v)
-			result[(i-1)/2] = zSetValue
-			isScore = false
-		} else {
-			zSetValue = ZSetValue{Value: v}
-			isScore = true
+	for i := range result {
+		result[i].Value = values[2*i]
+		if result[i].Score, err = strconv.ParseFloat(values[2*i+1], 64); err != nil {
+			return nil, err
 		}
 	}
-	return result
+	return result, nil
 }
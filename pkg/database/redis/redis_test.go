@@ -8,74 +8,126 @@ package redis
 import (
 	"fmt"
 	"testing"
-)
 
-var (
-	pool *Pool
+	"github.com/alicebob/miniredis/v2"
 )
 
-func init() {
-	pool = GetRedisPool(nil)
+//newClient 连接miniredis，测试结束时关闭连接池以及miniredis
+func newClient(t *testing.T) (*Client, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	pool := GetRedisPool(&Config{Addr: mr.Addr(), MaxIdle: 2})
+	t.Cleanup(func() { _ = pool.Close() })
+	client, err := pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client, mr
 }
 
-func TestClient_HmSet(t *testing.T) {
-	client, _ := pool.Get()
+func TestGetRedisPool(t *testing.T) {
+	mr := miniredis.RunT(t)
+	mr.RequireAuth("secret")
+	pool := GetRedisPool(&Config{Addr: mr.Addr(), Password: "secret", Database: "2"})
+	defer pool.Close()
+	client, err := pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
 	defer client.Close()
+	if err = client.Set("k", "v"); err != nil {
+		t.Fatal(err)
+	}
+	if mr.DB(2).Exists("k") != true {
+		t.Fatal("the database should be selected")
+	}
+	pool = GetRedisPool(&Config{Addr: mr.Addr(), Password: "wrong"})
+	defer pool.Close()
+	if _, err = pool.Get(); err == nil {
+		t.Fatal("wrong password should be rejected")
+	}
+}
+
+func TestClient_HmSet(t *testing.T) {
+	client, _ := newClient(t)
 	map1 := make(map[string]interface{})
 	map1["name"] = "张珊"
 	map1["sex"] = "女"
 	map1["age"] = 18
 	map1["list"] = []string{"111", "222", "333"}
 	if err := client.HmSet("map1", map1); err != nil {
-		panic(err)
+		t.Fatal(err)
+	}
+	if name, err := client.HGet("map1", "name"); err != nil || name != "张珊" {
+		t.Fatalf("HGet: %s, %v", name, err)
+	}
+	if all, err := client.HGetAll("map1"); err != nil || len(all) != 4 || all["age"] != "18" {
+		t.Fatalf("HGetAll: %v, %v", all, err)
+	}
+	if _, err := client.HGet("map1", "unknown"); err != ErrNil {
+		t.Fatalf("missing field should return ErrNil, got %v", err)
 	}
-}
-
-func TestClient_HGet(t *testing.T) {
-	client, _ := pool.Get()
-	defer client.Close()
-	fmt.Println(client.HGet("map1", "list"))
-}
-
-func TestClient_ZGetMaxScore(t *testing.T) {
-	client, _ := pool.Get()
-	defer client.Close()
-	i, _ := client.ZGetMaxScore("students")
-	fmt.Println(i)
 }
 
 func TestClient_DeleteKey(t *testing.T) {
-	client, _ := pool.Get()
-	defer client.Close()
-	i, _ := client.DeleteKey("a1", "a2")
-	fmt.Println(i)
+	client, _ := newClient(t)
+	_ = client.Set("a1", 1)
+	i, err := client.DeleteKey("a1", "a2")
+	if err != nil || i != 1 {
+		t.Fatalf("DeleteKey: %d, %v", i, err)
+	}
+	if exists, _ := client.Exists("a1"); exists {
+		t.Fatal("a1 should be deleted")
+	}
 }
 
 func TestClient_ZClearAndAddSet(t *testing.T) {
-	client, _ := pool.Get()
-	defer client.Close()
+	client, _ := newClient(t)
+	_ = client.ZAdd("zSetKey", 100, "old")
 	args := []interface{}{"1", "2", "a", "b", "c", "e"}
-	ok := client.ZClearAndAddSet("zSetKey", args)
-	fmt.Println(ok)
-}
-
-func TestClient_ZRemove(t *testing.T) {
-	client, _ := pool.Get()
-	defer client.Close()
-	i, _ := client.ZRemove("zSetKey", "b", "c", "v")
-	fmt.Println(i)
-}
-
-func TestClient_ZRange(t *testing.T) {
-	client, _ := pool.Get()
-	defer client.Close()
-	result := client.ZRange("zSetKey", 0, -1)
-	fmt.Println(result)
+	if err := client.ZClearAndAddSet("zSetKey", args); err != nil {
+		t.Fatal(err)
+	}
+	if max, err := client.ZGetMaxScore("zSetKey"); err != nil || max != 5 {
+		t.Fatalf("ZGetMaxScore: %v, %v", max, err)
+	}
+	if i, err := client.ZRemove("zSetKey", "b", "c", "v"); err != nil || i != 2 {
+		t.Fatalf("ZRemove: %d, %v", i, err)
+	}
+	result, err := client.ZRange("zSetKey", 0, -1)
+	if err != nil || fmt.Sprint(result) != "[{0 1} {1 2} {2 a} {5 e}]" {
+		t.Fatalf("ZRange: %v, %v", result, err)
+	}
+	result, err = client.ZRevRange("zSetKey", 0, 1)
+	if err != nil || fmt.Sprint(result) != "[{5 e} {2 a}]" {
+		t.Fatalf("ZRevRange: %v, %v", result, err)
+	}
+	if err = client.ZClearAndAddSet("zSetKey", nil); err != nil {
+		t.Fatal(err)
+	}
+	if max, err := client.ZGetMaxScore("zSetKey"); err != nil || max != -1 {
+		t.Fatalf("empty sorted set: %v, %v", max, err)
+	}
 }
 
-func TestClient_ZRevRange(t *testing.T) {
-	client, _ := pool.Get()
-	defer client.Close()
-	result := client.ZRevRange("zSetKey", 0, -1)
-	fmt.Println(result)
+func TestClient_ZScore(t *testing.T) {
+	client, _ := newClient(t)
+	if err := client.ZAdd("students", 90.5, "tom"); err != nil {
+		t.Fatal(err)
+	}
+	err := client.ZAddSetWithScore("students", []interface{}{"jerry", "spike"}, func(value interface{}) float64 {
+		return float64(len(value.(string))) * 10
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score, err := client.ZScore("students", "tom"); err != nil || score != 90.5 {
+		t.Fatalf("ZScore: %v, %v", score, err)
+	}
+	if card, err := client.ZCard("students"); err != nil || card != 3 {
+		t.Fatalf("ZCard: %d, %v", card, err)
+	}
+	if max, err := client.ZGetMaxScore("students"); err != nil || max != 90.5 {
+		t.Fatalf("ZGetMaxScore: %v, %v", max, err)
+	}
 }
@@ -0,0 +1,118 @@
+/**
+ * SCAN、SSCAN、HSCAN、ZSCAN的迭代器，按需向redis请求下一批数据，不会像KEYS、SMEMBERS一样一次返回所有数据：
+ *
+ *	it := client.Scan("user:*", 100)
+ *	for it.Next() {
+ *		fmt.Println(it.Member())
+ *	}
+ *	if err := it.Err(); err != nil {
+ *		return err
+ *	}
+ *
+ * 迭代过程中集合被修改时，同一个元素可能被返回多次
+ */
+package redis
+
+import (
+	"strconv"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+//ScanIterator 基于游标的迭代器，非并发安全
+type ScanIterator struct {
+	conn   redis.Conn
+	cmd    string
+	args   redis.Args //游标之后的参数：MATCH、COUNT
+	key    string     //SCAN时为空
+	pairs  bool       //HSCAN、ZSCAN每个元素由两个值组成
+	cursor string
+	page   []string
+	member string
+	value  string
+	err    error
+}
+
+func (c Client) Scan(match string, count int) *ScanIterator {
+	return newScanIterator(c.conn, "SCAN", "", false, match, count)
+}
+
+func (c Client) SScan(key, match string, count int) *ScanIterator {
+	return newScanIterator(c.conn, "SSCAN", key, false, match, count)
+}
+
+func (c Client) HScan(key, match string, count int) *ScanIterator {
+	return newScanIterator(c.conn, "HSCAN", key, true, match, count)
+}
+
+func (c Client) ZScan(key, match string, count int) *ScanIterator {
+	return newScanIterator(c.conn, "ZSCAN", key, true, match, count)
+}
+
+func newScanIterator(conn redis.Conn, cmd, key string, pairs bool, match string, count int) *ScanIterator {
+	it := &ScanIterator{conn: conn, cmd: cmd, key: key, pairs: pairs}
+	if match != "" {
+		it.args = it.args.Add("MATCH", match)
+	}
+	if count > 0 {
+		it.args = it.args.Add("COUNT", count)
+	}
+	return it
+}
+
+//Next 移动到下一个元素，当前批次读取完时请求下一批，没有更多的元素或者出错时返回false
+func (it *ScanIterator) Next() bool {
+	for it.err == nil {
+		if len(it.page) > 0 {
+			it.member, it.page = it.page[0], it.page[1:]
+			if it.pairs && len(it.page) > 0 {
+				it.value, it.page = it.page[0], it.page[1:]
+			}
+			return true
+		}
+		if it.cursor == "0" {
+			return false
+		}
+		it.fetch()
+	}
+	return false
+}
+
+//fetch 请求下一批元素，redis返回的游标为0时表示迭代结束，一批元素可能为空
+func (it *ScanIterator) fetch() {
+	cursor := it.cursor
+	if cursor == "" {
+		cursor = "0"
+	}
+	args := redis.Args{}
+	if it.key != "" {
+		args = args.Add(it.key)
+	}
+	args = append(args.Add(cursor), it.args...)
+	reply, err := redis.Values(it.conn.Do(it.cmd, args...))
+	if err == nil {
+		_, err = redis.Scan(reply, &it.cursor, &it.page)
+	}
+	it.err = err
+}
+
+//Member 当前元素：SCAN为key，SSCAN、ZSCAN为成员，HSCAN为字段
+func (it *ScanIterator) Member() string {
+	return it.member
+}
+
+//Value HSCAN为当前字段的值，ZSCAN为当前成员分数的字符串
+func (it *ScanIterator) Value() string {
+	return it.value
+}
+
+//Score ZSCAN当前成员的分数
+func (it *ScanIterator) Score() float64 {
+	score, _ := strconv.ParseFloat(it.value, 64)
+	return score
+}
+
+//Err 迭代过程中出现的错误
+func (it *ScanIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestClient_Scan(t *testing.T) {
+	client, _ := newClient(t)
+	for i := 0; i < 25; i++ {
+		_ = client.Set(fmt.Sprintf("user:%02d", i), i)
+	}
+	_ = client.Set("order:1", 1)
+	var keys []string
+	it := client.Scan("user:*", 10)
+	for it.Next() {
+		keys = append(keys, it.Member())
+	}
+	if err := it.Err(); err != nil || len(keys) != 25 {
+		t.Fatalf("Scan: %d keys, %v", len(keys), err)
+	}
+
+	_ = client.HmSet("user", map[string]interface{}{"name": "tom", "age": 18})
+	var fields []string
+	for it = client.HScan("user", "", 0); it.Next(); {
+		fields = append(fields, it.Member()+"="+it.Value())
+	}
+	sort.Strings(fields)
+	if strings.Join(fields, ",") != "age=18,name=tom" {
+		t.Fatalf("HScan: %v, %v", fields, it.Err())
+	}
+
+	_, _ = client.SAdd("tags", "go", "redis", "mysql")
+	var members []string
+	for it = client.SScan("tags", "*s*", 0); it.Next(); {
+		members = append(members, it.Member())
+	}
+	sort.Strings(members)
+	if strings.Join(members, ",") != "mysql,redis" {
+		t.Fatalf("SScan: %v, %v", members, it.Err())
+	}
+
+	_ = client.ZAdd("rank", 1.5, "a")
+	_ = client.ZAdd("rank", 2, "b")
+	var scores []string
+	for it = client.ZScan("rank", "", 0); it.Next(); {
+		scores = append(scores, fmt.Sprintf("%s:%v", it.Member(), it.Score()))
+	}
+	if strings.Join(scores, ",") != "a:1.5,b:2" {
+		t.Fatalf("ZScan: %v, %v", scores, it.Err())
+	}
+
+	_ = client.Set("string", "v")
+	if it = client.HScan("string", "", 0); it.Next() || it.Err() == nil {
+		t.Fatal("HScan on a string should fail")
+	}
+}
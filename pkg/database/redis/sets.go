@@ -0,0 +1,64 @@
+/**
+ * redis set类型的操作
+ */
+package redis
+
+import (
+	"github.com/gomodule/redigo/redis"
+)
+
+func (c Client) SAdd(key string, members ...interface{}) (int, error) {
+	return redis.Int(c.conn.Do("SADD", redis.Args{}.Add(key).Add(members...)...))
+}
+
+func (c Client) SRem(key string, members ...interface{}) (int, error) {
+	return redis.Int(c.conn.Do("SREM", redis.Args{}.Add(key).Add(members...)...))
+}
+
+func (c Client) SMembers(key string) ([]string, error) {
+	return redis.Strings(c.conn.Do("SMEMBERS", key))
+}
+
+func (c Client) SIsMember(key string, member interface{}) (bool, error) {
+	return redis.Bool(c.conn.Do("SISMEMBER", key, member))
+}
+
+func (c Client) SCard(key string) (int, error) {
+	return redis.Int(c.conn.Do("SCARD", key))
+}
+
+func (c Client) SPop(key string) (string, error) {
+	return redis.String(c.conn.Do("SPOP", key))
+}
+
+func (c Client) SRandMember(key string, count int) ([]string, error) {
+	return redis.Strings(c.conn.Do("SRANDMEMBER", key, count))
+}
+
+func (c Client) SMove(source, destination string, member interface{}) (bool, error) {
+	return redis.Bool(c.conn.Do("SMOVE", source, destination, member))
+}
+
+func (c Client) SInter(keys ...string) ([]string, error) {
+	return redis.Strings(c.conn.Do("SINTER", redis.Args{}.AddFlat(keys)...))
+}
+
+func (c Client) SUnion(keys ...string) ([]string, error) {
+	return redis.Strings(c.conn.Do("SUNION", redis.Args{}.AddFlat(keys)...))
+}
+
+func (c Client) SDiff(keys ...string) ([]string, error) {
+	return redis.Strings(c.conn.Do("SDIFF", redis.Args{}.AddFlat(keys)...))
+}
+
+func (c Client) SInterStore(destination string, keys ...string) (int, error) {
+	return redis.Int(c.conn.Do("SINTERSTORE", redis.Args{}.Add(destination).AddFlat(keys)...))
+}
+
+func (c Client) SUnionStore(destination string, keys ...string) (int, error) {
+	return redis.Int(c.conn.Do("SUNIONSTORE", redis.Args{}.Add(destination).AddFlat(keys)...))
+}
+
+func (c Client) SDiffStore(destination string, keys ...string) (int, error) {
+	return redis.Int(c.conn.Do("SDIFFSTORE", redis.Args{}.Add(destination).AddFlat(keys)...))
+}
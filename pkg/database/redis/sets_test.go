@@ -0,0 +1,62 @@
+package redis
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func sorted(values []string, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	sort.Strings(values)
+	return strings.Join(values, ",")
+}
+
+func TestClient_SAdd(t *testing.T) {
+	client, _ := newClient(t)
+	if n, err := client.SAdd("a", 1, 2, 3, 3); err != nil || n != 3 {
+		t.Fatalf("SAdd: %d, %v", n, err)
+	}
+	_, _ = client.SAdd("b", 2, 3, 4)
+	if ok, _ := client.SIsMember("a", 1); !ok {
+		t.Fatal("1 should be a member")
+	}
+	if got := sorted(client.SInter("a", "b")); got != "2,3" {
+		t.Fatalf("SInter: %s", got)
+	}
+	if got := sorted(client.SUnion("a", "b")); got != "1,2,3,4" {
+		t.Fatalf("SUnion: %s", got)
+	}
+	if got := sorted(client.SDiff("a", "b")); got != "1" {
+		t.Fatalf("SDiff: %s", got)
+	}
+	if n, _ := client.SInterStore("ab", "a", "b"); n != 2 {
+		t.Fatalf("SInterStore: %d", n)
+	}
+	if n, _ := client.SUnionStore("all", "a", "b"); n != 4 {
+		t.Fatalf("SUnionStore: %d", n)
+	}
+	if n, _ := client.SDiffStore("only", "a", "b"); n != 1 {
+		t.Fatalf("SDiffStore: %d", n)
+	}
+	if ok, _ := client.SMove("a", "b", 1); !ok {
+		t.Fatal("SMove should move the member")
+	}
+	if n, _ := client.SRem("b", 1, 4, 9); n != 2 {
+		t.Fatalf("SRem: %d", n)
+	}
+	if got := sorted(client.SMembers("b")); got != "2,3" {
+		t.Fatalf("SMembers: %s", got)
+	}
+	if members, _ := client.SRandMember("all", 2); len(members) != 2 {
+		t.Fatalf("SRandMember: %v", members)
+	}
+	if member, _ := client.SPop("only"); member != "1" {
+		t.Fatalf("SPop: %s", member)
+	}
+	if n, _ := client.SCard("only"); n != 0 {
+		t.Fatalf("SCard: %d", n)
+	}
+}
@@ -0,0 +1,411 @@
+/**
+ * Streams命令以及消费组：StreamConsumer通过XREADGROUP读取消息，handler返回nil时XACK确认，
+ * 处理失败的消息留在pending列表中，空闲超过minIdle之后通过XAUTOCLAIM重新投递，投递次数达到上限之后转入死信stream
+ */
+package redis
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/common/log"
+	"github.com/yinjk/go-utils/pkg/utils/syncs"
+	"github.com/yinjk/go-utils/pkg/utils/times"
+)
+
+const (
+	defaultStreamBatchSize     = 10
+	defaultStreamBlock         = 2 * time.Second
+	defaultStreamClaimIdle     = time.Minute
+	defaultStreamClaimInterval = 30 * time.Second
+	defaultStreamRetryInterval = time.Second
+)
+
+// StreamEntry is an entry of a stream.
+type StreamEntry struct {
+	ID     string
+	Values map[string]string
+}
+
+// PendingEntry is an entry delivered to a consumer of the group but not acknowledged yet.
+type PendingEntry struct {
+	ID         string
+	Consumer   string
+	Idle       time.Duration
+	Deliveries int64
+}
+
+/**
+ * 添加消息
+ * @param : stream stream的key
+ * @param : maxLen 大于0时使用MAXLEN ~ maxLen近似裁剪stream
+ * @param : values 消息的字段，map或者带有redis标签的结构体，同redis.Args.AddFlat
+ * @return: 消息的id
+ */
+func (c Client) XAdd(stream string, maxLen int64, values interface{}) (id string, err error) {
+	args := redis.Args{stream}
+	if maxLen > 0 {
+		args = args.Add("MAXLEN", "~", maxLen)
+	}
+	return redis.String(c.conn.Do("XADD", args.Add("*").AddFlat(values)...))
+}
+
+func (c Client) XLen(stream string) (length int64, err error) {
+	return redis.Int64(c.conn.Do("XLEN", stream))
+}
+
+//XRange 查询id在[start, end]之间的消息，-和+表示最小和最大的id，count小于等于0时不限制数量
+func (c Client) XRange(stream, start, end string, count int64) (entries []StreamEntry, err error) {
+	args := redis.Args{stream, start, end}
+	if count > 0 {
+		args = args.Add("COUNT", count)
+	}
+	return streamEntries(c.conn.Do("XRANGE", args...))
+}
+
+func (c Client) XDel(stream string, ids ...string) (deleted int64, err error) {
+	return redis.Int64(c.conn.Do("XDEL", redis.Args{stream}.AddFlat(ids)...))
+}
+
+//XGroupCreate 创建消费组，start为0时消费stream中已有的消息，为$时只消费之后添加的消息；stream不存在时自动创建
+func (c Client) XGroupCreate(stream, group, start string) (err error) {
+	_, err = c.conn.Do("XGROUP", "CREATE", stream, group, start, "MKSTREAM")
+	return err
+}
+
+/**
+ * 以消费组中consumer的身份读取新的消息
+ * @param : count 最多读取的消息数
+ * @param : block 没有新消息时阻塞等待的时间，小于等于0时不阻塞
+ * @return: 超时没有新消息时返回空的entries
+ */
+func (c Client) XReadGroup(group, consumer, stream string, count int64, block time.Duration) (entries []StreamEntry, err error) {
+	args := redis.Args{"GROUP", group, consumer, "COUNT", count}
+	if block > 0 {
+		args = args.Add("BLOCK", block.Milliseconds())
+	}
+	streams, err := redis.Values(c.conn.Do("XREADGROUP", args.Add("STREAMS", stream, ">")...))
+	if err == ErrNil || len(streams) == 0 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	//回复的格式为：[[stream [entry1 entry2 ...]]]
+	reply, err := redis.Values(streams[0], nil)
+	if err != nil || len(reply) != 2 {
+		return nil, err
+	}
+	return streamEntries(reply[1], nil)
+}
+
+func (c Client) XAck(stream, group string, ids ...string) (acked int64, err error) {
+	return redis.Int64(c.conn.Do("XACK", redis.Args{stream, group}.AddFlat(ids)...))
+}
+
+//XPending 查询消费组中空闲时间不小于minIdle的待确认消息，最多count条
+func (c Client) XPending(stream, group string, minIdle time.Duration, count int64) (entries []PendingEntry, err error) {
+	args := redis.Args{stream, group}
+	if minIdle > 0 {
+		args = args.Add("IDLE", minIdle.Milliseconds())
+	}
+	values, err := redis.Values(c.conn.Do("XPENDING", args.Add("-", "+", count)...))
+	if err == ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries = make([]PendingEntry, 0, len(values))
+	for _, value := range values {
+		//每条消息的格式为：[id consumer idle deliveries]
+		var (
+			entry PendingEntry
+			idle  int64
+		)
+		fields, err := redis.Values(value, nil)
+		if err == nil {
+			_, err = redis.Scan(fields, &entry.ID, &entry.Consumer, &idle, &entry.Deliveries)
+		}
+		if err != nil {
+			return nil, err
+		}
+		entry.Idle = time.Duration(idle) * time.Millisecond
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+/**
+ * 将消费组中空闲时间不小于minIdle的待确认消息转移给consumer，转移的消息投递次数加1
+ * @param : start 从start开始扫描pending列表，第一次扫描使用0-0
+ * @param : count 最多转移的消息数
+ * @return: next 下一次扫描的start，0-0表示扫描完成
+ * @return: entries 转移的消息
+ */
+func (c Client) XAutoClaim(stream, group, consumer string, minIdle time.Duration, start string, count int64) (next string, entries []StreamEntry, err error) {
+	reply, err := redis.Values(c.conn.Do("XAUTOCLAIM", stream, group, consumer, minIdle.Milliseconds(), start, "COUNT", count))
+	if err != nil {
+		return "", nil, err
+	}
+	if len(reply) < 2 {
+		return "", nil, redis.Error("redis: unexpected XAUTOCLAIM reply")
+	}
+	if next, err = redis.String(reply[0], nil); err != nil {
+		return "", nil, err
+	}
+	entries, err = streamEntries(reply[1], nil)
+	return next, entries, err
+}
+
+//streamEntries 解析消息列表，每条消息的格式为：[id [field1 value1 field2 value2 ...]]
+func streamEntries(reply interface{}, err error) ([]StreamEntry, error) {
+	values, err := redis.Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]StreamEntry, 0, len(values))
+	for _, value := range values {
+		fields, err := redis.Values(value, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) != 2 {
+			return nil, redis.Error("redis: unexpected stream entry")
+		}
+		var entry StreamEntry
+		if entry.ID, err = redis.String(fields[0], nil); err != nil {
+			return nil, err
+		}
+		entry.Values = make(map[string]string)
+		if fields[1] != nil {
+			if entry.Values, err = redis.StringMap(fields[1], nil); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// StreamHandler handles an entry of a stream, the entry is acknowledged when it returns nil.
+type StreamHandler func(entry *StreamEntry) error
+
+// StreamConsumer consumes a stream as a consumer of a consumer group.
+type StreamConsumer struct {
+	pool     *Pool
+	executor *syncs.TaskExecutor
+	stream   string
+	group    string
+	consumer string
+	handler  StreamHandler
+
+	batchSize     int64
+	block         time.Duration
+	claimIdle     time.Duration
+	claimInterval time.Duration
+	maxDeliveries int64
+	deadLetter    string
+	lastClaim     time.Time
+	clock         times.Clock
+}
+
+// ConsumerOption configures a StreamConsumer.
+type ConsumerOption func(c *StreamConsumer)
+
+//ConsumerBatchSize 每次最多读取的消息数，默认10
+func ConsumerBatchSize(size int64) ConsumerOption {
+	return func(c *StreamConsumer) {
+		c.batchSize = size
+	}
+}
+
+//ConsumerBlock 没有新消息时阻塞等待的时间，默认2秒，Run在ctx结束之后最多等待block返回
+func ConsumerBlock(block time.Duration) ConsumerOption {
+	return func(c *StreamConsumer) {
+		c.block = block
+	}
+}
+
+//ConsumerClaim 每隔interval检查一次pending列表，重新投递空闲时间超过minIdle的消息，默认1分钟以及30秒
+func ConsumerClaim(minIdle, interval time.Duration) ConsumerOption {
+	return func(c *StreamConsumer) {
+		c.claimIdle, c.claimInterval = minIdle, interval
+	}
+}
+
+//ConsumerDeadLetter 投递maxDeliveries次之后仍然没有确认的消息转入死信stream，stream为空时使用<stream>:dead
+func ConsumerDeadLetter(maxDeliveries int64, stream string) ConsumerOption {
+	return func(c *StreamConsumer) {
+		c.maxDeliveries, c.deadLetter = maxDeliveries, stream
+	}
+}
+
+//ConsumerClock 重新投递检查以及出错重试使用的时钟，默认为times.SystemClock，测试中可以使用times.FakeClock
+func ConsumerClock(clock times.Clock) ConsumerOption {
+	return func(c *StreamConsumer) {
+		c.clock = clock
+	}
+}
+
+/**
+ * 创建消费者
+ * @param : pool 连接池
+ * @param : executor 执行handler的协程池，为nil时依次执行；等待队列已满时在消费协程中执行，不使用executor的拒绝策略
+ * @param : stream、group、consumer stream、消费组以及消费者的名字，消费组不存在时自动创建并消费stream中已有的消息
+ * @param : handler 消息的处理方法
+ * @param : opts 批量大小、重新投递、死信以及时钟选项
+ * @return: 消费者，调用Run开始消费
+ */
+func NewStreamConsumer(pool *Pool, executor *syncs.TaskExecutor, stream, group, consumer string, handler StreamHandler, opts ...ConsumerOption) *StreamConsumer {
+	c := &StreamConsumer{
+		pool:          pool,
+		executor:      executor,
+		stream:        stream,
+		group:         group,
+		consumer:      consumer,
+		handler:       handler,
+		batchSize:     defaultStreamBatchSize,
+		block:         defaultStreamBlock,
+		claimIdle:     defaultStreamClaimIdle,
+		claimInterval: defaultStreamClaimInterval,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.maxDeliveries > 0 && c.deadLetter == "" {
+		c.deadLetter = stream + ":dead"
+	}
+	c.clock = times.ClockOrDefault(c.clock)
+	return c
+}
+
+//Run 持续消费直到ctx结束，返回ctx.Err()；读取或者确认出错时记录日志并在1秒后重试
+func (c *StreamConsumer) Run(ctx context.Context) error {
+	for {
+		if err := c.Poll(); err != nil && ctx.Err() == nil {
+			log.Errorf("redis: consume stream %s error: %v", c.stream, err)
+			select {
+			case <-ctx.Done():
+			case <-c.clock.After(defaultStreamRetryInterval):
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+//Poll 消费一次：到达检查间隔时先处理pending列表，然后读取并处理一批新消息
+func (c *StreamConsumer) Poll() error {
+	client, err := c.pool.Get()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if c.lastClaim.IsZero() {
+		if err = client.XGroupCreate(c.stream, c.group, "0"); err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return err
+		}
+		c.lastClaim = c.clock.Now()
+	}
+	if c.clock.Since(c.lastClaim) >= c.claimInterval {
+		if err = c.reclaim(client); err != nil {
+			return err
+		}
+		c.lastClaim = c.clock.Now()
+	}
+	entries, err := client.XReadGroup(c.group, c.consumer, c.stream, c.batchSize, c.block)
+	if err != nil {
+		return err
+	}
+	return c.process(client, entries)
+}
+
+//reclaim 将投递次数达到上限的消息转入死信stream，然后重新投递其余空闲的消息
+func (c *StreamConsumer) reclaim(client *Client) error {
+	if c.maxDeliveries > 0 {
+		pending, err := client.XPending(c.stream, c.group, c.claimIdle, c.batchSize)
+		if err != nil {
+			return err
+		}
+		for _, p := range pending {
+			if p.Deliveries < c.maxDeliveries {
+				continue
+			}
+			if err = c.kill(client, p); err != nil {
+				return err
+			}
+		}
+	}
+	_, entries, err := client.XAutoClaim(c.stream, c.group, c.consumer, c.claimIdle, "0-0", c.batchSize)
+	if err != nil {
+		return err
+	}
+	return c.process(client, entries)
+}
+
+//kill 将消息以及来源复制到死信stream并确认，消息已经被删除时直接确认
+func (c *StreamConsumer) kill(client *Client, p PendingEntry) error {
+	entries, err := client.XRange(c.stream, p.ID, p.ID, 1)
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		values := entries[0].Values
+		values["dead_letter_stream"] = c.stream
+		values["dead_letter_id"] = p.ID
+		values["dead_letter_deliveries"] = strconv.FormatInt(p.Deliveries, 10)
+		if _, err = client.XAdd(c.deadLetter, 0, values); err != nil {
+			return err
+		}
+		log.Warnf("redis: entry %s of stream %s is dead-lettered after %d deliveries", p.ID, c.stream, p.Deliveries)
+	}
+	_, err = client.XAck(c.stream, c.group, p.ID)
+	return err
+}
+
+//process 在executor中并发处理一批消息，等待全部处理完成后确认处理成功的消息
+func (c *StreamConsumer) process(client *Client, entries []StreamEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		acked []string
+	)
+	for i := range entries {
+		entry := &entries[i]
+		wg.Add(1)
+		run := func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					log.Errorf("redis: handler of stream %s panic on entry %s: %v", c.stream, entry.ID, r)
+				}
+			}()
+			if err := c.handler(entry); err != nil {
+				log.Errorf("redis: handle entry %s of stream %s error: %v", entry.ID, c.stream, err)
+				return
+			}
+			mu.Lock()
+			acked = append(acked, entry.ID)
+			mu.Unlock()
+		}
+		//executor已满时在当前协程中处理，拒绝策略可能丢弃任务，丢弃之后wg永远不会结束
+		if c.executor == nil || !c.executor.TryExecute(run) {
+			run()
+		}
+	}
+	wg.Wait()
+	if len(acked) == 0 {
+		return nil
+	}
+	_, err := client.XAck(c.stream, c.group, acked...)
+	return err
+}
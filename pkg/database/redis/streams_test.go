@@ -0,0 +1,184 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/yinjk/go-utils/pkg/utils/syncs"
+	"github.com/yinjk/go-utils/pkg/utils/times"
+)
+
+func TestClient_XReadGroup(t *testing.T) {
+	client, _ := newClient(t)
+	if err := client.XGroupCreate("orders", "billing", "$"); err != nil {
+		t.Fatal(err)
+	}
+	first, err := client.XAdd("orders", 0, map[string]interface{}{"id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = client.XAdd("orders", 100, struct {
+		ID int `redis:"id"`
+	}{2})
+	if n, _ := client.XLen("orders"); n != 2 {
+		t.Fatalf("XLen: %d", n)
+	}
+	entries, err := client.XReadGroup("billing", "c1", "orders", 10, 0)
+	if err != nil || len(entries) != 2 || entries[0].ID != first || entries[1].Values["id"] != "2" {
+		t.Fatalf("XReadGroup: %+v, %v", entries, err)
+	}
+	if entries, err = client.XReadGroup("billing", "c1", "orders", 10, 10*time.Millisecond); err != nil || len(entries) != 0 {
+		t.Fatalf("XReadGroup without new entries: %+v, %v", entries, err)
+	}
+	if n, _ := client.XAck("orders", "billing", first); n != 1 {
+		t.Fatalf("XAck: %d", n)
+	}
+	pending, err := client.XPending("orders", "billing", 0, 10)
+	if err != nil || len(pending) != 1 || pending[0].Consumer != "c1" || pending[0].Deliveries != 1 {
+		t.Fatalf("XPending: %+v, %v", pending, err)
+	}
+	next, claimed, err := client.XAutoClaim("orders", "billing", "c2", 0, "0-0", 10)
+	if err != nil || next != "0-0" || len(claimed) != 1 || claimed[0].ID != pending[0].ID {
+		t.Fatalf("XAutoClaim: %s %+v, %v", next, claimed, err)
+	}
+	if pending, _ = client.XPending("orders", "billing", 0, 10); pending[0].Consumer != "c2" || pending[0].Deliveries != 2 {
+		t.Fatalf("XAutoClaim should transfer the entry: %+v", pending)
+	}
+	if entries, _ = client.XRange("orders", "-", "+", 1); len(entries) != 1 || entries[0].ID != first {
+		t.Fatalf("XRange: %+v", entries)
+	}
+	if n, _ := client.XDel("orders", first); n != 1 {
+		t.Fatalf("XDel: %d", n)
+	}
+}
+
+func TestStreamConsumer_Run(t *testing.T) {
+	mr := miniredis.RunT(t)
+	pool := newPool(t, mr)
+	client, _ := pool.Get()
+	defer client.Close()
+	for _, job := range []string{"ok", "flaky", "poison"} {
+		_, _ = client.XAdd("jobs", 0, map[string]string{"job": job})
+	}
+	executor := syncs.NewTaskExecutor(2, 10, nil)
+	defer executor.Shutdown()
+
+	var (
+		mu       sync.Mutex
+		attempts = make(map[string]int)
+	)
+	handler := func(entry *StreamEntry) error {
+		mu.Lock()
+		defer mu.Unlock()
+		job := entry.Values["job"]
+		attempts[job]++
+		switch {
+		case job == "flaky" && attempts[job] < 2:
+			return errors.New("temporary failure")
+		case job == "poison":
+			panic("cannot handle")
+		}
+		return nil
+	}
+	attemptsOf := func(job string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return attempts[job]
+	}
+	clock := times.NewFakeClock(time.Time{})
+	consumer := NewStreamConsumer(pool, executor, "jobs", "workers", "w1", handler,
+		ConsumerBlock(10*time.Millisecond), ConsumerClaim(0, time.Minute), ConsumerDeadLetter(3, ""), ConsumerClock(clock))
+
+	if err := consumer.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	//未到检查间隔时不会重新投递
+	if err := consumer.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if attemptsOf("flaky") != 1 || attemptsOf("poison") != 1 {
+		t.Fatalf("entries should not be redelivered before the claim interval: %v", attempts)
+	}
+	//每个检查间隔重新投递一次，第三次投递之后转入死信stream
+	for i := 0; i < 3; i++ {
+		clock.Advance(time.Minute)
+		if err := consumer.Poll(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if attemptsOf("ok") != 1 || attemptsOf("flaky") != 2 || attemptsOf("poison") != 3 {
+		t.Fatalf("unexpected attempts %v", attempts)
+	}
+	if pending, _ := client.XPending("jobs", "workers", 0, 10); len(pending) != 0 {
+		t.Fatalf("all the entries should be acknowledged or dead-lettered: %+v", pending)
+	}
+	dead, _ := client.XRange("jobs:dead", "-", "+", 0)
+	if len(dead) != 1 || dead[0].Values["job"] != "poison" || dead[0].Values["dead_letter_deliveries"] != "3" ||
+		dead[0].Values["dead_letter_stream"] != "jobs" {
+		t.Fatalf("unexpected dead letter %+v", dead)
+	}
+
+	//出错之后在重试间隔之后继续消费
+	mr.Set("orders", "not a stream")
+	retrying := NewStreamConsumer(pool, nil, "orders", "workers", "w1", handler, ConsumerBlock(10*time.Millisecond), ConsumerClock(clock))
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- retrying.Run(ctx) }()
+	clock.BlockUntil(1)
+	mr.Del("orders")
+	_, _ = client.XAdd("orders", 0, map[string]string{"job": "late"})
+	clock.Advance(defaultStreamRetryInterval)
+	waitFor(t, "the consumer to retry", func() bool { return attemptsOf("late") == 1 })
+	cancel()
+	if err := <-stopped; err != context.Canceled {
+		t.Fatalf("Run should return ctx.Err(), got %v", err)
+	}
+}
+
+func TestStreamConsumer_ExecutorFull(t *testing.T) {
+	mr := miniredis.RunT(t)
+	pool := newPool(t, mr)
+	client, _ := pool.Get()
+	defer client.Close()
+	for i := 0; i < 10; i++ {
+		_, _ = client.XAdd("jobs", 0, map[string]string{"job": "sleep"})
+	}
+	//拒绝策略直接丢弃任务，唯一的协程以及等待队列都被占满
+	executor := syncs.NewTaskExecutor(1, 1, func(f func()) {})
+	defer executor.Shutdown()
+	release, busy := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	executor.Execute(func() {
+		close(busy)
+		<-release
+	})
+	<-busy
+	executor.Execute(func() { <-release })
+	var handled int32
+	consumer := NewStreamConsumer(pool, executor, "jobs", "workers", "w1", func(entry *StreamEntry) error {
+		atomic.AddInt32(&handled, 1)
+		return nil
+	}, ConsumerBlock(10*time.Millisecond))
+
+	done := make(chan error, 1)
+	go func() { done <- consumer.Poll() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Poll should not hang when the executor rejects tasks")
+	}
+	if n := atomic.LoadInt32(&handled); n != 10 {
+		t.Fatalf("all the entries should be handled, got %d", n)
+	}
+	if pending, _ := client.XPending("jobs", "workers", 0, 20); len(pending) != 0 {
+		t.Fatalf("all the entries should be acknowledged: %+v", pending)
+	}
+}
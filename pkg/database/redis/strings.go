@@ -0,0 +1,123 @@
+/**
+ * redis string类型的操作：带选项的SET、批量读写、自增自减
+ */
+package redis
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// SetOption is an option of SetWithOptions.
+type SetOption func(args redis.Args) redis.Args
+
+//ExpireAfter 设置过期时间，整秒时使用EX，否则使用PX
+func ExpireAfter(ttl time.Duration) SetOption {
+	return func(args redis.Args) redis.Args {
+		if ttl%time.Second == 0 {
+			return args.Add("EX", int64(ttl/time.Second))
+		}
+		return args.Add("PX", ttl.Milliseconds())
+	}
+}
+
+//IfNotExists key不存在时才设置（NX）
+func IfNotExists() SetOption {
+	return func(args redis.Args) redis.Args {
+		return args.Add("NX")
+	}
+}
+
+//IfExists key存在时才设置（XX）
+func IfExists() SetOption {
+	return func(args redis.Args) redis.Args {
+		return args.Add("XX")
+	}
+}
+
+//KeepTTL 保留key原来的过期时间（KEEPTTL），需要redis 6.0以上
+func KeepTTL() SetOption {
+	return func(args redis.Args) redis.Args {
+		return args.Add("KEEPTTL")
+	}
+}
+
+func (c Client) GetInt64(key string) (int64, error) {
+	return redis.Int64(c.conn.Do("GET", key))
+}
+
+func (c Client) GetFloat64(key string) (float64, error) {
+	return redis.Float64(c.conn.Do("GET", key))
+}
+
+func (c Client) GetBytes(key string) ([]byte, error) {
+	return redis.Bytes(c.conn.Do("GET", key))
+}
+
+func (c Client) GetSet(key string, value interface{}) (string, error) {
+	return redis.String(c.conn.Do("GETSET", key, value))
+}
+
+func (c Client) GetDel(key string) (string, error) {
+	return redis.String(c.conn.Do("GETDEL", key))
+}
+
+func (c Client) MGet(keys ...string) ([]string, error) {
+	return redis.Strings(c.conn.Do("MGET", redis.Args{}.AddFlat(keys)...))
+}
+
+//SetWithOptions NX、XX的条件不满足时redis返回nil
+func (c Client) SetWithOptions(key, value interface{}, opts ...SetOption) (bool, error) {
+	args := redis.Args{}.Add(key, value)
+	for _, opt := range opts {
+		args = opt(args)
+	}
+	reply, err := c.conn.Do("SET", args...)
+	if err != nil {
+		return false, err
+	}
+	return reply != nil, nil
+}
+
+func (c Client) SetNX(key, value interface{}) (bool, error) {
+	return redis.Bool(c.conn.Do("SETNX", key, value))
+}
+
+func (c Client) SetEX(key, value interface{}, ttl time.Duration) error {
+	_, err := c.SetWithOptions(key, value, ExpireAfter(ttl))
+	return err
+}
+
+func (c Client) MSet(pairs map[string]interface{}) error {
+	_, err := c.conn.Do("MSET", redis.Args{}.AddFlat(pairs)...)
+	return err
+}
+
+func (c Client) Incr(key string) (int64, error) {
+	return redis.Int64(c.conn.Do("INCR", key))
+}
+
+func (c Client) IncrBy(key string, n int64) (int64, error) {
+	return redis.Int64(c.conn.Do("INCRBY", key, n))
+}
+
+func (c Client) IncrByFloat(key string, f float64) (float64, error) {
+	return redis.Float64(c.conn.Do("INCRBYFLOAT", key, f))
+}
+
+func (c Client) Decr(key string) (int64, error) {
+	return redis.Int64(c.conn.Do("DECR", key))
+}
+
+func (c Client) DecrBy(key string, n int64) (int64, error) {
+	return redis.Int64(c.conn.Do("DECRBY", key, n))
+}
+
+func (c Client) Append(key string, value interface{}) (int, error) {
+	return redis.Int(c.conn.Do("APPEND", key, value))
+}
+
+func (c Client) StrLen(key string) (int, error) {
+	return redis.Int(c.conn.Do("STRLEN", key))
+}
@@ -0,0 +1,90 @@
+package redis
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClient_SetWithOptions(t *testing.T) {
+	client, mr := newClient(t)
+	if ok, err := client.SetWithOptions("lock", "a", IfNotExists(), ExpireAfter(10*time.Second)); err != nil || !ok {
+		t.Fatalf("SET NX EX: %v, %v", ok, err)
+	}
+	if ok, err := client.SetWithOptions("lock", "b", IfNotExists()); err != nil || ok {
+		t.Fatalf("SET NX on an existing key should fail: %v, %v", ok, err)
+	}
+	if mr.TTL("lock") != 10*time.Second {
+		t.Fatalf("unexpected ttl %v", mr.TTL("lock"))
+	}
+	if ok, _ := client.SetWithOptions("missing", "b", IfExists()); ok {
+		t.Fatal("SET XX on a missing key should fail")
+	}
+	if ok, _ := client.SetWithOptions("lock", "c", IfExists(), ExpireAfter(1500*time.Millisecond)); !ok || mr.TTL("lock") != 1500*time.Millisecond {
+		t.Fatalf("SET XX PX: %v", mr.TTL("lock"))
+	}
+	if ok, _ := client.SetNX("lock", "d"); ok {
+		t.Fatal("SETNX on an existing key should fail")
+	}
+	if err := client.SetEX("session", "s", time.Minute); err != nil || mr.TTL("session") != time.Minute {
+		t.Fatalf("SetEX: %v, %v", mr.TTL("session"), err)
+	}
+	if value, _ := client.GetString("lock"); value != "c" {
+		t.Fatalf("unexpected value %s", value)
+	}
+	if _, err := client.GetString("missing"); err != ErrNil {
+		t.Fatalf("missing key should return ErrNil, got %v", err)
+	}
+}
+
+func TestClient_Incr(t *testing.T) {
+	client, _ := newClient(t)
+	if n, err := client.Incr("counter"); err != nil || n != 1 {
+		t.Fatalf("Incr: %d, %v", n, err)
+	}
+	if n, _ := client.IncrBy("counter", 10); n != 11 {
+		t.Fatalf("IncrBy: %d", n)
+	}
+	if n, _ := client.DecrBy("counter", 3); n != 8 {
+		t.Fatalf("DecrBy: %d", n)
+	}
+	if n, _ := client.Decr("counter"); n != 7 {
+		t.Fatalf("Decr: %d", n)
+	}
+	if n, _ := client.GetInt64("counter"); n != 7 {
+		t.Fatalf("GetInt64: %d", n)
+	}
+	if f, err := client.IncrByFloat("price", 1.5); err != nil || f != 1.5 {
+		t.Fatalf("IncrByFloat: %v, %v", f, err)
+	}
+	if f, _ := client.GetFloat64("price"); f != 1.5 {
+		t.Fatalf("GetFloat64: %v", f)
+	}
+}
+
+func TestClient_MSet(t *testing.T) {
+	client, _ := newClient(t)
+	if err := client.MSet(map[string]interface{}{"a": 1, "b": "two"}); err != nil {
+		t.Fatal(err)
+	}
+	if values, err := client.MGet("a", "missing", "b"); err != nil || len(values) != 3 || values[0] != "1" || values[1] != "" || values[2] != "two" {
+		t.Fatalf("MGet: %q, %v", values, err)
+	}
+	if n, _ := client.Append("b", "-three"); n != 9 {
+		t.Fatalf("Append: %d", n)
+	}
+	if n, _ := client.StrLen("b"); n != 9 {
+		t.Fatalf("StrLen: %d", n)
+	}
+	if old, _ := client.GetSet("b", "new"); old != "two-three" {
+		t.Fatalf("GetSet: %s", old)
+	}
+	if data, _ := client.GetBytes("b"); string(data) != "new" {
+		t.Fatalf("GetBytes: %s", data)
+	}
+	if value, _ := client.GetDel("b"); value != "new" {
+		t.Fatalf("GetDel: %s", value)
+	}
+	if exists, _ := client.Exists("b"); exists {
+		t.Fatal("GetDel should delete the key")
+	}
+}
@@ -0,0 +1,56 @@
+/**
+ * redis sorted set类型的操作，分数使用float64
+ */
+package redis
+
+import (
+	"github.com/gomodule/redigo/redis"
+)
+
+func (c Client) ZAddValues(key string, values ...ZSetValue) (int, error) {
+	args := redis.Args{}.Add(key)
+	for _, v := range values {
+		args = args.Add(v.Score, v.Value)
+	}
+	return redis.Int(c.conn.Do("ZADD", args...))
+}
+
+func (c Client) ZCount(key, min, max string) (int, error) {
+	return redis.Int(c.conn.Do("ZCOUNT", key, min, max))
+}
+
+func (c Client) ZIncrBy(key string, increment float64, member interface{}) (float64, error) {
+	return redis.Float64(c.conn.Do("ZINCRBY", key, increment, member))
+}
+
+func (c Client) ZRank(key string, member interface{}) (int, error) {
+	return redis.Int(c.conn.Do("ZRANK", key, member))
+}
+
+func (c Client) ZRevRank(key string, member interface{}) (int, error) {
+	return redis.Int(c.conn.Do("ZREVRANK", key, member))
+}
+
+func (c Client) ZRemRangeByRank(key string, start, stop int) (int, error) {
+	return redis.Int(c.conn.Do("ZREMRANGEBYRANK", key, start, stop))
+}
+
+func (c Client) ZRemRangeByScore(key, min, max string) (int, error) {
+	return redis.Int(c.conn.Do("ZREMRANGEBYSCORE", key, min, max))
+}
+
+func (c Client) ZRangeByScore(key, min, max string, offset, count int) ([]ZSetValue, error) {
+	return zSetValues(c.conn.Do("ZRANGEBYSCORE", key, min, max, "WITHSCORES", "LIMIT", offset, count))
+}
+
+func (c Client) ZRevRangeByScore(key, max, min string, offset, count int) ([]ZSetValue, error) {
+	return zSetValues(c.conn.Do("ZREVRANGEBYSCORE", key, max, min, "WITHSCORES", "LIMIT", offset, count))
+}
+
+func (c Client) ZPopMin(key string, count int) ([]ZSetValue, error) {
+	return zSetValues(c.conn.Do("ZPOPMIN", key, count))
+}
+
+func (c Client) ZPopMax(key string, count int) ([]ZSetValue, error) {
+	return zSetValues(c.conn.Do("ZPOPMAX", key, count))
+}
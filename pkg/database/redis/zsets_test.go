@@ -0,0 +1,52 @@
+package redis
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestClient_ZRangeByScore(t *testing.T) {
+	client, _ := newClient(t)
+	n, err := client.ZAddValues("rank", ZSetValue{Score: 1.5, Value: "a"}, ZSetValue{Score: 2, Value: "b"},
+		ZSetValue{Score: 3.25, Value: "c"}, ZSetValue{Score: -1, Value: "d"})
+	if err != nil || n != 4 {
+		t.Fatalf("ZAddValues: %d, %v", n, err)
+	}
+	if values, err := client.ZRangeByScore("rank", "(1.5", "+inf", 0, -1); err != nil || fmt.Sprint(values) != "[{2 b} {3.25 c}]" {
+		t.Fatalf("ZRangeByScore: %v, %v", values, err)
+	}
+	if values, _ := client.ZRevRangeByScore("rank", "+inf", "-inf", 1, 2); fmt.Sprint(values) != "[{2 b} {1.5 a}]" {
+		t.Fatalf("ZRevRangeByScore: %v", values)
+	}
+	if n, _ := client.ZCount("rank", "0", "3"); n != 2 {
+		t.Fatalf("ZCount: %d", n)
+	}
+	if score, _ := client.ZIncrBy("rank", 0.5, "a"); score != 2 {
+		t.Fatalf("ZIncrBy: %v", score)
+	}
+	if rank, _ := client.ZRank("rank", "c"); rank != 3 {
+		t.Fatalf("ZRank: %d", rank)
+	}
+	if rank, _ := client.ZRevRank("rank", "c"); rank != 0 {
+		t.Fatalf("ZRevRank: %d", rank)
+	}
+	if _, err := client.ZRank("rank", "missing"); err != ErrNil {
+		t.Fatalf("ZRank of a missing member should return ErrNil, got %v", err)
+	}
+	if values, _ := client.ZPopMin("rank", 1); fmt.Sprint(values) != "[{-1 d}]" {
+		t.Fatalf("ZPopMin: %v", values)
+	}
+	if values, _ := client.ZPopMax("rank", 1); fmt.Sprint(values) != "[{3.25 c}]" {
+		t.Fatalf("ZPopMax: %v", values)
+	}
+	_ = client.ZAdd("rank", 10, "e")
+	if n, _ := client.ZRemRangeByScore("rank", "5", "+inf"); n != 1 {
+		t.Fatalf("ZRemRangeByScore: %d", n)
+	}
+	if n, _ := client.ZRemRangeByRank("rank", 0, 0); n != 1 {
+		t.Fatalf("ZRemRangeByRank: %d", n)
+	}
+	if card, _ := client.ZCard("rank"); card != 1 {
+		t.Fatalf("ZCard: %d", card)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/prometheus/common/model"
+	"github.com/yinjk/go-utils/pkg/utils/times"
 	"time"
 )
 
@@ -67,6 +68,18 @@ type Range struct {
 	Step time.Duration
 }
 
+// NewRange returns a Range whose boundaries are aligned to the step, Start is aligned backward and End forward,
+// so the range covers [start, end] and the samples land on the same timestamps as prometheus would evaluate them.
+func NewRange(start, end time.Time, step time.Duration) Range {
+	return Range{Start: times.AlignStep(start, step), End: times.AlignStepCeil(end, step), Step: step}
+}
+
+// LastRange returns the aligned Range of the last duration d ending now, the optional clock defaults to times.SystemClock.
+func LastRange(d, step time.Duration, clock ...times.Clock) Range {
+	now := times.ClockOrDefault(clock...).Now()
+	return NewRange(now.Add(-d), now, step)
+}
+
 const (
 	promSuccess = "success"
 	PromError   = "error"
@@ -11,6 +11,8 @@ package maps
 import (
 	"sync"
 	"time"
+
+	"github.com/yinjk/go-utils/pkg/utils/times"
 )
 
 type expireCache struct {
@@ -21,9 +23,9 @@ type expireCache struct {
 	Data interface{}
 }
 
-func newExpireCache(value interface{}, ttl time.Duration) expireCache {
+func newExpireCache(value interface{}, ttl time.Duration, now time.Time) expireCache {
 	expireData := expireCache{}
-	expireData.createTime = time.Now()
+	expireData.createTime = now
 	expireData.ttl = ttl
 	expireData.Data = value
 	return expireData
@@ -31,17 +33,18 @@ func newExpireCache(value interface{}, ttl time.Duration) expireCache {
 
 type ExpireCache struct {
 	sync.Mutex
-	data map[string]*expireCache
-	back bool
-	size int
+	data  map[string]*expireCache
+	back  bool
+	size  int
+	clock times.Clock
 }
 
-func NewExpireCache(b ...bool) *ExpireCache {
+//NewExpireCache 创建cache，back为true时会开启后台协程定时清理过期数据，clock可选，用于判断过期的时钟，默认为SystemClock
+func NewExpireCache(back bool, clock ...times.Clock) *ExpireCache {
 	em := &ExpireCache{
-		data: make(map[string]*expireCache),
-	}
-	if b != nil && len(b) > 0 {
-		em.back = b[0]
+		data:  make(map[string]*expireCache),
+		back:  back,
+		clock: times.ClockOrDefault(clock...),
 	}
 	if em.back {
 		go em.background()
@@ -50,10 +53,10 @@ func NewExpireCache(b ...bool) *ExpireCache {
 }
 
 func (em *ExpireCache) background() {
-	ticker := time.NewTicker(time.Second * 10)
+	ticker := em.clock.NewTicker(time.Second * 10)
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
 			em.clear()
 		}
 	}
@@ -64,10 +67,13 @@ func (em *ExpireCache) clear() {
 	})
 }
 func (em *ExpireCache) ForEach(test func(key string, data interface{})) {
-	if em.size == 0 {
-		return
+	em.Lock()
+	keys := make([]string, 0, len(em.data))
+	for key := range em.data {
+		keys = append(keys, key)
 	}
-	for key, _ := range em.data {
+	em.Unlock()
+	for _, key := range keys {
 		value := em.Get(key)
 		if value != nil {
 			test(key, value)
@@ -83,7 +89,7 @@ func (em *ExpireCache) GetAndFlush(key string) interface{} {
 	if expireData == nil {
 		return nil
 	}
-	now := time.Now()
+	now := em.clock.Now()
 	if expireData.ttl <= 0 {
 		expireData.createTime = now
 		return expireData.Data
@@ -108,7 +114,7 @@ func (em *ExpireCache) Get(key string) interface{} {
 	if expireData.ttl <= 0 {
 		return expireData.Data
 	}
-	if expireData.createTime.Add(expireData.ttl).Before(time.Now()) {
+	if expireData.createTime.Add(expireData.ttl).Before(em.clock.Now()) {
 		delete(em.data, key)
 		em.size--
 		return nil
@@ -127,7 +133,7 @@ func (em *ExpireCache) Put(key string, value interface{}, duration ...time.Durat
 	if len(duration) > 0 {
 		ttl = duration[0]
 	}
-	expireData := newExpireCache(value, ttl)
+	expireData := newExpireCache(value, ttl, em.clock.Now())
 	em.data[key] = &expireData
 	em.size++
 }
@@ -147,7 +153,7 @@ func (em *ExpireCache) Update(key string, value interface{}, duration ...time.Du
 	}
 	expireData.Data = value
 	if ttl != 0 {
-		expireData.createTime = time.Now()
+		expireData.createTime = em.clock.Now()
 		expireData.ttl = ttl
 	}
 	em.data[key] = expireData
@@ -172,18 +178,18 @@ func (em *ExpireCache) TTL(key string) time.Duration {
 	if expireData == nil {
 		return 0
 	}
-	ttl := time.Now().Sub(expireData.createTime)
+	ttl := em.clock.Now().Sub(expireData.createTime)
 	return expireData.ttl - ttl
 }
 
 //Size the cached data count
 func (em *ExpireCache) Size() int {
-	if em.back {
-		return em.size
-	} else {
+	if !em.back {
 		em.clear()
-		return em.size
 	}
+	em.Lock()
+	defer em.Unlock()
+	return em.size
 }
 
 //移除掉对应key的数据
@@ -204,7 +210,7 @@ func (em *ExpireCache) Remove(key string) interface{} {
 
 //回收过期key，这里进行条件触发删除
 func (em *ExpireCache) recycle() {
-	now := time.Now()
+	now := em.clock.Now()
 	var deleteKey []string
 	for key := range em.data {
 		temp := em.data[key]
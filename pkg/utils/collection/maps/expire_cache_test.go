@@ -7,43 +7,71 @@
 package maps
 
 import (
-	"fmt"
-	"strconv"
 	"testing"
 	"time"
+
+	"github.com/yinjk/go-utils/pkg/utils/times"
 )
 
 func TestExpireCache_Get(t *testing.T) {
-	cache := NewExpireCache()
+	clock := times.NewFakeClock(time.Time{})
+	cache := NewExpireCache(false, clock)
 	cache.Put("forevear", 1)
 	cache.Put("2s", 1, time.Second*2)
 	cache.Put("1s", 1, time.Second*1)
 	cache.Put("5s", 1, time.Second*5)
 	cache.Put("10s", 1, time.Second*10)
 	cache.Put("1m", 1, time.Minute*1)
-	i := 0
-	for true {
-		i++
-		fmt.Println("-------"+strconv.Itoa(i)+"--------", cache.Size())
-		cache.ForEach(func(key string, data interface{}) {
-			fmt.Println(key)
-		})
-		time.Sleep(time.Second)
+	expects := []struct {
+		advance time.Duration
+		size    int
+	}{
+		{0, 6},
+		{time.Second + time.Millisecond, 5},
+		{time.Second, 4},
+		{time.Second * 3, 3},
+		{time.Second * 5, 2},
+		{time.Minute, 1},
+	}
+	for _, expect := range expects {
+		clock.Advance(expect.advance)
+		if size := cache.Size(); size != expect.size {
+			t.Fatalf("after %v expect size %d, got %d", expect.advance, expect.size, size)
+		}
+	}
+	if cache.Get("forevear") == nil {
+		t.Fatal("the key without ttl should never expire")
+	}
+}
+
+func TestExpireCache_Background(t *testing.T) {
+	clock := times.NewFakeClock(time.Time{})
+	cache := NewExpireCache(true, clock)
+	cache.Put("1s", 1, time.Second)
+	clock.BlockUntil(1) //等待后台清理协程注册ticker
+	clock.Advance(time.Second * 10)
+	for i := 0; cache.Size() != 0; i++ {
+		if i > 100 {
+			t.Fatalf("background goroutine should recycle the expired key, size: %d", cache.Size())
+		}
+		time.Sleep(time.Millisecond)
 	}
 }
 
 func TestNewExpireMap(t *testing.T) {
-	expireMap := NewExpireMap(time.Second * 2)
+	clock := times.NewFakeClock(time.Time{})
+	expireMap := NewExpireMap(time.Second*2, clock)
 	expireMap.Put("aa", time.Second)
 	expireMap.Put("bb", time.Second)
-	i := 0
-	for true {
-		i++
-		fmt.Println("-------" + strconv.Itoa(i) + "--------")
-		expireMap.ForEach(func(key string, data interface{}) {
-			fmt.Println(key)
-		})
-		time.Sleep(time.Second)
+	clock.Advance(time.Second)
+	if expireMap.GetAndFlush("aa") == nil || expireMap.Get("bb") == nil {
+		t.Fatal("keys should not expire within the expire time")
+	}
+	clock.Advance(time.Second + time.Millisecond)
+	if expireMap.Get("aa") == nil {
+		t.Fatal("GetAndFlush should refresh the expire time")
+	}
+	if expireMap.Get("bb") != nil {
+		t.Fatal("bb should be expired")
 	}
-
 }
@@ -11,6 +11,8 @@ package maps
 import (
 	"sync"
 	"time"
+
+	"github.com/yinjk/go-utils/pkg/utils/times"
 )
 
 const (
@@ -25,9 +27,9 @@ type expireData struct {
 	Data interface{}
 }
 
-func newExpireData(value interface{}) expireData {
+func newExpireData(value interface{}, now time.Time) expireData {
 	expireData := expireData{}
-	expireData.LastTime = time.Now()
+	expireData.LastTime = now
 	expireData.Data = value
 	return expireData
 }
@@ -37,15 +39,15 @@ type ExpireMap struct {
 	lock       sync.Mutex
 	size       int64
 	expireTime time.Duration
+	clock      times.Clock
 }
 
-func NewExpireMap(times ...time.Duration) *ExpireMap {
-	if nil == times || len(times) == 0 {
-		return &ExpireMap{
-			expireTime: _defaultExpireTime,
-		}
+//NewExpireMap 创建map，expireTime为数据的过期时间，小于等于0时为10分钟，clock可选，用于判断过期的时钟，默认为SystemClock
+func NewExpireMap(expireTime time.Duration, clock ...times.Clock) *ExpireMap {
+	if expireTime <= 0 {
+		expireTime = _defaultExpireTime
 	}
-	return &ExpireMap{expireTime: times[0]}
+	return &ExpireMap{expireTime: expireTime, clock: times.ClockOrDefault(clock...)}
 }
 
 func (em *ExpireMap) ForEach(test func(key string, data interface{})) {
@@ -71,7 +73,7 @@ func (em *ExpireMap) GetAndFlush(key string) interface{} {
 	if expireData == nil {
 		return nil
 	}
-	now := time.Now()
+	now := em.now()
 	if expireData.LastTime.Add(em.expireTime).Before(now) {
 		delete(em.data, key)
 		em.size--
@@ -93,7 +95,7 @@ func (em *ExpireMap) Get(key string) interface{} {
 	if expireData == nil {
 		return nil
 	}
-	if expireData.LastTime.Add(em.expireTime).Before(time.Now()) {
+	if expireData.LastTime.Add(em.expireTime).Before(em.now()) {
 		delete(em.data, key)
 		em.size--
 		return nil
@@ -111,7 +113,7 @@ func (em *ExpireMap) Put(key string, value interface{}) {
 	if em.size >= _maxSize {
 		em.recycle()
 	}
-	expireData := newExpireData(value)
+	expireData := newExpireData(value, em.now())
 	em.data[key] = &expireData
 	em.size++
 }
@@ -132,9 +134,14 @@ func (em *ExpireMap) Remove(key string) interface{} {
 	return nil
 }
 
+//now 当前时间，零值的ExpireMap没有设置clock，使用times.SystemClock
+func (em *ExpireMap) now() time.Time {
+	return times.ClockOrDefault(em.clock).Now()
+}
+
 //如果超过大小就回收掉过期token，这里进行条件触发删除
 func (em *ExpireMap) recycle() {
-	now := time.Now()
+	now := em.now()
 	var deleteKey []string
 	for key := range em.data {
 		temp := em.data[key]
@@ -37,6 +37,9 @@ type limitedPipeline[T comparable] struct {
 
 	sink  func(in chan T) (out chan T) //该方法实现扇入扇出流式处理，每次stream调用，数据都会从一个通道流入下一个通道
 	depth int
+
+	done    chan struct{} //终止操作结束后关闭，通知上游的sink停止发送数据，只有head上的有效
+	onClose func()        //终止操作结束后回调，用于释放数据源，只有head上的有效
 }
 
 func newLimitedPipeline[T comparable](source []T) (p *limitedPipeline[T]) {
@@ -45,7 +48,9 @@ func newLimitedPipeline[T comparable](source []T) (p *limitedPipeline[T]) {
 	}
 	p.source = func(out chan<- T) {
 		for _, t := range source {
-			out <- t
+			if !p.emit(out, t) {
+				return
+			}
 		}
 	}
 	p.head = p
@@ -57,7 +62,9 @@ func newLimitedPipelineFromCh[T comparable](source <-chan T) (p *limitedPipeline
 	}
 	p.source = func(out chan<- T) {
 		for t := range source {
-			out <- t
+			if !p.emit(out, t) {
+				return
+			}
 		}
 	}
 	p.head = p
@@ -81,6 +88,25 @@ func StreamOfSlice[T comparable](slice []T) Stream[T] {
 	return newLimitedPipeline(slice)
 }
 
+// StreamOfChan Returns a stream consisting of the elements received from the source channel,
+// onClose (nullable) will be called once the terminal operation finished, even if it returns
+// before the source channel is closed (e.g. FindFirst, AnyMatch), so the producer can release its resources.
+func StreamOfChan[T comparable](source <-chan T, onClose func()) Stream[T] {
+	p := newLimitedPipelineFromCh(source)
+	p.onClose = onClose
+	return p
+}
+
+// emit 将t发送到下游，终止操作已经结束（下游不再接收数据）时返回false，调用方应当停止发送
+func (p *limitedPipeline[T]) emit(out chan<- T, t T) bool {
+	select {
+	case out <- t:
+		return true
+	case <-p.head.done:
+		return false
+	}
+}
+
 func (p *limitedPipeline[T]) isBatch() bool {
 	return true
 }
@@ -97,7 +123,9 @@ func (p *limitedPipeline[T]) Distinct() Stream[T] {
 					continue
 				}
 				distinctMap[t] = true
-				out <- t
+				if !current.emit(out, t) {
+					break
+				}
 			}
 			close(out)
 		}()
@@ -118,7 +146,9 @@ func (p *limitedPipeline[T]) DistinctComplex(equals func(o1, o2 T) bool) Stream[
 					continue
 				}
 				distinctMap[t] = true
-				out <- t
+				if !current.emit(out, t) {
+					break
+				}
 			}
 			close(out)
 		}()
@@ -134,8 +164,8 @@ func (p *limitedPipeline[T]) Filter(test func(v T) bool) Stream[T] {
 		out := make(chan T)
 		go func() {
 			for t := range in {
-				if test(t) {
-					out <- t
+				if test(t) && !current.emit(out, t) {
+					break
 				}
 			}
 			close(out)
@@ -167,7 +197,9 @@ func (p *limitedPipeline[T]) Skip(n int) Stream[T] {
 					index++
 					continue
 				}
-				out <- t
+				if !current.emit(out, t) {
+					break
+				}
 			}
 			close(out)
 		}()
@@ -184,12 +216,10 @@ func (p *limitedPipeline[T]) Limit(maxSize int) Stream[T] {
 		go func() {
 			index := 0
 			for t := range in {
-				if index >= maxSize {
-					//break
-					continue
+				if index >= maxSize || !current.emit(out, t) {
+					break //上游的sink在终止操作结束后停止发送
 				}
 				index++
-				out <- t
 			}
 			close(out)
 		}()
@@ -213,7 +243,9 @@ func (p *limitedPipeline[T]) Sorted(lessFunc func(o1, o2 T) bool) Stream[T] {
 				return lessFunc(l[i], l[j])
 			})
 			for _, t := range l {
-				out <- t
+				if !current.emit(out, t) {
+					break
+				}
 			}
 			close(out)
 		}()
@@ -230,7 +262,9 @@ func (p *limitedPipeline[T]) Peek(accept func(t T)) Stream[T] {
 		go func() {
 			for t := range in {
 				accept(t)
-				out <- t
+				if !current.emit(out, t) {
+					break
+				}
 			}
 			close(out)
 		}()
@@ -403,6 +437,15 @@ func (p *limitedPipeline[T]) ToArray() []T {
 
 // handSink Do execute the limitedPipeline chain
 func (p *limitedPipeline[T]) handSink() {
+	head := p.head
+	head.done = make(chan struct{})
+	defer func() {
+		//终止操作可能提前返回，通知上游的sink不再发送，并释放数据源
+		close(head.done)
+		if head.onClose != nil {
+			head.onClose()
+		}
+	}()
 	var out chan T
 	out = make(chan T)
 	go func() {
@@ -5,17 +5,22 @@
  */
 package list
 
+import "sync"
+
 func Map[I, O comparable](in Stream[I], m func(i I) O) Stream[O] {
 	outCh := make(chan O)
-	out := newLimitedPipelineFromCh(outCh)
-	//in.Peek(func(t I) {
-	//	o := m(t)
-	//	outCh <- o
-	//})
+	stop := make(chan struct{})
+	var once sync.Once
+	//下游的终止操作提前结束时，同时结束上游的流
+	out := StreamOfChan(outCh, func() { once.Do(func() { close(stop) }) })
 	go func() {
-		in.ForEach(func(v I) {
-			o := m(v)
-			outCh <- o
+		in.AnyMatch(func(v I) bool {
+			select {
+			case outCh <- m(v):
+				return false
+			case <-stop:
+				return true
+			}
 		})
 		close(outCh)
 	}()
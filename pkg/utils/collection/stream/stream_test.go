@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"strconv"
 	"testing"
+	"time"
 )
 
 func TestStreamOf(_ *testing.T) {
@@ -22,3 +23,37 @@ func TestStreamOf(_ *testing.T) {
 	}).ToArray()
 	fmt.Println(res)
 }
+
+func TestStreamOfChan(t *testing.T) {
+	ch := make(chan int)
+	produced := make(chan int, 1)
+	closed := make(chan struct{})
+	go func() {
+		defer close(ch)
+		for i := 1; i <= 100; i++ {
+			select {
+			case ch <- i:
+			case <-time.After(time.Second): //下游不再接收
+				produced <- i - 1
+				return
+			}
+		}
+		produced <- 100
+	}()
+	s := StreamOfChan(ch, func() { close(closed) })
+	res := Map(s, func(i int) string { return strconv.Itoa(i) }).Filter(func(v string) bool { return v != "2" }).Limit(3).ToArray()
+	if fmt.Sprint(res) != "[1 3 4]" {
+		t.Fatalf("unexpected %v", res)
+	}
+	select {
+	case <-closed: //Map的上游在下游结束后异步关闭
+	case <-time.After(time.Second):
+		t.Fatal("onClose should be called after the terminal operation")
+	}
+	if n := <-produced; n >= 100 {
+		t.Fatalf("source should stop after the terminal operation, produced %d", n)
+	}
+	if first := StreamOf(1, 2, 3).Filter(func(v int) bool { return v > 1 }).FindFirst(); first != 2 {
+		t.Fatalf("unexpected first %d", first)
+	}
+}
@@ -0,0 +1,754 @@
+/*
+ @Desc map与结构体之间的相互转换，支持json、mapstructure以及自定义tag，支持嵌套结构体、匿名嵌入结构体、slice、map、指针
+ 和time.Time，开启弱类型转换（WeaklyTyped）之后还会在string、数字、bool之间自动转换，转换失败的字段不会被静默跳过，
+ 所有的错误都会被收集到MapperError中一起返回。
+
+ For example:
+
+	type User struct {
+	    Name     string    `json:"name"`
+	    Age      int       `json:"age"`
+	    Birthday time.Time `json:"birthday"`
+	    Address  struct {
+	        City string `json:"city"`
+	    } `json:"address"`
+	}
+
+	var user User
+	err := convert.MapToStruct(map[string]interface{}{
+	    "name":     "zhangshan",
+	    "age":      "18",                                   //string会被转换成int
+	    "birthday": "2000-01-02T03:04:05Z",                 //RFC3339格式的字符串会被转换成time.Time
+	    "address":  map[string]interface{}{"city": "wuhan"}, //嵌套的map会被转换成嵌套的结构体
+	}, &user)
+*/
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+
+	//timeLayouts 弱类型模式下string转time.Time时依次尝试的格式，ListMap查询结果中的时间就是DateTime格式
+	timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"}
+
+	defaultMapper = NewMapper(MapperConfig{WeaklyTyped: true})
+)
+
+// DecodeHook is called before a value is decoded into a field, it can convert the data into another value.
+// from is the type of data, to is the type of the target field, the returned value will be decoded into the field.
+type DecodeHook func(from, to reflect.Type, data interface{}) (interface{}, error)
+
+// MapperConfig is the config of a Mapper.
+type MapperConfig struct {
+	// TagNames are the struct tags used to name the map keys, the first tag present on a field wins.
+	// Defaults to {"mapstructure", "json"}, the field name is used when none of them is present.
+	TagNames []string
+	// WeaklyTyped enables the conversions between strings, numbers and bools, and strings to time.Time.
+	WeaklyTyped bool
+	// Hooks are called in order before every value is decoded.
+	Hooks []DecodeHook
+}
+
+// Mapper converts maps to structs and structs to maps, it caches the field plan of every struct type
+// and is safe to use from multiple goroutines.
+type Mapper struct {
+	config MapperConfig
+	plans  sync.Map //reflect.Type -> *structPlan
+}
+
+// MapperError collects all errors that occurred during a decoding.
+type MapperError struct {
+	Errors []string
+}
+
+func (e *MapperError) Error() string {
+	return fmt.Sprintf("%d error(s) decoding:\n\n* %s", len(e.Errors), strings.Join(e.Errors, "\n* "))
+}
+
+// NewMapper creates a Mapper with the config.
+func NewMapper(config MapperConfig) *Mapper {
+	if len(config.TagNames) == 0 {
+		config.TagNames = []string{"mapstructure", "json"}
+	}
+	return &Mapper{config: config}
+}
+
+type structPlan struct {
+	fields []*fieldPlan
+	//keys 字段名称到字段的索引，包括原始名称、小写名称以及去掉下划线的小写名称
+	keys map[string]*fieldPlan
+}
+
+type fieldPlan struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+/**
+ * 将input（一般是map[string]interface{}）解码到output指向的结构体中，output必须是指针。
+ * map中的key按照以下顺序匹配结构体字段：tag名称 -> 忽略大小写 -> 忽略大小写和下划线（user_name可以匹配UserName）
+ * @param : input 要解码的数据，可以是map、结构体、slice或者基础类型
+ * @param : output 解码的目标，必须是非nil的指针
+ * @return: err 所有字段的解码错误，类型为*MapperError
+ */
+func (m *Mapper) Decode(input interface{}, output interface{}) error {
+	out := reflect.ValueOf(output)
+	if out.Kind() != reflect.Ptr || out.IsNil() {
+		return fmt.Errorf("convert: output must be a non-nil pointer, got %T", output)
+	}
+	var errs []string
+	m.decode("", input, out.Elem(), &errs)
+	if len(errs) > 0 {
+		return &MapperError{Errors: errs}
+	}
+	return nil
+}
+
+/**
+ * 将结构体编码成map，key为tag名称（没有tag时为字段名），嵌套的结构体会被编码成嵌套的map，匿名嵌入的结构体字段会被提升到外层，
+ * 支持tag的omitempty选项，tag为"-"的字段会被忽略，time.Time保持原样
+ * @param : obj 结构体或结构体指针
+ * @return: 编码之后的map
+ */
+func (m *Mapper) Encode(obj interface{}) (map[string]interface{}, error) {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, fmt.Errorf("convert: can not encode a nil %T", obj)
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("convert: can not encode %T to map, only struct is supported", obj)
+	}
+	return m.encodeStruct(v), nil
+}
+
+func (m *Mapper) decode(name string, data interface{}, out reflect.Value, errs *[]string) {
+	if data == nil {
+		return
+	}
+	for _, hook := range m.config.Hooks {
+		var err error
+		if data, err = hook(reflect.TypeOf(data), out.Type(), data); err != nil {
+			*errs = append(*errs, fmt.Sprintf("'%s' %v", name, err))
+			return
+		}
+		if data == nil {
+			return
+		}
+	}
+	in := reflect.ValueOf(data)
+	for in.Kind() == reflect.Ptr || in.Kind() == reflect.Interface {
+		if in.IsNil() {
+			return
+		}
+		in = in.Elem()
+	}
+	if out.Kind() == reflect.Interface {
+		if in.Type().AssignableTo(out.Type()) {
+			out.Set(in)
+			return
+		}
+		*errs = append(*errs, fmt.Sprintf("'%s' expected type '%s', got '%s'", name, out.Type(), in.Type()))
+		return
+	}
+	var err error
+	switch out.Kind() {
+	case reflect.Ptr:
+		if out.IsNil() {
+			out.Set(reflect.New(out.Type().Elem()))
+		}
+		m.decode(name, in.Interface(), out.Elem(), errs)
+		return
+	case reflect.Bool:
+		err = m.decodeBool(in, out)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		err = m.decodeInt(in, out)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		err = m.decodeUint(in, out)
+	case reflect.Float32, reflect.Float64:
+		err = m.decodeFloat(in, out)
+	case reflect.String:
+		err = m.decodeString(in, out)
+	case reflect.Struct:
+		if out.Type() == timeType {
+			err = m.decodeTime(in, out)
+		} else {
+			m.decodeStruct(name, in, out, errs)
+			return
+		}
+	case reflect.Map:
+		m.decodeMap(name, in, out, errs)
+		return
+	case reflect.Slice, reflect.Array:
+		m.decodeSlice(name, in, out, errs)
+		return
+	default:
+		if in.Type().AssignableTo(out.Type()) {
+			out.Set(in)
+			return
+		}
+		err = unsupported(in, out)
+	}
+	if err != nil {
+		*errs = append(*errs, fmt.Sprintf("'%s' %v", name, err))
+	}
+}
+
+func (m *Mapper) decodeBool(in, out reflect.Value) error {
+	switch {
+	case in.Kind() == reflect.Bool:
+		out.SetBool(in.Bool())
+	case !m.config.WeaklyTyped:
+		return unsupported(in, out)
+	case isInt(in.Kind()):
+		out.SetBool(in.Int() != 0)
+	case isUint(in.Kind()):
+		out.SetBool(in.Uint() != 0)
+	case isFloat(in.Kind()):
+		out.SetBool(in.Float() != 0)
+	case in.Kind() == reflect.String || isBytes(in):
+		s := stringOf(in)
+		if s == "" {
+			out.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("can not parse '%s' as bool: %v", s, err)
+		}
+		out.SetBool(b)
+	default:
+		return unsupported(in, out)
+	}
+	return nil
+}
+
+func (m *Mapper) decodeInt(in, out reflect.Value) error {
+	var i int64
+	switch {
+	case isInt(in.Kind()):
+		i = in.Int()
+	case isUint(in.Kind()):
+		if in.Uint() > math.MaxInt64 {
+			return fmt.Errorf("value %d overflows %s", in.Uint(), out.Type())
+		}
+		i = int64(in.Uint())
+	case isFloat(in.Kind()): //json解码出来的数字都是float64
+		n, err := floatToInt(in.Float(), out.Type())
+		if err != nil {
+			return err
+		}
+		i = n
+	case in.Type() == reflect.TypeOf(json.Number("")):
+		n, err := in.Interface().(json.Number).Int64()
+		if err != nil {
+			return err
+		}
+		i = n
+	case !m.config.WeaklyTyped:
+		return unsupported(in, out)
+	case in.Kind() == reflect.Bool:
+		if in.Bool() {
+			i = 1
+		}
+	case in.Kind() == reflect.String || isBytes(in):
+		s := stringOf(in)
+		if s == "" {
+			i = 0
+			break
+		}
+		n, err := strconv.ParseInt(s, 0, 64)
+		if err != nil {
+			f, ferr := strconv.ParseFloat(s, 64) //兼容"1.0"这种格式
+			if ferr != nil {
+				return fmt.Errorf("can not parse '%s' as int: %v", s, err)
+			}
+			if n, err = floatToInt(f, out.Type()); err != nil {
+				return err
+			}
+		}
+		i = n
+	default:
+		return unsupported(in, out)
+	}
+	if out.OverflowInt(i) {
+		return fmt.Errorf("value %d overflows %s", i, out.Type())
+	}
+	out.SetInt(i)
+	return nil
+}
+
+func (m *Mapper) decodeUint(in, out reflect.Value) error {
+	var u uint64
+	switch {
+	case isInt(in.Kind()):
+		if in.Int() < 0 {
+			return fmt.Errorf("can not decode negative value %d into %s", in.Int(), out.Type())
+		}
+		u = uint64(in.Int())
+	case isUint(in.Kind()):
+		u = in.Uint()
+	case isFloat(in.Kind()):
+		if in.Float() < 0 {
+			return fmt.Errorf("can not decode negative value %v into %s", in.Float(), out.Type())
+		}
+		n, err := floatToUint(in.Float(), out.Type())
+		if err != nil {
+			return err
+		}
+		u = n
+	case in.Type() == reflect.TypeOf(json.Number("")):
+		n, err := strconv.ParseUint(in.String(), 0, 64)
+		if err != nil {
+			return err
+		}
+		u = n
+	case !m.config.WeaklyTyped:
+		return unsupported(in, out)
+	case in.Kind() == reflect.Bool:
+		if in.Bool() {
+			u = 1
+		}
+	case in.Kind() == reflect.String || isBytes(in):
+		s := stringOf(in)
+		if s == "" {
+			u = 0
+			break
+		}
+		n, err := strconv.ParseUint(s, 0, 64)
+		if err != nil {
+			return fmt.Errorf("can not parse '%s' as uint: %v", s, err)
+		}
+		u = n
+	default:
+		return unsupported(in, out)
+	}
+	if out.OverflowUint(u) {
+		return fmt.Errorf("value %d overflows %s", u, out.Type())
+	}
+	out.SetUint(u)
+	return nil
+}
+
+func (m *Mapper) decodeFloat(in, out reflect.Value) error {
+	var f float64
+	switch {
+	case isInt(in.Kind()):
+		f = float64(in.Int())
+	case isUint(in.Kind()):
+		f = float64(in.Uint())
+	case isFloat(in.Kind()):
+		f = in.Float()
+	case in.Type() == reflect.TypeOf(json.Number("")):
+		n, err := in.Interface().(json.Number).Float64()
+		if err != nil {
+			return err
+		}
+		f = n
+	case !m.config.WeaklyTyped:
+		return unsupported(in, out)
+	case in.Kind() == reflect.Bool:
+		if in.Bool() {
+			f = 1
+		}
+	case in.Kind() == reflect.String || isBytes(in):
+		s := stringOf(in)
+		if s == "" {
+			f = 0
+			break
+		}
+		n, err := strconv.ParseFloat(s, out.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("can not parse '%s' as float: %v", s, err)
+		}
+		f = n
+	default:
+		return unsupported(in, out)
+	}
+	if out.OverflowFloat(f) {
+		return fmt.Errorf("value %v overflows %s", f, out.Type())
+	}
+	out.SetFloat(f)
+	return nil
+}
+
+//floatToInt 浮点数转换为整数，有小数部分或者超出int64范围时返回错误，不会静默截断
+func floatToInt(f float64, to reflect.Type) (int64, error) {
+	if f != math.Trunc(f) {
+		return 0, fmt.Errorf("can not decode %v into %s without losing the fractional part", f, to)
+	}
+	if f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, fmt.Errorf("value %v overflows %s", f, to)
+	}
+	return int64(f), nil
+}
+
+//floatToUint 非负的浮点数转换为无符号整数，有小数部分或者超出uint64范围时返回错误
+func floatToUint(f float64, to reflect.Type) (uint64, error) {
+	if f != math.Trunc(f) {
+		return 0, fmt.Errorf("can not decode %v into %s without losing the fractional part", f, to)
+	}
+	if f >= math.MaxUint64 {
+		return 0, fmt.Errorf("value %v overflows %s", f, to)
+	}
+	return uint64(f), nil
+}
+
+func (m *Mapper) decodeString(in, out reflect.Value) error {
+	switch {
+	case in.Kind() == reflect.String:
+		out.SetString(in.String())
+	case !m.config.WeaklyTyped:
+		return unsupported(in, out)
+	case isBytes(in):
+		out.SetString(string(in.Bytes()))
+	case in.Kind() == reflect.Bool:
+		out.SetString(strconv.FormatBool(in.Bool()))
+	case isInt(in.Kind()):
+		out.SetString(strconv.FormatInt(in.Int(), 10))
+	case isUint(in.Kind()):
+		out.SetString(strconv.FormatUint(in.Uint(), 10))
+	case isFloat(in.Kind()):
+		out.SetString(strconv.FormatFloat(in.Float(), 'f', -1, 64))
+	case in.Type() == timeType:
+		out.SetString(in.Interface().(time.Time).Format(time.RFC3339Nano))
+	default:
+		return unsupported(in, out)
+	}
+	return nil
+}
+
+func (m *Mapper) decodeTime(in, out reflect.Value) error {
+	switch {
+	case in.Type() == timeType:
+		out.Set(in)
+	case !m.config.WeaklyTyped:
+		return unsupported(in, out)
+	case in.Kind() == reflect.String || isBytes(in):
+		s := stringOf(in)
+		if s == "" {
+			return nil
+		}
+		for _, layout := range timeLayouts {
+			if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+				out.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return fmt.Errorf("can not parse '%s' as time", s)
+	case isInt(in.Kind()): //unix时间戳，单位秒
+		out.Set(reflect.ValueOf(time.Unix(in.Int(), 0)))
+	default:
+		return unsupported(in, out)
+	}
+	return nil
+}
+
+func (m *Mapper) decodeStruct(name string, in, out reflect.Value, errs *[]string) {
+	if in.Type() == out.Type() {
+		out.Set(in)
+		return
+	}
+	switch in.Kind() {
+	case reflect.Struct: //结构体之间先转成map再解码
+		in = reflect.ValueOf(m.encodeStruct(in))
+	case reflect.Map:
+		if in.Type().Key().Kind() != reflect.String {
+			*errs = append(*errs, fmt.Sprintf("'%s' needs a map with string keys, got '%s'", name, in.Type()))
+			return
+		}
+	default:
+		*errs = append(*errs, fmt.Sprintf("'%s' expected a map, got '%s'", name, in.Kind()))
+		return
+	}
+	plan := m.plan(out.Type())
+	for _, key := range in.MapKeys() {
+		field := plan.lookup(key.String())
+		if field == nil {
+			continue
+		}
+		fieldValue, ok := fieldByIndexAlloc(out, field.index)
+		if !ok {
+			continue
+		}
+		m.decode(joinName(name, field.name), in.MapIndex(key).Interface(), fieldValue, errs)
+	}
+}
+
+func (m *Mapper) decodeMap(name string, in, out reflect.Value, errs *[]string) {
+	if in.Kind() == reflect.Struct {
+		in = reflect.ValueOf(m.encodeStruct(in))
+	}
+	if in.Kind() != reflect.Map {
+		*errs = append(*errs, fmt.Sprintf("'%s' expected a map, got '%s'", name, in.Kind()))
+		return
+	}
+	result := reflect.MakeMapWithSize(out.Type(), in.Len())
+	for _, key := range in.MapKeys() {
+		k := reflect.New(out.Type().Key()).Elem()
+		v := reflect.New(out.Type().Elem()).Elem()
+		fieldName := joinName(name, fmt.Sprint(key.Interface()))
+		m.decode(fieldName, key.Interface(), k, errs)
+		m.decode(fieldName, in.MapIndex(key).Interface(), v, errs)
+		result.SetMapIndex(k, v)
+	}
+	out.Set(result)
+}
+
+func (m *Mapper) decodeSlice(name string, in, out reflect.Value, errs *[]string) {
+	if out.Kind() == reflect.Slice && out.Type().Elem().Kind() == reflect.Uint8 && in.Kind() == reflect.String {
+		out.SetBytes([]byte(in.String()))
+		return
+	}
+	if in.Kind() != reflect.Slice && in.Kind() != reflect.Array {
+		if !m.config.WeaklyTyped {
+			*errs = append(*errs, fmt.Sprintf("'%s' expected a slice, got '%s'", name, in.Kind()))
+			return
+		}
+		//弱类型模式下单个值转换成只有一个元素的slice
+		in = reflect.ValueOf([]interface{}{in.Interface()})
+	}
+	length := in.Len()
+	var result reflect.Value
+	if out.Kind() == reflect.Array {
+		result = reflect.New(out.Type()).Elem()
+		if length > out.Len() {
+			*errs = append(*errs, fmt.Sprintf("'%s' expected at most %d elements, got %d", name, out.Len(), length))
+			return
+		}
+	} else {
+		result = reflect.MakeSlice(out.Type(), length, length)
+	}
+	for i := 0; i < length; i++ {
+		m.decode(fmt.Sprintf("%s[%d]", name, i), in.Index(i).Interface(), result.Index(i), errs)
+	}
+	out.Set(result)
+}
+
+func (m *Mapper) encodeStruct(v reflect.Value) map[string]interface{} {
+	plan := m.plan(v.Type())
+	result := make(map[string]interface{}, len(plan.fields))
+	for _, field := range plan.fields {
+		fieldValue, ok := fieldByIndex(v, field.index)
+		if !ok {
+			continue
+		}
+		if field.omitEmpty && fieldValue.IsZero() {
+			continue
+		}
+		result[field.name] = m.encodeValue(fieldValue)
+	}
+	return result
+}
+
+func (m *Mapper) encodeValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return m.encodeValue(v.Elem())
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface()
+		}
+		return m.encodeStruct(v)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		if !needsEncode(v.Type().Elem()) {
+			return v.Interface()
+		}
+		result := make([]interface{}, v.Len())
+		for i := range result {
+			result[i] = m.encodeValue(v.Index(i))
+		}
+		return result
+	case reflect.Map:
+		if v.IsNil() || !needsEncode(v.Type().Elem()) {
+			return v.Interface()
+		}
+		result := make(map[string]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			result[fmt.Sprint(key.Interface())] = m.encodeValue(v.MapIndex(key))
+		}
+		return result
+	}
+	return v.Interface()
+}
+
+//plan 获取结构体类型的字段解析计划，解析结果会被缓存
+func (m *Mapper) plan(t reflect.Type) *structPlan {
+	if p, ok := m.plans.Load(t); ok {
+		return p.(*structPlan)
+	}
+	p := &structPlan{keys: make(map[string]*fieldPlan)}
+	m.collectFields(t, nil, p, make(map[reflect.Type]bool))
+	for _, field := range p.fields {
+		for _, key := range []string{strings.ToLower(field.name), normalizeKey(field.name)} {
+			if _, ok := p.keys[key]; !ok {
+				p.keys[key] = field
+			}
+		}
+	}
+	for _, field := range p.fields { //原始名称的优先级最高
+		p.keys[field.name] = field
+	}
+	m.plans.Store(t, p)
+	return p
+}
+
+func (m *Mapper) collectFields(t reflect.Type, index []int, p *structPlan, visited map[reflect.Type]bool) {
+	if visited[t] { //防止匿名嵌入的结构体循环引用
+		return
+	}
+	visited[t] = true
+	defer delete(visited, t)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, options := m.fieldTag(field)
+		if name == "-" {
+			continue
+		}
+		fieldIndex := append(index[:len(index):len(index)], i)
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		//没有指定tag名称的匿名嵌入结构体，将其字段提升到外层
+		squash := strings.Contains(options, "squash") || (field.Anonymous && name == "")
+		if squash && fieldType.Kind() == reflect.Struct && fieldType != timeType {
+			m.collectFields(fieldType, fieldIndex, p, visited)
+			continue
+		}
+		if field.PkgPath != "" { //未导出的字段
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		p.fields = append(p.fields, &fieldPlan{name: name, index: fieldIndex, omitEmpty: strings.Contains(options, "omitempty")})
+	}
+}
+
+//fieldTag 返回第一个存在的tag的名称和选项
+func (m *Mapper) fieldTag(field reflect.StructField) (name, options string) {
+	for _, tagName := range m.config.TagNames {
+		tag, ok := field.Tag.Lookup(tagName)
+		if !ok {
+			continue
+		}
+		if i := strings.Index(tag, ","); i != -1 {
+			return tag[:i], tag[i+1:]
+		}
+		return tag, ""
+	}
+	return "", ""
+}
+
+func (p *structPlan) lookup(key string) *fieldPlan {
+	if field, ok := p.keys[key]; ok {
+		return field
+	}
+	if field, ok := p.keys[strings.ToLower(key)]; ok {
+		return field
+	}
+	return p.keys[normalizeKey(key)]
+}
+
+//fieldByIndexAlloc 按照index获取字段，路径上为nil的嵌入指针会被初始化
+func fieldByIndexAlloc(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, v.CanSet()
+}
+
+//fieldByIndex 按照index获取字段，路径上的嵌入指针为nil时返回false
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+//needsEncode 判断该类型的值编码成map时是否需要递归处理
+func needsEncode(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		return t != timeType
+	case reflect.Interface, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	}
+	return false
+}
+
+func normalizeKey(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", ""))
+}
+
+func joinName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func isInt(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUint(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+func isFloat(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
+
+func isBytes(v reflect.Value) bool {
+	return v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8
+}
+
+func stringOf(v reflect.Value) string {
+	if isBytes(v) {
+		return string(v.Bytes())
+	}
+	return v.String()
+}
+
+func unsupported(in, out reflect.Value) error {
+	return fmt.Errorf("expected type '%s', got unconvertible type '%s'", out.Type(), in.Type())
+}
@@ -0,0 +1,180 @@
+package convert
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Base struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Address struct {
+	City   string `json:"city"`
+	Street string `json:"street,omitempty"`
+}
+
+type Account struct {
+	Base
+	UserName string            `json:"user_name"`
+	Age      int               `mapstructure:"years" json:"age"`
+	Score    float64           `json:"score"`
+	Enabled  bool              `json:"enabled"`
+	Address  Address           `json:"address"`
+	Backup   *Address          `json:"backup"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]int    `json:"labels"`
+	Friends  []Address         `json:"friends"`
+	Extra    map[string]string `json:"-"`
+	Remark   string
+}
+
+func TestMapToStruct(t *testing.T) {
+	var account Account
+	err := MapToStruct(map[string]interface{}{
+		"id":         "42",
+		"created_at": "2020-07-13 15:20:00",
+		"user_name":  "zhangshan",
+		"years":      18.0,
+		"score":      "99.5",
+		"enabled":    "true",
+		"address":    map[string]interface{}{"city": "wuhan"},
+		"backup":     map[string]string{"city": "beijing", "street": "chang'an"},
+		"tags":       []interface{}{"a", 1},
+		"labels":     map[string]interface{}{"x": "1", "y": 2.0},
+		"friends":    []map[string]interface{}{{"city": "shanghai"}},
+		"Extra":      map[string]string{"ignored": "true"},
+		"REMARK":     []byte("ok"),
+	}, &account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Account{
+		Base:     Base{ID: 42, CreatedAt: time.Date(2020, 7, 13, 15, 20, 0, 0, time.Local)},
+		UserName: "zhangshan",
+		Age:      18,
+		Score:    99.5,
+		Enabled:  true,
+		Address:  Address{City: "wuhan"},
+		Backup:   &Address{City: "beijing", Street: "chang'an"},
+		Tags:     []string{"a", "1"},
+		Labels:   map[string]int{"x": 1, "y": 2},
+		Friends:  []Address{{City: "shanghai"}},
+		Remark:   "ok",
+	}
+	if !reflect.DeepEqual(account, want) {
+		t.Fatalf("got %+v\nwant %+v", account, want)
+	}
+}
+
+func TestMapToStruct_Errors(t *testing.T) {
+	var account Account
+	err := MapToStruct(map[string]interface{}{
+		"years":   "eighteen",
+		"enabled": "maybe",
+		"address": "wuhan",
+		"score":   1,
+	}, &account)
+	mapperErr, ok := err.(*MapperError)
+	if !ok || len(mapperErr.Errors) != 3 {
+		t.Fatalf("expect 3 errors, got %v", err)
+	}
+	if account.Score != 1 || account.Enabled {
+		t.Fatalf("valid fields should still be decoded and bool should not be set on mismatch: %+v", account)
+	}
+
+	strict := NewMapper(MapperConfig{})
+	if err := strict.Decode(map[string]interface{}{"years": "18"}, &account); err == nil {
+		t.Fatal("strict mapper should not convert string to int")
+	}
+}
+
+func TestMapper_NumericLoss(t *testing.T) {
+	mapper := NewMapper(MapperConfig{WeaklyTyped: true})
+	var result struct {
+		Int   int     `json:"int"`
+		Small int8    `json:"small"`
+		Uint  uint    `json:"uint"`
+		Float float32 `json:"float"`
+	}
+	for _, data := range []map[string]interface{}{
+		{"int": uint64(1) << 63},
+		{"int": 1.5},
+		{"int": "1.5"},
+		{"int": 1e19},
+		{"small": "300"},
+		{"small": 128.0},
+		{"uint": 2.5},
+		{"uint": 1e20},
+		{"float": 1e300},
+	} {
+		if err := mapper.Decode(data, &result); err == nil {
+			t.Fatalf("decode %v should fail, got %+v", data, result)
+		}
+	}
+	if err := mapper.Decode(map[string]interface{}{"int": "2.0", "small": -128.0, "uint": 3.0, "float": 1.5}, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Int != 2 || result.Small != -128 || result.Uint != 3 || result.Float != 1.5 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+}
+
+func TestMapper_Hooks(t *testing.T) {
+	mapper := NewMapper(MapperConfig{
+		TagNames:    []string{"db"},
+		WeaklyTyped: true,
+		Hooks: []DecodeHook{func(from, to reflect.Type, data interface{}) (interface{}, error) {
+			if from.Kind() == reflect.String && to.Kind() == reflect.Slice {
+				return strings.Split(data.(string), ","), nil
+			}
+			return data, nil
+		}},
+	})
+	var result struct {
+		Names []string `db:"names"`
+	}
+	if err := mapper.Decode(map[string]interface{}{"names": "a,b,c"}, &result); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Names, []string{"a", "b", "c"}) {
+		t.Fatalf("unexpected names %v", result.Names)
+	}
+}
+
+func TestStructToMap(t *testing.T) {
+	created := time.Date(2020, 7, 13, 0, 0, 0, 0, time.UTC)
+	data := StructToMap(&Account{
+		Base:     Base{ID: 1, CreatedAt: created},
+		UserName: "lisi",
+		Address:  Address{City: "wuhan"},
+		Friends:  []Address{{City: "shanghai", Street: "nanjing road"}},
+		Extra:    map[string]string{"a": "b"},
+	})
+	if data["id"] != int64(1) || data["created_at"] != created || data["user_name"] != "lisi" {
+		t.Fatalf("unexpected map %v", data)
+	}
+	if _, ok := data["Extra"]; ok {
+		t.Fatal("field with tag '-' should be ignored")
+	}
+	if _, ok := data["years"]; !ok {
+		t.Fatal("mapstructure tag should take precedence")
+	}
+	if !reflect.DeepEqual(data["address"], map[string]interface{}{"city": "wuhan"}) {
+		t.Fatalf("nested struct should be converted to map with omitempty honored, got %v", data["address"])
+	}
+	friends := data["friends"].([]interface{})
+	if friends[0].(map[string]interface{})["street"] != "nanjing road" {
+		t.Fatalf("unexpected friends %v", friends)
+	}
+	var back Account
+	if err := MapToStruct(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.UserName != "lisi" || back.Friends[0].Street != "nanjing road" || !back.CreatedAt.Equal(created) {
+		t.Fatalf("round trip failed: %+v", back)
+	}
+}
@@ -6,53 +6,455 @@
 package convert
 
 import (
-	"github.com/yinjk/go-utils/pkg/utils/collection/collections"
+	"fmt"
+	"math"
 	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/yinjk/go-utils/pkg/utils/collection/collections"
 )
 
-//CopyProperties 将source结构体所有字段的值拷贝到target结构中
-func CopyProperties(source, target interface{}, ignore ...string) {
-	var (
-		targetTypeMap  = make(map[string]reflect.StructField)
-		targetValueMap = make(map[string]reflect.Value)
-		sourceType     = reflect.TypeOf(source)
-		sourceValue    = reflect.ValueOf(source)
-		targetType     = reflect.TypeOf(target).Elem()
-		targetValue    = reflect.ValueOf(target)
-	)
+var defaultCopier = NewCopier()
+
+// Copier deep copies values between differently-shaped types, such as gorm models and api DTOs.
+//
+// Struct fields are matched by their copy name: the `copy` tag if present, otherwise the field name,
+// e.g. a DTO field tagged `copy:"CreatedAt"` receives the CreatedAt field of the model.
+// A `copy:"-"` tag excludes the field. Anonymous embedded structs are flattened.
+// Fields with different types are converted by the registered converters first,
+// then by the built-in conversions (int->int64, float32->float64, named types...),
+// nested structs, slices, maps and pointers are copied recursively, the incompatible fields are skipped.
+// A numeric value that overflows the target type or loses its fractional part is an error, it is never wrapped or truncated.
+//
+// The field matching plan of every (source, target) type pair is cached, a Copier is safe to use from multiple goroutines.
+type Copier struct {
+	converters sync.Map //typePair -> func(reflect.Value) (reflect.Value, error)
+	plans      sync.Map //typePair -> []fieldPair
+}
+
+type typePair struct {
+	from, to reflect.Type
+}
+
+type fieldPair struct {
+	name     string //source字段的名称，用于ignore判断以及错误信息
+	copyName string
+	source   []int
+	target   []int
+}
+
+type copyOptions struct {
+	ignore   []string
+	skipZero bool
+	skipNil  bool
+	strict   bool
+}
+
+// CopyOption configures a single copy.
+type CopyOption func(o *copyOptions)
+
+// IgnoreFields excludes the source fields with the given names (field name or copy name) at every level.
+func IgnoreFields(names ...string) CopyOption {
+	return func(o *copyOptions) {
+		o.ignore = append(o.ignore, names...)
+	}
+}
+
+// SkipZero does not copy the source fields holding a zero value, the target keeps its current value.
+func SkipZero() CopyOption {
+	return func(o *copyOptions) {
+		o.skipZero = true
+	}
+}
+
+// SkipNil does not copy the nil pointer, slice, map and interface source fields, the target keeps its current value.
+func SkipNil() CopyOption {
+	return func(o *copyOptions) {
+		o.skipNil = true
+	}
+}
+
+// Strict makes the copy fail when a matched field can not be converted to the target type, instead of skipping it.
+func Strict() CopyOption {
+	return func(o *copyOptions) {
+		o.strict = true
+	}
+}
+
+// NewCopier creates a Copier without any converter.
+func NewCopier() *Copier {
+	return &Copier{}
+}
+
+/**
+ * 注册类型转换器，拷贝时如果source字段的类型为S、target字段的类型为T，则使用该转换器转换
+ * For example:
+ *
+ * convert.RegisterConverter(copier, func(t time.Time) (string, error) {
+ *     return t.Format(times.DateTimeFormat), nil
+ * })
+ * @param : c 要注册的Copier，为nil时注册到CopyProperties使用的默认Copier
+ * @param : converter 转换函数
+ * @return:
+ */
+func RegisterConverter[S, T any](c *Copier, converter func(S) (T, error)) {
+	if c == nil {
+		c = defaultCopier
+	}
+	from, to := reflect.TypeOf((*S)(nil)).Elem(), reflect.TypeOf((*T)(nil)).Elem()
+	c.converters.Store(typePair{from, to}, func(v reflect.Value) (reflect.Value, error) {
+		result, err := converter(v.Interface().(S))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(&result).Elem(), nil
+	})
+}
 
-	if targetValue.IsNil() {
-		targetValue = reflect.New(targetType)
+/**
+ * 将source的值深拷贝到target中，source可以是结构体、slice、map或者它们的指针，target必须是非nil的指针
+ * @param : source 拷贝的源
+ * @param : target 拷贝的目标，必须是指针
+ * @param : opts 拷贝选项，如：IgnoreFields、SkipZero、SkipNil、Strict
+ * @return: err 参数不合法，或者Strict模式下字段无法转换
+ */
+func (c *Copier) Copy(source, target interface{}, opts ...CopyOption) error {
+	options := &copyOptions{}
+	for _, opt := range opts {
+		opt(options)
 	}
-	if sourceValue.Kind() == reflect.Ptr { //如果source是指针，取其值递归
-		//fmt.Println("ok")
-		CopyProperties(sourceValue.Elem().Interface(), target, ignore...)
-		return
+	targetValue := reflect.ValueOf(target)
+	if targetValue.Kind() != reflect.Ptr || targetValue.IsNil() {
+		return fmt.Errorf("convert: copy target must be a non-nil pointer, got %T", target)
+	}
+	sourceValue := reflect.ValueOf(source)
+	if !sourceValue.IsValid() {
+		return fmt.Errorf("convert: copy source must not be nil")
+	}
+	for sourceValue.Kind() == reflect.Ptr {
+		if sourceValue.IsNil() {
+			return fmt.Errorf("convert: copy source must not be nil")
+		}
+		sourceValue = sourceValue.Elem()
+	}
+	ok, err := c.copyValue("", sourceValue, targetValue.Elem(), options)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("convert: can not copy %T to %T", source, target)
+	}
+	return nil
+}
+
+//CopyProperties 将source所有字段的值深拷贝到target中，ignore为要忽略的字段名，详见Copier
+func CopyProperties(source, target interface{}, ignore ...string) error {
+	return defaultCopier.Copy(source, target, IgnoreFields(ignore...))
+}
+
+//copyValue 将src拷贝到dst，返回false表示两者的类型不兼容
+func (c *Copier) copyValue(name string, src, dst reflect.Value, o *copyOptions) (bool, error) {
+	if converter, ok := c.converters.Load(typePair{src.Type(), dst.Type()}); ok {
+		result, err := converter.(func(reflect.Value) (reflect.Value, error))(src)
+		if err != nil {
+			return false, fmt.Errorf("convert: copy field '%s' failed: %v", name, err)
+		}
+		dst.Set(result)
+		return true, nil
 	}
-	targetValue = targetValue.Elem()
-	//将target的所有字段缓存到targetFieldMap中
-	for i := 0; i < targetType.NumField(); i++ {
-		fieldName := targetType.Field(i).Name
-		targetTypeMap[fieldName] = targetType.Field(i)
-		targetValueMap[fieldName] = targetValue.Field(i)
+	switch {
+	case src.Kind() == reflect.Interface:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return true, nil
+		}
+		return c.copyValue(name, src.Elem(), dst, o)
+	case src.Kind() == reflect.Ptr:
+		if src.IsNil() {
+			if !isNillable(dst.Kind()) {
+				return false, nil
+			}
+			dst.Set(reflect.Zero(dst.Type()))
+			return true, nil
+		}
+		return c.copyValue(name, src.Elem(), dst, o)
+	case dst.Kind() == reflect.Ptr:
+		elem := reflect.New(dst.Type().Elem())
+		ok, err := c.copyValue(name, src, elem.Elem(), o)
+		if ok && err == nil {
+			dst.Set(elem)
+		}
+		return ok, err
+	case dst.Kind() == reflect.Interface:
+		if !src.Type().AssignableTo(dst.Type()) {
+			return false, nil
+		}
+		dst.Set(src)
+		return true, nil
 	}
-	//遍历source的所有字段
-	for i := 0; i < sourceType.NumField(); i++ {
-		var (
-			targetType reflect.StructField
-			ok         bool
-		)
-		sourceType := sourceType.Field(i)
-		if targetType, ok = targetTypeMap[sourceType.Name]; !ok { //target中没有这个字段
+
+	switch src.Kind() {
+	case reflect.Struct:
+		if dst.Kind() != reflect.Struct {
+			return false, nil
+		}
+		//同类型并且有未导出字段的结构体（time.Time、big.Int等）整体复制，逐字段复制会丢失未导出字段
+		if src.Type() == dst.Type() && hasUnexported(src.Type()) {
+			dst.Set(src)
+			return true, nil
+		}
+		return true, c.copyStruct(name, src, dst, o)
+	case reflect.Slice, reflect.Array:
+		if dst.Kind() != reflect.Slice && dst.Kind() != reflect.Array {
+			return false, nil
+		}
+		if src.Kind() == reflect.Slice && src.IsNil() && dst.Kind() == reflect.Slice {
+			dst.Set(reflect.Zero(dst.Type()))
+			return true, nil
+		}
+		length := src.Len()
+		result := reflect.New(dst.Type()).Elem()
+		if dst.Kind() == reflect.Slice {
+			result = reflect.MakeSlice(dst.Type(), length, length)
+		} else if length > dst.Len() {
+			length = dst.Len()
+		}
+		for i := 0; i < length; i++ {
+			ok, err := c.copyValue(fmt.Sprintf("%s[%d]", name, i), src.Index(i), result.Index(i), o)
+			if err != nil || !ok {
+				return ok, err
+			}
+		}
+		dst.Set(result)
+		return true, nil
+	case reflect.Map:
+		if dst.Kind() != reflect.Map {
+			return false, nil
+		}
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return true, nil
+		}
+		result := reflect.MakeMapWithSize(dst.Type(), src.Len())
+		for _, key := range src.MapKeys() {
+			k := reflect.New(dst.Type().Key()).Elem()
+			v := reflect.New(dst.Type().Elem()).Elem()
+			fieldName := fmt.Sprintf("%s[%v]", name, key.Interface())
+			if ok, err := c.copyValue(fieldName, key, k, o); err != nil || !ok {
+				return ok, err
+			}
+			if ok, err := c.copyValue(fieldName, src.MapIndex(key), v, o); err != nil || !ok {
+				return ok, err
+			}
+			result.SetMapIndex(k, v)
+		}
+		dst.Set(result)
+		return true, nil
+	}
+	if !sameCategory(src.Kind(), dst.Kind()) || !src.Type().ConvertibleTo(dst.Type()) {
+		return false, nil
+	}
+	if err := checkNumber(src, dst.Type()); err != nil {
+		return false, fmt.Errorf("convert: copy field '%s' failed: %v", name, err)
+	}
+	dst.Set(src.Convert(dst.Type()))
+	return true, nil
+}
+
+//checkNumber 检查数字转换为to类型时是否会溢出或者丢失小数部分，reflect的Convert会静默地回绕或者截断
+func checkNumber(src reflect.Value, to reflect.Type) error {
+	target := reflect.New(to).Elem()
+	switch {
+	case isInt(to.Kind()):
+		var i int64
+		switch {
+		case isInt(src.Kind()):
+			i = src.Int()
+		case isUint(src.Kind()):
+			if src.Uint() > math.MaxInt64 {
+				return fmt.Errorf("value %d overflows %s", src.Uint(), to)
+			}
+			i = int64(src.Uint())
+		case isFloat(src.Kind()):
+			n, err := floatToInt(src.Float(), to)
+			if err != nil {
+				return err
+			}
+			i = n
+		}
+		if target.OverflowInt(i) {
+			return fmt.Errorf("value %d overflows %s", i, to)
+		}
+	case isUint(to.Kind()):
+		var u uint64
+		switch {
+		case isInt(src.Kind()):
+			if src.Int() < 0 {
+				return fmt.Errorf("can not convert negative value %d to %s", src.Int(), to)
+			}
+			u = uint64(src.Int())
+		case isUint(src.Kind()):
+			u = src.Uint()
+		case isFloat(src.Kind()):
+			if src.Float() < 0 {
+				return fmt.Errorf("can not convert negative value %v to %s", src.Float(), to)
+			}
+			n, err := floatToUint(src.Float(), to)
+			if err != nil {
+				return err
+			}
+			u = n
+		}
+		if target.OverflowUint(u) {
+			return fmt.Errorf("value %d overflows %s", u, to)
+		}
+	case isFloat(to.Kind()) && isFloat(src.Kind()):
+		if target.OverflowFloat(src.Float()) {
+			return fmt.Errorf("value %v overflows %s", src.Float(), to)
+		}
+	}
+	return nil
+}
+
+func (c *Copier) copyStruct(name string, src, dst reflect.Value, o *copyOptions) error {
+	for _, field := range c.plan(src.Type(), dst.Type()) {
+		if collections.IsStringIn(field.name, o.ignore...) || collections.IsStringIn(field.copyName, o.ignore...) {
+			continue
+		}
+		srcField, ok := fieldByIndex(src, field.source)
+		if !ok {
+			continue
+		}
+		if o.skipNil && isNillable(srcField.Kind()) && srcField.IsNil() {
 			continue
 		}
-		if sourceType.Type != targetType.Type { //类型不同的两个字段忽略
+		if o.skipZero && srcField.IsZero() {
 			continue
 		}
-		if collections.IsStringIn(sourceType.Name, ignore...) { //显示指定忽略的，忽略掉
+		dstField, ok := fieldByIndexAlloc(dst, field.target)
+		if !ok {
 			continue
 		}
-		//fmt.Println(reflect.ValueOf(sourceValue.Field(i).Interface()))
-		targetValue.FieldByName(sourceType.Name).Set(reflect.ValueOf(sourceValue.Field(i).Interface()))
+		fieldName := joinName(name, field.name)
+		//先拷贝到临时变量中，类型不兼容时不会修改target原有的值
+		temp := reflect.New(dstField.Type()).Elem()
+		copied, err := c.copyValue(fieldName, srcField, temp, o)
+		if err != nil {
+			return err
+		}
+		if !copied {
+			if o.strict {
+				return fmt.Errorf("convert: can not copy field '%s' from %s to %s", fieldName, srcField.Type(), dstField.Type())
+			}
+			continue
+		}
+		dstField.Set(temp)
+	}
+	return nil
+}
+
+//plan 获取source和target两个结构体类型之间的字段对应关系，结果会被缓存
+func (c *Copier) plan(src, dst reflect.Type) []fieldPair {
+	key := typePair{src, dst}
+	if p, ok := c.plans.Load(key); ok {
+		return p.([]fieldPair)
+	}
+	targetFields := make(map[string]copyField)
+	targetFolded := make(map[string]copyField)
+	for _, f := range copyFields(dst, nil, map[reflect.Type]bool{}) {
+		targetFields[f.copyName] = f
+		if _, ok := targetFolded[strings.ToLower(f.copyName)]; !ok {
+			targetFolded[strings.ToLower(f.copyName)] = f
+		}
+	}
+	var pairs []fieldPair
+	for _, f := range copyFields(src, nil, map[reflect.Type]bool{}) {
+		target, ok := targetFields[f.copyName]
+		if !ok {
+			if target, ok = targetFolded[strings.ToLower(f.copyName)]; !ok {
+				continue
+			}
+		}
+		pairs = append(pairs, fieldPair{name: f.name, copyName: f.copyName, source: f.index, target: target.index})
+	}
+	c.plans.Store(key, pairs)
+	return pairs
+}
+
+type copyField struct {
+	name     string
+	copyName string
+	index    []int
+}
+
+//copyFields 获取结构体所有可拷贝的字段，匿名嵌入的结构体字段会被提升到外层；
+//embedding为当前嵌入路径上的类型，A{*B}、B{*A}这种互相嵌入的指针不会被重复展开
+func copyFields(t reflect.Type, index []int, embedding map[reflect.Type]bool) []copyField {
+	embedding[t] = true
+	defer delete(embedding, t)
+	var fields []copyField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("copy")
+		if tag == "-" {
+			continue
+		}
+		fieldIndex := append(index[:len(index):len(index)], i)
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && tag == "" && fieldType.Kind() == reflect.Struct {
+			if !embedding[fieldType] {
+				fields = append(fields, copyFields(fieldType, fieldIndex, embedding)...)
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		copyName := tag
+		if copyName == "" {
+			copyName = field.Name
+		}
+		fields = append(fields, copyField{name: field.Name, copyName: copyName, index: fieldIndex})
+	}
+	return fields
+}
+
+func isNillable(k reflect.Kind) bool {
+	switch k {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map, reflect.Chan, reflect.Func:
+		return true
+	}
+	return false
+}
+
+//sameCategory 基础类型之间只允许同类转换，防止int被转换成string（rune）这种意料之外的结果
+func sameCategory(a, b reflect.Kind) bool {
+	category := func(k reflect.Kind) int {
+		switch {
+		case isInt(k) || isUint(k) || isFloat(k):
+			return 1
+		case k == reflect.String:
+			return 2
+		case k == reflect.Bool:
+			return 3
+		case k == reflect.Complex64 || k == reflect.Complex128:
+			return 4
+		}
+		return 0
+	}
+	return category(a) != 0 && category(a) == category(b)
+}
+
+//hasUnexported 结构体是否包含未导出的字段
+func hasUnexported(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath != "" {
+			return true
+		}
 	}
+	return false
 }
@@ -7,7 +7,10 @@ package convert
 
 import (
 	"fmt"
+	"math/big"
+	"reflect"
 	"testing"
+	"time"
 )
 
 type Student struct {
@@ -28,3 +31,190 @@ func TestCopyProperties(t *testing.T) {
 	CopyProperties(&Student{Name: "zhangshan", Sex: "man", Age: 18}, target, "age")
 	fmt.Println(target)
 }
+
+type OrderItem struct {
+	SkuID int
+	Price float32
+}
+
+type Order struct {
+	Base
+	UserName string
+	Items    []OrderItem
+	Address  *Address
+	Remark   *string
+	Attrs    map[string]int
+}
+
+type OrderItemDTO struct {
+	SkuID int64
+	Price float64
+}
+
+type OrderDTO struct {
+	ID       int64
+	Created  string `copy:"CreatedAt"`
+	Name     string `copy:"UserName"`
+	Items    []OrderItemDTO
+	Address  Address
+	Remark   string
+	Attrs    map[string]int64
+	Internal string `copy:"-"`
+}
+
+func TestCopier_Copy(t *testing.T) {
+	copier := NewCopier()
+	RegisterConverter(copier, func(t time.Time) (string, error) {
+		return t.Format("2006-01-02"), nil
+	})
+	remark := "fast"
+	order := &Order{
+		Base:     Base{ID: 7, CreatedAt: time.Date(2020, 7, 15, 10, 0, 0, 0, time.UTC)},
+		UserName: "zhangshan",
+		Items:    []OrderItem{{SkuID: 1, Price: 1.5}, {SkuID: 2, Price: 2}},
+		Address:  &Address{City: "wuhan"},
+		Remark:   &remark,
+		Attrs:    map[string]int{"weight": 3},
+	}
+	dto := OrderDTO{Internal: "keep"}
+	if err := copier.Copy(order, &dto); err != nil {
+		t.Fatal(err)
+	}
+	want := OrderDTO{
+		ID:       7,
+		Created:  "2020-07-15",
+		Name:     "zhangshan",
+		Items:    []OrderItemDTO{{SkuID: 1, Price: 1.5}, {SkuID: 2, Price: 2}},
+		Address:  Address{City: "wuhan"},
+		Remark:   "fast",
+		Attrs:    map[string]int64{"weight": 3},
+		Internal: "keep",
+	}
+	if !reflect.DeepEqual(dto, want) {
+		t.Fatalf("got %+v\nwant %+v", dto, want)
+	}
+
+	var orders []OrderDTO
+	if err := copier.Copy([]*Order{order}, &orders); err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 1 || orders[0].Name != "zhangshan" {
+		t.Fatalf("unexpected slice copy %+v", orders)
+	}
+
+	//深拷贝，修改source不影响target
+	var clone Order
+	if err := copier.Copy(order, &clone); err != nil {
+		t.Fatal(err)
+	}
+	order.Items[0].SkuID, order.Address.City, order.Attrs["weight"] = 100, "beijing", 100
+	if clone.Items[0].SkuID != 1 || clone.Address.City != "wuhan" || clone.Attrs["weight"] != 3 || clone.Remark == order.Remark {
+		t.Fatalf("copy should not share memory with source: %+v", clone)
+	}
+}
+
+func TestCopier_Options(t *testing.T) {
+	target := Student{Name: "lisi", Age: 20, Sex: "woman"}
+	if err := CopyProperties(&Student{Name: "zhangshan"}, &target, "Sex"); err != nil {
+		t.Fatal(err)
+	}
+	if target != (Student{Name: "zhangshan", Age: 0, Sex: "woman"}) {
+		t.Fatalf("ignore failed: %+v", target)
+	}
+
+	target = Student{Name: "lisi", Age: 20, Sex: "woman"}
+	if err := defaultCopier.Copy(Student{Name: "zhangshan"}, &target, SkipZero()); err != nil {
+		t.Fatal(err)
+	}
+	if target != (Student{Name: "zhangshan", Age: 20, Sex: "woman"}) {
+		t.Fatalf("skip zero failed: %+v", target)
+	}
+
+	dto := OrderDTO{Address: Address{City: "wuhan"}, Remark: "keep"}
+	if err := defaultCopier.Copy(&Order{UserName: "wangwu"}, &dto, SkipNil()); err != nil {
+		t.Fatal(err)
+	}
+	if dto.Name != "wangwu" || dto.Address.City != "wuhan" || dto.Remark != "keep" {
+		t.Fatalf("skip nil failed: %+v", dto)
+	}
+
+	var copied StudentCopy
+	if err := defaultCopier.Copy(&Student{Sex: "man"}, &copied, Strict()); err == nil {
+		t.Fatal("strict copy should fail on string to int")
+	}
+	if err := CopyProperties(1, &copied); err == nil {
+		t.Fatal("copy int to struct should fail")
+	}
+	if err := CopyProperties(&Student{}, copied); err == nil {
+		t.Fatal("non pointer target should fail")
+	}
+}
+
+func TestCopier_NumericLoss(t *testing.T) {
+	type Wide struct {
+		Count int64
+		Ratio float64
+		Size  int
+	}
+	type Narrow struct {
+		Count int8
+		Ratio float32
+		Size  uint
+	}
+	for _, src := range []Wide{{Count: 300}, {Ratio: 1e300}, {Size: -1}} {
+		var dst Narrow
+		if err := CopyProperties(&src, &dst); err == nil {
+			t.Fatalf("copy %+v should fail, got %+v", src, dst)
+		}
+	}
+	var dst Narrow
+	if err := CopyProperties(&Wide{Count: -128, Ratio: 0.5, Size: 3}, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if dst != (Narrow{Count: -128, Ratio: 0.5, Size: 3}) {
+		t.Fatalf("unexpected result %+v", dst)
+	}
+
+	type Price struct{ Amount float64 }
+	type Cents struct{ Amount int }
+	var cents Cents
+	if err := CopyProperties(&Price{Amount: 1.5}, &cents); err == nil {
+		t.Fatal("copy 1.5 to int should fail")
+	}
+}
+
+type Parent struct {
+	*Child
+	Name string
+}
+
+type Child struct {
+	*Parent
+	Age int
+}
+
+func TestCopier_MutualEmbedding(t *testing.T) {
+	var dst Parent
+	if err := CopyProperties(&Parent{Name: "zhangshan", Child: &Child{Age: 18}}, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if dst.Name != "zhangshan" || dst.Child == nil || dst.Age != 18 {
+		t.Fatalf("unexpected result %+v", dst)
+	}
+}
+
+func TestCopier_UnexportedFields(t *testing.T) {
+	type Account struct {
+		Balance big.Int
+		Limit   *big.Int
+	}
+	src := Account{Limit: big.NewInt(100)}
+	src.Balance.SetInt64(42)
+	var dst Account
+	if err := CopyProperties(&src, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if dst.Balance.Int64() != 42 || dst.Limit == nil || dst.Limit.Int64() != 100 {
+		t.Fatalf("unexpected result %v %v", &dst.Balance, dst.Limit)
+	}
+}
@@ -1,76 +1,33 @@
 package convert
 
 import (
-	"reflect"
 	"strings"
 
 	"github.com/ghodss/yaml"
 )
 
 /**
- * 结构体转map
- * @param : obj 要转换的结构体
- * @return: 转换后的map
+ * 结构体转map，key为json或mapstructure tag的名称（没有tag时为字段名），嵌套的结构体会被转换成嵌套的map，
+ * 如需自定义tag，请使用NewMapper
+ * @param : obj 要转换的结构体或结构体指针
+ * @return: 转换后的map，obj不是结构体时返回nil
  * @author: yinjk
  * @time  : 2019/2/12 19:29
  */
 func StructToMap(obj interface{}) map[string]interface{} {
-	t := reflect.TypeOf(obj)
-	v := reflect.ValueOf(obj)
-	var data = make(map[string]interface{})
-	for i := 0; i < t.NumField(); i++ {
-		data[t.Field(i).Name] = v.Field(i).Interface()
-	}
+	data, _ := defaultMapper.Encode(obj)
 	return data
 }
 
-// 该实现为一个简陋版本，只能对基础类型的字段转换，高级版后续再添加
-// 相同的类型、名称的属性可以通过反射转换，其他字段忽略
-func MapToStruct(maps map[string]interface{}, obj interface{}) {
-	v := reflect.ValueOf(obj).Elem()
-	for i := 0; i < v.NumField(); i++ {
-		mapVal := maps[v.Type().Field(i).Name]
-		field := v.Field(i)
-		switch mapVal.(type) {
-		case string:
-			if field.Type().Name() == "string" {
-				field.SetString(mapVal.(string))
-			}
-		case int:
-			if field.Type().Name() == "int" {
-				field.SetInt(int64(mapVal.(int)))
-			}
-		case int32:
-			if field.Type().Name() == "int32" {
-				field.SetInt(int64(mapVal.(int32)))
-			}
-		case int64:
-			if field.Type().Name() == "int64" {
-				field.SetInt(int64(mapVal.(int64)))
-			}
-		case float64:
-			if field.Type().Name() == "float64" {
-				field.SetFloat(mapVal.(float64))
-			}
-		case float32:
-			if field.Type().Name() == "float32" {
-				field.SetFloat(float64(mapVal.(float32)))
-			}
-		case bool:
-			if field.Type().Name() == "bool" {
-				field.SetBool(mapVal.(bool))
-			}
-			field.SetBool(mapVal.(bool))
-		case []byte:
-			if field.Type().Name() == "[]byte" {
-				field.SetBytes(mapVal.([]byte))
-			}
-		case uint64:
-			if field.Type().Name() == "uint64" {
-				field.SetUint(mapVal.(uint64))
-			}
-		}
-	}
+/**
+ * map转结构体，按照json或mapstructure tag匹配字段（没有tag时按照字段名，忽略大小写和下划线），支持嵌套结构体、
+ * 匿名嵌入结构体、slice、map、指针、time.Time，并且会在string、数字、bool之间进行弱类型转换
+ * @param : maps 要转换的map
+ * @param : obj 目标结构体指针
+ * @return: err 转换失败的所有字段的错误，类型为*MapperError
+ */
+func MapToStruct(maps map[string]interface{}, obj interface{}) error {
+	return defaultMapper.Decode(maps, obj)
 }
 
 /**
@@ -2,6 +2,7 @@ package httpclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"github.com/yinjk/go-utils/pkg/net/common"
 	"github.com/prometheus/common/log"
@@ -10,6 +11,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
 const (
@@ -185,6 +187,24 @@ func Execute(method, url string, h Header, body io.Reader, rv interface{}) (*com
 	return parseResponse(response, rv)
 }
 
+//ExecuteContext 与Execute相同，请求随ctx取消，timeout为整个请求（包括读取响应体）的超时时间，0表示不超时
+func ExecuteContext(ctx context.Context, timeout time.Duration, method, url string, h Header, body io.Reader, rv interface{}) (*common.Result, error) {
+	client := &http.Client{Timeout: timeout}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range h {
+		req.Header[k] = v
+	}
+	//执行请求
+	response, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	return parseResponse(response, rv)
+}
+
 func EncodeUrl(url string, value *FormValue) string {
 	if value != nil {
 		encodeArgs := value.Encode()
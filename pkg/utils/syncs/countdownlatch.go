@@ -10,6 +10,8 @@ import (
 	"errors"
 	"sync"
 	"time"
+
+	"github.com/yinjk/go-utils/pkg/utils/times"
 )
 
 var ErrCountDownLatchCompleted = errors.New("count down latch already complete")
@@ -35,14 +37,17 @@ type CountDownLatch struct {
 	m                   sync.Mutex
 	remainingCount      uint
 	countDownCompleteCh chan struct{}
+	clock               times.Clock
 }
 
 // NewCountDownLatch creates a CountDownLatch with the provided count.
 // If the count is 0, the latch will be set to immediately signal count down completion for any goroutines that subsequently call Wait or WaitTimeout.
-func NewCountDownLatch(count uint) *CountDownLatch {
+// The optional clock is used by WaitTimeout, it defaults to times.SystemClock.
+func NewCountDownLatch(count uint, clock ...times.Clock) *CountDownLatch {
 	latch := &CountDownLatch{
 		remainingCount:      count,
 		countDownCompleteCh: make(chan struct{}),
+		clock:               times.ClockOrDefault(clock...),
 	}
 	if latch.remainingCount == 0 {
 		close(latch.countDownCompleteCh)
@@ -93,10 +98,12 @@ func (latch *CountDownLatch) Wait() {
 // Otherwise it returns false.
 // WaitTimeout returns immediatley if the count down has already been completed.
 func (latch *CountDownLatch) WaitTimeout(timeout time.Duration) bool {
+	timer := latch.clock.NewTimer(timeout)
+	defer timer.Stop()
 	select {
 	case <-latch.countDownCompleteCh:
 		return true
-	case <-time.After(timeout):
+	case <-timer.C():
 		return false
 	}
 }
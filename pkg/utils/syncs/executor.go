@@ -106,6 +106,16 @@ func (t TaskExecutor) Execute(f func()) {
 	t.taskQueue <- f
 }
 
+//TryExecute 提交任务，等待队列已满时不执行拒绝策略，直接返回false，由调用方决定如何处理该任务
+func (t TaskExecutor) TryExecute(f func()) bool {
+	select {
+	case t.taskQueue <- f:
+		return true
+	default:
+		return false
+	}
+}
+
 // 默认拒绝策略：将被拒绝的task重新加入task队列中，直到加入成功
 func (t TaskExecutor) defaultReject(f func()) {
 	t.taskQueue <- f
@@ -0,0 +1,122 @@
+/*
+ @Desc 工作日计算，节假日由可插拔的HolidayCalendar决定，默认的Calendar把周六周日当作休息日，
+ 并且可以添加法定节假日以及调休上班的日期。
+*/
+package times
+
+import (
+	"sync"
+	"time"
+)
+
+// HolidayCalendar decides whether a date is a non-business day.
+type HolidayCalendar interface {
+	// IsHoliday returns true if the date (only year, month and day are used) is not a business day.
+	IsHoliday(date time.Time) bool
+}
+
+// HolidayCalendarFunc adapts an ordinary function to a HolidayCalendar.
+type HolidayCalendarFunc func(date time.Time) bool
+
+func (f HolidayCalendarFunc) IsHoliday(date time.Time) bool {
+	return f(date)
+}
+
+// Calendar is a HolidayCalendar which treats weekends and the added holidays as non-business days,
+// the added workdays (e.g. weekend days worked in lieu of a holiday) are always business days.
+type Calendar struct {
+	sync.RWMutex
+	holidays map[string]bool
+	workdays map[string]bool
+}
+
+// NewCalendar creates a Calendar with only weekends as non-business days.
+func NewCalendar() *Calendar {
+	return &Calendar{holidays: make(map[string]bool), workdays: make(map[string]bool)}
+}
+
+// AddHolidays marks the dates as non-business days.
+func (c *Calendar) AddHolidays(dates ...time.Time) *Calendar {
+	c.Lock()
+	defer c.Unlock()
+	for _, date := range dates {
+		c.holidays[dateKey(date)] = true
+	}
+	return c
+}
+
+// AddWorkdays marks the dates as business days even if they are weekends.
+func (c *Calendar) AddWorkdays(dates ...time.Time) *Calendar {
+	c.Lock()
+	defer c.Unlock()
+	for _, date := range dates {
+		c.workdays[dateKey(date)] = true
+	}
+	return c
+}
+
+func (c *Calendar) IsHoliday(date time.Time) bool {
+	c.RLock()
+	defer c.RUnlock()
+	key := dateKey(date)
+	if c.workdays[key] {
+		return false
+	}
+	if c.holidays[key] {
+		return true
+	}
+	return isWeekend(date)
+}
+
+//IsBusinessDay 判断date是否是工作日，calendar为nil时只把周六周日当作休息日
+func IsBusinessDay(date time.Time, calendar HolidayCalendar) bool {
+	if calendar == nil {
+		return !isWeekend(date)
+	}
+	return !calendar.IsHoliday(date)
+}
+
+/**
+ * 在t的基础上增加n个工作日，n为负数时向前计算，时分秒保持不变
+ * @param : t 开始时间，t本身是否为工作日不影响计算结果
+ * @param : n 要增加的工作日天数
+ * @param : calendar 节假日日历，为nil时只把周六周日当作休息日
+ * @return: 增加n个工作日之后的时间
+ */
+func AddBusinessDays(t time.Time, n int, calendar HolidayCalendar) time.Time {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for n > 0 {
+		t = t.AddDate(0, 0, step)
+		if IsBusinessDay(t, calendar) {
+			n--
+		}
+	}
+	return t
+}
+
+//BusinessDaysBetween 计算[start, end)之间的工作日天数，end在start之前时返回负数
+func BusinessDaysBetween(start, end time.Time, calendar HolidayCalendar) int {
+	sign := 1
+	if end.Before(start) {
+		start, end, sign = end, start, -1
+	}
+	count := 0
+	for day := StartOfDay(start); day.Before(StartOfDay(end)); day = day.AddDate(0, 0, 1) {
+		if IsBusinessDay(day, calendar) {
+			count++
+		}
+	}
+	return count * sign
+}
+
+func isWeekend(date time.Time) bool {
+	weekday := date.Weekday()
+	return weekday == time.Saturday || weekday == time.Sunday
+}
+
+func dateKey(date time.Time) string {
+	return date.Format(DateFormat)
+}
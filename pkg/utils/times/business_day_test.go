@@ -0,0 +1,35 @@
+package times
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAddBusinessDays(t *testing.T) {
+	friday := time.Date(2020, 9, 25, 9, 0, 0, 0, time.UTC)
+	if got := AddBusinessDays(friday, 1, nil); !got.Equal(time.Date(2020, 9, 28, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("next business day of friday = %v", got)
+	}
+	if got := AddBusinessDays(friday.AddDate(0, 0, 3), -1, nil); !got.Equal(friday) {
+		t.Errorf("previous business day of monday = %v", got)
+	}
+	//国庆节：10.1-10.8放假，9.27（周日）、10.10（周六）调休上班
+	calendar := NewCalendar()
+	for day := 1; day <= 8; day++ {
+		calendar.AddHolidays(time.Date(2020, 10, day, 0, 0, 0, 0, time.UTC))
+	}
+	calendar.AddWorkdays(time.Date(2020, 9, 27, 0, 0, 0, 0, time.UTC), time.Date(2020, 10, 10, 0, 0, 0, 0, time.UTC))
+	if got := AddBusinessDays(friday, 1, calendar); !got.Equal(time.Date(2020, 9, 27, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("next business day with calendar = %v", got)
+	}
+	if got := AddBusinessDays(time.Date(2020, 9, 30, 9, 0, 0, 0, time.UTC), 1, calendar); !got.Equal(time.Date(2020, 10, 9, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("next business day after holidays = %v", got)
+	}
+	if n := BusinessDaysBetween(time.Date(2020, 9, 28, 0, 0, 0, 0, time.UTC), time.Date(2020, 10, 12, 0, 0, 0, 0, time.UTC), calendar); n != 5 {
+		t.Errorf("business days between = %d, want 5", n)
+	}
+	weekends := HolidayCalendarFunc(isWeekend)
+	if n := BusinessDaysBetween(friday.AddDate(0, 0, 7), friday, weekends); n != -5 {
+		t.Errorf("business days between reversed = %d, want -5", n)
+	}
+}
@@ -0,0 +1,267 @@
+/*
+ @Desc 时钟抽象，所有依赖时间的组件都通过Clock获取当前时间、创建定时器，
+ 生产环境使用SystemClock，测试中使用FakeClock手动拨动时间，避免在测试中通过time.Sleep等待
+*/
+package times
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock provides the current time and the timers built on top of it.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// Since returns the time elapsed since t.
+	Since(t time.Time) time.Duration
+	// Sleep pauses the current goroutine for at least the duration d.
+	Sleep(d time.Duration)
+	// After waits for the duration to elapse and then sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+	// NewTimer creates a new Timer that will send the current time on its channel after at least duration d.
+	NewTimer(d time.Duration) Timer
+	// NewTicker returns a new Ticker that sends the current time on its channel every period d.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is the Clock counterpart of time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker is the Clock counterpart of time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// SystemClock is the Clock backed by the time package, it is the default clock of every time-based component.
+var SystemClock Clock = systemClock{}
+
+// ClockOrDefault returns the first clock of the given optional clocks, or SystemClock if there is none.
+func ClockOrDefault(clock ...Clock) Clock {
+	if len(clock) > 0 && clock[0] != nil {
+		return clock[0]
+	}
+	return SystemClock
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (systemClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (systemClock) NewTimer(d time.Duration) Timer         { return systemTimer{time.NewTimer(d)} }
+func (systemClock) NewTicker(d time.Duration) Ticker       { return systemTicker{time.NewTicker(d)} }
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+type systemTicker struct {
+	*time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+// FakeClock is a manually driven Clock for tests, its time only moves when Advance or Set is called.
+// Timers, tickers, Sleep and After registered on the clock fire once the clock reaches their deadline.
+type FakeClock struct {
+	sync.Mutex
+	now       time.Time
+	waiters   []*fakeWaiter
+	waitersCh chan struct{} //每次waiters数量变化时关闭并重建，用于唤醒BlockUntil
+}
+
+// NewFakeClock creates a FakeClock whose current time is start, or a fixed date if start is zero.
+func NewFakeClock(start time.Time) *FakeClock {
+	if start.IsZero() {
+		start = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return &FakeClock{now: start, waitersCh: make(chan struct{})}
+}
+
+type fakeWaiter struct {
+	clock    *FakeClock
+	deadline time.Time
+	period   time.Duration //ticker的周期，timer为0
+	ch       chan time.Time
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	c.Lock()
+	defer c.Unlock()
+	w := &fakeWaiter{clock: c, ch: make(chan time.Time, 1)}
+	c.addWaiter(w, d)
+	return &fakeTimer{w}
+}
+
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for FakeClock.NewTicker")
+	}
+	c.Lock()
+	defer c.Unlock()
+	w := &fakeWaiter{clock: c, period: d, ch: make(chan time.Time, 1)}
+	c.addWaiter(w, d)
+	return &fakeTicker{w}
+}
+
+// Advance moves the clock forward by d and fires every timer and ticker whose deadline has been reached.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.setTime(c.now.Add(d))
+}
+
+// Set moves the clock to t, it is a no-op if t is before the current time.
+func (c *FakeClock) Set(t time.Time) {
+	c.Lock()
+	defer c.Unlock()
+	if t.After(c.now) {
+		c.setTime(t)
+	}
+}
+
+// BlockUntil blocks until at least n timers, tickers or sleepers are waiting on the clock.
+// It is used to make sure a goroutine under test has registered its timer before calling Advance.
+func (c *FakeClock) BlockUntil(n int) {
+	for {
+		c.Lock()
+		if len(c.waiters) >= n {
+			c.Unlock()
+			return
+		}
+		ch := c.waitersCh
+		c.Unlock()
+		<-ch
+	}
+}
+
+// Waiters returns the count of timers, tickers and sleepers currently waiting on the clock.
+func (c *FakeClock) Waiters() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.waiters)
+}
+
+// This call must be guarded using the clock mutex.
+func (c *FakeClock) setTime(t time.Time) {
+	c.now = t
+	// 按截止时间依次触发，ticker触发之后重新计算下一次的截止时间
+	for {
+		sort.SliceStable(c.waiters, func(i, j int) bool {
+			return c.waiters[i].deadline.Before(c.waiters[j].deadline)
+		})
+		if len(c.waiters) == 0 || c.waiters[0].deadline.After(c.now) {
+			break
+		}
+		w := c.waiters[0]
+		select {
+		case w.ch <- w.deadline:
+		default: //和time.Ticker一样，消费者来不及处理时丢弃本次触发
+		}
+		if w.period > 0 {
+			w.deadline = w.deadline.Add(w.period)
+		} else {
+			c.removeWaiter(w)
+		}
+	}
+}
+
+// This call must be guarded using the clock mutex.
+func (c *FakeClock) addWaiter(w *fakeWaiter, d time.Duration) {
+	w.deadline = c.now.Add(d)
+	if w.period == 0 && d <= 0 {
+		select {
+		case w.ch <- c.now:
+		default: //上一次触发的时间还没有被读取，和fire一样丢弃本次触发，不能在持有锁时阻塞
+		}
+		return
+	}
+	c.waiters = append(c.waiters, w)
+	c.notifyWaiters()
+}
+
+// This call must be guarded using the clock mutex.
+func (c *FakeClock) removeWaiter(w *fakeWaiter) bool {
+	for i, waiter := range c.waiters {
+		if waiter == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			c.notifyWaiters()
+			return true
+		}
+	}
+	return false
+}
+
+// This call must be guarded using the clock mutex.
+func (c *FakeClock) notifyWaiters() {
+	close(c.waitersCh)
+	c.waitersCh = make(chan struct{})
+}
+
+type fakeTimer struct {
+	*fakeWaiter
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.Lock()
+	defer t.clock.Unlock()
+	return t.clock.removeWaiter(t.fakeWaiter)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.Lock()
+	defer t.clock.Unlock()
+	active := t.clock.removeWaiter(t.fakeWaiter)
+	t.clock.addWaiter(t.fakeWaiter, d)
+	return active
+}
+
+type fakeTicker struct {
+	*fakeWaiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.Lock()
+	defer t.clock.Unlock()
+	t.clock.removeWaiter(t.fakeWaiter)
+}
@@ -0,0 +1,113 @@
+package times
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock_Timer(t *testing.T) {
+	clock := NewFakeClock(time.Time{})
+	timer := clock.NewTimer(time.Second)
+	clock.Advance(time.Second - time.Millisecond)
+	select {
+	case <-timer.C():
+		t.Fatal("timer should not fire before its deadline")
+	default:
+	}
+	clock.Advance(time.Millisecond)
+	select {
+	case <-timer.C():
+	default:
+		t.Fatal("timer should fire at its deadline")
+	}
+	if timer.Stop() {
+		t.Fatal("Stop should return false once the timer fired")
+	}
+}
+
+func TestFakeClock_ResetUndrained(t *testing.T) {
+	clock := NewFakeClock(time.Time{})
+	timer := clock.NewTimer(time.Second)
+	clock.Advance(time.Second)
+	done := make(chan struct{})
+	go func() {
+		timer.Reset(0) //已经触发但没有被读取的timer
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Reset(0) should not block on an undrained timer")
+	}
+	<-timer.C()
+	//时钟没有被锁住，仍然可以继续使用
+	clock.Advance(time.Second)
+	if timer.Reset(0); len(timer.C()) != 1 {
+		t.Fatal("Reset(0) should fire the drained timer immediately")
+	}
+}
+
+func TestFakeClock_Ticker(t *testing.T) {
+	clock := NewFakeClock(time.Time{})
+	ticker := clock.NewTicker(time.Second)
+	defer ticker.Stop()
+	for i := 0; i < 3; i++ {
+		clock.Advance(time.Second)
+		select {
+		case <-ticker.C():
+		default:
+			t.Fatalf("ticker should fire at tick %d", i)
+		}
+	}
+}
+
+func TestFakeClock_BlockUntil(t *testing.T) {
+	clock := NewFakeClock(time.Time{})
+	done := make(chan struct{})
+	go func() {
+		clock.Sleep(time.Minute)
+		close(done)
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	<-done
+	if clock.Waiters() != 0 {
+		t.Fatalf("expect no waiters, got %d", clock.Waiters())
+	}
+}
+
+func TestTimeOutFunc_FakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Time{})
+	release := make(chan struct{})
+	defer close(release)
+	errCh := make(chan error)
+	go func() {
+		errCh <- TimeOutFunc(func() error {
+			<-release
+			return nil
+		}, time.Second, clock)
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	if err := <-errCh; err != ErrorTimeOut {
+		t.Fatalf("expect ErrorTimeOut, got %v", err)
+	}
+}
+
+func TestStopWatch_FakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Time{})
+	stopWatch := NewStopWatch(true, clock)
+	stopWatch.StartTask("query")
+	clock.Advance(300 * time.Millisecond)
+	_ = stopWatch.StopTask()
+	stopWatch.StartTask("render")
+	clock.Advance(100 * time.Millisecond)
+	_ = stopWatch.StopTask()
+	report := stopWatch.Report()
+	if report.Total != 400*time.Millisecond || report.Tasks[0].Percent != 75 {
+		t.Fatalf("unexpected report:\n%v", report)
+	}
+	if stopWatch.GetTimeMillis() != 400 {
+		t.Fatalf("expect 400ms, got %d", stopWatch.GetTimeMillis())
+	}
+}
@@ -0,0 +1,78 @@
+/*
+ @Desc 获取一天、一周、一个月的开始和结束时间，以及将时间对齐到固定步长的边界（如prometheus的step）。
+ 所有方法的loc参数都是可选的，不传时使用t自身的时区。
+*/
+package times
+
+import "time"
+
+//StartOfDay 获取t所在当天的开始时间，即 00:00:00
+func StartOfDay(t time.Time, loc ...*time.Location) time.Time {
+	t = inLocation(t, loc...)
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+//EndOfDay 获取t所在当天的结束时间，即 23:59:59.999999999
+func EndOfDay(t time.Time, loc ...*time.Location) time.Time {
+	return StartOfDay(t, loc...).AddDate(0, 0, 1).Add(-time.Nanosecond)
+}
+
+//StartOfWeek 获取t所在周的开始时间，一周从周一开始
+func StartOfWeek(t time.Time, loc ...*time.Location) time.Time {
+	day := StartOfDay(t, loc...)
+	offset := (int(day.Weekday()) + 6) % 7 //距离周一的天数
+	return day.AddDate(0, 0, -offset)
+}
+
+//EndOfWeek 获取t所在周的结束时间，即周日的 23:59:59.999999999
+func EndOfWeek(t time.Time, loc ...*time.Location) time.Time {
+	return StartOfWeek(t, loc...).AddDate(0, 0, 7).Add(-time.Nanosecond)
+}
+
+//StartOfMonth 获取t所在月的开始时间，即1号的 00:00:00
+func StartOfMonth(t time.Time, loc ...*time.Location) time.Time {
+	t = inLocation(t, loc...)
+	year, month, _ := t.Date()
+	return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+}
+
+//EndOfMonth 获取t所在月的结束时间，即最后一天的 23:59:59.999999999
+func EndOfMonth(t time.Time, loc ...*time.Location) time.Time {
+	return StartOfMonth(t, loc...).AddDate(0, 1, 0).Add(-time.Nanosecond)
+}
+
+/**
+ * 将时间向前对齐到step的整数倍（以unix时间戳计算），与prometheus计算range query的时间点方式一致，
+ * 例如step为1分钟时，10:03:27 会对齐到 10:03:00
+ * @param : t 要对齐的时间
+ * @param : step 步长，小于等于0时原样返回
+ * @return: 对齐后的时间，时区与t相同
+ */
+func AlignStep(t time.Time, step time.Duration) time.Time {
+	if step <= 0 {
+		return t
+	}
+	nanos := t.UnixNano()
+	aligned := nanos - nanos%int64(step)
+	if nanos%int64(step) < 0 { //1970年之前的时间取模为负数
+		aligned -= int64(step)
+	}
+	return time.Unix(0, aligned).In(t.Location())
+}
+
+//AlignStepCeil 将时间向后对齐到step的整数倍，t刚好在边界上时原样返回
+func AlignStepCeil(t time.Time, step time.Duration) time.Time {
+	aligned := AlignStep(t, step)
+	if aligned.Before(t) {
+		aligned = aligned.Add(step)
+	}
+	return aligned
+}
+
+func inLocation(t time.Time, loc ...*time.Location) time.Time {
+	if len(loc) > 0 && loc[0] != nil {
+		return t.In(loc[0])
+	}
+	return t
+}
@@ -0,0 +1,43 @@
+package times
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStartAndEnd(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	//UTC的周三 20:00 是东八区周四的 04:00
+	now := time.Date(2020, 7, 8, 20, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name string
+		got  time.Time
+		want time.Time
+	}{
+		{"StartOfDay", StartOfDay(now, loc), time.Date(2020, 7, 9, 0, 0, 0, 0, loc)},
+		{"EndOfDay", EndOfDay(now), time.Date(2020, 7, 8, 23, 59, 59, 999999999, time.UTC)},
+		{"StartOfWeek", StartOfWeek(now, loc), time.Date(2020, 7, 6, 0, 0, 0, 0, loc)},
+		{"EndOfWeek", EndOfWeek(now), time.Date(2020, 7, 12, 23, 59, 59, 999999999, time.UTC)},
+		{"StartOfWeek on sunday", StartOfWeek(time.Date(2020, 7, 12, 1, 0, 0, 0, time.UTC)), time.Date(2020, 7, 6, 0, 0, 0, 0, time.UTC)},
+		{"StartOfMonth", StartOfMonth(now, loc), time.Date(2020, 7, 1, 0, 0, 0, 0, loc)},
+		{"EndOfMonth", EndOfMonth(time.Date(2020, 2, 10, 0, 0, 0, 0, time.UTC)), time.Date(2020, 2, 29, 23, 59, 59, 999999999, time.UTC)},
+	}
+	for _, c := range cases {
+		if !c.got.Equal(c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestAlignStep(t *testing.T) {
+	ts := time.Date(2020, 7, 8, 10, 3, 27, 0, time.UTC)
+	if got := AlignStep(ts, time.Minute); !got.Equal(time.Date(2020, 7, 8, 10, 3, 0, 0, time.UTC)) {
+		t.Errorf("AlignStep = %v", got)
+	}
+	if got := AlignStepCeil(ts, 15*time.Second); !got.Equal(time.Date(2020, 7, 8, 10, 3, 30, 0, time.UTC)) {
+		t.Errorf("AlignStepCeil = %v", got)
+	}
+	if got := AlignStepCeil(ts, time.Second); !got.Equal(ts) {
+		t.Errorf("AlignStepCeil on boundary = %v", got)
+	}
+}
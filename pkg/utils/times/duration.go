@@ -0,0 +1,117 @@
+/*
+ @Desc 人类可读的时间间隔，在time.ParseDuration的基础上支持天（d）、周（w）、年（y，固定为365天）单位，
+ 如："1d2h"、"3w"、"1h30m"、"500ms"
+*/
+package times
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Day  = 24 * time.Hour
+	Week = 7 * Day
+	Year = 365 * Day
+)
+
+var (
+	//按照单位从长到短匹配，防止"ms"被匹配成"m"
+	durationUnits = []struct {
+		unit     string
+		duration time.Duration
+	}{
+		{"ms", time.Millisecond}, {"us", time.Microsecond}, {"µs", time.Microsecond}, {"ns", time.Nanosecond},
+		{"y", Year}, {"w", Week}, {"d", Day}, {"h", time.Hour}, {"m", time.Minute}, {"s", time.Second},
+	}
+	//格式化时使用的单位，从大到小
+	formatUnits = []struct {
+		unit     string
+		duration time.Duration
+	}{
+		{"w", Week}, {"d", Day}, {"h", time.Hour}, {"m", time.Minute}, {"s", time.Second},
+		{"ms", time.Millisecond}, {"us", time.Microsecond}, {"ns", time.Nanosecond},
+	}
+)
+
+/**
+ * 解析人类可读的时间间隔，支持的单位：y、w、d、h、m、s、ms、us(µs)、ns，数字可以是小数，如："1.5h"
+ * @param : s 要解析的字符串，可以以"-"或"+"开头
+ * @return: 解析得到的时间间隔
+ */
+func ParseDuration(s string) (time.Duration, error) {
+	orig := s
+	s = strings.TrimSpace(s)
+	neg := false
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	if s == "0" {
+		return 0, nil
+	}
+	if s == "" {
+		return 0, fmt.Errorf("times: invalid duration %q", orig)
+	}
+	var total float64
+	for s != "" {
+		i := 0
+		for i < len(s) && (s[i] == '.' || ('0' <= s[i] && s[i] <= '9')) {
+			i++
+		}
+		if i == 0 {
+			return 0, fmt.Errorf("times: invalid duration %q", orig)
+		}
+		value, err := strconv.ParseFloat(s[:i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("times: invalid duration %q", orig)
+		}
+		s = s[i:]
+		matched := false
+		for _, u := range durationUnits {
+			if strings.HasPrefix(s, u.unit) {
+				total += value * float64(u.duration)
+				s = s[len(u.unit):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return 0, fmt.Errorf("times: missing or unknown unit in duration %q", orig)
+		}
+	}
+	if total > float64(1<<63-1) {
+		return 0, fmt.Errorf("times: invalid duration %q", orig)
+	}
+	if neg {
+		return -time.Duration(total), nil
+	}
+	return time.Duration(total), nil
+}
+
+/**
+ * 将时间间隔格式化成人类可读的字符串，是ParseDuration的逆操作，如：26h格式化成"1d2h"，504h格式化成"3w"
+ * @param : d 时间间隔
+ * @return: 格式化之后的字符串，0返回"0s"
+ */
+func FormatDuration(d time.Duration) string {
+	if d == 0 {
+		return "0s"
+	}
+	var sb strings.Builder
+	u := uint64(d)
+	if d < 0 {
+		sb.WriteByte('-')
+		u = uint64(-d)
+	}
+	for _, unit := range formatUnits {
+		if n := u / uint64(unit.duration); n > 0 {
+			sb.WriteString(strconv.FormatUint(n, 10))
+			sb.WriteString(unit.unit)
+			u -= n * uint64(unit.duration)
+		}
+	}
+	return sb.String()
+}
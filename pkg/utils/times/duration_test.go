@@ -0,0 +1,53 @@
+package times
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	cases := map[string]time.Duration{
+		"1d2h":     26 * time.Hour,
+		"3w":       21 * Day,
+		"1h30m":    90 * time.Minute,
+		"1.5h":     90 * time.Minute,
+		"500ms":    500 * time.Millisecond,
+		"-1d":      -Day,
+		"1y":       365 * Day,
+		"0":        0,
+		"2m10s5µs": 2*time.Minute + 10*time.Second + 5*time.Microsecond,
+	}
+	for s, want := range cases {
+		got, err := ParseDuration(s)
+		if err != nil {
+			t.Errorf("ParseDuration(%q) error: %v", s, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseDuration(%q) = %v, want %v", s, got, want)
+		}
+	}
+	for _, s := range []string{"", "d", "1", "1x", "1d-2h"} {
+		if _, err := ParseDuration(s); err == nil {
+			t.Errorf("ParseDuration(%q) should fail", s)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	cases := map[time.Duration]string{
+		0:                                      "0s",
+		26 * time.Hour:                         "1d2h",
+		21 * Day:                               "3w",
+		8*Day + time.Minute + time.Millisecond: "1w1d1m1ms",
+		-90 * time.Second:                      "-1m30s",
+	}
+	for d, want := range cases {
+		if got := FormatDuration(d); got != want {
+			t.Errorf("FormatDuration(%v) = %q, want %q", d, got, want)
+		}
+		if back, _ := ParseDuration(want); back != d {
+			t.Errorf("ParseDuration(%q) = %v, want %v", want, back, d)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+/*
+ @Desc 使用java（yyyy-MM-dd HH:mm:ss）或strftime（%Y-%m-%d %H:%M:%S）风格的格式字符串格式化和解析时间，
+ 格式字符串会被转换成go的layout（2006-01-02 15:04:05）并缓存起来。
+ 注意：转换后的layout中如果包含go layout的保留字（如：1、2、Jan、Mon等），这些字符会被go当作时间字段处理，
+ java风格的格式中可以使用单引号包裹字面量，但字面量本身仍不能包含上述保留字。
+*/
+package times
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	javaLayouts     sync.Map
+	strftimeLayouts sync.Map
+
+	strftimeDirectives = map[byte]string{
+		'Y': "2006", 'y': "06",
+		'm': "01", 'b': "Jan", 'h': "Jan", 'B': "January",
+		'd': "02", 'e': "_2",
+		'a': "Mon", 'A': "Monday",
+		'H': "15", 'I': "03", 'M': "04", 'S': "05", 'p': "PM",
+		'L': "000", 'f': "000000",
+		'z': "-0700", 'Z': "MST",
+		'F': "2006-01-02", 'T': "15:04:05", 'D': "01/02/06", 'R': "15:04",
+		'n': "\n", 't': "\t", '%': "%",
+	}
+)
+
+/**
+ * 将java风格的时间格式转换成go的layout，如：yyyy-MM-dd HH:mm:ss.SSS 转换成 2006-01-02 15:04:05.000
+ * @param : pattern java风格的时间格式，单引号中的内容作为字面量原样输出，两个连续的单引号表示一个单引号
+ * @return: go的layout
+ */
+func JavaLayout(pattern string) string {
+	if layout, ok := javaLayouts.Load(pattern); ok {
+		return layout.(string)
+	}
+	var sb strings.Builder
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+		if c == '\'' {
+			end := strings.IndexByte(pattern[i+1:], '\'')
+			if end == -1 { //没有闭合的单引号，剩余部分全部作为字面量
+				sb.WriteString(pattern[i+1:])
+				break
+			}
+			if end == 0 {
+				sb.WriteByte('\'')
+			} else {
+				sb.WriteString(pattern[i+1 : i+1+end])
+			}
+			i += end + 2
+			continue
+		}
+		n := 1 //连续相同模式字符的个数，如：yyyy为4
+		for i+n < len(pattern) && pattern[i+n] == c {
+			n++
+		}
+		sb.WriteString(javaToken(c, n))
+		i += n
+	}
+	layout := sb.String()
+	javaLayouts.Store(pattern, layout)
+	return layout
+}
+
+//javaToken 将n个连续的java模式字符c转换成go的layout，不是模式字符的原样输出
+func javaToken(c byte, n int) string {
+	switch c {
+	case 'y':
+		if n == 2 {
+			return "06"
+		}
+		return "2006"
+	case 'M':
+		return pick(n, "1", "01", "Jan", "January")
+	case 'd':
+		return pick(n, "2", "02")
+	case 'E':
+		return pick(n, "Mon", "Mon", "Mon", "Monday")
+	case 'H':
+		return "15"
+	case 'h':
+		return pick(n, "3", "03")
+	case 'm':
+		return pick(n, "4", "04")
+	case 's':
+		return pick(n, "5", "05")
+	case 'S': //毫秒、微秒等小数部分，有几个S就保留几位小数
+		return strings.Repeat("0", n)
+	case 'a':
+		return "PM"
+	case 'X':
+		return pick(n, "Z07", "Z0700", "Z07:00")
+	case 'Z':
+		return "-0700"
+	case 'z':
+		return "MST"
+	}
+	return strings.Repeat(string(c), n)
+}
+
+//pick 按照模式字符的个数选择对应的layout，个数超过候选项时使用最后一个
+func pick(n int, layouts ...string) string {
+	if n > len(layouts) {
+		n = len(layouts)
+	}
+	return layouts[n-1]
+}
+
+/**
+ * 将strftime风格的时间格式转换成go的layout，如：%Y-%m-%d %H:%M:%S 转换成 2006-01-02 15:04:05
+ * @param : format strftime风格的时间格式，不支持的指令原样输出
+ * @return: go的layout
+ */
+func StrftimeLayout(format string) string {
+	if layout, ok := strftimeLayouts.Load(format); ok {
+		return layout.(string)
+	}
+	var sb strings.Builder
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' || i == len(format)-1 {
+			sb.WriteByte(c)
+			continue
+		}
+		i++
+		if layout, ok := strftimeDirectives[format[i]]; ok {
+			sb.WriteString(layout)
+		} else {
+			sb.WriteByte('%')
+			sb.WriteByte(format[i])
+		}
+	}
+	layout := sb.String()
+	strftimeLayouts.Store(format, layout)
+	return layout
+}
+
+//FormatPattern 使用java风格的时间格式格式化时间，如：FormatPattern(t, "yyyy-MM-dd HH:mm:ss")
+func FormatPattern(t time.Time, pattern string) string {
+	return t.Format(JavaLayout(pattern))
+}
+
+//ParsePattern 使用java风格的时间格式解析时间，loc可选，不传时按照time.Local解析没有时区信息的时间
+func ParsePattern(pattern, value string, loc ...*time.Location) (time.Time, error) {
+	return time.ParseInLocation(JavaLayout(pattern), value, locationOrLocal(loc...))
+}
+
+//Strftime 使用strftime风格的时间格式格式化时间，如：Strftime(t, "%Y-%m-%d %H:%M:%S")
+func Strftime(t time.Time, format string) string {
+	return t.Format(StrftimeLayout(format))
+}
+
+//Strptime 使用strftime风格的时间格式解析时间，loc可选，不传时按照time.Local解析没有时区信息的时间
+func Strptime(format, value string, loc ...*time.Location) (time.Time, error) {
+	return time.ParseInLocation(StrftimeLayout(format), value, locationOrLocal(loc...))
+}
+
+func locationOrLocal(loc ...*time.Location) *time.Location {
+	if len(loc) > 0 && loc[0] != nil {
+		return loc[0]
+	}
+	return time.Local
+}
@@ -0,0 +1,59 @@
+package times
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJavaLayout(t *testing.T) {
+	cases := map[string]string{
+		"yyyy-MM-dd HH:mm:ss":      DateTimeFormat,
+		"yyyy-MM-dd":               DateFormat,
+		"HH:mm:ss.SSS":             "15:04:05.000",
+		"yy/M/d h:mm a":            "06/1/2 3:04 PM",
+		"EEE, dd MMM yyyy":         "Mon, 02 Jan 2006",
+		"yyyy-MM-dd'T'HH:mm:ssXXX": "2006-01-02T15:04:05Z07:00",
+		"yyyy''MM":                 "2006'01",
+		"EEEE MMMM":                "Monday January",
+	}
+	for pattern, layout := range cases {
+		if got := JavaLayout(pattern); got != layout {
+			t.Errorf("JavaLayout(%q) = %q, want %q", pattern, got, layout)
+		}
+	}
+}
+
+func TestStrftimeLayout(t *testing.T) {
+	cases := map[string]string{
+		"%Y-%m-%d %H:%M:%S": DateTimeFormat,
+		"%F %T":             DateTimeFormat,
+		"%d %b %y %I%p %%":  "02 Jan 06 03PM %",
+		"%q":                "%q",
+	}
+	for format, layout := range cases {
+		if got := StrftimeLayout(format); got != layout {
+			t.Errorf("StrftimeLayout(%q) = %q, want %q", format, got, layout)
+		}
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	parsed, err := ParsePattern("yyyy-MM-dd HH:mm:ss", "2020-07-08 16:20:00", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.Equal(time.Date(2020, 7, 8, 8, 20, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected parsed time %v", parsed)
+	}
+	if s := FormatPattern(parsed, "yyyy/MM/dd HH:mm"); s != "2020/07/08 16:20" {
+		t.Fatalf("unexpected formatted time %s", s)
+	}
+	parsed, err = Strptime("%Y%m%d", "20200708", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := Strftime(parsed, "%F"); s != "2020-07-08" {
+		t.Fatalf("unexpected formatted time %s", s)
+	}
+}
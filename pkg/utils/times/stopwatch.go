@@ -19,17 +19,21 @@ type StopWatch struct {
 	printMethod     func(sw StopWatch, message string)
 	lastPrintTime   time.Time //最后一次打印的时间
 	active          bool
+	tasks           []*TaskInfo //已记录的顶层任务，嵌套任务挂在各自父任务的Children下
+	runningTasks    []*TaskInfo //正在执行的任务栈，栈顶为最内层任务
+	clock           Clock
 }
 
 /**
  * 创建一个秒表，并且自动开始计时
  * @param : active:是否激活秒表，默认传true，当我们解决了性能问题，不想再打印时间日志时，将这里改为false即可（不用再手动去删除每个打印方法）
+ * @param : clock:可选，秒表使用的时钟，默认为SystemClock
  * @return:
  * @author: yinjk
  * @time  : 2019/4/19 16:59
  */
-func NewStopWatch(active bool) *StopWatch {
-	watch := &StopWatch{stopped: false, active: active}
+func NewStopWatch(active bool, clock ...Clock) *StopWatch {
+	watch := &StopWatch{stopped: false, active: active, clock: ClockOrDefault(clock...)}
 	watch.Start()
 	return watch
 }
@@ -45,7 +49,7 @@ func (sw *StopWatch) Start() {
 	if !sw.active {
 		return
 	}
-	sw.startTime = time.Now()
+	sw.startTime = sw.now()
 	sw.stopped = false
 	sw.lastPrintTime = sw.startTime
 }
@@ -69,7 +73,7 @@ func (sw *StopWatch) Stop() {
 	if !sw.active {
 		return
 	}
-	sw.lastTime = time.Now()
+	sw.lastTime = sw.now()
 	sw.stopped = true
 }
 
@@ -159,7 +163,7 @@ func (sw *StopWatch) PrettyPrint(message string) {
 		//这里不直接传指针是为了防止用户在printMethod中去操作我们的秒表，比如：将秒表停掉
 		sw.printMethod(*sw, message)
 	}
-	sw.lastPrintTime = time.Now()
+	sw.lastPrintTime = sw.now()
 }
 
 /**
@@ -186,22 +190,91 @@ func (sw StopWatch) GetDefaultPrintFormat() func(sw StopWatch, message string) {
  */
 func (sw StopWatch) GetDurationPrintFormat() func(sw StopWatch, message string) {
 	return func(sw StopWatch, message string) {
-		duration := time.Now().Sub(sw.lastPrintTime).Nanoseconds() / 1000000 //计算与上一次打印之间的时间差
+		duration := sw.now().Sub(sw.lastPrintTime).Nanoseconds() / 1000000 //计算与上一次打印之间的时间差
 		fmt.Println("--------------------------------------------")
 		fmt.Println("duration: " + strconv.Itoa(int(duration)) + "ms   %   " + message)
 		fmt.Println("--------------------------------------------")
 	}
 }
 
+/**
+ * 开始一个任务分段，如果当前已有正在执行的任务，新任务会作为其子任务（嵌套任务）记录
+ * @param : name 任务名称
+ * @return:
+ */
+func (sw *StopWatch) StartTask(name string) {
+	if !sw.active {
+		return
+	}
+	task := &TaskInfo{Name: name, startTime: sw.now(), running: true}
+	if n := len(sw.runningTasks); n > 0 {
+		parent := sw.runningTasks[n-1]
+		parent.Children = append(parent.Children, task)
+	} else {
+		sw.tasks = append(sw.tasks, task)
+	}
+	sw.runningTasks = append(sw.runningTasks, task)
+	sw.currentTaskName = name
+}
+
+/**
+ * 结束当前最内层的任务分段，并记录该任务的耗时
+ * @param :
+ * @return: err 当前没有正在执行的任务时返回ErrNoRunningTask
+ */
+func (sw *StopWatch) StopTask() error {
+	if !sw.active {
+		return nil
+	}
+	n := len(sw.runningTasks)
+	if n == 0 {
+		return ErrNoRunningTask
+	}
+	task := sw.runningTasks[n-1]
+	task.Duration = sw.now().Sub(task.startTime)
+	task.running = false
+	sw.runningTasks = sw.runningTasks[:n-1]
+	sw.currentTaskName = ""
+	if n > 1 {
+		sw.currentTaskName = sw.runningTasks[n-2].Name
+	}
+	return nil
+}
+
+//CurrentTaskName 当前正在执行的最内层任务名称，没有任务在执行时返回空字符串
+func (sw StopWatch) CurrentTaskName() string {
+	return sw.currentTaskName
+}
+
+/**
+ * 生成任务耗时报告，报告中包含每个任务（及其嵌套任务）的耗时和占总耗时的百分比，
+ * 总耗时为所有顶层任务耗时之和，尚未结束的任务按截止到当前的耗时计算
+ * @param :
+ * @return: 任务耗时报告，可以通过String、JSON、Observe等方法输出
+ */
+func (sw StopWatch) Report() *Report {
+	now := sw.now()
+	report := &Report{Tasks: copyTasks(sw.tasks, now)}
+	for _, task := range report.Tasks {
+		report.Total += task.Duration
+	}
+	fillPercent(report.Tasks, report.Total)
+	return report
+}
+
 func (sw StopWatch) LastTime() time.Time {
 	return sw.lastPrintTime
 }
+func (sw StopWatch) now() time.Time {
+	return ClockOrDefault(sw.clock).Now()
+}
+
 func (sw StopWatch) getDuration() time.Duration {
 	var duration time.Duration
 	if sw.stopped {
 		duration = sw.lastTime.Sub(sw.startTime)
 	} else {
-		duration = time.Now().Sub(sw.startTime)
+		duration = sw.now().Sub(sw.startTime)
 	}
 	return duration
 }
@@ -0,0 +1,116 @@
+/**
+ * 秒表的任务分段报告
+ */
+package times
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrNoRunningTask = errors.New("stop watch has no running task")
+
+//TaskInfo 秒表中记录的一个任务分段
+type TaskInfo struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"` //任务耗时，单位纳秒
+	Percent  float64       `json:"percent"`  //占报告总耗时的百分比（0-100）
+	Running  bool          `json:"running"`  //生成报告时任务是否还在执行
+	Children []*TaskInfo   `json:"children,omitempty"`
+
+	startTime time.Time
+	running   bool
+}
+
+//Report 秒表的任务耗时报告
+type Report struct {
+	Total time.Duration `json:"total"` //所有顶层任务的耗时之和，单位纳秒
+	Tasks []*TaskInfo   `json:"tasks"`
+}
+
+/**
+ * 以文本表格的形式输出报告，嵌套任务会按层级缩进
+ * @param :
+ * @return:
+ */
+func (r *Report) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("StopWatch: running time = %dms\n", r.Total.Milliseconds()))
+	sb.WriteString("---------------------------------------------\n")
+	sb.WriteString("ms         %        Task name\n")
+	sb.WriteString("---------------------------------------------\n")
+	r.walk(func(path []string, task *TaskInfo) {
+		name := strings.Repeat("  ", len(path)-1) + task.Name
+		if task.Running {
+			name += " (running)"
+		}
+		sb.WriteString(fmt.Sprintf("%-10d %6.2f%%  %s\n", task.Duration.Milliseconds(), task.Percent, name))
+	})
+	return sb.String()
+}
+
+//JSON 以json格式输出报告
+func (r *Report) JSON() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+/**
+ * 将每个任务的耗时（秒）交给observe处理，一般用于记录到prometheus的histogram中，
+ * 嵌套任务的名称为以"/"连接的完整路径，如："handler/query"
+ * For example:
+ *
+ * histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "handler_task_seconds"}, []string{"task"})
+ * sw.Report().Observe(func(task string, seconds float64) {
+ *     histogram.WithLabelValues(task).Observe(seconds)
+ * })
+ * @param : observe 接收任务名称和耗时秒数的回调
+ * @return:
+ */
+func (r *Report) Observe(observe func(task string, seconds float64)) {
+	r.walk(func(path []string, task *TaskInfo) {
+		observe(strings.Join(path, "/"), task.Duration.Seconds())
+	})
+}
+
+//walk 深度优先遍历报告中的所有任务，path为从顶层任务到当前任务的名称路径
+func (r *Report) walk(visit func(path []string, task *TaskInfo)) {
+	var walk func(path []string, tasks []*TaskInfo)
+	walk = func(path []string, tasks []*TaskInfo) {
+		for _, task := range tasks {
+			current := append(path[:len(path):len(path)], task.Name)
+			visit(current, task)
+			walk(current, task.Children)
+		}
+	}
+	walk(nil, r.Tasks)
+}
+
+//copyTasks 拷贝一份任务树用于生成报告，防止报告生成之后秒表继续修改任务数据
+func copyTasks(tasks []*TaskInfo, now time.Time) []*TaskInfo {
+	if len(tasks) == 0 {
+		return nil
+	}
+	result := make([]*TaskInfo, len(tasks))
+	for i, task := range tasks {
+		c := &TaskInfo{Name: task.Name, Duration: task.Duration, Running: task.running}
+		if task.running {
+			c.Duration = now.Sub(task.startTime)
+		}
+		c.Children = copyTasks(task.Children, now)
+		result[i] = c
+	}
+	return result
+}
+
+func fillPercent(tasks []*TaskInfo, total time.Duration) {
+	for _, task := range tasks {
+		if total > 0 {
+			task.Percent = float64(task.Duration) * 100 / float64(total)
+		}
+		fillPercent(task.Children, total)
+	}
+}
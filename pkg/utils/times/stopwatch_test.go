@@ -62,3 +62,47 @@ func TestStopWatch_GetTimeMillis(t *testing.T) {
 	stopWatch.PrettyPrint("重新设置回默认打印方式")
 	fmt.Println("程序执行结束！")
 }
+
+func TestStopWatch_Report(t *testing.T) {
+	stopWatch := NewStopWatch(true)
+	stopWatch.StartTask("handler")
+	stopWatch.StartTask("query")
+	time.Sleep(20 * time.Millisecond)
+	if err := stopWatch.StopTask(); err != nil {
+		t.Fatal(err)
+	}
+	stopWatch.StartTask("render")
+	time.Sleep(10 * time.Millisecond)
+	if err := stopWatch.StopTask(); err != nil {
+		t.Fatal(err)
+	}
+	if stopWatch.CurrentTaskName() != "handler" {
+		t.Fatalf("current task should be handler, got %s", stopWatch.CurrentTaskName())
+	}
+	if err := stopWatch.StopTask(); err != nil {
+		t.Fatal(err)
+	}
+	if err := stopWatch.StopTask(); err != ErrNoRunningTask {
+		t.Fatalf("expect ErrNoRunningTask, got %v", err)
+	}
+	report := stopWatch.Report()
+	fmt.Print(report)
+	if len(report.Tasks) != 1 || len(report.Tasks[0].Children) != 2 {
+		t.Fatalf("unexpected task tree: %+v", report.Tasks)
+	}
+	if report.Tasks[0].Percent != 100 {
+		t.Fatalf("top level task percent should be 100, got %v", report.Tasks[0].Percent)
+	}
+	observed := make(map[string]float64)
+	report.Observe(func(task string, seconds float64) {
+		observed[task] = seconds
+	})
+	if _, ok := observed["handler/query"]; !ok || len(observed) != 3 {
+		t.Fatalf("unexpected observed tasks: %v", observed)
+	}
+	bytes, err := report.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(bytes))
+}
@@ -12,9 +12,10 @@ var (
 	TimeFormat     = "15:04:05"
 )
 
-//TimeOutWithResult 超时机制，如果defaultResult为空，超时会返回ErrorTimeOut错误，如果defaultResult不为空，超时会返回defaultResult
-func TimeOutWithResult(provide func() (result interface{}, err error), timeout time.Duration, defaultResult interface{}) (result interface{}, err error) {
-	timer := time.NewTimer(timeout)
+//TimeOutWithResult 超时机制，如果defaultResult为空，超时会返回ErrorTimeOut错误，如果defaultResult不为空，超时会返回defaultResult，
+//clock可选，用于计时的时钟，默认为SystemClock
+func TimeOutWithResult(provide func() (result interface{}, err error), timeout time.Duration, defaultResult interface{}, clock ...Clock) (result interface{}, err error) {
+	timer := ClockOrDefault(clock...).NewTimer(timeout)
 	defer timer.Stop()
 	resultCh := make(chan interface{}, 1)
 	errCh := make(chan error, 1)
@@ -29,7 +30,7 @@ func TimeOutWithResult(provide func() (result interface{}, err error), timeout t
 	}(resultCh, errCh)
 
 	select {
-	case <-timer.C:
+	case <-timer.C():
 		//time out, return the defaultResult
 		if defaultResult != nil {
 			return defaultResult, nil
@@ -44,9 +45,9 @@ func TimeOutWithResult(provide func() (result interface{}, err error), timeout t
 	}
 }
 
-//TimeOutFunc 超时机制，通过timeout表示多少时间超时，超时会返回一个ErrorTimeOut错误
-func TimeOutFunc(provide func() (err error), timeout time.Duration) (err error) {
-	timer := time.NewTimer(timeout)
+//TimeOutFunc 超时机制，通过timeout表示多少时间超时，超时会返回一个ErrorTimeOut错误，clock可选，用于计时的时钟，默认为SystemClock
+func TimeOutFunc(provide func() (err error), timeout time.Duration, clock ...Clock) (err error) {
+	timer := ClockOrDefault(clock...).NewTimer(timeout)
 	defer timer.Stop()
 	errCh := make(chan error, 1)
 	go func(errCh chan error) {
@@ -55,7 +56,7 @@ func TimeOutFunc(provide func() (err error), timeout time.Duration) (err error)
 	}(errCh)
 
 	select {
-	case <-timer.C:
+	case <-timer.C():
 		//time out, return the defaultResult
 		return ErrorTimeOut
 	case err = <-errCh: